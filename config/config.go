@@ -2,7 +2,10 @@ package config
 
 import (
 	"fmt"
+	"log/slog"
 	"os"
+	"strings"
+	"time"
 )
 
 type Config struct {
@@ -13,6 +16,15 @@ type Config struct {
 	DBUser       string
 	DBPassword   string
 	DBName       string
+	PublicURL    string
+	// Cada cuánto se crean los tickets de las plantillas periódicas; 0 desactiva el planificador
+	SchedulerInterval time.Duration
+	// Nivel mínimo de los registros: debug, info, warn o error
+	LogLevel slog.Level
+	// Dirección del servidor de /metrics, aparte de la API para que no salga por el ingress
+	MetricsAddr string
+	// Destino OTLP de las trazas; vacío las desactiva
+	OTLPEndpoint string
 }
 
 func LoadConfig() *Config {
@@ -57,13 +69,58 @@ func LoadConfig() *Config {
 		os.Exit(1)
 	}
 
+	// URL pública de la aplicación, para los enlaces de feeds y calendarios
+	public_url := os.Getenv("PUBLIC_URL")
+	if public_url == "" {
+		public_url = "https://issues.mydomain.com"
+	}
+
+	// Intervalo del planificador de plantillas, un minuto por defecto
+	scheduler_interval := time.Minute
+	if value := os.Getenv("SCHEDULER_INTERVAL"); value != "" {
+		interval, err := time.ParseDuration(value)
+		if err != nil || interval < 0 {
+			fmt.Println("ERROR SCHEDULER_INTERVAL no es una duración válida (p. ej. 1m, 0 para desactivarlo)")
+			os.Exit(1)
+		}
+		scheduler_interval = interval
+	}
+
+	// Nivel de los registros, info por defecto
+	log_level := slog.LevelInfo
+	if value := os.Getenv("LOG_LEVEL"); value != "" {
+		if err := log_level.UnmarshalText([]byte(value)); err != nil {
+			fmt.Println("ERROR LOG_LEVEL no es un nivel válido (debug, info, warn o error)")
+			os.Exit(1)
+		}
+	}
+
+	// Métricas de Prometheus en otro puerto, :9090 por defecto; "off" las desactiva
+	metrics_addr := os.Getenv("METRICS_ADDR")
+	if metrics_addr == "" {
+		metrics_addr = ":9090"
+	} else if metrics_addr == "off" {
+		metrics_addr = ""
+	}
+
+	// Las trazas solo se exportan si hay un colector OTLP configurado con las variables estándar
+	otlp_endpoint := os.Getenv("OTEL_EXPORTER_OTLP_TRACES_ENDPOINT")
+	if otlp_endpoint == "" {
+		otlp_endpoint = os.Getenv("OTEL_EXPORTER_OTLP_ENDPOINT")
+	}
+
 	return &Config{
-		AuthToken:    auth_token,
-		ClientSecret: client_secret,
-		DBHost:       db_host,
-		DBPort:       "5432", // Puerto por defecto de PostgreSQL
-		DBUser:       db_user,
-		DBPassword:   db_password,
-		DBName:       db_name,
+		AuthToken:         auth_token,
+		ClientSecret:      client_secret,
+		DBHost:            db_host,
+		DBPort:            "5432", // Puerto por defecto de PostgreSQL
+		DBUser:            db_user,
+		DBPassword:        db_password,
+		DBName:            db_name,
+		PublicURL:         strings.TrimRight(public_url, "/"),
+		SchedulerInterval: scheduler_interval,
+		LogLevel:          log_level,
+		MetricsAddr:       metrics_addr,
+		OTLPEndpoint:      otlp_endpoint,
 	}
 }
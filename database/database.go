@@ -1,32 +1,42 @@
 package database
 
 import (
+	"context"
 	"database/sql"
 	"fmt"
 
 	"go-redmine-ish/config"
 
-	_ "github.com/lib/pq" // Driver de PostgreSQL
+	"github.com/lib/pq" // Driver de PostgreSQL
 )
 
 func InitDB(cfg *config.Config) (*sql.DB, error) {
+	return InitDBContext(context.Background(), cfg)
+}
+
+// InitDBContext abre la base de datos con las consultas medidas y trazadas como hijas del span de
+// ctx; los handlers pasan el contexto de la petición
+func InitDBContext(ctx context.Context, cfg *config.Config) (*sql.DB, error) {
 	// Cadena de conexión a PostgreSQL
 	connStr := fmt.Sprintf("host=%s port=%s user=%s password=%s dbname=%s sslmode=disable",
 		cfg.DBHost, cfg.DBPort, cfg.DBUser, cfg.DBPassword, cfg.DBName)
 
 	// Conectar a la base de datos
-	db, err := sql.Open("postgres", connStr)
+	connector, err := pq.NewConnector(connStr)
 	if err != nil {
 		return nil, fmt.Errorf("error al conectar a la base de datos: %v", err)
 	}
+	db := sql.OpenDB(&tracedConnector{connector: connector, ctx: ctx})
 
 	// Verificar la conexión
 	err = db.Ping()
 	if err != nil {
+		db.Close()
 		return nil, fmt.Errorf("error al verificar la conexión: %v", err)
 	}
 
 	// log.Println("Conexión a PostgreSQL establecida")
+	trackPool(db)
 
 	return db, nil
 }
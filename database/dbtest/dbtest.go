@@ -0,0 +1,62 @@
+// Package dbtest prepara la base de datos PostgreSQL de las pruebas que la necesitan
+package dbtest
+
+import (
+	"database/sql"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+
+	"go-redmine-ish/config"
+	"go-redmine-ish/database"
+	"go-redmine-ish/handlers"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Open devuelve la base de datos de pruebas con el esquema y los datos de ejemplo recién creados
+// por /init. /init borra todas las tablas, así que se usa TEST_POSTGRES_DB y nunca POSTGRES_DB; si
+// no está definida la prueba se omite. El servidor y las credenciales son los de POSTGRES_SERVICE,
+// POSTGRES_USER y POSTGRES_PASSWORD
+func Open(t testing.TB) *sql.DB {
+	t.Helper()
+
+	db_name := os.Getenv("TEST_POSTGRES_DB")
+	if db_name == "" {
+		t.Skip("TEST_POSTGRES_DB no está definido, se omite la prueba con base de datos")
+	}
+	cfg := &config.Config{
+		DBHost:     getenv("POSTGRES_SERVICE", "localhost"),
+		DBPort:     getenv("TEST_POSTGRES_PORT", "5432"),
+		DBUser:     getenv("POSTGRES_USER", "postgres"),
+		DBPassword: os.Getenv("POSTGRES_PASSWORD"),
+		DBName:     db_name,
+	}
+
+	// Crear el esquema como en un despliegue nuevo
+	gin.SetMode(gin.TestMode)
+	recorder := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(recorder)
+	c.Request = httptest.NewRequest(http.MethodGet, "/init", nil)
+	handlers.InitHandler(cfg)(c)
+	if recorder.Code != http.StatusOK {
+		t.Fatalf("/init respondió %d: %s", recorder.Code, recorder.Body.String())
+	}
+
+	// Inicializar la base de datos
+	db, err := database.InitDB(cfg)
+	if err != nil {
+		t.Fatalf("error al abrir la base de datos de pruebas: %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+
+	return db
+}
+
+func getenv(name, fallback string) string {
+	if value := os.Getenv(name); value != "" {
+		return value
+	}
+	return fallback
+}
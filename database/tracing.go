@@ -0,0 +1,171 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"runtime"
+	"sync"
+	"time"
+	"weak"
+
+	"go-redmine-ish/telemetry"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// tracedConnector envuelve el conector de PostgreSQL para medir las consultas y crear un span por
+// cada una. Los modelos no pasan contexto a db.Query, así que el padre de los spans es el contexto
+// con el que se abrió el pool, que en los handlers es el de la petición
+type tracedConnector struct {
+	connector driver.Connector
+	ctx       context.Context
+}
+
+func (t *tracedConnector) Connect(ctx context.Context) (driver.Conn, error) {
+	conn, err := t.connector.Connect(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return &tracedConn{conn: conn, ctx: t.ctx}, nil
+}
+
+func (t *tracedConnector) Driver() driver.Driver {
+	return t.connector.Driver()
+}
+
+// tracedConn reenvía todo a la conexión de pq y mide Query y Exec
+type tracedConn struct {
+	conn driver.Conn
+	ctx  context.Context
+}
+
+// startSpan abre el span de una sentencia y devuelve la función que lo cierra. A pq se le sigue
+// pasando el contexto original para no cambiar cuándo se cancelan las consultas
+func (t *tracedConn) startSpan(ctx context.Context, operation, query string) func(error) {
+	parent := ctx
+	if !trace.SpanContextFromContext(ctx).IsValid() {
+		parent = t.ctx
+	}
+	_, span := telemetry.Tracer().Start(parent, "db."+operation,
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(
+			attribute.String("db.system", "postgresql"),
+			attribute.String("db.operation", operation),
+			attribute.String("db.statement", query),
+		),
+	)
+	start := time.Now()
+
+	return func(err error) {
+		if err == driver.ErrSkip {
+			span.End()
+			return
+		}
+		telemetry.ObserveSince(telemetry.DBQueryDuration.WithLabelValues(operation), start)
+		if err != nil {
+			span.RecordError(err)
+			span.SetStatus(codes.Error, err.Error())
+		}
+		span.End()
+	}
+}
+
+func (t *tracedConn) QueryContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Rows, error) {
+	queryer, ok := t.conn.(driver.QueryerContext)
+	if !ok {
+		return nil, driver.ErrSkip
+	}
+	end := t.startSpan(ctx, "query", query)
+	rows, err := queryer.QueryContext(ctx, query, args)
+	end(err)
+	return rows, err
+}
+
+func (t *tracedConn) ExecContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Result, error) {
+	execer, ok := t.conn.(driver.ExecerContext)
+	if !ok {
+		return nil, driver.ErrSkip
+	}
+	end := t.startSpan(ctx, "exec", query)
+	result, err := execer.ExecContext(ctx, query, args)
+	end(err)
+	return result, err
+}
+
+func (t *tracedConn) PrepareContext(ctx context.Context, query string) (driver.Stmt, error) {
+	if preparer, ok := t.conn.(driver.ConnPrepareContext); ok {
+		return preparer.PrepareContext(ctx, query)
+	}
+	return t.conn.Prepare(query)
+}
+
+func (t *tracedConn) BeginTx(ctx context.Context, opts driver.TxOptions) (driver.Tx, error) {
+	if beginner, ok := t.conn.(driver.ConnBeginTx); ok {
+		return beginner.BeginTx(ctx, opts)
+	}
+	return t.conn.Begin()
+}
+
+func (t *tracedConn) Ping(ctx context.Context) error {
+	if pinger, ok := t.conn.(driver.Pinger); ok {
+		return pinger.Ping(ctx)
+	}
+	return nil
+}
+
+func (t *tracedConn) ResetSession(ctx context.Context) error {
+	if resetter, ok := t.conn.(driver.SessionResetter); ok {
+		return resetter.ResetSession(ctx)
+	}
+	return nil
+}
+
+func (t *tracedConn) IsValid() bool {
+	if validator, ok := t.conn.(driver.Validator); ok {
+		return validator.IsValid()
+	}
+	return true
+}
+
+func (t *tracedConn) Prepare(query string) (driver.Stmt, error) {
+	return t.conn.Prepare(query)
+}
+
+func (t *tracedConn) Begin() (driver.Tx, error) {
+	return t.conn.Begin()
+}
+
+func (t *tracedConn) Close() error {
+	return t.conn.Close()
+}
+
+// pools son los pools abiertos con InitDB que aún no ha recogido el recolector de basura. Se
+// guardan con referencias débiles para no impedir que se liberen tras db.Close()
+var pools sync.Map
+
+// trackPool añade un pool a los que se miden en PoolStats
+func trackPool(db *sql.DB) {
+	pointer := weak.Make(db)
+	pools.Store(pointer, struct{}{})
+	runtime.AddCleanup(db, func(pointer weak.Pointer[sql.DB]) { pools.Delete(pointer) }, pointer)
+	telemetry.DBPoolsOpened.Inc()
+}
+
+// PoolStats suma las conexiones de todos los pools abiertos. Cada petición abre su propio pool,
+// así que el total es lo que la aplicación tiene abierto contra PostgreSQL
+func PoolStats() sql.DBStats {
+	total := sql.DBStats{}
+	pools.Range(func(key, _ any) bool {
+		if db := key.(weak.Pointer[sql.DB]).Value(); db != nil {
+			stats := db.Stats()
+			total.OpenConnections += stats.OpenConnections
+			total.InUse += stats.InUse
+			total.Idle += stats.Idle
+		}
+		return true
+	})
+	return total
+}
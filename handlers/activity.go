@@ -0,0 +1,248 @@
+package handlers
+
+import (
+	"encoding/base64"
+	"fmt"
+	"go-redmine-ish/config"
+	"go-redmine-ish/database"
+	"go-redmine-ish/models"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+type GetActivityHandlerData struct {
+	Events     []models.ActivityEvent `json:"events"`
+	NextCursor string                 `json:"next_cursor,omitempty"`
+}
+
+// encodeActivityCursor convierte la posición de un evento en un cursor opaco
+func encodeActivityCursor(event models.ActivityEvent) string {
+	raw := fmt.Sprintf("%s|%s|%d", event.CreatedAt.Format(time.RFC3339Nano), event.Type, event.ID)
+	return base64.RawURLEncoding.EncodeToString([]byte(raw))
+}
+
+// decodeActivityCursor es la operación inversa de encodeActivityCursor
+func decodeActivityCursor(cursor string) (*models.ActivityCursor, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return nil, fmt.Errorf("invalid cursor")
+	}
+	parts := strings.Split(string(raw), "|")
+	if len(parts) != 3 {
+		return nil, fmt.Errorf("invalid cursor")
+	}
+	at, err := time.Parse(time.RFC3339Nano, parts[0])
+	if err != nil {
+		return nil, fmt.Errorf("invalid cursor")
+	}
+	id, err := strconv.Atoi(parts[2])
+	if err != nil {
+		return nil, fmt.Errorf("invalid cursor")
+	}
+	return &models.ActivityCursor{CreatedAt: at, Type: parts[1], ID: id}, nil
+}
+
+// activityFilterFromQuery lee los filtros comunes de la actividad: type, user_id, cursor y limit
+func activityFilterFromQuery(c *gin.Context) (models.ActivityFilter, error) {
+	filter := models.ActivityFilter{Limit: 25}
+
+	if q := c.Query("type"); q != "" {
+		for _, t := range strings.Split(q, ",") {
+			t = strings.TrimSpace(t)
+			valid := false
+			for _, known := range models.ActivityTypes {
+				if t == known {
+					valid = true
+					break
+				}
+			}
+			if !valid {
+				return filter, fmt.Errorf("invalid type %q, expected one of %s", t, strings.Join(models.ActivityTypes, ", "))
+			}
+			filter.Types = append(filter.Types, t)
+		}
+	}
+
+	if q := c.Query("user_id"); q != "" {
+		id, err := strconv.Atoi(q)
+		if err != nil {
+			return filter, fmt.Errorf("invalid user_id: %v", err)
+		}
+		filter.UserID = id
+	}
+
+	if q := c.Query("limit"); q != "" {
+		limit, err := strconv.Atoi(q)
+		if err != nil || limit <= 0 {
+			return filter, fmt.Errorf("invalid limit")
+		}
+		if limit > 100 {
+			limit = 100
+		}
+		filter.Limit = limit
+	}
+
+	if q := c.Query("cursor"); q != "" {
+		cursor, err := decodeActivityCursor(q)
+		if err != nil {
+			return filter, err
+		}
+		filter.After = cursor
+	}
+
+	return filter, nil
+}
+
+// activityResponse devuelve la página de eventos y el cursor de la siguiente, si la hay
+func activityResponse(c *gin.Context, events []models.ActivityEvent, limit int) {
+	data := GetActivityHandlerData{Events: events}
+	if len(events) == limit {
+		data.NextCursor = encodeActivityCursor(events[len(events)-1])
+	}
+	c.JSON(http.StatusOK, data)
+}
+
+// @Summary: GetProjectActivityHandler
+// @Description: Get the activity of a project, most recent first
+// @Tags: activity
+// @Produce: json
+// @Param id path int true "Project ID"
+// @Param include_subprojects query bool false "Include the activity of subprojects"
+// @Param type query string false "Comma separated event types: issue, status, comment, time_entry, membership"
+// @Param user_id query int false "User who caused the event"
+// @Param cursor query string false "next_cursor of the previous page"
+// @Param limit query int false "Page size (max 100)"
+// @Success 200 {object} GetActivityHandlerData
+// @Failure 400 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Router /project/{id}/activity [get]
+// @Security BearerAuth
+func GetProjectActivityHandler(cfg *config.Config) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		pid := c.Param("id")
+
+		// pasar string id a int id
+		id, err := strconv.Atoi(pid)
+		if err != nil {
+			c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		filter, err := activityFilterFromQuery(c)
+		if err != nil {
+			c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		// Inicializar la base de datos
+		db, err := database.InitDBContext(c.Request.Context(), cfg)
+		if err != nil {
+			c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		defer db.Close()
+
+		project, err := models.GetProjectByID(db, id)
+		if err != nil {
+			c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+
+		visible, err := visibleProjectIDs(c, db)
+		if err != nil {
+			c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		isVisible := func(projectID int) bool {
+			if visible == nil {
+				return true
+			}
+			for _, v := range visible {
+				if v == projectID {
+					return true
+				}
+			}
+			return false
+		}
+
+		if project == nil || !isVisible(id) {
+			c.AbortWithStatusJSON(http.StatusNotFound, gin.H{"error": "Project not found"})
+			return
+		}
+
+		filter.ProjectIDs = []int{id}
+		if include, _ := strconv.ParseBool(c.Query("include_subprojects")); include {
+			ids, err := models.GetProjectDescendantIDs(db, id)
+			if err != nil {
+				c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+				return
+			}
+			// Solo los subproyectos que también puede ver el usuario
+			filter.ProjectIDs = filter.ProjectIDs[:0]
+			for _, child := range ids {
+				if isVisible(child) {
+					filter.ProjectIDs = append(filter.ProjectIDs, child)
+				}
+			}
+		}
+
+		events, err := models.GetActivity(db, filter)
+		if err != nil {
+			c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+
+		activityResponse(c, events, filter.Limit)
+	}
+}
+
+// @Summary: GetActivityHandler
+// @Description: Get the activity of every project visible to the caller, most recent first
+// @Tags: activity
+// @Produce: json
+// @Param type query string false "Comma separated event types: issue, status, comment, time_entry, membership"
+// @Param user_id query int false "User who caused the event"
+// @Param cursor query string false "next_cursor of the previous page"
+// @Param limit query int false "Page size (max 100)"
+// @Success 200 {object} GetActivityHandlerData
+// @Failure 400 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Router /activity [get]
+// @Security BearerAuth
+func GetActivityHandler(cfg *config.Config) gin.HandlerFunc {
+	return func(c *gin.Context) {
+
+		filter, err := activityFilterFromQuery(c)
+		if err != nil {
+			c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		// Inicializar la base de datos
+		db, err := database.InitDBContext(c.Request.Context(), cfg)
+		if err != nil {
+			c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		defer db.Close()
+
+		filter.ProjectIDs, err = visibleProjectIDs(c, db)
+		if err != nil {
+			c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+
+		events, err := models.GetActivity(db, filter)
+		if err != nil {
+			c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+
+		activityResponse(c, events, filter.Limit)
+	}
+}
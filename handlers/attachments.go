@@ -0,0 +1,114 @@
+package handlers
+
+import (
+	"go-redmine-ish/config"
+	"go-redmine-ish/database"
+	"go-redmine-ish/models"
+	"mime"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+// @Summary: GetIssueAttachmentsHandler
+// @Description: Get the attachments of an issue, without their content (e.g. the files of the emails received by the mail gateway)
+// @Tags: issues
+// @Produce: json
+// @Param id path int true "Issue ID"
+// @Success 200 {array} models.Attachment
+// @Failure 400 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Router /issue/{id}/attachments [get]
+// @Security BearerAuth
+func GetIssueAttachmentsHandler(cfg *config.Config) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		pid := c.Param("id")
+
+		// pasar string id a int id
+		id, err := strconv.Atoi(pid)
+		if err != nil {
+			c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		// Inicializar la base de datos
+		db, err := database.InitDBContext(c.Request.Context(), cfg)
+		if err != nil {
+			c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		defer db.Close()
+
+		if _, status, msg := getVisibleIssue(c, db, id); status != 0 {
+			c.AbortWithStatusJSON(status, gin.H{"error": msg})
+			return
+		}
+
+		attachments, err := models.GetAttachmentsByIssueID(db, id)
+		if err != nil {
+			c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+
+		c.JSON(http.StatusOK, attachments)
+	}
+}
+
+// @Summary: DownloadAttachmentHandler
+// @Description: Download the content of an attachment
+// @Tags: issues
+// @Produce: octet-stream
+// @Param id path int true "Attachment ID"
+// @Success 200 {file} file
+// @Failure 400 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Router /attachment/{id} [get]
+// @Security BearerAuth
+func DownloadAttachmentHandler(cfg *config.Config) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		pid := c.Param("id")
+
+		// pasar string id a int id
+		id, err := strconv.Atoi(pid)
+		if err != nil {
+			c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		// Inicializar la base de datos
+		db, err := database.InitDBContext(c.Request.Context(), cfg)
+		if err != nil {
+			c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		defer db.Close()
+
+		attachment, err := models.GetAttachmentByID(db, id)
+		if err != nil {
+			c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		if attachment == nil {
+			c.AbortWithStatusJSON(http.StatusNotFound, gin.H{"error": "Attachment not found"})
+			return
+		}
+		if _, status, _ := getVisibleIssue(c, db, attachment.IssueID); status != 0 {
+			c.AbortWithStatusJSON(http.StatusNotFound, gin.H{"error": "Attachment not found"})
+			return
+		}
+
+		content, err := models.GetAttachmentContent(db, id)
+		if err != nil {
+			c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+
+		// Siempre como descarga: el contenido viene de fuera y no debe mostrarse en el navegador
+		c.Header("Content-Disposition", mime.FormatMediaType("attachment", map[string]string{"filename": attachment.Filename}))
+		c.Header("X-Content-Type-Options", "nosniff")
+		c.Data(http.StatusOK, attachment.ContentType, content)
+	}
+}
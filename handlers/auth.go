@@ -1,7 +1,10 @@
 package handlers
 
 import (
+	"database/sql"
 	"go-redmine-ish/config"
+	"go-redmine-ish/middleware"
+	"go-redmine-ish/models"
 	"net/http"
 
 	"github.com/gin-gonic/gin"
@@ -21,3 +24,53 @@ func GetAuthHandler(cfg *config.Config) gin.HandlerFunc {
 		c.JSON(http.StatusOK, gin.H{"status": "success"})
 	}
 }
+
+// currentUserID devuelve el ID del usuario autenticado o nil si la petición usa el token de servicio
+func currentUserID(c *gin.Context) *int {
+	value, ok := c.Get(middleware.UserIDKey)
+	if !ok {
+		return nil
+	}
+	id, ok := value.(int)
+	if !ok || id == 0 {
+		return nil
+	}
+	return &id
+}
+
+// visibleProjectIDs devuelve los proyectos que puede ver el usuario autenticado
+// (aquellos de los que es miembro), o nil si puede verlos todos: token de
+// servicio o usuario con el rol global Admin
+func visibleProjectIDs(c *gin.Context, db *sql.DB) ([]int, error) {
+	user_id := currentUserID(c)
+	if user_id == nil {
+		return nil, nil
+	}
+
+	admin, err := models.IsAdminUser(db, *user_id)
+	if err != nil {
+		return nil, err
+	}
+	if admin {
+		return nil, nil
+	}
+
+	return models.GetProjectIDsByMemberUserID(db, *user_id)
+}
+
+// canViewProject indica si el usuario autenticado puede ver el proyecto
+func canViewProject(c *gin.Context, db *sql.DB, projectID int) (bool, error) {
+	ids, err := visibleProjectIDs(c, db)
+	if err != nil {
+		return false, err
+	}
+	if ids == nil {
+		return true, nil
+	}
+	for _, id := range ids {
+		if id == projectID {
+			return true, nil
+		}
+	}
+	return false, nil
+}
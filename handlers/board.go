@@ -0,0 +1,514 @@
+package handlers
+
+import (
+	"fmt"
+	"go-redmine-ish/config"
+	"go-redmine-ish/database"
+	"go-redmine-ish/models"
+	"net/http"
+	"sort"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+// BoardColumn es una columna del tablero: un estado con su límite de tickets en curso
+type BoardColumn struct {
+	StatusID  int    `json:"status_id"`
+	Name      string `json:"name"`
+	IsClosed  bool   `json:"is_closed"`
+	WIPLimit  *int   `json:"wip_limit"`
+	Count     int    `json:"count"`
+	OverLimit bool   `json:"over_limit"`
+}
+
+// BoardCell son los tickets de un carril en una columna, por su posición
+type BoardCell struct {
+	StatusID int            `json:"status_id"`
+	Issues   []models.Issue `json:"issues"`
+}
+
+// BoardLane es un carril del tablero. ID es nil para los tickets sin categoría o sin asignar
+type BoardLane struct {
+	ID    *int        `json:"id"`
+	Name  string      `json:"name"`
+	Cells []BoardCell `json:"cells"`
+}
+
+type GetProjectBoardHandlerData struct {
+	ProjectID int           `json:"project_id"`
+	Swimlane  string        `json:"swimlane"`
+	Columns   []BoardColumn `json:"columns"`
+	Lanes     []BoardLane   `json:"lanes"`
+}
+
+type BoardWIPLimitsData struct {
+	WIPLimits []models.BoardWIPLimit `json:"wip_limits"`
+}
+
+// BoardMovePayload mueve un ticket a una columna y a una posición dentro de ella
+type BoardMovePayload struct {
+	IssueID     int  `json:"issue_id" binding:"required"`
+	StatusID    int  `json:"status_id"`    // 0 para quedarse en la misma columna
+	Position    int  `json:"position"`     // desde 1; 0 o mayor que la columna lo pone al final
+	LockVersion *int `json:"lock_version"` // opcional, también se admite If-Match
+}
+
+// @Summary: GetProjectBoardHandler
+// @Description: Get the agile board of a project: one column per issue status with its WIP limit, and swimlanes by category, assignee or none. Issues are ordered by their position in the column. Accepts the same filters as GET /issues.
+// @Tags: board
+// @Produce: json
+// @Param id path int true "Project ID"
+// @Param swimlane query string false "category, assignee or none (default)"
+// @Param tracker_id query int false "Tracker ID"
+// @Param assigned_to_id query int false "Assigned user ID"
+// @Success 200 {object} GetProjectBoardHandlerData
+// @Failure 400 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Router /project/{id}/board [get]
+// @Security BearerAuth
+func GetProjectBoardHandler(cfg *config.Config) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		pid := c.Param("id")
+
+		// pasar string id a int id
+		id, err := strconv.Atoi(pid)
+		if err != nil {
+			c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		swimlane := c.DefaultQuery("swimlane", "none")
+		if swimlane != "none" && swimlane != "category" && swimlane != "assignee" {
+			c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": "swimlane must be category, assignee or none"})
+			return
+		}
+
+		filter, err := issueFilterFromQuery(c)
+		if err != nil {
+			c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		filter.ProjectID, filter.ProjectIDs = id, nil
+		filter.Sort, filter.Offset, filter.Limit = "position", 0, 0
+
+		// Inicializar la base de datos
+		db, err := database.InitDBContext(c.Request.Context(), cfg)
+		if err != nil {
+			c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		defer db.Close()
+
+		allowed, err := canViewProject(c, db, id)
+		if err != nil {
+			c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		if !allowed {
+			c.AbortWithStatusJSON(http.StatusNotFound, gin.H{"error": "Project not found"})
+			return
+		}
+
+		statuses, err := models.GetAllIssueStatuses(db)
+		if err != nil {
+			c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		limits, err := models.GetBoardWIPLimits(db, id)
+		if err != nil {
+			c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		issues, err := models.GetIssuesFiltered(db, filter)
+		if err != nil {
+			c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+
+		// Nombre de cada carril por ID; 0 es el carril de los tickets sin valor
+		lane_names := map[int]string{}
+		switch swimlane {
+		case "category":
+			categories, err := models.GetCategoriesByProjectID(db, id)
+			if err != nil {
+				c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+				return
+			}
+			for _, category := range categories {
+				lane_names[category.ID] = category.Name
+			}
+			lane_names[0] = "No category"
+		case "assignee":
+			users, err := models.GetAllUsers(db)
+			if err != nil {
+				c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+				return
+			}
+			for _, user := range users {
+				lane_names[user.ID] = user.Username
+			}
+			lane_names[0] = "Unassigned"
+		default:
+			lane_names[0] = "All issues"
+		}
+
+		data := GetProjectBoardHandlerData{ProjectID: id, Swimlane: swimlane, Columns: []BoardColumn{}, Lanes: []BoardLane{}}
+		column_index := map[string]int{}
+		for _, status := range statuses {
+			column := BoardColumn{StatusID: status.ID, Name: status.Name, IsClosed: status.IsClosed}
+			if limit, ok := limits[status.ID]; ok {
+				column.WIPLimit = &limit
+			}
+			column_index[status.Name] = len(data.Columns)
+			data.Columns = append(data.Columns, column)
+		}
+
+		// Las celdas de cada carril, con una por columna
+		cells := map[int][]BoardCell{}
+		lane := func(lane_id int) []BoardCell {
+			if _, ok := cells[lane_id]; !ok {
+				row := make([]BoardCell, len(data.Columns))
+				for i, column := range data.Columns {
+					row[i] = BoardCell{StatusID: column.StatusID, Issues: []models.Issue{}}
+				}
+				cells[lane_id] = row
+			}
+			return cells[lane_id]
+		}
+		// Con carriles por asignado solo aparecen los que tienen tickets
+		if swimlane != "assignee" {
+			for lane_id := range lane_names {
+				lane(lane_id)
+			}
+		}
+		for _, issue := range issues {
+			// Los tickets con un estado que no está en issue_statuses no tienen columna
+			i, ok := column_index[issue.Status]
+			if !ok {
+				continue
+			}
+			lane_id := 0
+			switch swimlane {
+			case "category":
+				if issue.CategoryID != nil {
+					lane_id = *issue.CategoryID
+				}
+			case "assignee":
+				if issue.AssignedToID != nil {
+					lane_id = *issue.AssignedToID
+				}
+			}
+			row := lane(lane_id)
+			row[i].Issues = append(row[i].Issues, issue)
+			data.Columns[i].Count++
+		}
+		for i, column := range data.Columns {
+			data.Columns[i].OverLimit = column.WIPLimit != nil && column.Count > *column.WIPLimit
+		}
+
+		// Carriles por nombre, con el de los tickets sin valor al final
+		lane_ids := []int{}
+		for lane_id := range cells {
+			lane_ids = append(lane_ids, lane_id)
+		}
+		sort.Slice(lane_ids, func(i, j int) bool {
+			a, b := lane_ids[i], lane_ids[j]
+			if (a == 0) != (b == 0) {
+				return b == 0
+			}
+			if lane_names[a] != lane_names[b] {
+				return lane_names[a] < lane_names[b]
+			}
+			return a < b
+		})
+		for _, lane_id := range lane_ids {
+			board_lane := BoardLane{Name: lane_names[lane_id], Cells: cells[lane_id]}
+			if lane_id != 0 {
+				value := lane_id
+				board_lane.ID = &value
+			}
+			data.Lanes = append(data.Lanes, board_lane)
+		}
+
+		c.JSON(http.StatusOK, data)
+	}
+}
+
+// @Summary: UpdateBoardWIPLimitsHandler
+// @Description: Replace the WIP limits of the columns (statuses) of a project board. Columns not listed have no limit.
+// @Tags: board
+// @Accept: json
+// @Produce: json
+// @Param id path int true "Project ID"
+// @Param limits body BoardWIPLimitsData true "WIP limits"
+// @Success 200 {object} BoardWIPLimitsData
+// @Failure 400 {object} map[string]string
+// @Failure 403 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Router /project/{id}/board/wip_limits [put]
+// @Security BearerAuth
+func UpdateBoardWIPLimitsHandler(cfg *config.Config) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		pid := c.Param("id")
+
+		// pasar string id a int id
+		id, err := strconv.Atoi(pid)
+		if err != nil {
+			c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		var data BoardWIPLimitsData
+		if err := c.ShouldBindJSON(&data); err != nil {
+			c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		// Inicializar la base de datos
+		db, err := database.InitDBContext(c.Request.Context(), cfg)
+		if err != nil {
+			c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		defer db.Close()
+
+		allowed, err := canViewProject(c, db, id)
+		if err != nil {
+			c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		if !allowed {
+			c.AbortWithStatusJSON(http.StatusNotFound, gin.H{"error": "Project not found"})
+			return
+		}
+		if status, msg := checkProjectWritable(db, id); status != 0 {
+			c.AbortWithStatusJSON(status, gin.H{"error": msg})
+			return
+		}
+
+		seen := map[int]bool{}
+		for _, limit := range data.WIPLimits {
+			status, err := models.GetIssueStatusByID(db, limit.StatusID)
+			if err != nil {
+				c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+				return
+			}
+			if status == nil {
+				c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("Issue status %d not found", limit.StatusID)})
+				return
+			}
+			if limit.WIPLimit <= 0 {
+				c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": "wip_limit must be greater than 0"})
+				return
+			}
+			if seen[limit.StatusID] {
+				c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("Issue status %d is repeated", limit.StatusID)})
+				return
+			}
+			seen[limit.StatusID] = true
+		}
+
+		tx, err := db.Begin()
+		if err != nil {
+			c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		defer tx.Rollback()
+
+		if err := models.SetBoardWIPLimits(tx, id, data.WIPLimits); err != nil {
+			c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		if err := tx.Commit(); err != nil {
+			c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+
+		limits, err := models.GetBoardWIPLimits(db, id)
+		if err != nil {
+			c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		data.WIPLimits = []models.BoardWIPLimit{}
+		for status_id, limit := range limits {
+			data.WIPLimits = append(data.WIPLimits, models.BoardWIPLimit{StatusID: status_id, WIPLimit: limit})
+		}
+		sort.Slice(data.WIPLimits, func(i, j int) bool { return data.WIPLimits[i].StatusID < data.WIPLimits[j].StatusID })
+
+		c.JSON(http.StatusOK, data)
+	}
+}
+
+// @Summary: MoveBoardIssueHandler
+// @Description: Move an issue on the project board: change its status (column) and its position within the column in one call. The status change follows the tracker workflow and the column WIP limit. The other issues of the column keep their relative order.
+// @Tags: board
+// @Accept: json
+// @Produce: json
+// @Param id path int true "Project ID"
+// @Param If-Match header string false "ETag returned by GET /issue/{id}"
+// @Param move body BoardMovePayload true "Issue, column and position"
+// @Success 200 {object} models.Issue
+// @Failure 400 {object} map[string]string
+// @Failure 403 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Failure 409 {object} map[string]interface{}
+// @Failure 422 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Router /project/{id}/board/move [post]
+// @Security BearerAuth
+func MoveBoardIssueHandler(cfg *config.Config) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		pid := c.Param("id")
+
+		// pasar string id a int id
+		id, err := strconv.Atoi(pid)
+		if err != nil {
+			c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		var payload BoardMovePayload
+		if err := c.ShouldBindJSON(&payload); err != nil {
+			c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		if payload.Position < 0 {
+			c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": "position must not be negative"})
+			return
+		}
+
+		// Inicializar la base de datos
+		db, err := database.InitDBContext(c.Request.Context(), cfg)
+		if err != nil {
+			c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		defer db.Close()
+
+		before, status, msg := getVisibleIssue(c, db, payload.IssueID)
+		if status != 0 {
+			c.AbortWithStatusJSON(status, gin.H{"error": msg})
+			return
+		}
+		if before.ProjectID != id {
+			c.AbortWithStatusJSON(http.StatusNotFound, gin.H{"error": "Issue not found in this project"})
+			return
+		}
+		if status, msg := checkProjectWritable(db, id); status != 0 {
+			c.AbortWithStatusJSON(status, gin.H{"error": msg})
+			return
+		}
+
+		if payload.LockVersion != nil || c.GetHeader("If-Match") != "" {
+			body_version := 0
+			if payload.LockVersion != nil {
+				body_version = *payload.LockVersion
+			}
+			lock_version, ok := lockVersionFromRequest(c, body_version)
+			if !ok {
+				return
+			}
+			if lock_version != before.LockVersion {
+				respondStale(c, before.LockVersion, before)
+				return
+			}
+		}
+
+		issue := *before
+		if payload.StatusID != 0 {
+			target, err := models.GetIssueStatusByID(db, payload.StatusID)
+			if err != nil {
+				c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+				return
+			}
+			if target == nil {
+				c.AbortWithStatusJSON(http.StatusUnprocessableEntity, gin.H{"error": "status_id does not exist"})
+				return
+			}
+			issue.Status = target.Name
+		}
+		if status, msg := checkIssueWorkflow(c, db, before, &issue); status != 0 {
+			c.AbortWithStatusJSON(status, gin.H{"error": msg})
+			return
+		}
+
+		tx, err := db.Begin()
+		if err != nil {
+			c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		defer tx.Rollback()
+
+		// La columna de destino queda bloqueada hasta el final, también para el límite WIP
+		column, err := models.GetBoardColumnIssueIDs(tx, id, issue.Status)
+		if err != nil {
+			c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		ordered := []int{}
+		for _, issue_id := range column {
+			if issue_id != issue.ID {
+				ordered = append(ordered, issue_id)
+			}
+		}
+
+		if issue.Status != before.Status {
+			limits, err := models.GetBoardWIPLimits(db, id)
+			if err != nil {
+				c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+				return
+			}
+			if limit, ok := limits[payload.StatusID]; ok && len(ordered) >= limit {
+				c.AbortWithStatusJSON(http.StatusUnprocessableEntity, gin.H{"error": fmt.Sprintf("The %s column has reached its WIP limit of %d", issue.Status, limit)})
+				return
+			}
+
+			if err := models.UpdateIssue(tx, &issue); err == models.ErrStaleObject {
+				// Otra petición lo modificó entre la lectura y la escritura
+				tx.Rollback()
+				current, err := models.GetIssueByID(db, issue.ID)
+				if err != nil {
+					c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+					return
+				}
+				respondStale(c, current.LockVersion, current)
+				return
+			} else if err != nil {
+				c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+				return
+			}
+
+			if err := models.RecordIssueChanges(tx, before, &issue, currentUserID(c)); err != nil {
+				c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+				return
+			}
+		}
+
+		index := len(ordered)
+		if payload.Position > 0 && payload.Position-1 < index {
+			index = payload.Position - 1
+		}
+		ordered = append(ordered[:index], append([]int{issue.ID}, ordered[index:]...)...)
+		if err := models.SetIssuePositions(tx, ordered); err != nil {
+			c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+
+		if err := tx.Commit(); err != nil {
+			c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+
+		updated, err := models.GetIssueByID(db, issue.ID)
+		if err != nil {
+			c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+
+		c.Header("ETag", etag(updated.LockVersion))
+		c.JSON(http.StatusOK, updated)
+	}
+}
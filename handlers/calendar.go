@@ -0,0 +1,346 @@
+package handlers
+
+import (
+	"database/sql"
+	"fmt"
+	"go-redmine-ish/config"
+	"go-redmine-ish/database"
+	"go-redmine-ish/models"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// calendarICSMonthsBack es cuántos meses hacia atrás incluye el feed .ics si no se indica from
+const calendarICSMonthsBack = 3
+
+// CalendarEvent es un ticket o una versión en el calendario. Start y End son fechas YYYY-MM-DD,
+// ambas incluidas; un ticket con una sola fecha y las versiones ocupan un día. UID no cambia
+// nunca y Sequence aumenta con cada modificación del ticket
+type CalendarEvent struct {
+	UID       string `json:"uid"`
+	Type      string `json:"type"` // issue o version
+	IssueID   *int   `json:"issue_id,omitempty"`
+	VersionID *int   `json:"version_id,omitempty"`
+	ProjectID int    `json:"project_id"`
+	Title     string `json:"title"`
+	Status    string `json:"status"`
+	Start     string `json:"start"`
+	End       string `json:"end"`
+	URL       string `json:"url"`
+	Sequence  int    `json:"sequence"`
+	UpdatedAt string `json:"updated_at"`
+}
+
+type GetCalendarHandlerData struct {
+	Month  string          `json:"month"`
+	From   string          `json:"from"`
+	To     string          `json:"to"`
+	Events []CalendarEvent `json:"events"`
+}
+
+// calendarFilter lee los filtros de GET /issues y limita los tickets a los proyectos visibles, o
+// al proyecto de la ruta si la hay. Si algo falla responde y devuelve false
+func calendarFilter(c *gin.Context, db *sql.DB) (models.IssueFilter, bool) {
+	filter, err := issueFilterFromQuery(c)
+	if err != nil {
+		c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return filter, false
+	}
+	filter.Offset, filter.Limit = 0, 0
+
+	if pid := c.Param("id"); pid != "" {
+		// pasar string id a int id
+		id, err := strconv.Atoi(pid)
+		if err != nil {
+			c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return filter, false
+		}
+		filter.ProjectID = id
+	}
+
+	if filter.ProjectID != 0 {
+		allowed, err := canViewProject(c, db, filter.ProjectID)
+		if err != nil {
+			c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return filter, false
+		}
+		if !allowed {
+			c.AbortWithStatusJSON(http.StatusNotFound, gin.H{"error": "Project not found"})
+			return filter, false
+		}
+	}
+
+	filter.ProjectIDs, err = visibleProjectIDs(c, db)
+	if err != nil {
+		c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return filter, false
+	}
+
+	return filter, true
+}
+
+// calendarEvents obtiene los tickets y las versiones que se solapan con [from, to]. Las
+// versiones solo se incluyen en los proyectos con la planificación (roadmap) habilitada
+func calendarEvents(cfg *config.Config, db *sql.DB, filter models.IssueFilter, from, to string) ([]CalendarEvent, error) {
+	filter.DatesFrom, filter.DatesTo = from, to
+	issues, err := models.GetIssuesFiltered(db, filter)
+	if err != nil {
+		return nil, err
+	}
+
+	trackers, err := models.GetAllTrackers(db)
+	if err != nil {
+		return nil, err
+	}
+	tracker_names := map[int]string{}
+	for _, tracker := range trackers {
+		tracker_names[tracker.ID] = tracker.Name
+	}
+
+	events := []CalendarEvent{}
+	for _, issue := range issues {
+		start, end := issue.StartDate, issue.DueDate
+		if start == nil {
+			start = end
+		}
+		if end == nil {
+			end = start
+		}
+		id := issue.ID
+		events = append(events, CalendarEvent{
+			UID:       fmt.Sprintf("issue-%d@go-redmine-ish", issue.ID),
+			Type:      "issue",
+			IssueID:   &id,
+			ProjectID: issue.ProjectID,
+			Title:     strings.TrimSpace(fmt.Sprintf("%s #%d: %s", tracker_names[issue.TrackerID], issue.ID, issue.Subject)),
+			Status:    issue.Status,
+			Start:     *start,
+			End:       *end,
+			URL:       issueURL(cfg, issue.ID),
+			Sequence:  issue.LockVersion,
+			UpdatedAt: issue.UpdatedAt,
+		})
+	}
+
+	project_ids := filter.ProjectIDs
+	if filter.ProjectID != 0 {
+		project_ids = []int{filter.ProjectID}
+	}
+	versions, err := models.GetVersionsDueBetween(db, project_ids, from, to)
+	if err != nil {
+		return nil, err
+	}
+	roadmap := map[int]bool{}
+	for _, version := range versions {
+		enabled, ok := roadmap[version.ProjectID]
+		if !ok {
+			if enabled, err = models.IsProjectModuleEnabled(db, version.ProjectID, models.ModuleRoadmap); err != nil {
+				return nil, err
+			}
+			roadmap[version.ProjectID] = enabled
+		}
+		if !enabled {
+			continue
+		}
+		id := version.ID
+		events = append(events, CalendarEvent{
+			UID:       fmt.Sprintf("version-%d@go-redmine-ish", version.ID),
+			Type:      "version",
+			VersionID: &id,
+			ProjectID: version.ProjectID,
+			Title:     "Version " + version.Name,
+			Status:    version.Status,
+			Start:     *version.DueDate,
+			End:       *version.DueDate,
+			URL:       fmt.Sprintf("%s/version/%d", cfg.PublicURL, version.ID),
+			UpdatedAt: version.UpdatedAt,
+		})
+	}
+
+	sort.SliceStable(events, func(i, j int) bool { return events[i].Start < events[j].Start })
+	return events, nil
+}
+
+// @Summary: GetCalendarHandler
+// @Description: Calendar of a month: issues whose start to due dates overlap the month, and version due dates. Accepts the same filters as GET /issues; under /project/{id} it is limited to that project.
+// @Tags: calendar
+// @Produce: json
+// @Param id path int false "Project ID"
+// @Param month query string false "Month (YYYY-MM), the current one by default"
+// @Param tracker_id query int false "Tracker ID"
+// @Param status query string false "Status name, open or closed"
+// @Param assigned_to_id query int false "Assigned user ID"
+// @Success 200 {object} GetCalendarHandlerData
+// @Failure 400 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Router /calendar [get]
+// @Router /project/{id}/calendar [get]
+// @Security BearerAuth
+func GetCalendarHandler(cfg *config.Config) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		month := time.Now()
+		if value := c.Query("month"); value != "" {
+			var err error
+			month, err = time.Parse("2006-01", value)
+			if err != nil {
+				c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": "invalid month: " + err.Error()})
+				return
+			}
+		}
+		first := time.Date(month.Year(), month.Month(), 1, 0, 0, 0, 0, time.UTC)
+		last := first.AddDate(0, 1, -1)
+
+		// Inicializar la base de datos
+		db, err := database.InitDBContext(c.Request.Context(), cfg)
+		if err != nil {
+			c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		defer db.Close()
+
+		filter, ok := calendarFilter(c, db)
+		if !ok {
+			return
+		}
+
+		data := GetCalendarHandlerData{
+			Month: first.Format("2006-01"),
+			From:  first.Format("2006-01-02"),
+			To:    last.Format("2006-01-02"),
+		}
+		data.Events, err = calendarEvents(cfg, db, filter, data.From, data.To)
+		if err != nil {
+			c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+
+		c.JSON(http.StatusOK, data)
+	}
+}
+
+// icsText escapa un valor TEXT de iCalendar (RFC 5545, 3.3.11)
+func icsText(value string) string {
+	return strings.NewReplacer(`\`, `\\`, ";", `\;`, ",", `\,`, "\r\n", `\n`, "\n", `\n`, "\r", "").Replace(value)
+}
+
+// icsLine escribe una línea de contenido partida en líneas de 75 octetos como máximo, sin
+// cortar caracteres UTF-8 por la mitad
+func icsLine(b *strings.Builder, line string) {
+	limit := 75
+	for len(line) > limit {
+		cut := limit
+		for cut > 0 && line[cut]&0xC0 == 0x80 {
+			cut--
+		}
+		b.WriteString(line[:cut] + "\r\n ")
+		line = line[cut:]
+		limit = 74 // el espacio inicial de la continuación cuenta
+	}
+	b.WriteString(line + "\r\n")
+}
+
+// icsDate pasa una fecha YYYY-MM-DD al formato DATE de iCalendar, desplazada days días
+func icsDate(value string, days int) string {
+	t, _ := time.Parse("2006-01-02", value)
+	return t.AddDate(0, 0, days).Format("20060102")
+}
+
+// @Summary: GetCalendarICSHandler
+// @Description: iCalendar feed of issues and version due dates, to subscribe from calendar clients. Accepts the same filters as GET /issues; under /project/{id} it is limited to that project. Each event keeps its UID and its SEQUENCE grows with every change of the issue.
+// @Tags: calendar
+// @Produce: text/calendar
+// @Param id path int false "Project ID"
+// @Param key query string true "Feed key"
+// @Param from query string false "First date (YYYY-MM-DD), three months ago by default"
+// @Param to query string false "Last date (YYYY-MM-DD), unbounded by default"
+// @Success 200 {string} string "iCalendar"
+// @Failure 400 {object} map[string]string
+// @Failure 401 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Router /calendar.ics [get]
+// @Router /project/{id}/calendar.ics [get]
+func GetCalendarICSHandler(cfg *config.Config) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		now := time.Now().UTC()
+		from := time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, time.UTC).AddDate(0, -calendarICSMonthsBack, 0).Format("2006-01-02")
+		if value := c.Query("from"); value != "" {
+			from = value
+		}
+		to := c.Query("to")
+		for name, value := range map[string]string{"from": from, "to": to} {
+			if value == "" {
+				continue
+			}
+			if _, err := time.Parse("2006-01-02", value); err != nil {
+				c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": "invalid " + name + ": " + err.Error()})
+				return
+			}
+		}
+
+		// Inicializar la base de datos
+		db, err := database.InitDBContext(c.Request.Context(), cfg)
+		if err != nil {
+			c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		defer db.Close()
+
+		filter, ok := calendarFilter(c, db)
+		if !ok {
+			return
+		}
+
+		name := "Issues"
+		if filter.ProjectID != 0 {
+			project, err := models.GetProjectByID(db, filter.ProjectID)
+			if err != nil {
+				c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+				return
+			}
+			if project == nil {
+				c.AbortWithStatusJSON(http.StatusNotFound, gin.H{"error": "Project not found"})
+				return
+			}
+			name = project.Name
+		}
+
+		events, err := calendarEvents(cfg, db, filter, from, to)
+		if err != nil {
+			c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+
+		var b strings.Builder
+		icsLine(&b, "BEGIN:VCALENDAR")
+		icsLine(&b, "VERSION:2.0")
+		icsLine(&b, "PRODID:-//go-redmine-ish//calendar//EN")
+		icsLine(&b, "CALSCALE:GREGORIAN")
+		icsLine(&b, "METHOD:PUBLISH")
+		icsLine(&b, "X-WR-CALNAME:"+icsText(name))
+		for _, event := range events {
+			stamp := parseDBTime(event.UpdatedAt).UTC().Format("20060102T150405Z")
+			icsLine(&b, "BEGIN:VEVENT")
+			icsLine(&b, "UID:"+event.UID)
+			icsLine(&b, "DTSTAMP:"+stamp)
+			icsLine(&b, "LAST-MODIFIED:"+stamp)
+			icsLine(&b, fmt.Sprintf("SEQUENCE:%d", event.Sequence))
+			// DTEND de un evento de día completo es el día siguiente al último
+			icsLine(&b, "DTSTART;VALUE=DATE:"+icsDate(event.Start, 0))
+			icsLine(&b, "DTEND;VALUE=DATE:"+icsDate(event.End, 1))
+			icsLine(&b, "SUMMARY:"+icsText(fmt.Sprintf("%s (%s)", event.Title, event.Status)))
+			icsLine(&b, "URL:"+event.URL)
+			icsLine(&b, "TRANSP:TRANSPARENT")
+			icsLine(&b, "END:VEVENT")
+		}
+		icsLine(&b, "END:VCALENDAR")
+
+		c.Data(http.StatusOK, "text/calendar; charset=utf-8", []byte(b.String()))
+	}
+}
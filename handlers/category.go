@@ -1,6 +1,7 @@
 package handlers
 
 import (
+	"database/sql"
 	"fmt"
 	"go-redmine-ish/config"
 	"go-redmine-ish/database"
@@ -41,7 +42,7 @@ func GetCategoryHandler(cfg *config.Config) gin.HandlerFunc {
 		}
 
 		// Inicializar la base de datos
-		db, err := database.InitDB(cfg)
+		db, err := database.InitDBContext(c.Request.Context(), cfg)
 		if err != nil {
 			c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 			return
@@ -60,7 +61,7 @@ func GetCategoryHandler(cfg *config.Config) gin.HandlerFunc {
 			return
 		}
 
-		trackers, err := models.GetAllTrackers(db)
+		trackers, err := models.GetTrackersByProjectID(db, category.ProjectID)
 		if err != nil {
 			c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 			return
@@ -117,13 +118,18 @@ func CreateCategoryHandler(cfg *config.Config) gin.HandlerFunc {
 		}
 
 		// Inicializar la base de datos
-		db, err := database.InitDB(cfg)
+		db, err := database.InitDBContext(c.Request.Context(), cfg)
 		if err != nil {
 			c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 			return
 		}
 		defer db.Close()
 
+		if status, msg := checkProjectWritable(db, category.ProjectID); status != 0 {
+			c.AbortWithStatusJSON(status, gin.H{"error": msg})
+			return
+		}
+
 		id, err := models.CreateCategory(db, &category)
 		if err != nil {
 			c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
@@ -169,13 +175,28 @@ func UpdateCategoryHandler(cfg *config.Config) gin.HandlerFunc {
 		}
 
 		// Inicializar la base de datos
-		db, err := database.InitDB(cfg)
+		db, err := database.InitDBContext(c.Request.Context(), cfg)
 		if err != nil {
 			c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 			return
 		}
 		defer db.Close()
 
+		existing, err := models.GetCategoryByID(db, id)
+		if err == sql.ErrNoRows {
+			c.AbortWithStatusJSON(http.StatusNotFound, gin.H{"error": "Category not found"})
+			return
+		}
+		if err != nil {
+			c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+
+		if status, msg := checkProjectWritable(db, existing.ProjectID); status != 0 {
+			c.AbortWithStatusJSON(status, gin.H{"error": msg})
+			return
+		}
+
 		err = models.UpdateCategory(db, &category)
 		if err != nil {
 			c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
@@ -206,13 +227,28 @@ func DeleteCategoryHandler(cfg *config.Config) gin.HandlerFunc {
 		}
 
 		// Inicializar la base de datos
-		db, err := database.InitDB(cfg)
+		db, err := database.InitDBContext(c.Request.Context(), cfg)
 		if err != nil {
 			c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 			return
 		}
 		defer db.Close()
 
+		existing, err := models.GetCategoryByID(db, id)
+		if err == sql.ErrNoRows {
+			c.AbortWithStatusJSON(http.StatusNotFound, gin.H{"error": "Category not found"})
+			return
+		}
+		if err != nil {
+			c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+
+		if status, msg := checkProjectWritable(db, existing.ProjectID); status != 0 {
+			c.AbortWithStatusJSON(status, gin.H{"error": msg})
+			return
+		}
+
 		err = models.DeleteCategory(db, id)
 		if err != nil {
 			c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
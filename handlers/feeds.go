@@ -0,0 +1,377 @@
+package handlers
+
+import (
+	"encoding/xml"
+	"fmt"
+	"go-redmine-ish/config"
+	"go-redmine-ish/database"
+	"go-redmine-ish/models"
+	"net/http"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// feedMaxEntries limita el número de entradas de cada feed
+const feedMaxEntries = 50
+
+type atomLink struct {
+	Href string `xml:"href,attr"`
+	Rel  string `xml:"rel,attr,omitempty"`
+	Type string `xml:"type,attr,omitempty"`
+}
+
+type atomAuthor struct {
+	Name  string `xml:"name"`
+	Email string `xml:"email,omitempty"`
+}
+
+type atomContent struct {
+	Type string `xml:"type,attr"`
+	Body string `xml:",chardata"`
+}
+
+type atomEntry struct {
+	Title   string      `xml:"title"`
+	ID      string      `xml:"id"`
+	Updated string      `xml:"updated"`
+	Link    atomLink    `xml:"link"`
+	Author  *atomAuthor `xml:"author,omitempty"`
+	Content atomContent `xml:"content"`
+
+	// momento del evento, para ordenar las entradas
+	at time.Time
+}
+
+type atomFeed struct {
+	XMLName xml.Name    `xml:"http://www.w3.org/2005/Atom feed"`
+	Title   string      `xml:"title"`
+	ID      string      `xml:"id"`
+	Updated string      `xml:"updated"`
+	Links   []atomLink  `xml:"link"`
+	Entries []atomEntry `xml:"entry"`
+}
+
+type GetFeedKeyHandlerData struct {
+	FeedKey string `json:"feed_key"`
+}
+
+// parseDBTime interpreta las fechas que devuelven los modelos como string
+func parseDBTime(value string) time.Time {
+	for _, layout := range []string{time.RFC3339Nano, "2006-01-02 15:04:05.999999", "2006-01-02"} {
+		if t, err := time.Parse(layout, value); err == nil {
+			return t
+		}
+	}
+	return time.Time{}
+}
+
+// issueURL es el enlace público a un ticket en la interfaz web
+func issueURL(cfg *config.Config, id int) string {
+	return fmt.Sprintf("%s/issue/%d", cfg.PublicURL, id)
+}
+
+// writeAtomFeed ordena las entradas de la más reciente a la más antigua, las recorta y responde el feed
+func writeAtomFeed(c *gin.Context, feed atomFeed) {
+	sort.SliceStable(feed.Entries, func(i, j int) bool {
+		return feed.Entries[i].at.After(feed.Entries[j].at)
+	})
+	if len(feed.Entries) > feedMaxEntries {
+		feed.Entries = feed.Entries[:feedMaxEntries]
+	}
+
+	feed.Updated = time.Now().UTC().Format(time.RFC3339)
+	if len(feed.Entries) > 0 {
+		feed.Updated = feed.Entries[0].Updated
+	}
+
+	out, err := xml.MarshalIndent(feed, "", "  ")
+	if err != nil {
+		c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.Data(http.StatusOK, "application/atom+xml; charset=utf-8", append([]byte(xml.Header), out...))
+}
+
+// @Summary: GetFeedKeyHandler
+// @Description: Get the feed key of the authenticated user, creating it if needed
+// @Tags: feeds
+// @Produce: json
+// @Success 200 {object} GetFeedKeyHandlerData
+// @Failure 400 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Router /my/feed_key [get]
+// @Security BearerAuth
+func GetFeedKeyHandler(cfg *config.Config) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		user_id := currentUserID(c)
+		if user_id == nil {
+			c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": "The token is not associated with a user"})
+			return
+		}
+
+		// Inicializar la base de datos
+		db, err := database.InitDBContext(c.Request.Context(), cfg)
+		if err != nil {
+			c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		defer db.Close()
+
+		key, err := models.GetOrCreateUserFeedKey(db, *user_id)
+		if err != nil {
+			c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+
+		c.JSON(http.StatusOK, GetFeedKeyHandlerData{FeedKey: key})
+	}
+}
+
+// @Summary: ResetFeedKeyHandler
+// @Description: Generate a new feed key for the authenticated user, invalidating the previous one
+// @Tags: feeds
+// @Produce: json
+// @Success 200 {object} GetFeedKeyHandlerData
+// @Failure 400 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Router /my/feed_key [post]
+// @Security BearerAuth
+func ResetFeedKeyHandler(cfg *config.Config) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		user_id := currentUserID(c)
+		if user_id == nil {
+			c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": "The token is not associated with a user"})
+			return
+		}
+
+		// Inicializar la base de datos
+		db, err := database.InitDBContext(c.Request.Context(), cfg)
+		if err != nil {
+			c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		defer db.Close()
+
+		key, err := models.ResetUserFeedKey(db, *user_id)
+		if err != nil {
+			c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+
+		c.JSON(http.StatusOK, GetFeedKeyHandlerData{FeedKey: key})
+	}
+}
+
+// @Summary: GetProjectActivityFeedHandler
+// @Description: Atom feed of a project's activity: new issues, updates and comments
+// @Tags: feeds
+// @Produce: application/atom+xml
+// @Param id path int true "Project ID"
+// @Param key query string true "Feed key"
+// @Success 200 {string} string "Atom feed"
+// @Failure 400 {object} map[string]string
+// @Failure 401 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Router /project/{id}/activity.atom [get]
+func GetProjectActivityFeedHandler(cfg *config.Config) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		pid := c.Param("id")
+
+		// pasar string id a int id
+		id, err := strconv.Atoi(pid)
+		if err != nil {
+			c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		// Inicializar la base de datos
+		db, err := database.InitDBContext(c.Request.Context(), cfg)
+		if err != nil {
+			c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		defer db.Close()
+
+		project, err := models.GetProjectByID(db, id)
+		if err != nil {
+			c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+
+		// Un proyecto no visible se trata igual que uno inexistente
+		allowed, err := canViewProject(c, db, id)
+		if err != nil {
+			c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		if project == nil || !allowed {
+			c.AbortWithStatusJSON(http.StatusNotFound, gin.H{"error": "Project not found"})
+			return
+		}
+
+		issues, err := models.GetIssuesByProjectID(db, id)
+		if err != nil {
+			c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+
+		users, err := models.GetAllUsers(db)
+		if err != nil {
+			c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		authors := map[int]*atomAuthor{}
+		for _, user := range users {
+			authors[user.ID] = &atomAuthor{Name: user.Username}
+		}
+
+		feed := atomFeed{
+			Title: project.Name + ": activity",
+			ID:    fmt.Sprintf("urn:go-redmine-ish:project:%d:activity", project.ID),
+			Links: []atomLink{
+				{Href: fmt.Sprintf("%s/project/%d", cfg.PublicURL, project.ID), Rel: "alternate", Type: "text/html"},
+			},
+		}
+
+		for _, issue := range issues {
+			created := parseDBTime(issue.CreatedAt)
+			feed.Entries = append(feed.Entries, atomEntry{
+				Title:   fmt.Sprintf("Issue #%d created: %s", issue.ID, issue.Subject),
+				ID:      fmt.Sprintf("urn:go-redmine-ish:issue:%d", issue.ID),
+				Updated: created.Format(time.RFC3339),
+				Link:    atomLink{Href: issueURL(cfg, issue.ID), Rel: "alternate"},
+				Content: atomContent{Type: "text", Body: issue.Description},
+				at:      created,
+			})
+
+			if updated := parseDBTime(issue.UpdatedAt); updated.After(created) {
+				feed.Entries = append(feed.Entries, atomEntry{
+					Title:   fmt.Sprintf("Issue #%d updated (%s): %s", issue.ID, issue.Status, issue.Subject),
+					ID:      fmt.Sprintf("urn:go-redmine-ish:issue:%d:updated:%d", issue.ID, updated.Unix()),
+					Updated: updated.Format(time.RFC3339),
+					Link:    atomLink{Href: issueURL(cfg, issue.ID), Rel: "alternate"},
+					Content: atomContent{Type: "text", Body: issue.Description},
+					at:      updated,
+				})
+			}
+
+			comments, err := models.GetCommentsByIssueID(db, issue.ID)
+			if err != nil {
+				c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+				return
+			}
+			for _, comment := range comments {
+				at := parseDBTime(comment.CreatedAt)
+				feed.Entries = append(feed.Entries, atomEntry{
+					Title:   fmt.Sprintf("Comment on issue #%d: %s", issue.ID, issue.Subject),
+					ID:      fmt.Sprintf("urn:go-redmine-ish:comment:%d", comment.ID),
+					Updated: at.Format(time.RFC3339),
+					Link:    atomLink{Href: issueURL(cfg, issue.ID), Rel: "alternate"},
+					Author:  authors[comment.UserID],
+					Content: atomContent{Type: "text", Body: comment.Content},
+					at:      at,
+				})
+			}
+		}
+
+		writeAtomFeed(c, feed)
+	}
+}
+
+// @Summary: GetIssuesFeedHandler
+// @Description: Atom feed of an issues query, with the same filters as GET /issues
+// @Tags: feeds
+// @Produce: application/atom+xml
+// @Param key query string true "Feed key"
+// @Param project_id query int false "Project ID"
+// @Param tracker_id query int false "Tracker ID"
+// @Param status query string false "Status name, open or closed"
+// @Param assigned_to_id query int false "Assigned user ID"
+// @Param category_id query int false "Category ID"
+// @Success 200 {string} string "Atom feed"
+// @Failure 400 {object} map[string]string
+// @Failure 401 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Router /issues.atom [get]
+func GetIssuesFeedHandler(cfg *config.Config) gin.HandlerFunc {
+	return func(c *gin.Context) {
+
+		filter, err := issueFilterFromQuery(c)
+		if err != nil {
+			c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		if filter.Sort == "" {
+			filter.Sort = "updated_on:desc"
+		}
+		if filter.Limit <= 0 || filter.Limit > feedMaxEntries {
+			filter.Limit = feedMaxEntries
+		}
+
+		// Inicializar la base de datos
+		db, err := database.InitDBContext(c.Request.Context(), cfg)
+		if err != nil {
+			c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		defer db.Close()
+
+		// Solo los tickets de los proyectos visibles para el dueño de la clave
+		filter.ProjectIDs, err = visibleProjectIDs(c, db)
+		if err != nil {
+			c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+
+		issues, err := models.GetIssuesFiltered(db, filter)
+		if err != nil {
+			c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+
+		refs, err := newRedmineRefs(db)
+		if err != nil {
+			c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+
+		feed := atomFeed{
+			Title: "Issues",
+			Links: []atomLink{
+				{Href: cfg.PublicURL + "/issues", Rel: "alternate", Type: "text/html"},
+			},
+		}
+		// La clave no forma parte de la identidad del feed
+		query := c.Request.URL.Query()
+		query.Del("key")
+		feed.ID = "urn:go-redmine-ish:issues?" + query.Encode()
+
+		for _, issue := range issues {
+			data := refs.issue(issue)
+			tracker := ""
+			if data.Tracker != nil {
+				tracker = data.Tracker.Name + " "
+			}
+			updated := parseDBTime(issue.UpdatedAt)
+			entry := atomEntry{
+				Title:   fmt.Sprintf("%s#%d (%s): %s", tracker, issue.ID, issue.Status, issue.Subject),
+				ID:      fmt.Sprintf("urn:go-redmine-ish:issue:%d", issue.ID),
+				Updated: updated.Format(time.RFC3339),
+				Link:    atomLink{Href: issueURL(cfg, issue.ID), Rel: "alternate"},
+				Content: atomContent{Type: "text", Body: issue.Description},
+				at:      updated,
+			}
+			if data.AssignedTo != nil {
+				entry.Author = &atomAuthor{Name: data.AssignedTo.Name}
+			}
+			feed.Entries = append(feed.Entries, entry)
+		}
+
+		writeAtomFeed(c, feed)
+	}
+}
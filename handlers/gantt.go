@@ -0,0 +1,378 @@
+package handlers
+
+import (
+	"go-redmine-ish/config"
+	"go-redmine-ish/database"
+	"go-redmine-ish/models"
+	"net/http"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// GanttIssue es un ticket del diagrama con su planificación calculada. Las fechas tempranas y
+// tardías y la holgura solo se calculan para los tickets con alguna fecha
+type GanttIssue struct {
+	models.Issue
+	EarlyStart  *string      `json:"early_start,omitempty"`
+	EarlyFinish *string      `json:"early_finish,omitempty"`
+	LateStart   *string      `json:"late_start,omitempty"`
+	LateFinish  *string      `json:"late_finish,omitempty"`
+	Slack       *int         `json:"slack,omitempty"` // días que puede retrasarse sin retrasar el fin del proyecto
+	Critical    bool         `json:"critical"`
+	Children    []GanttIssue `json:"children,omitempty"`
+}
+
+// GanttProject es un proyecto del árbol con sus tickets anidados y sus versiones como hitos
+type GanttProject struct {
+	ID         int              `json:"id"`
+	Name       string           `json:"name"`
+	ParentID   *int             `json:"parent_id"`
+	Issues     []GanttIssue     `json:"issues"`
+	Milestones []models.Version `json:"milestones"`
+}
+
+type GetProjectGanttHandlerData struct {
+	From         string                 `json:"from,omitempty"`
+	To           string                 `json:"to,omitempty"`
+	Finish       *string                `json:"finish"` // fin temprano del proyecto
+	Projects     []GanttProject         `json:"projects"`
+	Relations    []models.IssueRelation `json:"relations"` // precedes entre los tickets del diagrama
+	CriticalPath []int                  `json:"critical_path"`
+}
+
+// ganttDate convierte una fecha YYYY-MM-DD en número de día, para operar con ella
+func ganttDate(value string) int {
+	t, _ := time.Parse("2006-01-02", value)
+	return int(t.Unix() / 86400)
+}
+
+func ganttDay(day int) *string {
+	value := time.Unix(int64(day)*86400, 0).UTC().Format("2006-01-02")
+	return &value
+}
+
+// ganttTask es un ticket con fechas en el cálculo del camino crítico
+type ganttTask struct {
+	start, finish                                  int // fechas planificadas
+	earlyStart, earlyFinish, lateStart, lateFinish int
+	next, prev                                     []ganttLink
+	scheduled                                      bool
+}
+
+type ganttLink struct {
+	id, delay int
+}
+
+// ganttSchedule calcula el camino crítico (CPM) con las relaciones precedes. Cada ticket dura
+// de su fecha de inicio a la de fin, ambas incluidas; si solo tiene una, un día. El inicio
+// planificado es el más temprano posible, y un sucesor no empieza antes del día siguiente al
+// fin de su predecesor más el retraso. Los tickets en un ciclo quedan sin planificar
+func ganttSchedule(tasks map[int]*ganttTask, relations []models.IssueRelation) (finish int, ok bool) {
+	indegree := map[int]int{}
+	for _, relation := range relations {
+		from, to := tasks[relation.IssueFromID], tasks[relation.IssueToID]
+		if from == nil || to == nil {
+			continue
+		}
+		delay := 0
+		if relation.Delay != nil {
+			delay = *relation.Delay
+		}
+		from.next = append(from.next, ganttLink{relation.IssueToID, delay})
+		to.prev = append(to.prev, ganttLink{relation.IssueFromID, delay})
+		indegree[relation.IssueToID]++
+	}
+
+	// Orden topológico, en orden de ID para que el resultado sea estable
+	ids := make([]int, 0, len(tasks))
+	for id := range tasks {
+		ids = append(ids, id)
+	}
+	sort.Ints(ids)
+	order := []int{}
+	for _, id := range ids {
+		if indegree[id] == 0 {
+			order = append(order, id)
+		}
+	}
+	for i := 0; i < len(order); i++ {
+		for _, link := range tasks[order[i]].next {
+			indegree[link.id]--
+			if indegree[link.id] == 0 {
+				order = append(order, link.id)
+			}
+		}
+	}
+
+	// Pasada hacia delante: inicio y fin tempranos
+	for _, id := range order {
+		task := tasks[id]
+		task.earlyStart = task.start
+		for _, link := range task.prev {
+			if start := tasks[link.id].earlyFinish + 1 + link.delay; start > task.earlyStart {
+				task.earlyStart = start
+			}
+		}
+		task.earlyFinish = task.earlyStart + task.finish - task.start
+		task.scheduled = true
+		if !ok || task.earlyFinish > finish {
+			finish, ok = task.earlyFinish, true
+		}
+	}
+
+	// Pasada hacia atrás: inicio y fin tardíos sin retrasar el fin del proyecto
+	for i := len(order) - 1; i >= 0; i-- {
+		task := tasks[order[i]]
+		task.lateFinish = finish
+		for _, link := range task.next {
+			if end := tasks[link.id].lateStart - 1 - link.delay; end < task.lateFinish {
+				task.lateFinish = end
+			}
+		}
+		task.lateStart = task.lateFinish - (task.finish - task.start)
+	}
+
+	return finish, ok
+}
+
+// ganttNest anida cada ticket bajo su padre si el padre está en el diagrama
+func ganttNest(issues []GanttIssue) []GanttIssue {
+	index := map[int]int{}
+	for i, issue := range issues {
+		index[issue.ID] = i
+	}
+
+	children := map[int][]int{}
+	roots := []int{}
+	for i, issue := range issues {
+		if issue.ParentID != nil {
+			if _, ok := index[*issue.ParentID]; ok {
+				children[*issue.ParentID] = append(children[*issue.ParentID], i)
+				continue
+			}
+		}
+		roots = append(roots, i)
+	}
+
+	var build func(i int) GanttIssue
+	build = func(i int) GanttIssue {
+		issue := issues[i]
+		for _, child := range children[issue.ID] {
+			issue.Children = append(issue.Children, build(child))
+		}
+		return issue
+	}
+
+	nested := []GanttIssue{}
+	for _, i := range roots {
+		nested = append(nested, build(i))
+	}
+	return nested
+}
+
+// @Summary: GetProjectGanttHandler
+// @Description: Get the Gantt chart data of a project and its visible subprojects: issues with start or due dates nested under their parent, precedes relations and versions as milestones. Only issues and milestones overlapping the from / to window are returned, but the critical path and the slack of each issue are computed over the whole plan.
+// @Tags: projects
+// @Produce: json
+// @Param id path int true "Project ID"
+// @Param from query string false "Window start (YYYY-MM-DD)"
+// @Param to query string false "Window end (YYYY-MM-DD)"
+// @Success 200 {object} GetProjectGanttHandlerData
+// @Failure 400 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Router /project/{id}/gantt [get]
+// @Security BearerAuth
+func GetProjectGanttHandler(cfg *config.Config) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		pid := c.Param("id")
+
+		// pasar string id a int id
+		id, err := strconv.Atoi(pid)
+		if err != nil {
+			c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		from, to := c.Query("from"), c.Query("to")
+		for name, value := range map[string]string{"from": from, "to": to} {
+			if value == "" {
+				continue
+			}
+			if _, err := time.Parse("2006-01-02", value); err != nil {
+				c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": "invalid " + name + ": " + err.Error()})
+				return
+			}
+		}
+		if from != "" && to != "" && to < from {
+			c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": "to must be on or after from"})
+			return
+		}
+
+		// Inicializar la base de datos
+		db, err := database.InitDBContext(c.Request.Context(), cfg)
+		if err != nil {
+			c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		defer db.Close()
+
+		allowed, err := canViewProject(c, db, id)
+		if err != nil {
+			c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		if !allowed {
+			c.AbortWithStatusJSON(http.StatusNotFound, gin.H{"error": "Project not found"})
+			return
+		}
+
+		// El proyecto y los subproyectos que el usuario ve y que tienen tickets
+		project_ids, err := models.GetProjectDescendantIDs(db, id)
+		if err != nil {
+			c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+
+		data := GetProjectGanttHandlerData{
+			From:         from,
+			To:           to,
+			Projects:     []GanttProject{},
+			Relations:    []models.IssueRelation{},
+			CriticalPath: []int{},
+		}
+		tasks := map[int]*ganttTask{}
+		issue_ids := []int{}
+		for _, project_id := range project_ids {
+			if project_id != id {
+				visible, err := canViewProject(c, db, project_id)
+				if err != nil {
+					c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+					return
+				}
+				enabled, err := models.IsProjectModuleEnabled(db, project_id, models.ModuleIssues)
+				if err != nil {
+					c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+					return
+				}
+				if !visible || !enabled {
+					continue
+				}
+			}
+
+			project, err := models.GetProjectByID(db, project_id)
+			if err != nil {
+				c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+				return
+			}
+			if project == nil {
+				continue
+			}
+
+			gantt := GanttProject{ID: project.ID, Name: project.Name, ParentID: project.ParentID, Issues: []GanttIssue{}, Milestones: []models.Version{}}
+			if project.ID == id {
+				gantt.ParentID = nil
+			}
+
+			issues, err := models.GetIssuesByProjectID(db, project_id)
+			if err != nil {
+				c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+				return
+			}
+			sort.Slice(issues, func(i, j int) bool { return issues[i].ID < issues[j].ID })
+			for _, issue := range issues {
+				if issue.StartDate == nil && issue.DueDate == nil {
+					continue
+				}
+				start, finish := issue.StartDate, issue.DueDate
+				if start == nil {
+					start = finish
+				}
+				if finish == nil {
+					finish = start
+				}
+				tasks[issue.ID] = &ganttTask{start: ganttDate(*start), finish: ganttDate(*finish)}
+				issue_ids = append(issue_ids, issue.ID)
+
+				// Solo los tickets que se solapan con la ventana
+				if (to != "" && *start > to) || (from != "" && *finish < from) {
+					continue
+				}
+				gantt.Issues = append(gantt.Issues, GanttIssue{Issue: issue})
+			}
+
+			versions, err := models.GetVersionsByProjectID(db, project_id)
+			if err != nil {
+				c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+				return
+			}
+			for _, version := range versions {
+				if version.DueDate == nil {
+					continue
+				}
+				if (to != "" && *version.DueDate > to) || (from != "" && *version.DueDate < from) {
+					continue
+				}
+				gantt.Milestones = append(gantt.Milestones, version)
+			}
+
+			data.Projects = append(data.Projects, gantt)
+		}
+
+		relations, err := models.GetIssueRelationsBetween(db, issue_ids, models.IssueRelationPrecedes)
+		if err != nil {
+			c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+
+		finish, scheduled := ganttSchedule(tasks, relations)
+		if scheduled {
+			data.Finish = ganttDay(finish)
+		}
+
+		shown := map[int]bool{}
+		for p := range data.Projects {
+			issues := data.Projects[p].Issues
+			for i := range issues {
+				shown[issues[i].ID] = true
+				task := tasks[issues[i].ID]
+				if !task.scheduled {
+					continue
+				}
+				slack := task.lateStart - task.earlyStart
+				issues[i].EarlyStart = ganttDay(task.earlyStart)
+				issues[i].EarlyFinish = ganttDay(task.earlyFinish)
+				issues[i].LateStart = ganttDay(task.lateStart)
+				issues[i].LateFinish = ganttDay(task.lateFinish)
+				issues[i].Slack = &slack
+				issues[i].Critical = slack <= 0
+			}
+			data.Projects[p].Issues = ganttNest(issues)
+		}
+
+		for _, relation := range relations {
+			if shown[relation.IssueFromID] || shown[relation.IssueToID] {
+				data.Relations = append(data.Relations, relation)
+			}
+		}
+
+		// El camino crítico completo, aunque salga de la ventana, en orden de inicio
+		for issue_id, task := range tasks {
+			if task.scheduled && task.lateStart <= task.earlyStart {
+				data.CriticalPath = append(data.CriticalPath, issue_id)
+			}
+		}
+		sort.Slice(data.CriticalPath, func(i, j int) bool {
+			a, b := tasks[data.CriticalPath[i]], tasks[data.CriticalPath[j]]
+			if a.earlyStart != b.earlyStart {
+				return a.earlyStart < b.earlyStart
+			}
+			return data.CriticalPath[i] < data.CriticalPath[j]
+		})
+
+		c.JSON(http.StatusOK, data)
+	}
+}
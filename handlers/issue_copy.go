@@ -0,0 +1,440 @@
+package handlers
+
+import (
+	"database/sql"
+	"fmt"
+	"go-redmine-ish/config"
+	"go-redmine-ish/database"
+	"go-redmine-ish/models"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+// IssueCopyPayload indica el destino de la copia o del traslado. Sin project_id la copia queda en
+// el mismo proyecto, y sin tracker_id se conserva el tracker
+type IssueCopyPayload struct {
+	ProjectID   int  `json:"project_id"`
+	TrackerID   int  `json:"tracker_id"`
+	Subtasks    bool `json:"subtasks"`    // solo copy: copiar también las subtareas
+	Comments    bool `json:"comments"`    // solo copy: copiar también los comentarios
+	Attachments bool `json:"attachments"` // solo copy: copiar también los adjuntos
+}
+
+type IssueCopyHandlerData struct {
+	Issue  *models.Issue `json:"issue"`
+	Copied map[int]int   `json:"copied"` // ID original -> ID de la copia
+}
+
+// issueTree devuelve el ticket seguido de todas sus subtareas, cada una después de su padre
+func issueTree(db *sql.DB, root *models.Issue) ([]*models.Issue, error) {
+	tree := []*models.Issue{root}
+	for i := 0; i < len(tree); i++ {
+		children, err := models.GetIssuesByParentID(db, tree[i].ID)
+		if err != nil {
+			return nil, err
+		}
+		for j := range children {
+			tree = append(tree, &children[j])
+		}
+	}
+	return tree, nil
+}
+
+// retargetIssue pasa el ticket a otro proyecto: la categoría se sustituye por la del mismo nombre
+// en el destino, o se vacía si no la hay, y la versión se vacía porque es propia del proyecto
+func retargetIssue(db *sql.DB, issue *models.Issue, projectID int) error {
+	if projectID == issue.ProjectID {
+		return nil
+	}
+
+	if issue.CategoryID != nil {
+		var target *models.Category
+		category, err := models.GetCategoryByID(db, *issue.CategoryID)
+		if err != nil && err != sql.ErrNoRows {
+			return err
+		}
+		if category != nil {
+			target, err = models.GetCategoryByProjectAndName(db, projectID, category.Name)
+			if err != nil {
+				return err
+			}
+		}
+		issue.CategoryID = nil
+		if target != nil {
+			issue.CategoryID = &target.ID
+		}
+	}
+
+	issue.FixedVersionID = nil
+	issue.ProjectID = projectID
+	return nil
+}
+
+// issueCopyTarget lee el ticket y el cuerpo de copy o move, comprobando que el usuario ve el
+// ticket y el proyecto de destino. Si algo falla responde y devuelve nil
+func issueCopyTarget(c *gin.Context, db *sql.DB, payload *IssueCopyPayload) *models.Issue {
+	pid := c.Param("id")
+
+	// pasar string id a int id
+	id, err := strconv.Atoi(pid)
+	if err != nil {
+		c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return nil
+	}
+
+	if err := c.ShouldBindJSON(payload); err != nil {
+		c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return nil
+	}
+
+	issue, err := models.GetIssueByID(db, id)
+	if err != nil && err != sql.ErrNoRows {
+		c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return nil
+	}
+	visible := err == nil
+	if visible {
+		if visible, err = canViewProject(c, db, issue.ProjectID); err != nil {
+			c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return nil
+		}
+	}
+	if !visible {
+		c.AbortWithStatusJSON(http.StatusNotFound, gin.H{"error": "Issue not found"})
+		return nil
+	}
+
+	if payload.ProjectID == 0 {
+		payload.ProjectID = issue.ProjectID
+	}
+	visible, err = canViewProject(c, db, payload.ProjectID)
+	if err != nil {
+		c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return nil
+	}
+	if !visible {
+		c.AbortWithStatusJSON(http.StatusNotFound, gin.H{"error": "Project not found"})
+		return nil
+	}
+
+	return issue
+}
+
+// @Summary: CopyIssueHandler
+// @Description: Copy an issue, to the same or another project. The category is replaced by the same-named one in the target project or cleared, and the version is cleared when the project changes. Optionally copies subtasks, comments and attachments; an attachment that came with a comment stays on the copied comment, or on the issue alone when comments are not copied. Each copy is linked to its original with a copied_to / copied_from relation.
+// @Tags: issues
+// @Accept: json
+// @Produce: json
+// @Param id path int true "Issue ID"
+// @Param copy body IssueCopyPayload true "Target and options"
+// @Success 201 {object} IssueCopyHandlerData
+// @Failure 400 {object} map[string]string
+// @Failure 403 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Failure 422 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Router /issue/{id}/copy [post]
+// @Security BearerAuth
+func CopyIssueHandler(cfg *config.Config) gin.HandlerFunc {
+	return func(c *gin.Context) {
+
+		// Inicializar la base de datos
+		db, err := database.InitDBContext(c.Request.Context(), cfg)
+		if err != nil {
+			c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		defer db.Close()
+
+		var payload IssueCopyPayload
+		source := issueCopyTarget(c, db, &payload)
+		if source == nil {
+			return
+		}
+
+		originals := []*models.Issue{source}
+		if payload.Subtasks {
+			if originals, err = issueTree(db, source); err != nil {
+				c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+				return
+			}
+		}
+
+		if status, msg := checkProjectWritable(db, payload.ProjectID); status != 0 {
+			c.AbortWithStatusJSON(status, gin.H{"error": msg})
+			return
+		}
+		if status, msg := checkProjectModule(db, payload.ProjectID, models.ModuleIssues); status != 0 {
+			c.AbortWithStatusJSON(status, gin.H{"error": msg})
+			return
+		}
+
+		// Se preparan y validan todas las copias antes de crear ninguna
+		copies := make([]models.Issue, len(originals))
+		for i, original := range originals {
+			issue := *original
+			if err := retargetIssue(db, &issue, payload.ProjectID); err != nil {
+				c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+				return
+			}
+			if i == 0 && payload.TrackerID != 0 {
+				issue.TrackerID = payload.TrackerID
+			}
+			// La copia principal conserva el padre si sigue en el mismo proyecto; las subtareas
+			// cuelgan de la copia de su padre, que se asigna al crearlas
+			if i > 0 || issue.ProjectID != source.ProjectID {
+				issue.ParentID = nil
+			}
+
+			status, msg := checkProjectTracker(db, issue.ProjectID, issue.TrackerID)
+			if status == 0 {
+				// Los campos que el tracker de destino no usa se vacían en lugar de rechazarse
+				status, msg = checkTrackerFields(db, &issue, original)
+			}
+			if status == 0 {
+				status, msg = checkIssueReferences(db, &issue, nil)
+			}
+			if status != 0 {
+				if i > 0 {
+					msg = fmt.Sprintf("subtask #%d: %s", original.ID, msg)
+				}
+				c.AbortWithStatusJSON(status, gin.H{"error": msg})
+				return
+			}
+			copies[i] = issue
+		}
+
+		tx, err := db.Begin()
+		if err != nil {
+			c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		defer tx.Rollback()
+
+		data := IssueCopyHandlerData{Copied: map[int]int{}}
+		for i, original := range originals {
+			issue := &copies[i]
+			if i > 0 {
+				parent_id := data.Copied[*original.ParentID]
+				issue.ParentID = &parent_id
+			}
+			// La copia la crea el usuario autenticado
+			issue.AuthorID = currentUserID(c)
+
+			id, err := models.CreateIssue(tx, issue)
+			if err != nil {
+				c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+				return
+			}
+			data.Copied[original.ID] = id
+
+			relation := &models.IssueRelation{IssueFromID: original.ID, IssueToID: id, RelationType: models.IssueRelationCopiedTo}
+			if _, err := models.CreateIssueRelation(tx, relation); err != nil {
+				c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+				return
+			}
+
+			values, err := models.GetCustomFieldValuesByEntity(db, "issue", original.ID)
+			if err != nil {
+				c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+				return
+			}
+			for _, value := range values {
+				value.EntityID = id
+				if _, err := models.CreateCustomFieldValue(tx, &value); err != nil {
+					c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+					return
+				}
+			}
+
+			// ID del comentario original -> ID de su copia, para los adjuntos
+			comment_ids := map[int]int{}
+			if payload.Comments {
+				comments, err := models.GetCommentsByIssueID(db, original.ID)
+				if err != nil {
+					c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+					return
+				}
+				for _, comment := range comments {
+					comment.IssueID = id
+					comment_id, err := models.CreateComment(tx, &comment)
+					if err == nil {
+						// Conservar las fechas de los comentarios originales
+						err = models.SetCommentTimestamps(tx, comment_id, comment.CreatedAt, comment.UpdatedAt)
+					}
+					if err != nil {
+						c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+						return
+					}
+					comment_ids[comment.ID] = comment_id
+				}
+			}
+
+			if payload.Attachments {
+				attachments, err := models.GetAttachmentsByIssueID(db, original.ID)
+				if err != nil {
+					c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+					return
+				}
+				for _, attachment := range attachments {
+					var comment_id *int
+					if attachment.CommentID != nil {
+						if copied, ok := comment_ids[*attachment.CommentID]; ok {
+							comment_id = &copied
+						}
+					}
+					if _, err := models.CopyAttachment(tx, attachment.ID, id, comment_id); err != nil {
+						c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+						return
+					}
+				}
+			}
+		}
+
+		if err := tx.Commit(); err != nil {
+			c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+
+		data.Issue, err = models.GetIssueByID(db, data.Copied[source.ID])
+		if err != nil {
+			c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+
+		c.JSON(http.StatusCreated, data)
+	}
+}
+
+// @Summary: MoveIssueHandler
+// @Description: Move an issue and its subtasks to another project. The category is replaced by the same-named one in the target project or cleared, the version is cleared, and the move is recorded in the issue history.
+// @Tags: issues
+// @Accept: json
+// @Produce: json
+// @Param id path int true "Issue ID"
+// @Param move body IssueCopyPayload true "Target project and optional tracker"
+// @Param If-Match header string false "ETag returned by GET"
+// @Success 200 {object} models.Issue
+// @Failure 400 {object} map[string]string
+// @Failure 403 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Failure 409 {object} map[string]interface{}
+// @Failure 422 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Router /issue/{id}/move [post]
+// @Security BearerAuth
+func MoveIssueHandler(cfg *config.Config) gin.HandlerFunc {
+	return func(c *gin.Context) {
+
+		// Inicializar la base de datos
+		db, err := database.InitDBContext(c.Request.Context(), cfg)
+		if err != nil {
+			c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		defer db.Close()
+
+		var payload IssueCopyPayload
+		source := issueCopyTarget(c, db, &payload)
+		if source == nil {
+			return
+		}
+		if payload.ProjectID == source.ProjectID {
+			c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": "project_id must be another project"})
+			return
+		}
+
+		if c.GetHeader("If-Match") != "" {
+			lock_version, ok := lockVersionFromRequest(c, 0)
+			if !ok {
+				return
+			}
+			if lock_version != source.LockVersion {
+				respondStale(c, source.LockVersion, source)
+				return
+			}
+		}
+
+		// Las subtareas se mueven con su padre, ya que deben estar en su mismo proyecto
+		originals, err := issueTree(db, source)
+		if err != nil {
+			c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+
+		moved := make([]models.Issue, len(originals))
+		for i, original := range originals {
+			issue := *original
+			if err := retargetIssue(db, &issue, payload.ProjectID); err != nil {
+				c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+				return
+			}
+			if i == 0 && payload.TrackerID != 0 {
+				issue.TrackerID = payload.TrackerID
+			}
+
+			// El padre del ticket principal se queda en el proyecto de origen. El de las subtareas
+			// se mueve con ellas, pero aún no lo ha hecho, así que se valida sin él
+			parent_id := issue.ParentID
+			issue.ParentID = nil
+			status, msg := validateIssue(db, &issue, original)
+			if i > 0 {
+				issue.ParentID = parent_id
+			}
+			if status != 0 {
+				if i > 0 {
+					msg = fmt.Sprintf("subtask #%d: %s", original.ID, msg)
+				}
+				c.AbortWithStatusJSON(status, gin.H{"error": msg})
+				return
+			}
+			moved[i] = issue
+		}
+
+		tx, err := db.Begin()
+		if err != nil {
+			c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		defer tx.Rollback()
+
+		for i, original := range originals {
+			if err := models.UpdateIssue(tx, &moved[i]); err == models.ErrStaleObject {
+				// Otra petición lo modificó entre la lectura y la escritura
+				tx.Rollback()
+				current, err := models.GetIssueByID(db, original.ID)
+				if err != nil {
+					c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+					return
+				}
+				respondStale(c, current.LockVersion, current)
+				return
+			} else if err != nil {
+				c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+				return
+			}
+
+			// Historial de cambios: el traslado queda como cambio de project_id
+			if err := models.RecordIssueChanges(tx, original, &moved[i], currentUserID(c)); err != nil {
+				c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+				return
+			}
+		}
+
+		if err := tx.Commit(); err != nil {
+			c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+
+		updated, err := models.GetIssueByID(db, source.ID)
+		if err != nil {
+			c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+
+		c.Header("ETag", etag(updated.LockVersion))
+		c.JSON(http.StatusOK, updated)
+	}
+}
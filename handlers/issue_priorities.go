@@ -0,0 +1,39 @@
+package handlers
+
+import (
+	"go-redmine-ish/config"
+	"go-redmine-ish/database"
+	"go-redmine-ish/models"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// @Summary: GetIssuePrioritiesHandler
+// @Description: Get the issue priorities, from lowest to highest
+// @Tags: issues
+// @Produce: json
+// @Success 200 {array} models.IssuePriority
+// @Failure 500 {object} map[string]string
+// @Router /issue_priorities [get]
+// @Security BearerAuth
+func GetIssuePrioritiesHandler(cfg *config.Config) gin.HandlerFunc {
+	return func(c *gin.Context) {
+
+		// Inicializar la base de datos
+		db, err := database.InitDBContext(c.Request.Context(), cfg)
+		if err != nil {
+			c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		defer db.Close()
+
+		priorities, err := models.GetAllIssuePriorities(db)
+		if err != nil {
+			c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+
+		c.JSON(http.StatusOK, priorities)
+	}
+}
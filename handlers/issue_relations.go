@@ -0,0 +1,268 @@
+package handlers
+
+import (
+	"database/sql"
+	"go-redmine-ish/config"
+	"go-redmine-ish/database"
+	"go-redmine-ish/models"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+type GetIssueRelationsHandlerData struct {
+	Relations []models.IssueRelation `json:"relations"`
+}
+
+// getVisibleIssue obtiene un ticket si existe y el usuario ve su proyecto. Devuelve 0 si se
+// encontró, o el código HTTP y el mensaje de error a responder
+func getVisibleIssue(c *gin.Context, db *sql.DB, id int) (*models.Issue, int, string) {
+	issue, err := models.GetIssueByID(db, id)
+	if err == sql.ErrNoRows {
+		return nil, http.StatusNotFound, "Issue not found"
+	}
+	if err != nil {
+		return nil, http.StatusInternalServerError, err.Error()
+	}
+
+	visible, err := canViewProject(c, db, issue.ProjectID)
+	if err != nil {
+		return nil, http.StatusInternalServerError, err.Error()
+	}
+	if !visible {
+		return nil, http.StatusNotFound, "Issue not found"
+	}
+
+	return issue, 0, ""
+}
+
+// @Summary: GetIssueRelationsHandler
+// @Description: Get the relations of an issue, seen from it (e.g. follows for a relation stored as precedes from the other issue)
+// @Tags: issues
+// @Produce: json
+// @Param id path int true "Issue ID"
+// @Success 200 {object} GetIssueRelationsHandlerData
+// @Failure 400 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Router /issue/{id}/relations [get]
+// @Security BearerAuth
+func GetIssueRelationsHandler(cfg *config.Config) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		pid := c.Param("id")
+
+		// pasar string id a int id
+		id, err := strconv.Atoi(pid)
+		if err != nil {
+			c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		// Inicializar la base de datos
+		db, err := database.InitDBContext(c.Request.Context(), cfg)
+		if err != nil {
+			c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		defer db.Close()
+
+		if _, status, msg := getVisibleIssue(c, db, id); status != 0 {
+			c.AbortWithStatusJSON(status, gin.H{"error": msg})
+			return
+		}
+
+		relations, err := models.GetIssueRelationsByIssueID(db, id)
+		if err != nil {
+			c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+
+		c.JSON(http.StatusOK, GetIssueRelationsHandlerData{Relations: relations})
+	}
+}
+
+// @Summary: CreateIssueRelationHandler
+// @Description: Relate an issue to another one. relation_type is relates, copied_to, precedes or their inverses (copied_from, follows), which are stored from the other issue. delay is only used by precedes / follows, in days. A precedes relation that would make a cycle is rejected.
+// @Tags: issues
+// @Accept: json
+// @Produce: json
+// @Param id path int true "Issue ID"
+// @Param relation body models.IssueRelation true "issue_to_id, relation_type and delay"
+// @Success 201 {object} models.IssueRelation
+// @Failure 400 {object} map[string]string
+// @Failure 403 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Failure 422 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Router /issue/{id}/relations [post]
+// @Security BearerAuth
+func CreateIssueRelationHandler(cfg *config.Config) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		pid := c.Param("id")
+
+		// pasar string id a int id
+		id, err := strconv.Atoi(pid)
+		if err != nil {
+			c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		var relation models.IssueRelation
+		if err := c.ShouldBindJSON(&relation); err != nil {
+			c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		// Inicializar la base de datos
+		db, err := database.InitDBContext(c.Request.Context(), cfg)
+		if err != nil {
+			c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		defer db.Close()
+
+		issue, status, msg := getVisibleIssue(c, db, id)
+		if status != 0 {
+			c.AbortWithStatusJSON(status, gin.H{"error": msg})
+			return
+		}
+		if status, msg := checkProjectWritable(db, issue.ProjectID); status != 0 {
+			c.AbortWithStatusJSON(status, gin.H{"error": msg})
+			return
+		}
+
+		if relation.IssueToID == id {
+			c.AbortWithStatusJSON(http.StatusUnprocessableEntity, gin.H{"error": "An issue cannot be related to itself"})
+			return
+		}
+		if _, status, msg := getVisibleIssue(c, db, relation.IssueToID); status == http.StatusNotFound {
+			c.AbortWithStatusJSON(http.StatusUnprocessableEntity, gin.H{"error": "issue_to_id does not exist"})
+			return
+		} else if status != 0 {
+			c.AbortWithStatusJSON(status, gin.H{"error": msg})
+			return
+		}
+
+		// Los tipos inversos se guardan desde el otro ticket
+		relation.IssueFromID = id
+		if !models.IsIssueRelationType(relation.RelationType) {
+			stored := models.InverseIssueRelationType(relation.RelationType)
+			if stored == "" {
+				c.AbortWithStatusJSON(http.StatusUnprocessableEntity, gin.H{"error": "Unknown relation_type " + strconv.Quote(relation.RelationType)})
+				return
+			}
+			relation.RelationType = stored
+			relation.IssueFromID, relation.IssueToID = relation.IssueToID, relation.IssueFromID
+		}
+
+		if relation.RelationType == models.IssueRelationPrecedes {
+			if relation.Delay == nil {
+				delay := 0
+				relation.Delay = &delay
+			}
+			cycle, err := models.IssuePrecedes(db, relation.IssueToID, relation.IssueFromID)
+			if err != nil {
+				c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+				return
+			}
+			if cycle {
+				c.AbortWithStatusJSON(http.StatusUnprocessableEntity, gin.H{"error": "The relation would create a circular dependency"})
+				return
+			}
+		} else {
+			relation.Delay = nil
+		}
+
+		existing, err := models.GetIssueRelationsByIssueID(db, relation.IssueFromID)
+		if err != nil {
+			c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		for _, other := range existing {
+			if other.IssueToID == relation.IssueToID {
+				c.AbortWithStatusJSON(http.StatusUnprocessableEntity, gin.H{"error": "The issues are already related"})
+				return
+			}
+		}
+
+		relation_id, err := models.CreateIssueRelation(db, &relation)
+		if err != nil {
+			c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+
+		created, err := models.GetIssueRelationByID(db, relation_id)
+		if err != nil {
+			c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+
+		c.JSON(http.StatusCreated, created)
+	}
+}
+
+// @Summary: DeleteIssueRelationHandler
+// @Description: Delete a relation of an issue, from either of its two issues
+// @Tags: issues
+// @Param id path int true "Issue ID"
+// @Param relation_id path int true "Relation ID"
+// @Success 204 {object} nil
+// @Failure 400 {object} map[string]string
+// @Failure 403 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Router /issue/{id}/relations/{relation_id} [delete]
+// @Security BearerAuth
+func DeleteIssueRelationHandler(cfg *config.Config) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		pid := c.Param("id")
+
+		// pasar string id a int id
+		id, err := strconv.Atoi(pid)
+		if err != nil {
+			c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		relation_id, err := strconv.Atoi(c.Param("relation_id"))
+		if err != nil {
+			c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		// Inicializar la base de datos
+		db, err := database.InitDBContext(c.Request.Context(), cfg)
+		if err != nil {
+			c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		defer db.Close()
+
+		issue, status, msg := getVisibleIssue(c, db, id)
+		if status != 0 {
+			c.AbortWithStatusJSON(status, gin.H{"error": msg})
+			return
+		}
+		if status, msg := checkProjectWritable(db, issue.ProjectID); status != 0 {
+			c.AbortWithStatusJSON(status, gin.H{"error": msg})
+			return
+		}
+
+		relation, err := models.GetIssueRelationByID(db, relation_id)
+		if err != nil {
+			c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		if relation == nil || (relation.IssueFromID != id && relation.IssueToID != id) {
+			c.AbortWithStatusJSON(http.StatusNotFound, gin.H{"error": "Relation not found"})
+			return
+		}
+
+		if err := models.DeleteIssueRelation(db, relation_id); err != nil {
+			c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+
+		c.JSON(http.StatusNoContent, nil)
+	}
+}
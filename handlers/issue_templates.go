@@ -0,0 +1,547 @@
+package handlers
+
+import (
+	"database/sql"
+	"errors"
+	"go-redmine-ish/config"
+	"go-redmine-ish/database"
+	"go-redmine-ish/models"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/gin-gonic/gin"
+)
+
+type GetIssueTemplateHandlerData struct {
+	Template *models.IssueTemplate     `json:"template"`
+	Runs     []models.IssueTemplateRun `json:"runs"`
+}
+
+// issueFromTemplate construye el ticket de una ejecución de la plantilla
+func issueFromTemplate(template *models.IssueTemplate, scheduled time.Time) models.Issue {
+	issue := models.Issue{
+		ProjectID:    template.ProjectID,
+		TrackerID:    template.TrackerID,
+		Subject:      truncateRunes(strings.ReplaceAll(template.Subject, "{date}", scheduled.Format("2006-01-02")), 255),
+		Description:  template.Description,
+		AssignedToID: template.AssignedToID,
+		CategoryID:   template.CategoryID,
+	}
+	if template.PriorityID != nil {
+		issue.PriorityID = *template.PriorityID
+	}
+	return issue
+}
+
+// truncateRunes recorta value a max caracteres sin partir ninguno
+func truncateRunes(value string, max int) string {
+	for utf8.RuneCountInString(value) > max {
+		_, size := utf8.DecodeLastRuneInString(value)
+		value = value[:len(value)-size]
+	}
+	return value
+}
+
+// prepareIssueTemplate valida una plantilla antes de guardarla, normaliza starts_at a UTC y calcula
+// su próxima ejecución. Devuelve 0 si es válida, o el código HTTP y el mensaje de error a responder
+func prepareIssueTemplate(db *sql.DB, template *models.IssueTemplate) (int, string) {
+	if strings.TrimSpace(template.Name) == "" {
+		return http.StatusUnprocessableEntity, "name is required"
+	}
+	schedule, err := models.ParseSchedule(template.Schedule)
+	if err != nil {
+		return http.StatusUnprocessableEntity, err.Error()
+	}
+
+	now := time.Now().UTC()
+	start := now.Truncate(time.Minute)
+	if template.StartsAt != "" {
+		if start, err = time.Parse(time.RFC3339, template.StartsAt); err != nil {
+			if start, err = time.Parse("2006-01-02", template.StartsAt); err != nil {
+				return http.StatusUnprocessableEntity, "starts_at must be a date (YYYY-MM-DD) or an RFC 3339 time"
+			}
+		}
+	}
+	template.StartsAt = start.UTC().Format(time.RFC3339)
+
+	if template.CustomFields == nil {
+		template.CustomFields = map[string]string{}
+	}
+	if _, err := bulkCustomFields(db, template.CustomFields); err != nil {
+		return http.StatusUnprocessableEntity, err.Error()
+	}
+
+	// El ticket de la plantilla debe poder crearse ahora mismo en el proyecto
+	issue := issueFromTemplate(template, start)
+	if status, msg := validateIssue(db, &issue, nil); status != 0 {
+		return status, msg
+	}
+
+	template.NextRunAt = nil
+	if next, ok := schedule.Next(start, now); ok {
+		value := next.Format(time.RFC3339)
+		template.NextRunAt = &value
+	}
+
+	return 0, ""
+}
+
+// getVisibleIssueTemplate obtiene una plantilla si existe y el usuario ve su proyecto. Devuelve 0
+// si se encontró, o el código HTTP y el mensaje de error a responder
+func getVisibleIssueTemplate(c *gin.Context, db *sql.DB, id int) (*models.IssueTemplate, int, string) {
+	template, err := models.GetIssueTemplateByID(db, id)
+	if err != nil {
+		return nil, http.StatusInternalServerError, err.Error()
+	}
+	if template == nil {
+		return nil, http.StatusNotFound, "Issue template not found"
+	}
+
+	visible, err := canViewProject(c, db, template.ProjectID)
+	if err != nil {
+		return nil, http.StatusInternalServerError, err.Error()
+	}
+	if !visible {
+		return nil, http.StatusNotFound, "Issue template not found"
+	}
+
+	return template, 0, ""
+}
+
+// @Summary: GetProjectIssueTemplatesHandler
+// @Description: Get the recurring issue templates of a project
+// @Tags: issue_templates
+// @Produce: json
+// @Param id path int true "Project ID"
+// @Success 200 {array} models.IssueTemplate
+// @Failure 400 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Router /project/{id}/issue_templates [get]
+// @Security BearerAuth
+func GetProjectIssueTemplatesHandler(cfg *config.Config) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		pid := c.Param("id")
+
+		// pasar string id a int id
+		id, err := strconv.Atoi(pid)
+		if err != nil {
+			c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		// Inicializar la base de datos
+		db, err := database.InitDBContext(c.Request.Context(), cfg)
+		if err != nil {
+			c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		defer db.Close()
+
+		allowed, err := canViewProject(c, db, id)
+		if err != nil {
+			c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		if !allowed {
+			c.AbortWithStatusJSON(http.StatusNotFound, gin.H{"error": "Project not found"})
+			return
+		}
+
+		templates, err := models.GetIssueTemplatesByProjectID(db, id)
+		if err != nil {
+			c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+
+		c.JSON(http.StatusOK, templates)
+	}
+}
+
+// @Summary: CreateIssueTemplateHandler
+// @Description: Create a recurring issue template in a project. schedule is a cron expression (minute hour day month weekday, UTC) or "every N days|weeks|months" counted from starts_at. {date} in the subject is replaced by the date of each run. custom_fields maps custom field IDs to values. enabled defaults to true.
+// @Tags: issue_templates
+// @Accept: json
+// @Produce: json
+// @Param id path int true "Project ID"
+// @Param template body models.IssueTemplate true "Template"
+// @Success 201 {object} models.IssueTemplate
+// @Failure 400 {object} map[string]string
+// @Failure 403 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Failure 422 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Router /project/{id}/issue_templates [post]
+// @Security BearerAuth
+func CreateIssueTemplateHandler(cfg *config.Config) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		pid := c.Param("id")
+
+		// pasar string id a int id
+		id, err := strconv.Atoi(pid)
+		if err != nil {
+			c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		template := models.IssueTemplate{Enabled: true}
+		if err := c.ShouldBindJSON(&template); err != nil {
+			c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		template.ProjectID = id
+
+		// Inicializar la base de datos
+		db, err := database.InitDBContext(c.Request.Context(), cfg)
+		if err != nil {
+			c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		defer db.Close()
+
+		allowed, err := canViewProject(c, db, id)
+		if err != nil {
+			c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		if !allowed {
+			c.AbortWithStatusJSON(http.StatusNotFound, gin.H{"error": "Project not found"})
+			return
+		}
+
+		if status, msg := prepareIssueTemplate(db, &template); status != 0 {
+			c.AbortWithStatusJSON(status, gin.H{"error": msg})
+			return
+		}
+
+		template_id, err := models.CreateIssueTemplate(db, &template)
+		if err != nil {
+			c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+
+		created, err := models.GetIssueTemplateByID(db, template_id)
+		if err != nil {
+			c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+
+		c.JSON(http.StatusCreated, created)
+	}
+}
+
+// @Summary: GetIssueTemplateHandler
+// @Description: Get a recurring issue template with its runs, newest first, and the issue each run created
+// @Tags: issue_templates
+// @Produce: json
+// @Param id path int true "Issue template ID"
+// @Success 200 {object} GetIssueTemplateHandlerData
+// @Failure 400 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Router /issue_template/{id} [get]
+// @Security BearerAuth
+func GetIssueTemplateHandler(cfg *config.Config) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		pid := c.Param("id")
+
+		// pasar string id a int id
+		id, err := strconv.Atoi(pid)
+		if err != nil {
+			c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		// Inicializar la base de datos
+		db, err := database.InitDBContext(c.Request.Context(), cfg)
+		if err != nil {
+			c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		defer db.Close()
+
+		template, status, msg := getVisibleIssueTemplate(c, db, id)
+		if status != 0 {
+			c.AbortWithStatusJSON(status, gin.H{"error": msg})
+			return
+		}
+
+		runs, err := models.GetIssueTemplateRuns(db, id)
+		if err != nil {
+			c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+
+		c.JSON(http.StatusOK, GetIssueTemplateHandlerData{Template: template, Runs: runs})
+	}
+}
+
+// @Summary: UpdateIssueTemplateHandler
+// @Description: Replace a recurring issue template. The next run is recalculated from the new schedule.
+// @Tags: issue_templates
+// @Accept: json
+// @Produce: json
+// @Param id path int true "Issue template ID"
+// @Param template body models.IssueTemplate true "Template"
+// @Success 200 {object} models.IssueTemplate
+// @Failure 400 {object} map[string]string
+// @Failure 403 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Failure 422 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Router /issue_template/{id} [put]
+// @Security BearerAuth
+func UpdateIssueTemplateHandler(cfg *config.Config) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		pid := c.Param("id")
+
+		// pasar string id a int id
+		id, err := strconv.Atoi(pid)
+		if err != nil {
+			c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		template := models.IssueTemplate{Enabled: true}
+		if err := c.ShouldBindJSON(&template); err != nil {
+			c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		// Inicializar la base de datos
+		db, err := database.InitDBContext(c.Request.Context(), cfg)
+		if err != nil {
+			c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		defer db.Close()
+
+		existing, status, msg := getVisibleIssueTemplate(c, db, id)
+		if status != 0 {
+			c.AbortWithStatusJSON(status, gin.H{"error": msg})
+			return
+		}
+		template.ID = id
+		template.ProjectID = existing.ProjectID
+
+		if status, msg := prepareIssueTemplate(db, &template); status != 0 {
+			c.AbortWithStatusJSON(status, gin.H{"error": msg})
+			return
+		}
+
+		if err := models.UpdateIssueTemplate(db, &template); err != nil {
+			c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+
+		updated, err := models.GetIssueTemplateByID(db, id)
+		if err != nil {
+			c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+
+		c.JSON(http.StatusOK, updated)
+	}
+}
+
+// @Summary: DeleteIssueTemplateHandler
+// @Description: Delete a recurring issue template and its runs. The issues it created are kept.
+// @Tags: issue_templates
+// @Param id path int true "Issue template ID"
+// @Success 204 {object} nil
+// @Failure 400 {object} map[string]string
+// @Failure 403 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Router /issue_template/{id} [delete]
+// @Security BearerAuth
+func DeleteIssueTemplateHandler(cfg *config.Config) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		pid := c.Param("id")
+
+		// pasar string id a int id
+		id, err := strconv.Atoi(pid)
+		if err != nil {
+			c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		// Inicializar la base de datos
+		db, err := database.InitDBContext(c.Request.Context(), cfg)
+		if err != nil {
+			c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		defer db.Close()
+
+		template, status, msg := getVisibleIssueTemplate(c, db, id)
+		if status != 0 {
+			c.AbortWithStatusJSON(status, gin.H{"error": msg})
+			return
+		}
+		if status, msg := checkProjectWritable(db, template.ProjectID); status != 0 {
+			c.AbortWithStatusJSON(status, gin.H{"error": msg})
+			return
+		}
+
+		if err := models.DeleteIssueTemplate(db, id); err != nil {
+			c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+
+		c.JSON(http.StatusNoContent, nil)
+	}
+}
+
+// RunIssueTemplateScheduler crea cada interval los tickets de las plantillas cuya ejecución ha
+// llegado. Puede estar activo en varias réplicas a la vez: cada plantilla se procesa en una
+// transacción que la bloquea y la ejecución queda registrada con una clave única, así que ni un
+// reinicio a mitad ni otra réplica crean el ticket dos veces
+func RunIssueTemplateScheduler(cfg *config.Config, interval time.Duration) {
+	for {
+		if err := runDueIssueTemplates(cfg); err != nil {
+			slog.Error("issue templates: run failed", "error", err)
+		}
+		time.Sleep(interval)
+	}
+}
+
+// runDueIssueTemplates ejecuta todas las plantillas pendientes
+func runDueIssueTemplates(cfg *config.Config) error {
+	// Inicializar la base de datos
+	db, err := database.InitDB(cfg)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	for {
+		pending, err := runNextIssueTemplate(db)
+		if err != nil || !pending {
+			return err
+		}
+	}
+}
+
+// runNextIssueTemplate ejecuta una plantilla pendiente y la pasa a su siguiente ejecución. Devuelve
+// false si no quedaba ninguna. Si el servicio estuvo parado, las ejecuciones perdidas se resumen
+// en un solo ticket y la siguiente es la primera futura
+func runNextIssueTemplate(db *sql.DB) (bool, error) {
+	tx, err := db.Begin()
+	if err != nil {
+		return false, err
+	}
+	defer tx.Rollback()
+
+	template, err := models.ClaimDueIssueTemplate(tx)
+	if err != nil || template == nil {
+		return false, err
+	}
+
+	scheduled, err := time.Parse(time.RFC3339, *template.NextRunAt)
+	if err != nil {
+		return false, err
+	}
+
+	var runError *string
+	run := &models.IssueTemplateRun{TemplateID: template.ID, ScheduledAt: *template.NextRunAt}
+	created, err := models.CreateIssueTemplateRun(tx, run)
+	if err != nil {
+		return false, err
+	}
+	if created {
+		issue_id, msg, err := createIssueFromTemplate(db, tx, template, scheduled)
+		if err != nil {
+			return false, err
+		}
+		if msg != "" {
+			runError = &msg
+			slog.Warn("issue template run failed", "template_id", template.ID, "error", msg)
+		}
+		if err := models.SetIssueTemplateRunResult(tx, run.ID, issue_id, runError); err != nil {
+			return false, err
+		}
+	}
+
+	var next_run_at *string
+	schedule, err := models.ParseSchedule(template.Schedule)
+	if err != nil {
+		msg := err.Error()
+		runError = &msg
+	} else {
+		start, err := time.Parse(time.RFC3339, template.StartsAt)
+		if err != nil {
+			return false, err
+		}
+		after := time.Now().UTC()
+		if scheduled.After(after) {
+			after = scheduled
+		}
+		if next, ok := schedule.Next(start, after); ok {
+			value := next.Format(time.RFC3339)
+			next_run_at = &value
+		}
+	}
+
+	if err := models.FinishIssueTemplateRun(tx, template.ID, next_run_at, runError); err != nil {
+		return false, err
+	}
+	if err := tx.Commit(); err != nil {
+		return false, err
+	}
+
+	return true, nil
+}
+
+// createIssueFromTemplate crea dentro de la transacción el ticket de una ejecución con sus campos
+// personalizados. Si el ticket no es válido (p. ej. el proyecto se archivó) devuelve el motivo
+func createIssueFromTemplate(db *sql.DB, tx *sql.Tx, template *models.IssueTemplate, scheduled time.Time) (*int, string, error) {
+	issue := issueFromTemplate(template, scheduled)
+	if status, msg := validateIssue(db, &issue, nil); status == http.StatusInternalServerError {
+		return nil, "", errors.New(msg)
+	} else if status != 0 {
+		return nil, msg, nil
+	}
+
+	values, err := bulkCustomFields(db, template.CustomFields)
+	if err != nil {
+		return nil, err.Error(), nil
+	}
+
+	// Si la base de datos rechaza el ticket se vuelve al punto de guardado, para poder registrar el
+	// error y pasar a la siguiente ejecución en vez de reintentar para siempre la misma plantilla
+	if _, err := tx.Exec(`SAVEPOINT template_issue`); err != nil {
+		return nil, "", err
+	}
+	id, err := createTemplateIssue(tx, &issue, values)
+	if models.IsPermanentError(err) {
+		if _, err := tx.Exec(`ROLLBACK TO SAVEPOINT template_issue`); err != nil {
+			return nil, "", err
+		}
+		return nil, err.Error(), nil
+	}
+	if err != nil {
+		return nil, "", err
+	}
+
+	return &id, "", nil
+}
+
+// createTemplateIssue crea el ticket y sus campos personalizados
+func createTemplateIssue(tx *sql.Tx, issue *models.Issue, values []models.CustomFieldValue) (int, error) {
+	id, err := models.CreateIssue(tx, issue)
+	if err != nil {
+		return 0, err
+	}
+	for _, value := range values {
+		value.EntityID = id
+		if _, err := models.CreateCustomFieldValue(tx, &value); err != nil {
+			return 0, err
+		}
+	}
+	return id, nil
+}
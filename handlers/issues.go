@@ -1,11 +1,15 @@
 package handlers
 
 import (
+	"database/sql"
+	"fmt"
 	"go-redmine-ish/config"
 	"go-redmine-ish/database"
 	"go-redmine-ish/models"
 	"net/http"
 	"strconv"
+	"strings"
+	"time"
 
 	"github.com/gin-gonic/gin"
 )
@@ -14,31 +18,132 @@ type GetIssuesHandlerData struct {
 	Issues []models.Issue `json:"issues"`
 }
 
-// @Summary: InitHandler
-// @Description: Initialize the database
-// @Tags: init
+// issueFilterFromQuery lee los filtros del listado de tickets de la query string
+func issueFilterFromQuery(c *gin.Context) (models.IssueFilter, error) {
+	filter := models.IssueFilter{
+		Status: c.Query("status"),
+		Sort:   c.Query("sort"),
+	}
+
+	params := map[string]*int{
+		"project_id":       &filter.ProjectID,
+		"tracker_id":       &filter.TrackerID,
+		"assigned_to_id":   &filter.AssignedToID,
+		"category_id":      &filter.CategoryID,
+		"fixed_version_id": &filter.FixedVersionID,
+		"priority_id":      &filter.PriorityID,
+		"author_id":        &filter.AuthorID,
+		"offset":           &filter.Offset,
+		"limit":            &filter.Limit,
+	}
+	for name, value := range params {
+		if q := c.Query(name); q != "" {
+			n, err := strconv.Atoi(q)
+			if err != nil {
+				return filter, fmt.Errorf("invalid %s: %v", name, err)
+			}
+			*value = n
+		}
+	}
+
+	ratios := map[string]**int{
+		"done_ratio_min": &filter.DoneRatioMin,
+		"done_ratio_max": &filter.DoneRatioMax,
+	}
+	for name, value := range ratios {
+		if q := c.Query(name); q != "" {
+			n, err := strconv.Atoi(q)
+			if err != nil {
+				return filter, fmt.Errorf("invalid %s: %v", name, err)
+			}
+			*value = &n
+		}
+	}
+
+	dates := map[string]*string{
+		"start_date_from": &filter.StartDateFrom,
+		"start_date_to":   &filter.StartDateTo,
+		"due_date_from":   &filter.DueDateFrom,
+		"due_date_to":     &filter.DueDateTo,
+	}
+	for name, value := range dates {
+		if q := c.Query(name); q != "" {
+			if _, err := time.Parse("2006-01-02", q); err != nil {
+				return filter, fmt.Errorf("invalid %s: %v", name, err)
+			}
+			*value = q
+		}
+	}
+
+	if q := c.Query("overdue"); q != "" {
+		overdue, err := strconv.ParseBool(q)
+		if err != nil {
+			return filter, fmt.Errorf("invalid overdue: %v", err)
+		}
+		filter.Overdue = overdue
+	}
+
+	return filter, nil
+}
+
+// @Summary: GetIssuesHandler
+// @Description: Get issues, optionally filtered
+// @Tags: issues
 // @Produce: json
-// @Success 200 {object} map[string]string
+// @Param project_id query int false "Project ID"
+// @Param tracker_id query int false "Tracker ID"
+// @Param status query string false "Status name, open or closed"
+// @Param assigned_to_id query int false "Assigned user ID"
+// @Param category_id query int false "Category ID"
+// @Param fixed_version_id query int false "Version ID"
+// @Param priority_id query int false "Priority ID"
+// @Param author_id query int false "Author user ID"
+// @Param start_date_from query string false "Start date on or after (YYYY-MM-DD)"
+// @Param start_date_to query string false "Start date on or before (YYYY-MM-DD)"
+// @Param due_date_from query string false "Due date on or after (YYYY-MM-DD)"
+// @Param due_date_to query string false "Due date on or before (YYYY-MM-DD)"
+// @Param done_ratio_min query int false "Minimum done ratio"
+// @Param done_ratio_max query int false "Maximum done ratio"
+// @Param overdue query bool false "Only open issues past their due date"
+// @Param sort query string false "Sort column, e.g. updated_on:desc"
+// @Param offset query int false "Offset"
+// @Param limit query int false "Limit"
+// @Param render query string false "html to include description_html"
+// @Success 200 {object} GetIssuesHandlerData
+// @Failure 400 {object} map[string]string
 // @Failure 500 {object} map[string]string
-// @Router /init [get]
+// @Router /issues [get]
 // @Security BearerAuth
 func GetIssuesHandler(cfg *config.Config) gin.HandlerFunc {
 	return func(c *gin.Context) {
 
+		filter, err := issueFilterFromQuery(c)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
 		// Inicializar la base de datos
-		db, err := database.InitDB(cfg)
+		db, err := database.InitDBContext(c.Request.Context(), cfg)
 		if err != nil {
 			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 			return
 		}
 		defer db.Close()
 
-		issues, err := models.GetAllIssues(db)
+		issues, err := models.GetIssuesFiltered(db, filter)
 		if err != nil {
 			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 			return
 		}
 
+		if wantsHTML(c) {
+			if err := renderIssueHTML(newMarkdownRenderer(cfg, db), issues); err != nil {
+				c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+				return
+			}
+		}
+
 		data := GetIssuesHandlerData{
 			Issues: issues,
 		}
@@ -48,12 +153,16 @@ func GetIssuesHandler(cfg *config.Config) gin.HandlerFunc {
 }
 
 type GetIssueHandlerData struct {
-	Issue      *models.Issue     `json:"issue,omitempty"`
-	Trackers   []models.Tracker  `json:"trackers"`
-	Project    *models.Project   `json:"project,omitempty"`
-	Users      []models.User     `json:"users,omitempty"`
-	Categories []models.Category `json:"categories,omitempty"`
-	Comments   []models.Comment  `json:"comments,omitempty"`
+	Issue       *models.Issue            `json:"issue,omitempty"`
+	Trackers    []models.Tracker         `json:"trackers"`
+	Project     *models.Project          `json:"project,omitempty"`
+	Users       []models.User            `json:"users,omitempty"`
+	Categories  []models.Category        `json:"categories,omitempty"`
+	Comments    []models.Comment         `json:"comments,omitempty"`
+	Subtasks    []models.Issue           `json:"subtasks,omitempty"`
+	Relations   []models.IssueRelation   `json:"relations,omitempty"`
+	TemplateRun *models.IssueTemplateRun `json:"template_run,omitempty"`
+	Attachments []models.Attachment      `json:"attachments,omitempty"`
 }
 
 // @Summary: GetIssueHandler
@@ -61,9 +170,12 @@ type GetIssueHandlerData struct {
 // @Tags: issues
 // @Produce: json
 // @Param id path int true "Issue ID"
+// @Param render query string false "html to include description_html and content_html"
+// @Param If-Match header string false "ETag returned by a previous GET"
 // @Success 200 {object} GetIssueHandlerData
 // @Failure 400 {object} map[string]string
 // @Failure 404 {object} map[string]string
+// @Failure 412 {object} map[string]string
 // @Failure 500 {object} map[string]string
 // @Router /issue/{id} [get]
 // @Security BearerAuth
@@ -90,7 +202,7 @@ func GetIssueHandler(cfg *config.Config) gin.HandlerFunc {
 		}
 
 		// Inicializar la base de datos
-		db, err := database.InitDB(cfg)
+		db, err := database.InitDBContext(c.Request.Context(), cfg)
 		if err != nil {
 			c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 			return
@@ -122,6 +234,10 @@ func GetIssueHandler(cfg *config.Config) gin.HandlerFunc {
 
 			data.Issue = issue
 
+			if !checkETag(c, issue.LockVersion) {
+				return
+			}
+
 			if issue.ProjectID != 0 {
 				project_id = issue.ProjectID
 			}
@@ -143,6 +259,13 @@ func GetIssueHandler(cfg *config.Config) gin.HandlerFunc {
 			if len(categories) > 0 {
 				data.Categories = categories
 			}
+
+			// Solo los trackers habilitados en el proyecto
+			data.Trackers, err = models.GetTrackersByProjectID(db, project_id)
+			if err != nil {
+				c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+				return
+			}
 		}
 
 		if id > 0 {
@@ -165,6 +288,57 @@ func GetIssueHandler(cfg *config.Config) gin.HandlerFunc {
 			if len(comments) > 0 {
 				data.Comments = comments
 			}
+
+			subtasks, err := models.GetIssuesByParentID(db, id)
+			if err != nil {
+				c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+				return
+			}
+
+			if len(subtasks) > 0 {
+				data.Subtasks = subtasks
+			}
+
+			relations, err := models.GetIssueRelationsByIssueID(db, id)
+			if err != nil {
+				c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+				return
+			}
+
+			if len(relations) > 0 {
+				data.Relations = relations
+			}
+
+			data.TemplateRun, err = models.GetIssueTemplateRunByIssueID(db, id)
+			if err != nil {
+				c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+				return
+			}
+
+			attachments, err := models.GetAttachmentsByIssueID(db, id)
+			if err != nil {
+				c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+				return
+			}
+
+			if len(attachments) > 0 {
+				data.Attachments = attachments
+			}
+
+			if wantsHTML(c) {
+				renderer := newMarkdownRenderer(cfg, db)
+				html, err := renderer.render(data.Issue.Description)
+				if err != nil {
+					c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+					return
+				}
+				data.Issue.DescriptionHTML = html
+
+				if err := renderCommentHTML(renderer, data.Comments); err != nil {
+					c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+					return
+				}
+			}
 		}
 
 		c.JSON(http.StatusOK, data)
@@ -179,6 +353,7 @@ func GetIssueHandler(cfg *config.Config) gin.HandlerFunc {
 // @Param issue body models.Issue true "Issue"
 // @Success 201 {object} models.Issue
 // @Failure 400 {object} map[string]string
+// @Failure 422 {object} map[string]string
 // @Failure 500 {object} map[string]string
 // @Router /issue [post]
 // @Security BearerAuth
@@ -191,20 +366,44 @@ func CreateIssueHandler(cfg *config.Config) gin.HandlerFunc {
 		}
 
 		// Inicializar la base de datos
-		db, err := database.InitDB(cfg)
+		db, err := database.InitDBContext(c.Request.Context(), cfg)
 		if err != nil {
 			c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 			return
 		}
 		defer db.Close()
 
-		id, err := models.CreateIssue(db, &issue)
+		if status, msg := validateIssue(db, &issue, nil); status != 0 {
+			c.AbortWithStatusJSON(status, gin.H{"error": msg})
+			return
+		}
+		issue.AuthorID = currentUserID(c)
+
+		// El ticket y sus menciones se guardan juntos
+		tx, err := db.Begin()
+		if err != nil {
+			c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		defer tx.Rollback()
+
+		id, err := models.CreateIssue(tx, &issue)
 		if err != nil {
 			c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 			return
 		}
 		issue.ID = id
 
+		if err := recordMentions(c, cfg, db, tx, id, nil, issue.Description); err != nil {
+			c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+
+		if err := tx.Commit(); err != nil {
+			c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+
 		c.JSON(http.StatusCreated, issue)
 	}
 }
@@ -215,10 +414,13 @@ func CreateIssueHandler(cfg *config.Config) gin.HandlerFunc {
 // @Accept: json
 // @Produce: json
 // @Param id path int true "Issue ID"
-// @Param issue body models.Issue true "Issue"
+// @Param issue body models.Issue true "Issue, with the lock_version that was read"
+// @Param If-Match header string false "ETag returned by GET, instead of lock_version"
 // @Success 200 {object} models.Issue
 // @Failure 400 {object} map[string]string
 // @Failure 404 {object} map[string]string
+// @Failure 409 {object} map[string]interface{}
+// @Failure 428 {object} map[string]string
 // @Failure 500 {object} map[string]string
 // @Router /issue/{id} [put]
 // @Security BearerAuth
@@ -245,26 +447,317 @@ func UpdateIssueHandler(cfg *config.Config) gin.HandlerFunc {
 		}
 
 		// Inicializar la base de datos
-		db, err := database.InitDB(cfg)
+		db, err := database.InitDBContext(c.Request.Context(), cfg)
+		if err != nil {
+			c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		defer db.Close()
+
+		before, err := models.GetIssueByID(db, id)
+		if err == sql.ErrNoRows {
+			c.AbortWithStatusJSON(http.StatusNotFound, gin.H{"error": "Issue not found"})
+			return
+		}
+		if err != nil {
+			c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+
+		lock_version, ok := lockVersionFromRequest(c, issue.LockVersion)
+		if !ok {
+			return
+		}
+		issue.LockVersion = lock_version
+
+		updateIssue(c, cfg, db, before, &issue)
+	}
+}
+
+// @Summary: PatchIssueHandler
+// @Description: Partially update an issue with a JSON Merge Patch. Only the fields present change; null clears assigned_to_id or category_id
+// @Tags: issues
+// @Accept: json
+// @Produce: json
+// @Param id path int true "Issue ID"
+// @Param issue body models.Issue true "Only the fields to change; null clears optional fields"
+// @Param If-Match header string false "ETag returned by GET"
+// @Success 200 {object} models.Issue
+// @Failure 400 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Failure 409 {object} map[string]interface{}
+// @Failure 422 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Router /issue/{id} [patch]
+// @Security BearerAuth
+func PatchIssueHandler(cfg *config.Config) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		pid := c.Param("id")
+
+		// pasar string id a int id
+		id, err := strconv.Atoi(pid)
+		if err != nil {
+			c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		// Inicializar la base de datos
+		db, err := database.InitDBContext(c.Request.Context(), cfg)
 		if err != nil {
 			c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 			return
 		}
 		defer db.Close()
 
-		if err := models.UpdateIssue(db, &issue); err != nil {
+		before, err := models.GetIssueByID(db, id)
+		if err == sql.ErrNoRows {
+			c.AbortWithStatusJSON(http.StatusNotFound, gin.H{"error": "Issue not found"})
+			return
+		}
+		if err != nil {
 			c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 			return
 		}
 
-		updated, err := models.GetIssueByID(db, id)
+		var issue models.Issue
+		if !bindMergePatch(c, before, &issue) {
+			return
+		}
+		if id != issue.ID {
+			c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": "ID in body and URL do not match"})
+			return
+		}
+
+		// Sin lock_version en el parche ni If-Match se aplica sobre la versión que se acaba de leer
+		lock_version, ok := lockVersionFromRequest(c, issue.LockVersion)
+		if !ok {
+			return
+		}
+		issue.LockVersion = lock_version
+
+		updateIssue(c, cfg, db, before, &issue)
+	}
+}
+
+// validateIssue comprueba un ticket antes de crearlo (before es nil) o de modificarlo. Devuelve 0
+// si es válido, o el código HTTP y el mensaje de error a responder
+func validateIssue(db *sql.DB, issue *models.Issue, before *models.Issue) (int, string) {
+	if strings.TrimSpace(issue.Subject) == "" {
+		return http.StatusUnprocessableEntity, "subject is required"
+	}
+	if status, msg := checkIssuePlanning(db, issue); status != 0 {
+		return status, msg
+	}
+
+	if before == nil {
+		if status, msg := checkProjectWritable(db, issue.ProjectID); status != 0 {
+			return status, msg
+		}
+		if status, msg := checkProjectModule(db, issue.ProjectID, models.ModuleIssues); status != 0 {
+			return status, msg
+		}
+		if status, msg := checkProjectTracker(db, issue.ProjectID, issue.TrackerID); status != 0 {
+			return status, msg
+		}
+		if status, msg := checkTrackerFields(db, issue, nil); status != 0 {
+			return status, msg
+		}
+		return checkIssueReferences(db, issue, nil)
+	}
+
+	// Tanto el proyecto de origen como el de destino deben admitir cambios
+	if status, msg := checkProjectWritable(db, before.ProjectID); status != 0 {
+		return status, msg
+	}
+	if issue.ProjectID != before.ProjectID {
+		if status, msg := checkProjectWritable(db, issue.ProjectID); status != 0 {
+			return status, msg
+		}
+		if status, msg := checkProjectModule(db, issue.ProjectID, models.ModuleIssues); status != 0 {
+			return status, msg
+		}
+	}
+	if issue.ProjectID != before.ProjectID || issue.TrackerID != before.TrackerID {
+		if status, msg := checkProjectTracker(db, issue.ProjectID, issue.TrackerID); status != 0 {
+			return status, msg
+		}
+	}
+	if status, msg := checkTrackerFields(db, issue, before); status != 0 {
+		return status, msg
+	}
+	return checkIssueReferences(db, issue, before)
+}
+
+// checkIssuePlanning comprueba la prioridad, las fechas y el porcentaje realizado del ticket
+func checkIssuePlanning(db *sql.DB, issue *models.Issue) (int, string) {
+	if issue.PriorityID != 0 {
+		priority, err := models.GetIssuePriorityByID(db, issue.PriorityID)
+		if err != nil {
+			return http.StatusInternalServerError, err.Error()
+		}
+		if priority == nil {
+			return http.StatusUnprocessableEntity, "priority_id does not exist"
+		}
+	}
+
+	dates := map[string]*string{"start_date": issue.StartDate, "due_date": issue.DueDate}
+	for name, value := range dates {
+		if value == nil {
+			continue
+		}
+		if _, err := time.Parse("2006-01-02", *value); err != nil {
+			return http.StatusUnprocessableEntity, fmt.Sprintf("%s must be a date (YYYY-MM-DD)", name)
+		}
+	}
+	// Con el formato YYYY-MM-DD el orden de las cadenas es el de las fechas
+	if issue.StartDate != nil && issue.DueDate != nil && *issue.DueDate < *issue.StartDate {
+		return http.StatusUnprocessableEntity, "due_date must be on or after start_date"
+	}
+
+	if issue.DoneRatio < 0 || issue.DoneRatio > 100 {
+		return http.StatusUnprocessableEntity, "done_ratio must be between 0 and 100"
+	}
+	if issue.EstimatedHours != nil && *issue.EstimatedHours < 0 {
+		return http.StatusUnprocessableEntity, "estimated_hours must not be negative"
+	}
+
+	return 0, ""
+}
+
+// checkIssueReferences comprueba que la categoría, la versión y el ticket padre que se asignan,
+// o que se conservan al mover el ticket de proyecto, son del proyecto del ticket, que una versión
+// nueva sigue abierta (las bloqueadas o cerradas no admiten más tickets) y que el padre no crea un ciclo
+func checkIssueReferences(db *sql.DB, issue *models.Issue, before *models.Issue) (int, string) {
+	moved := before == nil || issue.ProjectID != before.ProjectID
+
+	if issue.CategoryID != nil && (moved || !sameOptionalID(issue.CategoryID, before.CategoryID)) {
+		category, err := models.GetCategoryByID(db, *issue.CategoryID)
+		if err == sql.ErrNoRows {
+			return http.StatusUnprocessableEntity, "category_id does not exist"
+		}
+		if err != nil {
+			return http.StatusInternalServerError, err.Error()
+		}
+		if category.ProjectID != issue.ProjectID {
+			return http.StatusUnprocessableEntity, "category_id belongs to another project"
+		}
+	}
+
+	if issue.FixedVersionID != nil && (moved || !sameOptionalID(issue.FixedVersionID, before.FixedVersionID)) {
+		version, err := models.GetVersionByID(db, *issue.FixedVersionID)
+		if err != nil {
+			return http.StatusInternalServerError, err.Error()
+		}
+		if version == nil {
+			return http.StatusUnprocessableEntity, "fixed_version_id does not exist"
+		}
+		if version.ProjectID != issue.ProjectID {
+			return http.StatusUnprocessableEntity, "fixed_version_id belongs to another project"
+		}
+		if version.Status != models.VersionStatusOpen {
+			return http.StatusUnprocessableEntity, fmt.Sprintf("version %s is %s", version.Name, version.Status)
+		}
+	}
+
+	if issue.ParentID != nil && (moved || !sameOptionalID(issue.ParentID, before.ParentID)) {
+		parent, err := models.GetIssueByID(db, *issue.ParentID)
+		if err == sql.ErrNoRows {
+			return http.StatusUnprocessableEntity, "parent_id does not exist"
+		}
+		if err != nil {
+			return http.StatusInternalServerError, err.Error()
+		}
+		if parent.ProjectID != issue.ProjectID {
+			return http.StatusUnprocessableEntity, "parent_id belongs to another project"
+		}
+		if before != nil {
+			cycle, err := models.IsIssueAncestor(db, issue.ID, parent.ID)
+			if err != nil {
+				return http.StatusInternalServerError, err.Error()
+			}
+			if cycle {
+				return http.StatusUnprocessableEntity, "An issue cannot be its own ancestor"
+			}
+		}
+	}
+
+	return 0, ""
+}
+
+// sameOptionalID indica si dos IDs opcionales son iguales, ambos nil incluido
+func sameOptionalID(a, b *int) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return *a == *b
+}
+
+// updateIssue guarda los cambios de issue sobre before, comprobando su lock_version, y responde
+// con el ticket actualizado. Lo comparten PUT y PATCH
+func updateIssue(c *gin.Context, cfg *config.Config, db *sql.DB, before *models.Issue, issue *models.Issue) {
+	if issue.LockVersion != before.LockVersion {
+		respondStale(c, before.LockVersion, before)
+		return
+	}
+
+	if status, msg := validateIssue(db, issue, before); status != 0 {
+		c.AbortWithStatusJSON(status, gin.H{"error": msg})
+		return
+	}
+	if status, msg := checkIssueWorkflow(c, db, before, issue); status != 0 {
+		c.AbortWithStatusJSON(status, gin.H{"error": msg})
+		return
+	}
+
+	// El ticket y su historial se guardan juntos: la actividad, el burndown y el flujo acumulado
+	// se reconstruyen a partir del historial
+	tx, err := db.Begin()
+	if err != nil {
+		c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	defer tx.Rollback()
+
+	if err := models.UpdateIssue(tx, issue); err == models.ErrStaleObject {
+		// Otra petición lo modificó entre la lectura y la escritura
+		current, err := models.GetIssueByID(db, issue.ID)
 		if err != nil {
 			c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 			return
 		}
+		respondStale(c, current.LockVersion, current)
+		return
+	} else if err != nil {
+		c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
 
-		c.JSON(http.StatusOK, updated)
+	// Historial de cambios, usado por la actividad del proyecto
+	if err := models.RecordIssueChanges(tx, before, issue, currentUserID(c)); err != nil {
+		c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
 	}
+
+	if issue.Description != before.Description {
+		if err := recordMentions(c, cfg, db, tx, issue.ID, nil, issue.Description); err != nil {
+			c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	updated, err := models.GetIssueByID(db, issue.ID)
+	if err != nil {
+		c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.Header("ETag", etag(updated.LockVersion))
+	c.JSON(http.StatusOK, updated)
 }
 
 // @Summary: UpdateIssueHandler
@@ -291,13 +784,28 @@ func DeleteIssueHandler(cfg *config.Config) gin.HandlerFunc {
 		}
 
 		// Inicializar la base de datos
-		db, err := database.InitDB(cfg)
+		db, err := database.InitDBContext(c.Request.Context(), cfg)
 		if err != nil {
 			c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 			return
 		}
 		defer db.Close()
 
+		issue, err := models.GetIssueByID(db, id)
+		if err == sql.ErrNoRows {
+			c.AbortWithStatusJSON(http.StatusNotFound, gin.H{"error": "Issue not found"})
+			return
+		}
+		if err != nil {
+			c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+
+		if status, msg := checkProjectWritable(db, issue.ProjectID); status != 0 {
+			c.AbortWithStatusJSON(status, gin.H{"error": msg})
+			return
+		}
+
 		if err := models.DeleteIssue(db, id); err != nil {
 			c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 			return
@@ -0,0 +1,385 @@
+package handlers
+
+import (
+	"database/sql"
+	"fmt"
+	"go-redmine-ish/config"
+	"go-redmine-ish/database"
+	"go-redmine-ish/models"
+	"net/http"
+	"sort"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+// maxBulkIssues es el máximo de tickets de una operación masiva
+const maxBulkIssues = 500
+
+// bulkIssueChangeFields son los campos que puede cambiar bulk_update
+var bulkIssueChangeFields = map[string]bool{
+	"status":           true,
+	"assigned_to_id":   true,
+	"category_id":      true,
+	"tracker_id":       true,
+	"fixed_version_id": true,
+}
+
+// BulkIssuesFilter selecciona los tickets de una operación masiva, con los mismos criterios que GET /issues
+type BulkIssuesFilter struct {
+	ProjectID      int    `json:"project_id"`
+	TrackerID      int    `json:"tracker_id"`
+	Status         string `json:"status"`
+	AssignedToID   int    `json:"assigned_to_id"`
+	CategoryID     int    `json:"category_id"`
+	FixedVersionID int    `json:"fixed_version_id"`
+}
+
+// BulkIssuesPayload indica los tickets, por ids o por filter, y en bulk_update los cambios:
+// changes es un JSON Merge Patch (null vacía el campo) y custom_fields va de ID de campo a valor
+type BulkIssuesPayload struct {
+	IDs          []int                  `json:"ids"`
+	Filter       *BulkIssuesFilter      `json:"filter"`
+	Changes      map[string]interface{} `json:"changes"`
+	CustomFields map[string]string      `json:"custom_fields"`
+}
+
+type BulkIssueResult struct {
+	IssueID int      `json:"issue_id"`
+	Subject string   `json:"subject,omitempty"`
+	Errors  []string `json:"errors,omitempty"`
+}
+
+type BulkIssuesHandlerData struct {
+	Total     int               `json:"total"`
+	Valid     int               `json:"valid"`
+	Processed int               `json:"processed"`
+	Issues    []BulkIssueResult `json:"issues"`
+}
+
+// bulkIssues devuelve los tickets de la operación que puede ver el usuario, y el informe con una
+// entrada por ticket, en la que ya constan como error los que no existen o no puede ver
+func bulkIssues(c *gin.Context, db *sql.DB, payload *BulkIssuesPayload) ([]*models.Issue, *BulkIssuesHandlerData, int, error) {
+	if (payload.IDs == nil) == (payload.Filter == nil) {
+		return nil, nil, http.StatusBadRequest, fmt.Errorf("either ids or filter is required")
+	}
+
+	visible, err := visibleProjectIDs(c, db)
+	if err != nil {
+		return nil, nil, http.StatusInternalServerError, err
+	}
+	canView := func(projectID int) bool {
+		if visible == nil {
+			return true
+		}
+		for _, id := range visible {
+			if id == projectID {
+				return true
+			}
+		}
+		return false
+	}
+
+	data := &BulkIssuesHandlerData{Issues: []BulkIssueResult{}}
+	issues := []*models.Issue{}
+
+	if payload.Filter != nil {
+		filter := models.IssueFilter{
+			ProjectID:      payload.Filter.ProjectID,
+			ProjectIDs:     visible,
+			TrackerID:      payload.Filter.TrackerID,
+			Status:         payload.Filter.Status,
+			AssignedToID:   payload.Filter.AssignedToID,
+			CategoryID:     payload.Filter.CategoryID,
+			FixedVersionID: payload.Filter.FixedVersionID,
+			Limit:          maxBulkIssues + 1,
+		}
+		found, err := models.GetIssuesFiltered(db, filter)
+		if err != nil {
+			return nil, nil, http.StatusInternalServerError, err
+		}
+		if len(found) > maxBulkIssues {
+			return nil, nil, http.StatusBadRequest, fmt.Errorf("the filter matches more than %d issues", maxBulkIssues)
+		}
+		for i := range found {
+			issues = append(issues, &found[i])
+		}
+	} else {
+		ids := map[int]bool{}
+		for _, id := range payload.IDs {
+			ids[id] = true
+		}
+		if len(ids) > maxBulkIssues {
+			return nil, nil, http.StatusBadRequest, fmt.Errorf("at most %d issues can be changed at once", maxBulkIssues)
+		}
+		sorted := []int{}
+		for id := range ids {
+			sorted = append(sorted, id)
+		}
+		sort.Ints(sorted)
+
+		for _, id := range sorted {
+			issue, err := models.GetIssueByID(db, id)
+			if err != nil && err != sql.ErrNoRows {
+				return nil, nil, http.StatusInternalServerError, err
+			}
+			// Los tickets de proyectos que no puede ver se tratan como inexistentes
+			if err == sql.ErrNoRows || !canView(issue.ProjectID) {
+				data.Issues = append(data.Issues, BulkIssueResult{IssueID: id, Errors: []string{"Issue not found"}})
+				continue
+			}
+			issues = append(issues, issue)
+		}
+	}
+
+	data.Total = len(data.Issues) + len(issues)
+	return issues, data, 0, nil
+}
+
+// bulkCustomFields comprueba que los campos personalizados existen y devuelve los valores a guardar
+func bulkCustomFields(db *sql.DB, values map[string]string) ([]models.CustomFieldValue, error) {
+	fields, err := models.GetCustomFields(db)
+	if err != nil {
+		return nil, err
+	}
+
+	result := []models.CustomFieldValue{}
+	for ref, value := range values {
+		id, err := strconv.Atoi(ref)
+		if err != nil {
+			return nil, fmt.Errorf("custom_fields keys must be custom field IDs, got %q", ref)
+		}
+		var field *models.CustomField
+		for i := range fields {
+			if fields[i].ID == id {
+				field = &fields[i]
+			}
+		}
+		if field == nil {
+			return nil, fmt.Errorf("custom field %d not found", id)
+		}
+		if err := validateCustomFieldValue(field, value); err != nil {
+			return nil, err
+		}
+		result = append(result, models.CustomFieldValue{CustomFieldID: id, EntityType: "issue", Value: value})
+	}
+
+	return result, nil
+}
+
+// @Summary: BulkUpdateIssuesHandler
+// @Description: Change status, assignee, category, tracker, version and custom fields of several issues, selected by ids or filter. changes is a JSON Merge Patch where null clears the field. Every issue is checked against its project, tracker and workflow; if any fails nothing is changed and the report lists the errors, otherwise all issues are updated in one transaction.
+// @Tags: issues
+// @Accept: json
+// @Produce: json
+// @Param payload body BulkIssuesPayload true "Issues and changes"
+// @Success 200 {object} BulkIssuesHandlerData
+// @Failure 400 {object} map[string]string
+// @Failure 409 {object} BulkIssuesHandlerData
+// @Failure 422 {object} BulkIssuesHandlerData
+// @Failure 500 {object} map[string]string
+// @Router /issues/bulk_update [post]
+// @Security BearerAuth
+func BulkUpdateIssuesHandler(cfg *config.Config) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var payload BulkIssuesPayload
+		if err := c.ShouldBindJSON(&payload); err != nil {
+			c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		if len(payload.Changes) == 0 && len(payload.CustomFields) == 0 {
+			c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": "changes or custom_fields is required"})
+			return
+		}
+		for field := range payload.Changes {
+			if !bulkIssueChangeFields[field] {
+				c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("field %q cannot be changed in bulk", field)})
+				return
+			}
+		}
+
+		// Inicializar la base de datos
+		db, err := database.InitDBContext(c.Request.Context(), cfg)
+		if err != nil {
+			c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		defer db.Close()
+
+		values, err := bulkCustomFields(db, payload.CustomFields)
+		if err != nil {
+			c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		befores, data, status, err := bulkIssues(c, db, &payload)
+		if err != nil {
+			c.AbortWithStatusJSON(status, gin.H{"error": err.Error()})
+			return
+		}
+
+		// Se valida cada ticket con las mismas reglas que PUT y PATCH antes de cambiar ninguno
+		updates := []*models.Issue{}
+		for _, before := range befores {
+			result := BulkIssueResult{IssueID: before.ID, Subject: before.Subject}
+
+			var issue models.Issue
+			if err := applyMergePatch(before, payload.Changes, &issue); err != nil {
+				c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+				return
+			}
+
+			status, msg := validateIssue(db, &issue, before)
+			if status == 0 {
+				status, msg = checkIssueWorkflow(c, db, before, &issue)
+			}
+			if status == http.StatusInternalServerError {
+				c.AbortWithStatusJSON(status, gin.H{"error": msg})
+				return
+			}
+			if status != 0 {
+				result.Errors = []string{msg}
+			} else {
+				data.Valid++
+			}
+
+			data.Issues = append(data.Issues, result)
+			updates = append(updates, &issue)
+		}
+
+		if data.Valid != data.Total {
+			c.AbortWithStatusJSON(http.StatusUnprocessableEntity, data)
+			return
+		}
+
+		// Todos los tickets en una única transacción
+		tx, err := db.Begin()
+		if err != nil {
+			c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		defer tx.Rollback()
+
+		for i, issue := range updates {
+			if err := models.UpdateIssue(tx, issue); err == models.ErrStaleObject {
+				// Otra petición lo modificó mientras se validaba
+				data.Issues[i].Errors = []string{err.Error()}
+				c.AbortWithStatusJSON(http.StatusConflict, data)
+				return
+			} else if err != nil {
+				c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+				return
+			}
+
+			if err := models.RecordIssueChanges(tx, befores[i], issue, currentUserID(c)); err != nil {
+				c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+				return
+			}
+
+			for _, value := range values {
+				value.EntityID = issue.ID
+				if err := models.SetCustomFieldValue(tx, &value); err != nil {
+					c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+					return
+				}
+			}
+		}
+
+		if err := tx.Commit(); err != nil {
+			c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		data.Processed = len(updates)
+
+		c.JSON(http.StatusOK, data)
+	}
+}
+
+// @Summary: BulkDeleteIssuesHandler
+// @Description: Delete several issues, selected by ids or filter. If any issue cannot be deleted nothing is deleted and the report lists the errors, otherwise all are deleted in one transaction.
+// @Tags: issues
+// @Accept: json
+// @Produce: json
+// @Param payload body BulkIssuesPayload true "Issues"
+// @Success 200 {object} BulkIssuesHandlerData
+// @Failure 400 {object} map[string]string
+// @Failure 422 {object} BulkIssuesHandlerData
+// @Failure 500 {object} map[string]string
+// @Router /issues/bulk_delete [post]
+// @Security BearerAuth
+func BulkDeleteIssuesHandler(cfg *config.Config) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var payload BulkIssuesPayload
+		if err := c.ShouldBindJSON(&payload); err != nil {
+			c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		// Inicializar la base de datos
+		db, err := database.InitDBContext(c.Request.Context(), cfg)
+		if err != nil {
+			c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		defer db.Close()
+
+		issues, data, status, err := bulkIssues(c, db, &payload)
+		if err != nil {
+			c.AbortWithStatusJSON(status, gin.H{"error": err.Error()})
+			return
+		}
+
+		// Los proyectos cerrados o archivados no admiten borrar tickets
+		writable := map[int]string{}
+		for _, issue := range issues {
+			result := BulkIssueResult{IssueID: issue.ID, Subject: issue.Subject}
+
+			msg, ok := writable[issue.ProjectID]
+			if !ok {
+				var status int
+				status, msg = checkProjectWritable(db, issue.ProjectID)
+				if status == http.StatusInternalServerError {
+					c.AbortWithStatusJSON(status, gin.H{"error": msg})
+					return
+				}
+				writable[issue.ProjectID] = msg
+			}
+			if msg != "" {
+				result.Errors = []string{msg}
+			} else {
+				data.Valid++
+			}
+
+			data.Issues = append(data.Issues, result)
+		}
+
+		if data.Valid != data.Total {
+			c.AbortWithStatusJSON(http.StatusUnprocessableEntity, data)
+			return
+		}
+
+		// Todos los tickets en una única transacción
+		tx, err := db.Begin()
+		if err != nil {
+			c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		defer tx.Rollback()
+
+		for _, issue := range issues {
+			if err := models.DeleteIssue(tx, issue.ID); err != nil {
+				c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+				return
+			}
+		}
+
+		if err := tx.Commit(); err != nil {
+			c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		data.Processed = len(issues)
+
+		c.JSON(http.StatusOK, data)
+	}
+}
@@ -0,0 +1,651 @@
+package handlers
+
+import (
+	"database/sql"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"go-redmine-ish/config"
+	"go-redmine-ish/database"
+	"go-redmine-ish/models"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// @Summary: GetIssuesCSVHandler
+// @Description: Export issues as CSV, with the same filters as GET /issues
+// @Tags: issues
+// @Produce: text/csv
+// @Param project_id query int false "Project ID"
+// @Param tracker_id query int false "Tracker ID"
+// @Param status query string false "Status name, open or closed"
+// @Param assigned_to_id query int false "Assigned user ID"
+// @Param category_id query int false "Category ID"
+// @Param fixed_version_id query int false "Version ID"
+// @Param priority_id query int false "Priority ID"
+// @Param author_id query int false "Author user ID"
+// @Param start_date_from query string false "Start date on or after (YYYY-MM-DD)"
+// @Param start_date_to query string false "Start date on or before (YYYY-MM-DD)"
+// @Param due_date_from query string false "Due date on or after (YYYY-MM-DD)"
+// @Param due_date_to query string false "Due date on or before (YYYY-MM-DD)"
+// @Param done_ratio_min query int false "Minimum done ratio"
+// @Param done_ratio_max query int false "Maximum done ratio"
+// @Param overdue query bool false "Only open issues past their due date"
+// @Param sort query string false "Sort column, e.g. updated_on:desc"
+// @Success 200 {string} string "CSV"
+// @Failure 400 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Router /issues.csv [get]
+// @Security BearerAuth
+func GetIssuesCSVHandler(cfg *config.Config) gin.HandlerFunc {
+	return func(c *gin.Context) {
+
+		filter, err := issueFilterFromQuery(c)
+		if err != nil {
+			c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		// Inicializar la base de datos
+		db, err := database.InitDBContext(c.Request.Context(), cfg)
+		if err != nil {
+			c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		defer db.Close()
+
+		// Solo se exportan los tickets de los proyectos que ve el usuario
+		filter.ProjectIDs, err = visibleProjectIDs(c, db)
+		if err != nil {
+			c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+
+		issues, err := models.GetIssuesFiltered(db, filter)
+		if err != nil {
+			c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+
+		refs, err := newRedmineRefs(db)
+		if err != nil {
+			c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+
+		customFields, err := models.GetCustomFields(db)
+		if err != nil {
+			c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+
+		issue_ids := make([]int, len(issues))
+		for i, issue := range issues {
+			issue_ids[i] = issue.ID
+		}
+		values, err := models.GetCustomFieldValuesByEntityIDs(db, "issue", issue_ids)
+		if err != nil {
+			c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		// valores por ticket y campo
+		issueValues := map[int]map[int]string{}
+		for _, value := range values {
+			if issueValues[value.EntityID] == nil {
+				issueValues[value.EntityID] = map[int]string{}
+			}
+			issueValues[value.EntityID][value.CustomFieldID] = value.Value
+		}
+
+		c.Header("Content-Type", "text/csv; charset=utf-8")
+		c.Header("Content-Disposition", `attachment; filename="issues.csv"`)
+		c.Status(http.StatusOK)
+
+		// BOM para que las hojas de cálculo detecten UTF-8
+		c.Writer.WriteString("\ufeff")
+
+		writer := csv.NewWriter(c.Writer)
+
+		header := []string{"id", "project", "tracker", "status", "subject", "description", "assignee", "category", "created_at", "updated_at"}
+		for _, field := range customFields {
+			header = append(header, csvSafeCell(field.Name))
+		}
+		writer.Write(header)
+
+		name := func(ref *RedmineRef) string {
+			if ref == nil {
+				return ""
+			}
+			return ref.Name
+		}
+
+		for _, issue := range issues {
+			data := refs.issue(issue)
+			record := []string{
+				strconv.Itoa(issue.ID),
+				name(data.Project),
+				name(data.Tracker),
+				issue.Status,
+				issue.Subject,
+				issue.Description,
+				name(data.AssignedTo),
+				name(data.Category),
+				issue.CreatedAt,
+				issue.UpdatedAt,
+			}
+			for _, field := range customFields {
+				record = append(record, issueValues[issue.ID][field.ID])
+			}
+			for i := range record {
+				record[i] = csvSafeCell(record[i])
+			}
+			writer.Write(record)
+		}
+
+		writer.Flush()
+	}
+}
+
+// csvSafeCell evita que una hoja de cálculo ejecute como fórmula una celda que empieza por =, +,
+// -, @ (o tabulador / retorno de carro, que algunas ignoran antes de la fórmula) anteponiendo '
+func csvSafeCell(value string) string {
+	if value != "" && strings.ContainsRune("=+-@\t\r", rune(value[0])) {
+		return "'" + value
+	}
+	return value
+}
+
+// Campos de destino de la importación CSV. Los campos personalizados se indican
+// como "custom_field:<id>" o "custom_field:<nombre>".
+const (
+	csvFieldSubject     = "subject"
+	csvFieldDescription = "description"
+	csvFieldTracker     = "tracker"
+	csvFieldStatus      = "status"
+	csvFieldAssignee    = "assignee"
+	csvFieldCategory    = "category"
+	csvFieldCustomField = "custom_field:"
+)
+
+type ImportIssuesRowResult struct {
+	Row     int      `json:"row"`
+	IssueID int      `json:"issue_id,omitempty"`
+	Subject string   `json:"subject"`
+	Errors  []string `json:"errors,omitempty"`
+}
+
+type ImportIssuesHandlerData struct {
+	DryRun   bool                    `json:"dry_run"`
+	Total    int                     `json:"total"`
+	Valid    int                     `json:"valid"`
+	Imported int                     `json:"imported"`
+	Rows     []ImportIssuesRowResult `json:"rows"`
+}
+
+// csvIssueRow es una fila del CSV ya validada y lista para insertar
+type csvIssueRow struct {
+	issue  models.Issue
+	values []models.CustomFieldValue
+}
+
+// csvImportLookups resuelve nombres del CSV a IDs, con caché por valor
+type csvImportLookups struct {
+	db              *sql.DB
+	projectID       int
+	trackers        map[string]int
+	trackersByID    map[int]*models.Tracker
+	enabledTrackers map[int]bool // trackers habilitados en el proyecto
+	statuses        map[string]bool
+	statusNames     map[int]string
+	users           map[string]*int
+	categories      map[string]*int
+	customFields    []models.CustomField
+	defaultStatus   string
+}
+
+func newCSVImportLookups(db *sql.DB, projectID int) (*csvImportLookups, error) {
+	l := &csvImportLookups{
+		db:              db,
+		projectID:       projectID,
+		trackers:        map[string]int{},
+		trackersByID:    map[int]*models.Tracker{},
+		enabledTrackers: map[int]bool{},
+		statuses:        map[string]bool{},
+		statusNames:     map[int]string{},
+		users:           map[string]*int{},
+		categories:      map[string]*int{},
+		defaultStatus:   "Open",
+	}
+
+	trackers, err := models.GetAllTrackers(db)
+	if err != nil {
+		return nil, err
+	}
+	for i := range trackers {
+		l.trackers[strings.ToLower(trackers[i].Name)] = trackers[i].ID
+		l.trackersByID[trackers[i].ID] = &trackers[i]
+	}
+
+	enabled, err := models.GetTrackersByProjectID(db, projectID)
+	if err != nil {
+		return nil, err
+	}
+	for _, tracker := range enabled {
+		l.enabledTrackers[tracker.ID] = true
+	}
+
+	statuses, err := models.GetAllIssueStatuses(db)
+	if err != nil {
+		return nil, err
+	}
+	for i, status := range statuses {
+		if i == 0 {
+			l.defaultStatus = status.Name
+		}
+		l.statuses[status.Name] = true
+		l.statusNames[status.ID] = status.Name
+	}
+
+	l.customFields, err = models.GetCustomFields(db)
+	if err != nil {
+		return nil, err
+	}
+
+	return l, nil
+}
+
+// user busca un usuario por nombre de usuario o por correo
+func (l *csvImportLookups) user(value string) (*int, error) {
+	if id, ok := l.users[value]; ok {
+		return id, nil
+	}
+
+	var user *models.User
+	var err error
+	if strings.Contains(value, "@") {
+		user, err = models.GetUserByEmail(l.db, value)
+	} else {
+		user, err = models.GetUserByUsername(l.db, value)
+	}
+	if err != nil && err != sql.ErrNoRows {
+		return nil, err
+	}
+
+	var id *int
+	if user != nil {
+		id = &user.ID
+	}
+	l.users[value] = id
+	return id, nil
+}
+
+func (l *csvImportLookups) category(name string) (*int, error) {
+	if id, ok := l.categories[name]; ok {
+		return id, nil
+	}
+
+	category, err := models.GetCategoryByProjectAndName(l.db, l.projectID, name)
+	if err != nil {
+		return nil, err
+	}
+
+	var id *int
+	if category != nil {
+		id = &category.ID
+	}
+	l.categories[name] = id
+	return id, nil
+}
+
+// customField busca un campo personalizado por ID o por nombre
+func (l *csvImportLookups) customField(ref string) *models.CustomField {
+	id, err := strconv.Atoi(ref)
+	for i, field := range l.customFields {
+		if (err == nil && field.ID == id) || strings.EqualFold(field.Name, ref) {
+			return &l.customFields[i]
+		}
+	}
+	return nil
+}
+
+// validateCustomFieldValue comprueba el valor según el tipo del campo personalizado
+func validateCustomFieldValue(field *models.CustomField, value string) error {
+	if value == "" {
+		if field.IsRequired {
+			return fmt.Errorf("%s is required", field.Name)
+		}
+		return nil
+	}
+
+	switch field.FieldType {
+	case "number":
+		if _, err := strconv.ParseFloat(value, 64); err != nil {
+			return fmt.Errorf("%s must be a number", field.Name)
+		}
+	case "date":
+		if _, err := time.Parse("2006-01-02", value); err != nil {
+			return fmt.Errorf("%s must be a date (YYYY-MM-DD)", field.Name)
+		}
+	case "bool", "boolean":
+		if _, err := strconv.ParseBool(value); err != nil {
+			return fmt.Errorf("%s must be true or false", field.Name)
+		}
+	}
+	return nil
+}
+
+// parseCSVIssueRow convierte una fila del CSV en un ticket, acumulando los errores de validación
+func (l *csvImportLookups) parseCSVIssueRow(record []string, columns map[int]string) (*csvIssueRow, []string, error) {
+	row := &csvIssueRow{issue: models.Issue{ProjectID: l.projectID}}
+	errors := []string{}
+	mappedFields := map[int]bool{}
+	trackerReported := false
+
+	for index, field := range columns {
+		if index >= len(record) {
+			continue
+		}
+		value := strings.TrimSpace(record[index])
+
+		switch {
+		case field == csvFieldSubject:
+			row.issue.Subject = value
+		case field == csvFieldDescription:
+			row.issue.Description = value
+		case field == csvFieldTracker:
+			if id, ok := l.trackers[strings.ToLower(value)]; ok && !l.enabledTrackers[id] {
+				errors = append(errors, fmt.Sprintf("tracker %q is not enabled in the project", value))
+				trackerReported = true
+			} else if ok {
+				row.issue.TrackerID = id
+			} else if value != "" {
+				errors = append(errors, fmt.Sprintf("unknown tracker %q", value))
+				trackerReported = true
+			}
+		case field == csvFieldStatus:
+			if value == "" {
+				break
+			}
+			if !l.statuses[value] {
+				errors = append(errors, fmt.Sprintf("unknown status %q", value))
+			}
+			row.issue.Status = value
+		case field == csvFieldAssignee:
+			if value == "" {
+				break
+			}
+			id, err := l.user(value)
+			if err != nil {
+				return nil, nil, err
+			}
+			if id == nil {
+				errors = append(errors, fmt.Sprintf("unknown user %q", value))
+			}
+			row.issue.AssignedToID = id
+		case field == csvFieldCategory:
+			if value == "" {
+				break
+			}
+			id, err := l.category(value)
+			if err != nil {
+				return nil, nil, err
+			}
+			if id == nil {
+				errors = append(errors, fmt.Sprintf("unknown category %q", value))
+			}
+			row.issue.CategoryID = id
+		case strings.HasPrefix(field, csvFieldCustomField):
+			customField := l.customField(strings.TrimPrefix(field, csvFieldCustomField))
+			mappedFields[customField.ID] = true
+			if err := validateCustomFieldValue(customField, value); err != nil {
+				errors = append(errors, err.Error())
+			} else if value != "" {
+				row.values = append(row.values, models.CustomFieldValue{
+					CustomFieldID: customField.ID,
+					EntityType:    "issue",
+					Value:         value,
+				})
+			}
+		}
+	}
+
+	if row.issue.Subject == "" {
+		errors = append(errors, "subject is required")
+	}
+	if row.issue.TrackerID == 0 && !trackerReported {
+		errors = append(errors, "tracker is required")
+	}
+	if tracker := l.trackersByID[row.issue.TrackerID]; tracker != nil {
+		for _, field := range models.ApplyTrackerFields(tracker, &row.issue, nil) {
+			errors = append(errors, fmt.Sprintf("tracker %q does not use the field %s", tracker.Name, field))
+		}
+		if row.issue.Status == "" && tracker.DefaultStatusID != nil {
+			row.issue.Status = l.statusNames[*tracker.DefaultStatusID]
+		}
+	}
+	if row.issue.Status == "" {
+		row.issue.Status = l.defaultStatus
+	}
+	for i := range l.customFields {
+		if field := &l.customFields[i]; field.IsRequired && !mappedFields[field.ID] && field.DefaultValue == "" {
+			errors = append(errors, fmt.Sprintf("%s is required", field.Name))
+		}
+	}
+
+	return row, errors, nil
+}
+
+// csvColumns traduce la cabecera del CSV a los campos de destino. Sin mapping
+// explícito se usan las columnas cuyo nombre coincide con un campo o con un campo personalizado.
+func (l *csvImportLookups) csvColumns(header []string, mapping map[string]string) (map[int]string, error) {
+	columns := map[int]string{}
+	known := map[string]bool{
+		csvFieldSubject: true, csvFieldDescription: true, csvFieldTracker: true,
+		csvFieldStatus: true, csvFieldAssignee: true, csvFieldCategory: true,
+	}
+
+	for index, name := range header {
+		name = strings.TrimSpace(strings.TrimPrefix(name, "\ufeff"))
+
+		if len(mapping) > 0 {
+			field, ok := mapping[name]
+			if !ok || field == "" {
+				continue
+			}
+			if strings.HasPrefix(field, csvFieldCustomField) {
+				if l.customField(strings.TrimPrefix(field, csvFieldCustomField)) == nil {
+					return nil, fmt.Errorf("unknown custom field in mapping for column %q", name)
+				}
+			} else if !known[field] {
+				return nil, fmt.Errorf("unknown field %q in mapping for column %q", field, name)
+			}
+			columns[index] = field
+			continue
+		}
+
+		if known[strings.ToLower(name)] {
+			columns[index] = strings.ToLower(name)
+		} else if field := l.customField(name); field != nil {
+			columns[index] = csvFieldCustomField + strconv.Itoa(field.ID)
+		}
+	}
+
+	for _, field := range columns {
+		if field == csvFieldSubject {
+			return columns, nil
+		}
+	}
+	return nil, fmt.Errorf("no column is mapped to subject")
+}
+
+// @Summary: ImportIssuesHandler
+// @Description: Import issues into a project from a CSV file. The mapping form field is a JSON object from CSV column to field (subject, description, tracker, status, assignee, category or custom_field:<id|name>). With dry_run nothing is inserted; otherwise all rows are inserted in one transaction or none.
+// @Tags: issues
+// @Accept: multipart/form-data
+// @Produce: json
+// @Param id path int true "Project ID"
+// @Param file formData file true "CSV file"
+// @Param mapping formData string false "Column to field mapping (JSON)"
+// @Param separator formData string false "Field separator, defaults to ,"
+// @Param dry_run query bool false "Validate only"
+// @Success 200 {object} ImportIssuesHandlerData
+// @Success 201 {object} ImportIssuesHandlerData
+// @Failure 400 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Failure 422 {object} ImportIssuesHandlerData
+// @Failure 500 {object} map[string]string
+// @Router /project/{id}/issues/import [post]
+// @Security BearerAuth
+func ImportIssuesHandler(cfg *config.Config) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		pid := c.Param("id")
+
+		// pasar string id a int id
+		id, err := strconv.Atoi(pid)
+		if err != nil {
+			c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		dryRun, _ := strconv.ParseBool(c.DefaultQuery("dry_run", c.PostForm("dry_run")))
+
+		mapping := map[string]string{}
+		if value := c.PostForm("mapping"); value != "" {
+			if err := json.Unmarshal([]byte(value), &mapping); err != nil {
+				c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": "invalid mapping: " + err.Error()})
+				return
+			}
+		}
+
+		// El CSV llega como fichero multipart o directamente en el cuerpo
+		var body io.Reader = c.Request.Body
+		if file, err := c.FormFile("file"); err == nil {
+			f, err := file.Open()
+			if err != nil {
+				c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+				return
+			}
+			defer f.Close()
+			body = f
+		}
+
+		reader := csv.NewReader(body)
+		reader.FieldsPerRecord = -1
+		if separator := c.DefaultPostForm("separator", c.Query("separator")); separator != "" {
+			reader.Comma = []rune(separator)[0]
+		}
+
+		records, err := reader.ReadAll()
+		if err != nil {
+			c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": "invalid CSV: " + err.Error()})
+			return
+		}
+		if len(records) < 2 {
+			c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": "CSV must have a header and at least one row"})
+			return
+		}
+
+		// Inicializar la base de datos
+		db, err := database.InitDBContext(c.Request.Context(), cfg)
+		if err != nil {
+			c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		defer db.Close()
+
+		if status, msg := checkProjectWritable(db, id); status != 0 {
+			c.AbortWithStatusJSON(status, gin.H{"error": msg})
+			return
+		}
+		if status, msg := checkProjectModule(db, id, models.ModuleIssues); status != 0 {
+			c.AbortWithStatusJSON(status, gin.H{"error": msg})
+			return
+		}
+
+		lookups, err := newCSVImportLookups(db, id)
+		if err != nil {
+			c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+
+		columns, err := lookups.csvColumns(records[0], mapping)
+		if err != nil {
+			c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		data := ImportIssuesHandlerData{
+			DryRun: dryRun,
+			Total:  len(records) - 1,
+			Rows:   []ImportIssuesRowResult{},
+		}
+
+		rows := []*csvIssueRow{}
+		for i, record := range records[1:] {
+			row, errors, err := lookups.parseCSVIssueRow(record, columns)
+			if err != nil {
+				c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+				return
+			}
+			// La fila 1 es la cabecera
+			result := ImportIssuesRowResult{Row: i + 2, Subject: row.issue.Subject}
+			if len(errors) > 0 {
+				result.Errors = errors
+			} else {
+				data.Valid++
+			}
+			data.Rows = append(data.Rows, result)
+			rows = append(rows, row)
+		}
+
+		if dryRun {
+			c.JSON(http.StatusOK, data)
+			return
+		}
+
+		if data.Valid != data.Total {
+			c.AbortWithStatusJSON(http.StatusUnprocessableEntity, data)
+			return
+		}
+
+		// Todas las filas en una única transacción
+		tx, err := db.Begin()
+		if err != nil {
+			c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		defer tx.Rollback()
+
+		for i, row := range rows {
+			row.issue.AuthorID = currentUserID(c)
+			issueID, err := models.CreateIssue(tx, &row.issue)
+			if err != nil {
+				data.Rows[i].Errors = []string{err.Error()}
+				c.AbortWithStatusJSON(http.StatusUnprocessableEntity, data)
+				return
+			}
+			for _, value := range row.values {
+				value.EntityID = issueID
+				if _, err := models.CreateCustomFieldValue(tx, &value); err != nil {
+					data.Rows[i].Errors = []string{err.Error()}
+					c.AbortWithStatusJSON(http.StatusUnprocessableEntity, data)
+					return
+				}
+			}
+			data.Rows[i].IssueID = issueID
+		}
+
+		if err := tx.Commit(); err != nil {
+			c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		data.Imported = len(rows)
+
+		c.JSON(http.StatusCreated, data)
+	}
+}
@@ -0,0 +1,75 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Bloqueo optimista: tickets, proyectos y usuarios tienen un lock_version que se incrementa
+// en cada modificación. GET lo devuelve también como ETag y PUT exige la versión leída,
+// en el cuerpo o en If-Match, y responde 409 con el estado actual si ya no es la vigente
+
+// etag devuelve el ETag correspondiente a un lock_version
+func etag(lockVersion int) string {
+	return fmt.Sprintf(`"%d"`, lockVersion)
+}
+
+// parseETag devuelve el lock_version de un ETag, admitiendo la forma débil W/"n"
+func parseETag(value string) (int, error) {
+	value = strings.TrimPrefix(strings.TrimSpace(value), "W/")
+	return strconv.Atoi(strings.Trim(value, `"`))
+}
+
+// checkETag pone la cabecera ETag y, si la petición trae If-Match, comprueba que alguna
+// de sus etiquetas coincide. Si no coincide responde 412 y devuelve false
+func checkETag(c *gin.Context, lockVersion int) bool {
+	c.Header("ETag", etag(lockVersion))
+
+	header := c.GetHeader("If-Match")
+	if header == "" {
+		return true
+	}
+	for _, tag := range strings.Split(header, ",") {
+		if strings.TrimSpace(tag) == "*" {
+			return true
+		}
+		if version, err := parseETag(tag); err == nil && version == lockVersion {
+			return true
+		}
+	}
+
+	c.AbortWithStatusJSON(http.StatusPreconditionFailed, gin.H{"error": "If-Match does not match the current version"})
+	return false
+}
+
+// lockVersionFromRequest devuelve la versión que leyó el cliente: la de If-Match o, si no la
+// envía, el lock_version del cuerpo. Sin ninguna de las dos responde 428 y devuelve false
+func lockVersionFromRequest(c *gin.Context, bodyVersion int) (int, bool) {
+	if header := c.GetHeader("If-Match"); header != "" {
+		version, err := parseETag(header)
+		if err != nil {
+			c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": "If-Match must be a single ETag returned by GET"})
+			return 0, false
+		}
+		return version, true
+	}
+	if bodyVersion > 0 {
+		return bodyVersion, true
+	}
+
+	c.AbortWithStatusJSON(http.StatusPreconditionRequired, gin.H{"error": "lock_version or If-Match is required"})
+	return 0, false
+}
+
+// respondStale responde 409 con el estado actual del registro para que el cliente pueda fusionar sus cambios
+func respondStale(c *gin.Context, lockVersion int, current interface{}) {
+	c.Header("ETag", etag(lockVersion))
+	c.AbortWithStatusJSON(http.StatusConflict, gin.H{
+		"error":   "The record was modified by someone else since it was read",
+		"current": current,
+	})
+}
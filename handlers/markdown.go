@@ -0,0 +1,138 @@
+package handlers
+
+import (
+	"database/sql"
+	"fmt"
+	"go-redmine-ish/config"
+	"go-redmine-ish/markdown"
+	"go-redmine-ish/models"
+	"net/url"
+
+	"github.com/gin-gonic/gin"
+)
+
+// markdownRenderer convierte Markdown a HTML resolviendo las referencias contra la base de datos.
+// Solo enlaza tickets, usuarios y proyectos que existen, con caché durante la petición,
+// y acumula los usuarios mencionados
+type markdownRenderer struct {
+	cfg       *config.Config
+	db        *sql.DB
+	issues    map[int]bool
+	users     map[string]int
+	projects  map[string]bool
+	mentioned []int
+}
+
+func newMarkdownRenderer(cfg *config.Config, db *sql.DB) *markdownRenderer {
+	return &markdownRenderer{
+		cfg:      cfg,
+		db:       db,
+		issues:   map[int]bool{},
+		users:    map[string]int{},
+		projects: map[string]bool{},
+	}
+}
+
+// render convierte source a HTML saneado. Los errores al resolver una referencia la dejan como texto
+func (r *markdownRenderer) render(source string) (string, error) {
+	r.mentioned = nil
+	return markdown.Render(source, markdown.Links{
+		Issue:   r.issueURL,
+		User:    r.userURL,
+		Project: r.projectURL,
+	})
+}
+
+// mentions devuelve los IDs de los usuarios mencionados en el último render, sin repetir
+func (r *markdownRenderer) mentions() []int {
+	seen := map[int]bool{}
+	ids := []int{}
+	for _, id := range r.mentioned {
+		if !seen[id] {
+			seen[id] = true
+			ids = append(ids, id)
+		}
+	}
+	return ids
+}
+
+func (r *markdownRenderer) issueURL(id int) string {
+	exists, ok := r.issues[id]
+	if !ok {
+		_, err := models.GetIssueByID(r.db, id)
+		exists = err == nil
+		r.issues[id] = exists
+	}
+	if !exists {
+		return ""
+	}
+	return issueURL(r.cfg, id)
+}
+
+func (r *markdownRenderer) userURL(username string) string {
+	id, ok := r.users[username]
+	if !ok {
+		if user, err := models.GetUserByUsername(r.db, username); err == nil {
+			id = user.ID
+		}
+		r.users[username] = id
+	}
+	if id == 0 {
+		return ""
+	}
+	r.mentioned = append(r.mentioned, id)
+	return fmt.Sprintf("%s/user/%d", r.cfg.PublicURL, id)
+}
+
+func (r *markdownRenderer) projectURL(identifier string) string {
+	exists, ok := r.projects[identifier]
+	if !ok {
+		project, err := models.GetProjectByIdentifier(r.db, identifier)
+		exists = err == nil && project != nil
+		r.projects[identifier] = exists
+	}
+	if !exists {
+		return ""
+	}
+	return fmt.Sprintf("%s/project/%s", r.cfg.PublicURL, url.PathEscape(identifier))
+}
+
+// wantsHTML indica si la petición pide los campos *_html con ?render=html
+func wantsHTML(c *gin.Context) bool {
+	return c.Query("render") == "html"
+}
+
+// renderIssueHTML rellena description_html de los tickets
+func renderIssueHTML(r *markdownRenderer, issues []models.Issue) error {
+	for i := range issues {
+		html, err := r.render(issues[i].Description)
+		if err != nil {
+			return err
+		}
+		issues[i].DescriptionHTML = html
+	}
+	return nil
+}
+
+// renderCommentHTML rellena content_html de los comentarios
+func renderCommentHTML(r *markdownRenderer, comments []models.Comment) error {
+	for i := range comments {
+		html, err := r.render(comments[i].Content)
+		if err != nil {
+			return err
+		}
+		comments[i].ContentHTML = html
+	}
+	return nil
+}
+
+// recordMentions registra las menciones @usuario de la descripción de un ticket
+// (commentID nil) o de un comentario, para poder avisar a los mencionados. Las referencias se
+// resuelven con db y las menciones se escriben con tx, que es db si no hay transacción
+func recordMentions(c *gin.Context, cfg *config.Config, db *sql.DB, tx models.DBTX, issueID int, commentID *int, source string) error {
+	renderer := newMarkdownRenderer(cfg, db)
+	if _, err := renderer.render(source); err != nil {
+		return err
+	}
+	return models.CreateMentions(tx, issueID, commentID, renderer.mentions(), currentUserID(c))
+}
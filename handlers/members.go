@@ -0,0 +1,259 @@
+package handlers
+
+import (
+	"database/sql"
+	"go-redmine-ish/config"
+	"go-redmine-ish/database"
+	"go-redmine-ish/models"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+type ProjectMemberPayload struct {
+	UserID int `json:"user_id"`
+	RoleID int `json:"role_id" binding:"required"`
+}
+
+// saveMemberChange aplica el cambio de miembros y lo registra para la actividad en una misma transacción
+func saveMemberChange(db *sql.DB, change *models.MemberChange, apply func(tx *sql.Tx) error) error {
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if err := apply(tx); err != nil {
+		return err
+	}
+	if _, err := models.CreateMemberChange(tx, change); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// projectMemberFromParams lee el proyecto y el miembro de la URL y comprueba que el miembro pertenece
+// al proyecto y que este admite cambios
+func projectMemberFromParams(c *gin.Context, db *sql.DB) (*models.Member, bool) {
+	project_id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return nil, false
+	}
+	member_id, err := strconv.Atoi(c.Param("member_id"))
+	if err != nil {
+		c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return nil, false
+	}
+
+	member, err := models.GetMemberByID(db, member_id)
+	if err == sql.ErrNoRows || (err == nil && member.ProjectID != project_id) {
+		c.AbortWithStatusJSON(http.StatusNotFound, gin.H{"error": "Member not found"})
+		return nil, false
+	}
+	if err != nil {
+		c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return nil, false
+	}
+
+	if status, msg := checkProjectWritable(db, project_id); status != 0 {
+		c.AbortWithStatusJSON(status, gin.H{"error": msg})
+		return nil, false
+	}
+
+	return member, true
+}
+
+// @Summary: CreateProjectMemberHandler
+// @Description: Add a user to a project with a role
+// @Tags: members
+// @Accept: json
+// @Produce: json
+// @Param id path int true "Project ID"
+// @Param member body ProjectMemberPayload true "Member"
+// @Success 201 {object} models.Member
+// @Failure 400 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Failure 409 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Router /project/{id}/member [post]
+// @Security BearerAuth
+func CreateProjectMemberHandler(cfg *config.Config) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		pid := c.Param("id")
+
+		// pasar string id a int id
+		id, err := strconv.Atoi(pid)
+		if err != nil {
+			c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		var payload ProjectMemberPayload
+		if err := c.ShouldBindJSON(&payload); err != nil {
+			c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		if payload.UserID == 0 {
+			c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": "user_id is required"})
+			return
+		}
+
+		// Inicializar la base de datos
+		db, err := database.InitDBContext(c.Request.Context(), cfg)
+		if err != nil {
+			c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		defer db.Close()
+
+		if status, msg := checkProjectWritable(db, id); status != 0 {
+			c.AbortWithStatusJSON(status, gin.H{"error": msg})
+			return
+		}
+
+		existing, err := models.GetMemberByProjectAndUserID(db, id, payload.UserID)
+		if err != nil {
+			c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		if existing != nil {
+			c.AbortWithStatusJSON(http.StatusConflict, gin.H{"error": "User is already a member of the project"})
+			return
+		}
+
+		member := models.Member{UserID: payload.UserID, ProjectID: id, RoleID: payload.RoleID}
+		change := &models.MemberChange{
+			ProjectID: id,
+			UserID:    payload.UserID,
+			RoleID:    &payload.RoleID,
+			Action:    models.MemberChangeAdded,
+			AuthorID:  currentUserID(c),
+		}
+		err = saveMemberChange(db, change, func(tx *sql.Tx) error {
+			member.ID, err = models.CreateMember(tx, &member)
+			return err
+		})
+		if err != nil {
+			c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+
+		created, err := models.GetMemberByID(db, member.ID)
+		if err != nil {
+			c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+
+		c.JSON(http.StatusCreated, created)
+	}
+}
+
+// @Summary: UpdateProjectMemberHandler
+// @Description: Change the role of a project member
+// @Tags: members
+// @Accept: json
+// @Produce: json
+// @Param id path int true "Project ID"
+// @Param member_id path int true "Member ID"
+// @Param member body ProjectMemberPayload true "Member"
+// @Success 200 {object} models.Member
+// @Failure 400 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Router /project/{id}/member/{member_id} [put]
+// @Security BearerAuth
+func UpdateProjectMemberHandler(cfg *config.Config) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var payload ProjectMemberPayload
+		if err := c.ShouldBindJSON(&payload); err != nil {
+			c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		// Inicializar la base de datos
+		db, err := database.InitDBContext(c.Request.Context(), cfg)
+		if err != nil {
+			c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		defer db.Close()
+
+		member, ok := projectMemberFromParams(c, db)
+		if !ok {
+			return
+		}
+
+		member.RoleID = payload.RoleID
+		change := &models.MemberChange{
+			ProjectID: member.ProjectID,
+			UserID:    member.UserID,
+			RoleID:    &payload.RoleID,
+			Action:    models.MemberChangeUpdated,
+			AuthorID:  currentUserID(c),
+		}
+		err = saveMemberChange(db, change, func(tx *sql.Tx) error {
+			return models.UpdateMember(tx, member)
+		})
+		if err != nil {
+			c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+
+		updated, err := models.GetMemberByID(db, member.ID)
+		if err != nil {
+			c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+
+		c.JSON(http.StatusOK, updated)
+	}
+}
+
+// @Summary: DeleteProjectMemberHandler
+// @Description: Remove a user from a project
+// @Tags: members
+// @Param id path int true "Project ID"
+// @Param member_id path int true "Member ID"
+// @Success 204 {object} nil
+// @Failure 400 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Router /project/{id}/member/{member_id} [delete]
+// @Security BearerAuth
+func DeleteProjectMemberHandler(cfg *config.Config) gin.HandlerFunc {
+	return func(c *gin.Context) {
+
+		// Inicializar la base de datos
+		db, err := database.InitDBContext(c.Request.Context(), cfg)
+		if err != nil {
+			c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		defer db.Close()
+
+		member, ok := projectMemberFromParams(c, db)
+		if !ok {
+			return
+		}
+
+		change := &models.MemberChange{
+			ProjectID: member.ProjectID,
+			UserID:    member.UserID,
+			RoleID:    &member.RoleID,
+			Action:    models.MemberChangeRemoved,
+			AuthorID:  currentUserID(c),
+		}
+		err = saveMemberChange(db, change, func(tx *sql.Tx) error {
+			return models.DeleteMember(tx, member.ID)
+		})
+		if err != nil {
+			c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+
+		c.JSON(http.StatusNoContent, nil)
+	}
+}
@@ -0,0 +1,83 @@
+package handlers
+
+import (
+	"go-redmine-ish/config"
+	"go-redmine-ish/database"
+	"go-redmine-ish/models"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+type GetMentionsHandlerData struct {
+	Mentions []models.Mention `json:"mentions"`
+}
+
+// @Summary: GetMyMentionsHandler
+// @Description: List the @mentions of the authenticated user in issue descriptions and comments, newest first
+// @Tags: mentions
+// @Produce: json
+// @Param pending query bool false "Only mentions not yet notified"
+// @Success 200 {object} GetMentionsHandlerData
+// @Failure 400 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Router /my/mentions [get]
+// @Security BearerAuth
+func GetMyMentionsHandler(cfg *config.Config) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		user_id := currentUserID(c)
+		if user_id == nil {
+			c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": "The token is not associated with a user"})
+			return
+		}
+
+		// Inicializar la base de datos
+		db, err := database.InitDBContext(c.Request.Context(), cfg)
+		if err != nil {
+			c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		defer db.Close()
+
+		mentions, err := models.GetMentionsByUserID(db, *user_id, c.Query("pending") == "true" || c.Query("pending") == "1")
+		if err != nil {
+			c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+
+		c.JSON(http.StatusOK, GetMentionsHandlerData{Mentions: mentions})
+	}
+}
+
+// @Summary: MarkMyMentionsNotifiedHandler
+// @Description: Mark the pending @mentions of the authenticated user as notified
+// @Tags: mentions
+// @Success 204
+// @Failure 400 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Router /my/mentions/notified [post]
+// @Security BearerAuth
+func MarkMyMentionsNotifiedHandler(cfg *config.Config) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		user_id := currentUserID(c)
+		if user_id == nil {
+			c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": "The token is not associated with a user"})
+			return
+		}
+
+		// Inicializar la base de datos
+		db, err := database.InitDBContext(c.Request.Context(), cfg)
+		if err != nil {
+			c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		defer db.Close()
+
+		if err := models.MarkMentionsNotified(db, *user_id); err != nil {
+			c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+
+		c.JSON(http.StatusNoContent, nil)
+	}
+}
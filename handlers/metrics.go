@@ -0,0 +1,87 @@
+package handlers
+
+import (
+	"database/sql"
+	"go-redmine-ish/config"
+	"go-redmine-ish/database"
+	"go-redmine-ish/models"
+	"log/slog"
+	"sync"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+var (
+	projectOpenIssuesDesc = prometheus.NewDesc("redmineish_project_open_issues",
+		"Open issues per project.", []string{"project"}, nil)
+	projectOverdueIssuesDesc = prometheus.NewDesc("redmineish_project_overdue_issues",
+		"Open issues past their due date per project.", []string{"project"}, nil)
+)
+
+// IssueMetricsCollector publica los tickets abiertos y vencidos de cada proyecto no archivado.
+// Se calculan con una consulta en cada lectura de /metrics, así que siempre están al día. El pool
+// se abre en la primera lectura y se reutiliza en las siguientes
+type IssueMetricsCollector struct {
+	cfg *config.Config
+	mu  sync.Mutex
+	db  *sql.DB
+}
+
+func NewIssueMetricsCollector(cfg *config.Config) *IssueMetricsCollector {
+	return &IssueMetricsCollector{cfg: cfg}
+}
+
+func (m *IssueMetricsCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- projectOpenIssuesDesc
+	ch <- projectOverdueIssuesDesc
+}
+
+func (m *IssueMetricsCollector) Collect(ch chan<- prometheus.Metric) {
+	db, err := m.pool()
+	if err != nil {
+		slog.Error("issue metrics", "error", err)
+		return
+	}
+
+	gauges, err := models.GetProjectIssueGauges(db)
+	if err != nil {
+		slog.Error("issue metrics", "error", err)
+		return
+	}
+
+	for _, gauge := range gauges {
+		ch <- prometheus.MustNewConstMetric(projectOpenIssuesDesc, prometheus.GaugeValue, float64(gauge.Open), gauge.Identifier)
+		ch <- prometheus.MustNewConstMetric(projectOverdueIssuesDesc, prometheus.GaugeValue, float64(gauge.Overdue), gauge.Identifier)
+	}
+}
+
+// pool devuelve el pool del colector, abriéndolo si aún no existe. Si falla se reintenta en la
+// siguiente lectura
+func (m *IssueMetricsCollector) pool() (*sql.DB, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.db != nil {
+		return m.db, nil
+	}
+	// Inicializar la base de datos
+	db, err := database.InitDB(m.cfg)
+	if err != nil {
+		return nil, err
+	}
+	// Una lectura de /metrics hace una sola consulta
+	db.SetMaxOpenConns(2)
+	m.db = db
+	return db, nil
+}
+
+// Close cierra el pool del colector
+func (m *IssueMetricsCollector) Close() error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.db == nil {
+		return nil
+	}
+	err := m.db.Close()
+	m.db = nil
+	return err
+}
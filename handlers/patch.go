@@ -0,0 +1,78 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Actualizaciones parciales con JSON Merge Patch (RFC 7386): el cuerpo del PATCH es un objeto
+// con solo los campos a cambiar. Un campo con null se borra, lo que en los campos opcionales
+// (assigned_to_id, category_id, parent_id...) equivale a dejarlos sin valor
+
+// mergeJSON aplica patch sobre target siguiendo el RFC 7386
+func mergeJSON(target, patch interface{}) interface{} {
+	patchObject, ok := patch.(map[string]interface{})
+	if !ok {
+		return patch
+	}
+
+	targetObject, ok := target.(map[string]interface{})
+	if !ok {
+		targetObject = map[string]interface{}{}
+	}
+	for key, value := range patchObject {
+		if value == nil {
+			delete(targetObject, key)
+			continue
+		}
+		targetObject[key] = mergeJSON(targetObject[key], value)
+	}
+	return targetObject
+}
+
+// bindMergePatch aplica el cuerpo de la petición sobre el JSON de current y decodifica el
+// resultado en target. Si el cuerpo no es un objeto JSON responde 400 y devuelve false
+func bindMergePatch(c *gin.Context, current interface{}, target interface{}) bool {
+	body, err := c.GetRawData()
+	if err != nil {
+		c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return false
+	}
+
+	var patch interface{}
+	if err := json.Unmarshal(body, &patch); err != nil {
+		c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return false
+	}
+	if _, ok := patch.(map[string]interface{}); !ok {
+		c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": "the merge patch must be a JSON object"})
+		return false
+	}
+
+	if err := applyMergePatch(current, patch, target); err != nil {
+		c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return false
+	}
+
+	return true
+}
+
+// applyMergePatch aplica patch sobre el JSON de current y decodifica el resultado en target
+func applyMergePatch(current interface{}, patch interface{}, target interface{}) error {
+	original, err := json.Marshal(current)
+	if err != nil {
+		return err
+	}
+	var document interface{}
+	if err := json.Unmarshal(original, &document); err != nil {
+		return err
+	}
+
+	merged, err := json.Marshal(mergeJSON(document, patch))
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(merged, target)
+}
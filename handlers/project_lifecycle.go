@@ -0,0 +1,404 @@
+package handlers
+
+import (
+	"database/sql"
+	"go-redmine-ish/config"
+	"go-redmine-ish/database"
+	"go-redmine-ish/models"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+// projectStatusHandler cambia el estado de un proyecto si su estado actual es uno de from.
+// Con subprojects el cambio se aplica también a todos los subproyectos
+func projectStatusHandler(cfg *config.Config, to string, subprojects bool, from ...string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		pid := c.Param("id")
+
+		// pasar string id a int id
+		id, err := strconv.Atoi(pid)
+		if err != nil {
+			c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		// Inicializar la base de datos
+		db, err := database.InitDBContext(c.Request.Context(), cfg)
+		if err != nil {
+			c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		defer db.Close()
+
+		project, err := models.GetProjectByID(db, id)
+		if err != nil {
+			c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		if project == nil {
+			c.AbortWithStatusJSON(http.StatusNotFound, gin.H{"error": "Project not found"})
+			return
+		}
+
+		allowed := false
+		for _, status := range from {
+			if project.Status == status {
+				allowed = true
+				break
+			}
+		}
+		if !allowed {
+			c.AbortWithStatusJSON(http.StatusConflict, gin.H{"error": "Project is " + project.Status + ", cannot change it to " + to})
+			return
+		}
+
+		ids := []int{id}
+		if subprojects {
+			ids, err = models.GetProjectDescendantIDs(db, id)
+			if err != nil {
+				c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+				return
+			}
+		}
+		for _, project_id := range ids {
+			if err := models.SetProjectStatus(db, project_id, to); err != nil {
+				c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+				return
+			}
+		}
+
+		updated, err := models.GetProjectByID(db, id)
+		if err != nil {
+			c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+
+		c.JSON(http.StatusOK, updated)
+	}
+}
+
+// @Summary: CloseProjectHandler
+// @Description: Close an active project, making it read-only
+// @Tags: projects
+// @Produce: json
+// @Param id path int true "Project ID"
+// @Success 200 {object} models.Project
+// @Failure 400 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Failure 409 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Router /project/{id}/close [post]
+// @Security BearerAuth
+func CloseProjectHandler(cfg *config.Config) gin.HandlerFunc {
+	return projectStatusHandler(cfg, models.ProjectStatusClosed, false, models.ProjectStatusActive)
+}
+
+// @Summary: ReopenProjectHandler
+// @Description: Reopen a closed project
+// @Tags: projects
+// @Produce: json
+// @Param id path int true "Project ID"
+// @Success 200 {object} models.Project
+// @Failure 400 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Failure 409 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Router /project/{id}/reopen [post]
+// @Security BearerAuth
+func ReopenProjectHandler(cfg *config.Config) gin.HandlerFunc {
+	return projectStatusHandler(cfg, models.ProjectStatusActive, false, models.ProjectStatusClosed)
+}
+
+// @Summary: ArchiveProjectHandler
+// @Description: Archive a project and its subprojects, hiding them from the project list
+// @Tags: projects
+// @Produce: json
+// @Param id path int true "Project ID"
+// @Success 200 {object} models.Project
+// @Failure 400 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Failure 409 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Router /project/{id}/archive [post]
+// @Security BearerAuth
+func ArchiveProjectHandler(cfg *config.Config) gin.HandlerFunc {
+	return projectStatusHandler(cfg, models.ProjectStatusArchived, true, models.ProjectStatusActive, models.ProjectStatusClosed)
+}
+
+// @Summary: UnarchiveProjectHandler
+// @Description: Unarchive a project, leaving it active
+// @Tags: projects
+// @Produce: json
+// @Param id path int true "Project ID"
+// @Success 200 {object} models.Project
+// @Failure 400 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Failure 409 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Router /project/{id}/unarchive [post]
+// @Security BearerAuth
+func UnarchiveProjectHandler(cfg *config.Config) gin.HandlerFunc {
+	return projectStatusHandler(cfg, models.ProjectStatusActive, false, models.ProjectStatusArchived)
+}
+
+type CopyProjectPayload struct {
+	Name        string  `json:"name"`
+	Identifier  string  `json:"identifier" binding:"required"`
+	Description *string `json:"description"`
+	ParentID    *int    `json:"parent_id"`
+	Issues      bool    `json:"issues"` // Copiar también los tickets
+}
+
+// copyProject copia en tx el proyecto source con sus trackers, módulos, categorías, miembros,
+// versiones y, si se pide, sus tickets
+func copyProject(db *sql.DB, tx *sql.Tx, source *models.Project, target *models.Project, issues bool, authorID *int) error {
+	var err error
+	target.ID, err = models.CreateProject(tx, target)
+	if err != nil {
+		return err
+	}
+
+	trackers, err := models.GetTrackersByProjectID(db, source.ID)
+	if err != nil {
+		return err
+	}
+	tracker_ids := []int{}
+	for _, tracker := range trackers {
+		tracker_ids = append(tracker_ids, tracker.ID)
+	}
+	if err := models.SetProjectTrackers(tx, target.ID, tracker_ids); err != nil {
+		return err
+	}
+
+	modules, err := models.GetProjectModules(db, source.ID)
+	if err != nil {
+		return err
+	}
+	if err := models.SetProjectModules(tx, target.ID, modules); err != nil {
+		return err
+	}
+
+	categories, err := models.GetCategoriesByProjectID(db, source.ID)
+	if err != nil {
+		return err
+	}
+	category_ids := map[int]int{}
+	for _, category := range categories {
+		old_id := category.ID
+		category.ProjectID = target.ID
+		category_ids[old_id], err = models.CreateCategory(tx, &category)
+		if err != nil {
+			return err
+		}
+	}
+
+	members, err := models.GetMembersByProjectID(db, source.ID)
+	if err != nil {
+		return err
+	}
+	for _, member := range members {
+		member.ProjectID = target.ID
+		if _, err := models.CreateMember(tx, &member); err != nil {
+			return err
+		}
+		role_id := member.RoleID
+		change := &models.MemberChange{
+			ProjectID: target.ID,
+			UserID:    member.UserID,
+			RoleID:    &role_id,
+			Action:    models.MemberChangeAdded,
+			AuthorID:  authorID,
+		}
+		if _, err := models.CreateMemberChange(tx, change); err != nil {
+			return err
+		}
+	}
+
+	versions, err := models.GetVersionsByProjectID(db, source.ID)
+	if err != nil {
+		return err
+	}
+	version_ids := map[int]int{}
+	for _, version := range versions {
+		old_id := version.ID
+		version.ProjectID = target.ID
+		version_ids[old_id], err = models.CreateVersion(tx, &version)
+		if err != nil {
+			return err
+		}
+	}
+
+	if !issues {
+		return nil
+	}
+
+	source_issues, err := models.GetIssuesByProjectID(db, source.ID)
+	if err != nil {
+		return err
+	}
+	in_source := map[int]bool{}
+	for _, issue := range source_issues {
+		in_source[issue.ID] = true
+	}
+
+	// Los tickets padre se crean antes que sus subtareas para poder apuntarlas a la copia. Las
+	// referencias a versiones, categorías o padres de otros proyectos se quitan
+	issue_ids := map[int]int{}
+	pending := source_issues
+	for len(pending) > 0 {
+		waiting := []models.Issue{}
+		for _, issue := range pending {
+			if issue.ParentID != nil && in_source[*issue.ParentID] {
+				if _, ok := issue_ids[*issue.ParentID]; !ok {
+					waiting = append(waiting, issue)
+					continue
+				}
+			}
+
+			old_id := issue.ID
+			issue.ProjectID = target.ID
+			issue.CategoryID = remapID(issue.CategoryID, category_ids)
+			issue.FixedVersionID = remapID(issue.FixedVersionID, version_ids)
+			issue.ParentID = remapID(issue.ParentID, issue_ids)
+			issue_ids[old_id], err = models.CreateIssue(tx, &issue)
+			if err != nil {
+				return err
+			}
+		}
+		// Sin avance solo quedan ciclos, que no deberían existir: se copian sin padre
+		if len(waiting) == len(pending) {
+			for i := range waiting {
+				waiting[i].ParentID = nil
+			}
+		}
+		pending = waiting
+	}
+
+	return nil
+}
+
+// remapID devuelve el ID de la copia correspondiente a id, o nil si no se ha copiado
+func remapID(id *int, ids map[int]int) *int {
+	if id == nil {
+		return nil
+	}
+	if new_id, ok := ids[*id]; ok {
+		return &new_id
+	}
+	return nil
+}
+
+// @Summary: CopyProjectHandler
+// @Description: Copy a project with its trackers, modules, categories, members and versions (and optionally its issues) into a new identifier
+// @Tags: projects
+// @Accept: json
+// @Produce: json
+// @Param id path int true "Project ID"
+// @Param copy body CopyProjectPayload true "New project"
+// @Success 201 {object} models.Project
+// @Failure 400 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Failure 409 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Router /project/{id}/copy [post]
+// @Security BearerAuth
+func CopyProjectHandler(cfg *config.Config) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		pid := c.Param("id")
+
+		// pasar string id a int id
+		id, err := strconv.Atoi(pid)
+		if err != nil {
+			c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		var payload CopyProjectPayload
+		if err := c.ShouldBindJSON(&payload); err != nil {
+			c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		if _, err := strconv.Atoi(payload.Identifier); err == nil {
+			c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": "identifier cannot be a number"})
+			return
+		}
+
+		// Inicializar la base de datos
+		db, err := database.InitDBContext(c.Request.Context(), cfg)
+		if err != nil {
+			c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		defer db.Close()
+
+		source, err := models.GetProjectByID(db, id)
+		if err != nil {
+			c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		if source == nil {
+			c.AbortWithStatusJSON(http.StatusNotFound, gin.H{"error": "Project not found"})
+			return
+		}
+
+		existing, err := models.GetProjectByIdentifier(db, payload.Identifier)
+		if err != nil {
+			c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		if existing != nil {
+			c.AbortWithStatusJSON(http.StatusConflict, gin.H{"error": "Identifier is already in use"})
+			return
+		}
+
+		// Lo que no viene en la petición se toma del proyecto de origen
+		target := &models.Project{
+			Name:        payload.Name,
+			Identifier:  payload.Identifier,
+			Description: source.Description,
+			ParentID:    source.ParentID,
+			Status:      models.ProjectStatusActive,
+		}
+		if target.Name == "" {
+			target.Name = source.Name
+		}
+		if payload.Description != nil {
+			target.Description = *payload.Description
+		}
+		if payload.ParentID != nil {
+			target.ParentID = payload.ParentID
+		}
+
+		if status, msg := validateProjectParent(db, 0, target.ParentID); status != 0 {
+			c.AbortWithStatusJSON(status, gin.H{"error": msg})
+			return
+		}
+
+		tx, err := db.Begin()
+		if err != nil {
+			c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		defer tx.Rollback()
+
+		if err := copyProject(db, tx, source, target, payload.Issues, currentUserID(c)); err != nil {
+			c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+
+		if err := tx.Commit(); err != nil {
+			c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+
+		created, err := models.GetProjectByID(db, target.ID)
+		if err != nil {
+			c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+
+		c.JSON(http.StatusCreated, created)
+	}
+}
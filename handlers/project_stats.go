@@ -0,0 +1,129 @@
+package handlers
+
+import (
+	"go-redmine-ish/config"
+	"go-redmine-ish/database"
+	"go-redmine-ish/models"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// projectStatsDefaultDays es el intervalo por defecto de las estadísticas: las últimas 12 semanas
+const projectStatsDefaultDays = 12 * 7
+
+type GetProjectStatsHandlerData struct {
+	ProjectIDs []int  `json:"project_ids"`
+	From       string `json:"from"`
+	To         string `json:"to"`
+	*models.ProjectStats
+}
+
+// @Summary: GetProjectStatsHandler
+// @Description: Issue statistics of a project for a date range: totals and breakdowns by status, tracker, assignee, priority and version of the issues created in the range, created / closed / reopened per week with the open issues at the end of each week, and the average days to close of the issues closed in the range. Computed with SQL aggregation.
+// @Tags: projects
+// @Produce: json
+// @Param id path int true "Project ID"
+// @Param from query string false "First date (YYYY-MM-DD), 12 weeks before to by default"
+// @Param to query string false "Last date (YYYY-MM-DD), today by default"
+// @Param subprojects query bool false "Include the visible subprojects"
+// @Success 200 {object} GetProjectStatsHandlerData
+// @Failure 400 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Router /project/{id}/statistics [get]
+// @Security BearerAuth
+func GetProjectStatsHandler(cfg *config.Config) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		pid := c.Param("id")
+
+		// pasar string id a int id
+		id, err := strconv.Atoi(pid)
+		if err != nil {
+			c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		to := time.Now().UTC()
+		if value := c.Query("to"); value != "" {
+			if to, err = time.Parse("2006-01-02", value); err != nil {
+				c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": "invalid to: " + err.Error()})
+				return
+			}
+		}
+		from := to.AddDate(0, 0, -projectStatsDefaultDays+1)
+		if value := c.Query("from"); value != "" {
+			if from, err = time.Parse("2006-01-02", value); err != nil {
+				c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": "invalid from: " + err.Error()})
+				return
+			}
+		}
+		if to.Before(from) {
+			c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": "to must be on or after from"})
+			return
+		}
+
+		subprojects := false
+		if value := c.Query("subprojects"); value != "" {
+			if subprojects, err = strconv.ParseBool(value); err != nil {
+				c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": "invalid subprojects: " + err.Error()})
+				return
+			}
+		}
+
+		// Inicializar la base de datos
+		db, err := database.InitDBContext(c.Request.Context(), cfg)
+		if err != nil {
+			c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		defer db.Close()
+
+		allowed, err := canViewProject(c, db, id)
+		if err != nil {
+			c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		if !allowed {
+			c.AbortWithStatusJSON(http.StatusNotFound, gin.H{"error": "Project not found"})
+			return
+		}
+
+		project_ids := []int{id}
+		if subprojects {
+			descendants, err := models.GetProjectDescendantIDs(db, id)
+			if err != nil {
+				c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+				return
+			}
+			for _, project_id := range descendants {
+				if project_id == id {
+					continue
+				}
+				visible, err := canViewProject(c, db, project_id)
+				if err != nil {
+					c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+					return
+				}
+				if visible {
+					project_ids = append(project_ids, project_id)
+				}
+			}
+		}
+
+		data := GetProjectStatsHandlerData{
+			ProjectIDs: project_ids,
+			From:       from.Format("2006-01-02"),
+			To:         to.Format("2006-01-02"),
+		}
+		data.ProjectStats, err = models.GetProjectStats(db, project_ids, data.From, data.To)
+		if err != nil {
+			c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+
+		c.JSON(http.StatusOK, data)
+	}
+}
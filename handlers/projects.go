@@ -1,26 +1,90 @@
 package handlers
 
 import (
+	"database/sql"
+	"fmt"
 	"go-redmine-ish/config"
 	"go-redmine-ish/database"
+	"go-redmine-ish/middleware"
 	"go-redmine-ish/models"
-	"log"
 	"net/http"
 	"strconv"
+	"strings"
 
 	"github.com/gin-gonic/gin"
 )
 
 type GetProjectsHandlerData struct {
-	Projects        []models.Project `json:"projects"`
-	Count           int              `json:"count"`
-	IssuesNoProject []models.Issue   `json:"issues,omitempty"`
+	Projects        []models.Project   `json:"projects"`
+	Count           int                `json:"count"`
+	IssuesNoProject []models.Issue     `json:"issues,omitempty"`
+	Tree            []*ProjectTreeNode `json:"tree,omitempty"`
+}
+
+// ProjectTreeNode es un proyecto con sus subproyectos anidados y sus totales acumulados
+type ProjectTreeNode struct {
+	models.Project
+	Rollup   models.ProjectRollup `json:"rollup"`
+	Children []*ProjectTreeNode   `json:"children"`
+}
+
+// buildProjectTree anida los proyectos bajo su padre. Un proyecto cuyo padre no está
+// en la lista (por ejemplo porque está archivado) queda como raíz
+func buildProjectTree(projects []models.Project, rollups map[int]models.ProjectRollup) []*ProjectTreeNode {
+	nodes := map[int]*ProjectTreeNode{}
+	for _, project := range projects {
+		nodes[project.ID] = &ProjectTreeNode{Project: project, Rollup: rollups[project.ID], Children: []*ProjectTreeNode{}}
+	}
+
+	roots := []*ProjectTreeNode{}
+	for _, project := range projects {
+		node := nodes[project.ID]
+		if project.ParentID != nil {
+			if parent, ok := nodes[*project.ParentID]; ok {
+				parent.Children = append(parent.Children, node)
+				continue
+			}
+		}
+		roots = append(roots, node)
+	}
+
+	return roots
+}
+
+// validateProjectParent comprueba que el padre existe y que no crea un ciclo: un proyecto
+// no puede tener como padre a sí mismo ni a uno de sus subproyectos. projectID es 0 al crear
+func validateProjectParent(db *sql.DB, projectID int, parentID *int) (int, string) {
+	if parentID == nil {
+		return 0, ""
+	}
+
+	parent, err := models.GetProjectByID(db, *parentID)
+	if err != nil {
+		return http.StatusInternalServerError, err.Error()
+	}
+	if parent == nil {
+		return http.StatusBadRequest, "Parent project not found"
+	}
+
+	if projectID > 0 {
+		cycle, err := models.IsProjectAncestor(db, projectID, *parentID)
+		if err != nil {
+			return http.StatusInternalServerError, err.Error()
+		}
+		if cycle {
+			return http.StatusBadRequest, "A project cannot be its own ancestor"
+		}
+	}
+
+	return 0, ""
 }
 
 // @Summary: GetProjectsHandler
-// @Description: Get all projects
+// @Description: Get all projects. Archived projects are hidden unless status=archived or status=all
 // @Tags: projects
 // @Produce: json
+// @Param status query string false "active, closed, archived or all"
+// @Param tree query bool false "Also return the projects nested under their parents, with totals including subprojects"
 // @Success 200 {object} GetProjectsHandlerData
 // @Failure 500 {object} map[string]string
 // @Router /projects [get]
@@ -29,31 +93,39 @@ func GetProjectsHandler(cfg *config.Config) gin.HandlerFunc {
 	return func(c *gin.Context) {
 
 		// Inicializar la base de datos
-		db, err := database.InitDB(cfg)
+		db, err := database.InitDBContext(c.Request.Context(), cfg)
 		if err != nil {
-			log.Println("Error GetProjectsHandler initializing database:", err)
+			middleware.Logger(c).Error("GetProjectsHandler initializing database", "error", err)
 			c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 			return
 		}
 		defer db.Close()
 
-		projects, err := models.GetAllProjects(db)
+		all, err := models.GetAllProjects(db)
 		if err != nil {
-			log.Println("Error GetProjectsHandler getting all projects:", err)
+			middleware.Logger(c).Error("GetProjectsHandler getting all projects", "error", err)
 			c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 			return
 		}
 
-		count, err := models.CountProjects(db)
-		if err != nil {
-			log.Println("Error GetProjectsHandler counting projects:", err)
-			c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
-			return
+		// Sin filtro se ocultan los archivados
+		status := c.Query("status")
+		projects := []models.Project{}
+		for _, project := range all {
+			switch {
+			case status == "all":
+			case status == "" && project.Status != models.ProjectStatusArchived:
+			case status != "" && project.Status == status:
+			default:
+				continue
+			}
+			projects = append(projects, project)
 		}
+		count := len(projects)
 
 		issues, err := models.GetIssuesWhereProjectIsNull(db)
 		if err != nil {
-			log.Println("Error GetProjectsHandler getting issues where project is null:", err)
+			middleware.Logger(c).Error("GetProjectsHandler getting issues where project is null", "error", err)
 			c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 			return
 		}
@@ -64,6 +136,15 @@ func GetProjectsHandler(cfg *config.Config) gin.HandlerFunc {
 			IssuesNoProject: issues,
 		}
 
+		if tree, _ := strconv.ParseBool(c.Query("tree")); tree {
+			rollups, err := models.GetProjectRollups(db)
+			if err != nil {
+				c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+				return
+			}
+			data.Tree = buildProjectTree(projects, rollups)
+		}
+
 		c.JSON(http.StatusOK, data)
 	}
 }
@@ -77,6 +158,10 @@ type GetProjectHandlerData struct {
 	CategoryNumberOfIssues []models.CategoryNumberOfIssues `json:"categorynumberofissues,omitempty"`
 	IssuesNoCategory       []models.Issue                  `json:"issues_no_category,omitempty"`
 	Trackers               []models.Tracker                `json:"trackers"`
+	EnabledModules         []string                        `json:"enabled_modules"`
+	Rollup                 models.ProjectRollup            `json:"rollup"`
+	TreeMembers            []models.Member                 `json:"tree_members,omitempty"`
+	OverdueIssues          []models.Issue                  `json:"overdue_issues,omitempty"`
 }
 
 // @Summary: GetProjectHandler
@@ -84,8 +169,10 @@ type GetProjectHandlerData struct {
 // @Tags: projects
 // @Produce: json
 // @Param id path int true "Project ID"
+// @Param If-Match header string false "ETag returned by a previous GET"
 // @Success 200 {object} GetProjectHandlerData
 // @Failure 400 {object} map[string]string
+// @Failure 412 {object} map[string]string
 // @Failure 500 {object} map[string]string
 // @Router /project/{id} [get]
 // @Security BearerAuth
@@ -94,9 +181,9 @@ func GetProjectHandler(cfg *config.Config) gin.HandlerFunc {
 		pid := c.Param("id")
 
 		// Inicializar la base de datos
-		db, err := database.InitDB(cfg)
+		db, err := database.InitDBContext(c.Request.Context(), cfg)
 		if err != nil {
-			log.Println("Error initializing database:", err)
+			middleware.Logger(c).Error("GetProjectHandler initializing database", "error", err)
 			c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 			return
 		}
@@ -105,40 +192,55 @@ func GetProjectHandler(cfg *config.Config) gin.HandlerFunc {
 		// pasar string id a int id
 		id, err := strconv.Atoi(pid)
 		if err != nil {
-			log.Println("Error converting project ID:", err)
+			middleware.Logger(c).Warn("GetProjectHandler converting project ID", "error", err)
 			c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 			return
 		}
 
 		project, err := models.GetProjectByID(db, id)
 		if err != nil {
-			log.Println("Error getting project by ID:", err)
+			middleware.Logger(c).Error("GetProjectHandler getting project by ID", "error", err)
 			c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 			return
 		}
+		if project == nil {
+			c.AbortWithStatusJSON(http.StatusNotFound, gin.H{"error": "Project not found"})
+			return
+		}
+
+		if !checkETag(c, project.LockVersion) {
+			return
+		}
 
 		roles, err := models.GetAllRoles(db)
 		if err != nil {
-			log.Println("Error getting roles:", err)
+			middleware.Logger(c).Error("GetProjectHandler getting roles", "error", err)
 			c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 			return
 		}
 
-		trackers, err := models.GetAllTrackers(db)
+		trackers, err := models.GetTrackersByProjectID(db, id)
+		if err != nil {
+			c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+
+		modules, err := models.GetProjectModules(db, id)
 		if err != nil {
 			c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 			return
 		}
 
 		data := GetProjectHandlerData{
-			Project:  *project,
-			Roles:    roles,
-			Trackers: trackers,
+			Project:        *project,
+			Roles:          roles,
+			Trackers:       trackers,
+			EnabledModules: modules,
 		}
 
 		categories, err := models.GetCategoriesByProjectID(db, id)
 		if err != nil {
-			log.Println("Error getting categories by project ID:", err)
+			middleware.Logger(c).Error("GetProjectHandler getting categories by project ID", "error", err)
 			c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 			return
 		}
@@ -149,7 +251,7 @@ func GetProjectHandler(cfg *config.Config) gin.HandlerFunc {
 
 		users, err := models.GetUsersByProjectID(db, id)
 		if err != nil {
-			log.Println("Error getting users by project ID:", err)
+			middleware.Logger(c).Error("GetProjectHandler getting users by project ID", "error", err)
 			c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 			return
 		}
@@ -160,7 +262,7 @@ func GetProjectHandler(cfg *config.Config) gin.HandlerFunc {
 
 		members, err := models.GetMembersByProjectID(db, id)
 		if err != nil {
-			log.Println("Error getting members by project ID:", err)
+			middleware.Logger(c).Error("GetProjectHandler getting members by project ID", "error", err)
 			c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 			return
 		}
@@ -169,9 +271,26 @@ func GetProjectHandler(cfg *config.Config) gin.HandlerFunc {
 			data.Members = members
 		}
 
+		// Totales y miembros incluyendo los subproyectos
+		rollups, err := models.GetProjectRollups(db)
+		if err != nil {
+			c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		data.Rollup = rollups[id]
+
+		tree_members, err := models.GetMembersByProjectTree(db, id)
+		if err != nil {
+			c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		if len(tree_members) > len(members) {
+			data.TreeMembers = tree_members
+		}
+
 		categorynumberofissues, err := models.CountIssuesByCategoryWhereProject(db, id)
 		if err != nil {
-			log.Println("Error counting issues by category where project:", err)
+			middleware.Logger(c).Error("GetProjectHandler counting issues by category where project", "error", err)
 			c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 			return
 		}
@@ -190,59 +309,175 @@ func GetProjectHandler(cfg *config.Config) gin.HandlerFunc {
 			data.IssuesNoCategory = issues
 		}
 
+		// Tickets abiertos del proyecto con la fecha de fin pasada
+		overdue, err := models.GetIssuesFiltered(db, models.IssueFilter{ProjectID: id, Overdue: true, Sort: "due_date"})
+		if err != nil {
+			c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+
+		if len(overdue) > 0 {
+			data.OverdueIssues = overdue
+		}
+
 		c.JSON(http.StatusOK, data)
 	}
 }
 
+// ProjectPayload es el cuerpo de creación y actualización de proyectos: el proyecto con sus
+// trackers y módulos habilitados. Si se omiten no se modifican; al crear se habilitan todos
+type ProjectPayload struct {
+	models.Project
+	TrackerIDs     *[]int    `json:"tracker_ids,omitempty"`
+	EnabledModules *[]string `json:"enabled_modules,omitempty"`
+}
+
+// validateProjectSettings comprueba que los trackers existen y que los módulos son conocidos
+func validateProjectSettings(db *sql.DB, payload *ProjectPayload) (int, string) {
+	if payload.TrackerIDs != nil {
+		for _, tracker_id := range *payload.TrackerIDs {
+			if _, err := models.GetTrackerByID(db, tracker_id); err == sql.ErrNoRows {
+				return http.StatusBadRequest, fmt.Sprintf("Tracker %d not found", tracker_id)
+			} else if err != nil {
+				return http.StatusInternalServerError, err.Error()
+			}
+		}
+	}
+	if payload.EnabledModules != nil {
+		for _, module := range *payload.EnabledModules {
+			if !models.IsProjectModule(module) {
+				return http.StatusBadRequest, fmt.Sprintf("Unknown module %q, expected one of %s", module, strings.Join(models.ProjectModules, ", "))
+			}
+		}
+	}
+	return 0, ""
+}
+
+// saveProjectSettings guarda los trackers y módulos del proyecto que vienen en la petición
+func saveProjectSettings(tx *sql.Tx, projectID int, payload *ProjectPayload) error {
+	if payload.TrackerIDs != nil {
+		if err := models.SetProjectTrackers(tx, projectID, *payload.TrackerIDs); err != nil {
+			return err
+		}
+	}
+	if payload.EnabledModules != nil {
+		if err := models.SetProjectModules(tx, projectID, *payload.EnabledModules); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// projectPayload devuelve el proyecto con sus trackers y módulos habilitados
+func projectPayload(db *sql.DB, id int) (*ProjectPayload, error) {
+	project, err := models.GetProjectByID(db, id)
+	if err != nil || project == nil {
+		return nil, err
+	}
+
+	trackers, err := models.GetTrackersByProjectID(db, id)
+	if err != nil {
+		return nil, err
+	}
+	tracker_ids := []int{}
+	for _, tracker := range trackers {
+		tracker_ids = append(tracker_ids, tracker.ID)
+	}
+
+	modules, err := models.GetProjectModules(db, id)
+	if err != nil {
+		return nil, err
+	}
+
+	return &ProjectPayload{Project: *project, TrackerIDs: &tracker_ids, EnabledModules: &modules}, nil
+}
+
 // @Summary: CreateProjectHandler
-// @Description: Create a new project
+// @Description: Create a new project. tracker_ids and enabled_modules default to all trackers and modules
 // @Tags: projects
 // @Accept: json
 // @Produce: json
-// @Param project body models.Project true "Project"
-// @Success 201 {object} models.Project
+// @Param project body ProjectPayload true "Project"
+// @Success 201 {object} ProjectPayload
 // @Failure 400 {object} map[string]string
 // @Failure 500 {object} map[string]string
 // @Router /project [post]
 // @Security BearerAuth
 func CreateProjectHandler(cfg *config.Config) gin.HandlerFunc {
 	return func(c *gin.Context) {
-		var project models.Project
-		err := c.BindJSON(&project)
+		var payload ProjectPayload
+		err := c.BindJSON(&payload)
 		if err != nil {
 			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 			return
 		}
+		project := payload.Project
 
 		// Inicializar la base de datos
-		db, err := database.InitDB(cfg)
+		db, err := database.InitDBContext(c.Request.Context(), cfg)
 		if err != nil {
 			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 			return
 		}
 		defer db.Close()
 
-		id, err := models.CreateProject(db, &project)
+		if status, msg := validateProject(db, 0, &payload); status != 0 {
+			c.JSON(status, gin.H{"error": msg})
+			return
+		}
+
+		tx, err := db.Begin()
 		if err != nil {
 			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 			return
 		}
+		defer tx.Rollback()
 
-		project.ID = id
+		id, err := models.CreateProject(tx, &project)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
 
-		c.JSON(http.StatusCreated, project)
+		if payload.TrackerIDs == nil {
+			err = models.EnableAllTrackersForProject(tx, id)
+		}
+		if err == nil && payload.EnabledModules == nil {
+			err = models.SetProjectModules(tx, id, models.ProjectModules)
+		}
+		if err == nil {
+			err = saveProjectSettings(tx, id, &payload)
+		}
+		if err == nil {
+			err = tx.Commit()
+		}
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+
+		created, err := projectPayload(db, id)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+
+		c.JSON(http.StatusCreated, created)
 	}
 }
 
 // @Summary: UpdateProjectHandler
-// @Description: Update a project by ID
+// @Description: Update a project by ID, and optionally its enabled trackers and modules
 // @Tags: projects
 // @Accept: json
 // @Produce: json
 // @Param id path int true "Project ID"
-// @Param project body models.Project true "Project"
-// @Success 200 {object} models.Project
+// @Param project body ProjectPayload true "Project, with the lock_version that was read"
+// @Param If-Match header string false "ETag returned by GET, instead of lock_version"
+// @Success 200 {object} ProjectPayload
 // @Failure 400 {object} map[string]string
+// @Failure 409 {object} map[string]interface{}
+// @Failure 428 {object} map[string]string
 // @Failure 500 {object} map[string]string
 // @Router /project/{id} [put]
 // @Security BearerAuth
@@ -257,11 +492,12 @@ func UpdateProjectHandler(cfg *config.Config) gin.HandlerFunc {
 			return
 		}
 
-		var project models.Project
-		if err := c.ShouldBindJSON(&project); err != nil {
+		var payload ProjectPayload
+		if err := c.ShouldBindJSON(&payload); err != nil {
 			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 			return
 		}
+		project := payload.Project
 
 		if id != project.ID {
 			c.JSON(http.StatusBadRequest, gin.H{"error": "ID in body and URL do not match"})
@@ -269,40 +505,185 @@ func UpdateProjectHandler(cfg *config.Config) gin.HandlerFunc {
 		}
 
 		// Inicializar la base de datos
-		db, err := database.InitDB(cfg)
+		db, err := database.InitDBContext(c.Request.Context(), cfg)
 		if err != nil {
 			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 			return
 		}
 		defer db.Close()
 
-		err = models.UpdateProject(db, &project)
+		// Un proyecto cerrado o archivado hay que reabrirlo antes de modificarlo
+		if status, msg := checkProjectWritable(db, id); status != 0 {
+			c.JSON(status, gin.H{"error": msg})
+			return
+		}
+
+		lock_version, ok := lockVersionFromRequest(c, project.LockVersion)
+		if !ok {
+			return
+		}
+		payload.LockVersion = lock_version
+
+		updateProject(c, db, &payload)
+	}
+}
+
+// @Summary: PatchProjectHandler
+// @Description: Partially update a project with a JSON Merge Patch. Only the fields present change; null clears parent_id
+// @Tags: projects
+// @Accept: json
+// @Produce: json
+// @Param id path int true "Project ID"
+// @Param project body ProjectPayload true "Only the fields to change; null clears optional fields"
+// @Param If-Match header string false "ETag returned by GET"
+// @Success 200 {object} ProjectPayload
+// @Failure 400 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Failure 409 {object} map[string]interface{}
+// @Failure 500 {object} map[string]string
+// @Router /project/{id} [patch]
+// @Security BearerAuth
+func PatchProjectHandler(cfg *config.Config) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		pid := c.Param("id")
+
+		// pasar string id a int id
+		id, err := strconv.Atoi(pid)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		// Inicializar la base de datos
+		db, err := database.InitDBContext(c.Request.Context(), cfg)
 		if err != nil {
 			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 			return
 		}
+		defer db.Close()
+
+		// Un proyecto cerrado o archivado hay que reabrirlo antes de modificarlo
+		if status, msg := checkProjectWritable(db, id); status != 0 {
+			c.JSON(status, gin.H{"error": msg})
+			return
+		}
 
-		updated, err := models.GetProjectByID(db, id)
+		current, err := projectPayload(db, id)
 		if err != nil {
 			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 			return
 		}
 
-		c.JSON(http.StatusOK, updated)
+		var payload ProjectPayload
+		if !bindMergePatch(c, current, &payload) {
+			return
+		}
+		if id != payload.ID {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "ID in body and URL do not match"})
+			return
+		}
+
+		// Sin lock_version en el parche ni If-Match se aplica sobre la versión que se acaba de leer
+		lock_version, ok := lockVersionFromRequest(c, payload.LockVersion)
+		if !ok {
+			return
+		}
+		payload.LockVersion = lock_version
+
+		updateProject(c, db, &payload)
 	}
 }
 
-// @Summary: UpdateProjectHandler
-// @Description: Update a project by ID
+// validateProject comprueba un proyecto antes de crearlo (projectID es 0) o de modificarlo.
+// Devuelve 0 si es válido, o el código HTTP y el mensaje de error a responder
+func validateProject(db *sql.DB, projectID int, payload *ProjectPayload) (int, string) {
+	// El identificador sustituye al ID en las URLs, así que no puede ser numérico
+	if _, err := strconv.Atoi(payload.Identifier); err == nil || payload.Identifier == "" {
+		return http.StatusBadRequest, "identifier is required and cannot be a number"
+	}
+	if strings.TrimSpace(payload.Name) == "" {
+		return http.StatusBadRequest, "name is required"
+	}
+	// Los proyectos nacen activos; el estado solo cambia con close, reopen, archive y unarchive
+	if projectID == 0 && payload.Status != "" && payload.Status != models.ProjectStatusActive {
+		return http.StatusBadRequest, "a new project must be active"
+	}
+
+	if status, msg := validateProjectParent(db, projectID, payload.ParentID); status != 0 {
+		return status, msg
+	}
+	return validateProjectSettings(db, payload)
+}
+
+// updateProject guarda el proyecto y sus trackers y módulos, comprobando su lock_version, y
+// responde con el proyecto actualizado. Lo comparten PUT y PATCH
+func updateProject(c *gin.Context, db *sql.DB, payload *ProjectPayload) {
+	id := payload.ID
+	project := payload.Project
+
+	current, err := projectPayload(db, id)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	if project.LockVersion != current.LockVersion {
+		respondStale(c, current.LockVersion, current)
+		return
+	}
+
+	if status, msg := validateProject(db, id, payload); status != 0 {
+		c.JSON(status, gin.H{"error": msg})
+		return
+	}
+
+	tx, err := db.Begin()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	defer tx.Rollback()
+
+	err = models.UpdateProject(tx, &project)
+	if err == nil {
+		err = saveProjectSettings(tx, id, payload)
+	}
+	if err == nil {
+		err = tx.Commit()
+	}
+	if err == models.ErrStaleObject {
+		// Otra petición lo modificó entre la lectura y la escritura
+		tx.Rollback()
+		if current, err = projectPayload(db, id); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		respondStale(c, current.LockVersion, current)
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	updated, err := projectPayload(db, id)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.Header("ETag", etag(updated.LockVersion))
+	c.JSON(http.StatusOK, updated)
+}
+
+// @Summary: DeleteProjectHandler
+// @Description: Delete a project by ID, with its issues, categories, members and versions
 // @Tags: projects
-// @Accept: json
 // @Produce: json
 // @Param id path int true "Project ID"
-// @Param project body models.Project true "Project"
-// @Success 200 {object} models.Project
+// @Success 204 {object} nil
 // @Failure 400 {object} map[string]string
 // @Failure 500 {object} map[string]string
-// @Router /project/{id} [put]
+// @Router /project/{id} [delete]
 // @Security BearerAuth
 func DeleteProjectHandler(cfg *config.Config) gin.HandlerFunc {
 	return func(c *gin.Context) {
@@ -316,7 +697,7 @@ func DeleteProjectHandler(cfg *config.Config) gin.HandlerFunc {
 		}
 
 		// Inicializar la base de datos
-		db, err := database.InitDB(cfg)
+		db, err := database.InitDBContext(c.Request.Context(), cfg)
 		if err != nil {
 			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 			return
@@ -332,3 +713,75 @@ func DeleteProjectHandler(cfg *config.Config) gin.HandlerFunc {
 		c.JSON(http.StatusNoContent, nil)
 	}
 }
+
+// checkProjectWritable comprueba que el proyecto existe y admite cambios. Devuelve 0 si se puede
+// escribir, o el código HTTP y el mensaje de error a responder
+func checkProjectWritable(db *sql.DB, projectID int) (int, string) {
+	project, err := models.GetProjectByID(db, projectID)
+	if err != nil {
+		return http.StatusInternalServerError, err.Error()
+	}
+	if project == nil {
+		return http.StatusNotFound, "Project not found"
+	}
+
+	switch project.Status {
+	case models.ProjectStatusClosed:
+		return http.StatusForbidden, "Project is closed and read-only"
+	case models.ProjectStatusArchived:
+		return http.StatusForbidden, "Project is archived"
+	}
+
+	return 0, ""
+}
+
+// checkProjectModule comprueba que el módulo está habilitado en el proyecto. Devuelve 0 si lo está,
+// o el código HTTP y el mensaje de error a responder
+func checkProjectModule(db *sql.DB, projectID int, module string) (int, string) {
+	enabled, err := models.IsProjectModuleEnabled(db, projectID, module)
+	if err != nil {
+		return http.StatusInternalServerError, err.Error()
+	}
+	if !enabled {
+		return http.StatusForbidden, "The " + module + " module is disabled for this project"
+	}
+	return 0, ""
+}
+
+// checkProjectTracker comprueba que el tracker está habilitado en el proyecto
+func checkProjectTracker(db *sql.DB, projectID, trackerID int) (int, string) {
+	enabled, err := models.IsTrackerEnabledForProject(db, projectID, trackerID)
+	if err != nil {
+		return http.StatusInternalServerError, err.Error()
+	}
+	if !enabled {
+		return http.StatusUnprocessableEntity, "Tracker is not enabled for this project"
+	}
+	return 0, ""
+}
+
+// RequireProjectModule corta las rutas /project/:id/... cuyo módulo no está habilitado en el proyecto
+func RequireProjectModule(cfg *config.Config, module string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id, err := strconv.Atoi(c.Param("id"))
+		if err != nil {
+			c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		// Inicializar la base de datos
+		db, err := database.InitDBContext(c.Request.Context(), cfg)
+		if err != nil {
+			c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		status, msg := checkProjectModule(db, id, module)
+		db.Close()
+		if status != 0 {
+			c.AbortWithStatusJSON(status, gin.H{"error": msg})
+			return
+		}
+
+		c.Next()
+	}
+}
@@ -0,0 +1,1595 @@
+package handlers
+
+import (
+	"database/sql"
+	"go-redmine-ish/config"
+	"go-redmine-ish/database"
+	"go-redmine-ish/models"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Capa de compatibilidad con la API REST de Redmine (rutas *.json).
+// Las respuestas usan los sobres de Redmine ({"issue": {...}}, total_count/offset/limit)
+// y los objetos anidados {id, name} en lugar de los *_id planos del resto de la API.
+
+type RedmineRef struct {
+	ID   int    `json:"id"`
+	Name string `json:"name"`
+}
+
+// RedmineIDRef es una referencia solo con id, como el padre de un ticket
+type RedmineIDRef struct {
+	ID int `json:"id"`
+}
+
+type RedmineStatusRef struct {
+	ID       int    `json:"id"`
+	Name     string `json:"name"`
+	IsClosed bool   `json:"is_closed"`
+}
+
+type RedmineJournal struct {
+	ID        int           `json:"id"`
+	User      *RedmineRef   `json:"user,omitempty"`
+	Notes     string        `json:"notes"`
+	CreatedOn string        `json:"created_on"`
+	Details   []interface{} `json:"details"`
+}
+
+type RedmineIssue struct {
+	ID             int               `json:"id"`
+	Project        *RedmineRef       `json:"project,omitempty"`
+	Tracker        *RedmineRef       `json:"tracker,omitempty"`
+	Status         *RedmineStatusRef `json:"status,omitempty"`
+	AssignedTo     *RedmineRef       `json:"assigned_to,omitempty"`
+	Category       *RedmineRef       `json:"category,omitempty"`
+	FixedVersion   *RedmineRef       `json:"fixed_version,omitempty"`
+	Parent         *RedmineIDRef     `json:"parent,omitempty"`
+	Priority       *RedmineRef       `json:"priority,omitempty"`
+	Author         *RedmineRef       `json:"author,omitempty"`
+	Subject        string            `json:"subject"`
+	Description    string            `json:"description"`
+	StartDate      *string           `json:"start_date"`
+	DueDate        *string           `json:"due_date"`
+	DoneRatio      int               `json:"done_ratio"`
+	EstimatedHours *float64          `json:"estimated_hours"`
+	CreatedOn      string            `json:"created_on"`
+	UpdatedOn      string            `json:"updated_on"`
+	Journals       []RedmineJournal  `json:"journals,omitempty"`
+}
+
+type RedmineProject struct {
+	ID              int          `json:"id"`
+	Name            string       `json:"name"`
+	Identifier      string       `json:"identifier"`
+	Description     string       `json:"description"`
+	Parent          *RedmineRef  `json:"parent,omitempty"`
+	Status          int          `json:"status"`
+	CreatedOn       string       `json:"created_on"`
+	UpdatedOn       string       `json:"updated_on"`
+	Trackers        []RedmineRef `json:"trackers,omitempty"`
+	IssueCategories []RedmineRef `json:"issue_categories,omitempty"`
+}
+
+type RedmineUser struct {
+	ID        int    `json:"id"`
+	Login     string `json:"login"`
+	Firstname string `json:"firstname"`
+	Lastname  string `json:"lastname"`
+	Mail      string `json:"mail"`
+	CreatedOn string `json:"created_on"`
+	UpdatedOn string `json:"updated_on"`
+}
+
+type RedmineMembership struct {
+	ID      int          `json:"id"`
+	Project RedmineRef   `json:"project"`
+	User    *RedmineRef  `json:"user,omitempty"`
+	Roles   []RedmineRef `json:"roles"`
+}
+
+type RedmineIssueCategory struct {
+	ID         int         `json:"id"`
+	Project    RedmineRef  `json:"project"`
+	Name       string      `json:"name"`
+	AssignedTo *RedmineRef `json:"assigned_to,omitempty"`
+}
+
+type RedmineIssuesData struct {
+	Issues     []RedmineIssue `json:"issues"`
+	TotalCount int            `json:"total_count"`
+	Offset     int            `json:"offset"`
+	Limit      int            `json:"limit"`
+}
+
+type RedmineIssueData struct {
+	Issue RedmineIssue `json:"issue"`
+}
+
+type RedmineProjectsData struct {
+	Projects   []RedmineProject `json:"projects"`
+	TotalCount int              `json:"total_count"`
+	Offset     int              `json:"offset"`
+	Limit      int              `json:"limit"`
+}
+
+type RedmineProjectData struct {
+	Project RedmineProject `json:"project"`
+}
+
+type RedmineUsersData struct {
+	Users      []RedmineUser `json:"users"`
+	TotalCount int           `json:"total_count"`
+	Offset     int           `json:"offset"`
+	Limit      int           `json:"limit"`
+}
+
+type RedmineUserData struct {
+	User RedmineUser `json:"user"`
+}
+
+type RedmineMembershipsData struct {
+	Memberships []RedmineMembership `json:"memberships"`
+	TotalCount  int                 `json:"total_count"`
+	Offset      int                 `json:"offset"`
+	Limit       int                 `json:"limit"`
+}
+
+type RedmineIssueCategoriesData struct {
+	IssueCategories []RedmineIssueCategory `json:"issue_categories"`
+	TotalCount      int                    `json:"total_count"`
+}
+
+// RedmineTracker es un tracker en el formato de /trackers.json
+type RedmineTracker struct {
+	ID                    int         `json:"id"`
+	Name                  string      `json:"name"`
+	DefaultStatus         *RedmineRef `json:"default_status,omitempty"`
+	Description           string      `json:"description"`
+	EnabledStandardFields []string    `json:"enabled_standard_fields"`
+}
+
+type RedmineTrackersData struct {
+	Trackers []RedmineTracker `json:"trackers"`
+}
+
+type RedmineIssueStatusesData struct {
+	IssueStatuses []RedmineStatusRef `json:"issue_statuses"`
+}
+
+// RedmineIssuePriority es una prioridad en el formato de /enumerations/issue_priorities.json
+type RedmineIssuePriority struct {
+	ID        int    `json:"id"`
+	Name      string `json:"name"`
+	IsDefault bool   `json:"is_default"`
+}
+
+type RedmineIssuePrioritiesData struct {
+	IssuePriorities []RedmineIssuePriority `json:"issue_priorities"`
+}
+
+type RedmineRolesData struct {
+	Roles []RedmineRef `json:"roles"`
+}
+
+// RedmineIssueFields son los campos que aceptan POST /issues.json y PUT /issues/{id}.json.
+// Los campos ausentes no se modifican.
+type RedmineIssueFields struct {
+	ProjectID      *int     `json:"project_id"`
+	TrackerID      *int     `json:"tracker_id"`
+	StatusID       *int     `json:"status_id"`
+	Subject        *string  `json:"subject"`
+	Description    *string  `json:"description"`
+	AssignedToID   *int     `json:"assigned_to_id"`
+	CategoryID     *int     `json:"category_id"`
+	FixedVersionID *int     `json:"fixed_version_id"`
+	ParentIssueID  *int     `json:"parent_issue_id"`
+	PriorityID     *int     `json:"priority_id"`
+	StartDate      *string  `json:"start_date"`
+	DueDate        *string  `json:"due_date"`
+	DoneRatio      *int     `json:"done_ratio"`
+	EstimatedHours *float64 `json:"estimated_hours"`
+	Notes          *string  `json:"notes"`
+}
+
+type RedmineIssuePayload struct {
+	Issue RedmineIssueFields `json:"issue"`
+}
+
+// redmineError responde con el formato de errores de Redmine
+func redmineError(c *gin.Context, status int, messages ...string) {
+	c.AbortWithStatusJSON(status, gin.H{"errors": messages})
+}
+
+// redmineParam devuelve el parámetro de ruta sin el sufijo .json e indica si lo tenía
+func redmineParam(c *gin.Context, name string) (string, bool) {
+	value := c.Param(name)
+	if strings.HasSuffix(value, ".json") {
+		return strings.TrimSuffix(value, ".json"), true
+	}
+	return value, false
+}
+
+// redmineOffsetLimit lee offset/limit (o page) con los mismos límites que Redmine
+func redmineOffsetLimit(c *gin.Context) (int, int) {
+	limit, _ := strconv.Atoi(c.Query("limit"))
+	if limit <= 0 {
+		limit = 25
+	}
+	if limit > 100 {
+		limit = 100
+	}
+
+	offset, err := strconv.Atoi(c.Query("offset"))
+	if err != nil {
+		offset = 0
+		if page, err := strconv.Atoi(c.Query("page")); err == nil && page > 0 {
+			offset = (page - 1) * limit
+		}
+	}
+	if offset < 0 {
+		offset = 0
+	}
+
+	return offset, limit
+}
+
+// redminePage devuelve los índices [start, end) de la página dentro de total elementos
+func redminePage(total, offset, limit int) (int, int) {
+	start := offset
+	if start > total {
+		start = total
+	}
+	end := start + limit
+	if end > total {
+		end = total
+	}
+	return start, end
+}
+
+// redmineProject busca un proyecto por ID numérico o por identificador
+func redmineProject(db *sql.DB, value string) (*models.Project, error) {
+	if id, err := strconv.Atoi(value); err == nil {
+		return models.GetProjectByID(db, id)
+	}
+	return models.GetProjectByIdentifier(db, value)
+}
+
+// redmineCheckVisible comprueba que el usuario autenticado puede ver el proyecto. Como en Redmine,
+// los proyectos que no ve responden 404. Devuelve false si ya se ha respondido
+func redmineCheckVisible(c *gin.Context, db *sql.DB, projectID int) bool {
+	visible, err := canViewProject(c, db, projectID)
+	if err != nil {
+		redmineError(c, http.StatusInternalServerError, err.Error())
+		return false
+	}
+	if !visible {
+		redmineError(c, http.StatusNotFound, "Not found")
+		return false
+	}
+	return true
+}
+
+// redmineVisibleProject busca un proyecto por ID o identificador que el usuario autenticado pueda
+// ver. Devuelve nil si ya se ha respondido con el error
+func redmineVisibleProject(c *gin.Context, db *sql.DB, value string) *models.Project {
+	project, err := redmineProject(db, value)
+	if err != nil {
+		redmineError(c, http.StatusInternalServerError, err.Error())
+		return nil
+	}
+	if project == nil {
+		redmineError(c, http.StatusNotFound, "Not found")
+		return nil
+	}
+	if !redmineCheckVisible(c, db, project.ID) {
+		return nil
+	}
+	return project
+}
+
+// redmineRefs resuelve los *_id de los modelos a los objetos anidados de Redmine
+type redmineRefs struct {
+	db         *sql.DB
+	projects   map[int]RedmineRef
+	trackers   map[int]RedmineRef
+	users      map[int]RedmineRef
+	statuses   map[string]RedmineStatusRef
+	categories map[int]*RedmineRef
+	versions   map[int]*RedmineRef
+	priorities map[int]RedmineRef
+}
+
+func newRedmineRefs(db *sql.DB) (*redmineRefs, error) {
+	refs := &redmineRefs{
+		db:         db,
+		projects:   map[int]RedmineRef{},
+		trackers:   map[int]RedmineRef{},
+		users:      map[int]RedmineRef{},
+		statuses:   map[string]RedmineStatusRef{},
+		categories: map[int]*RedmineRef{},
+		versions:   map[int]*RedmineRef{},
+		priorities: map[int]RedmineRef{},
+	}
+
+	projects, err := models.GetAllProjects(db)
+	if err != nil {
+		return nil, err
+	}
+	for _, project := range projects {
+		refs.projects[project.ID] = RedmineRef{ID: project.ID, Name: project.Name}
+	}
+
+	trackers, err := models.GetAllTrackers(db)
+	if err != nil {
+		return nil, err
+	}
+	for _, tracker := range trackers {
+		refs.trackers[tracker.ID] = RedmineRef{ID: tracker.ID, Name: tracker.Name}
+	}
+
+	users, err := models.GetAllUsers(db)
+	if err != nil {
+		return nil, err
+	}
+	for _, user := range users {
+		refs.users[user.ID] = RedmineRef{ID: user.ID, Name: user.Username}
+	}
+
+	statuses, err := models.GetAllIssueStatuses(db)
+	if err != nil {
+		return nil, err
+	}
+	for _, status := range statuses {
+		refs.statuses[status.Name] = RedmineStatusRef{ID: status.ID, Name: status.Name, IsClosed: status.IsClosed}
+	}
+
+	priorities, err := models.GetAllIssuePriorities(db)
+	if err != nil {
+		return nil, err
+	}
+	for _, priority := range priorities {
+		refs.priorities[priority.ID] = RedmineRef{ID: priority.ID, Name: priority.Name}
+	}
+
+	return refs, nil
+}
+
+func (r *redmineRefs) project(id int) *RedmineRef {
+	if ref, ok := r.projects[id]; ok {
+		return &ref
+	}
+	return nil
+}
+
+func (r *redmineRefs) user(id *int) *RedmineRef {
+	if id == nil {
+		return nil
+	}
+	if ref, ok := r.users[*id]; ok {
+		return &ref
+	}
+	return nil
+}
+
+func (r *redmineRefs) category(id *int) *RedmineRef {
+	if id == nil {
+		return nil
+	}
+	if ref, ok := r.categories[*id]; ok {
+		return ref
+	}
+	var ref *RedmineRef
+	if category, err := models.GetCategoryByID(r.db, *id); err == nil {
+		ref = &RedmineRef{ID: category.ID, Name: category.Name}
+	}
+	r.categories[*id] = ref
+	return ref
+}
+
+func (r *redmineRefs) version(id *int) *RedmineRef {
+	if id == nil {
+		return nil
+	}
+	if ref, ok := r.versions[*id]; ok {
+		return ref
+	}
+	var ref *RedmineRef
+	if version, err := models.GetVersionByID(r.db, *id); err == nil && version != nil {
+		ref = &RedmineRef{ID: version.ID, Name: version.Name}
+	}
+	r.versions[*id] = ref
+	return ref
+}
+
+func (r *redmineRefs) issue(issue models.Issue) RedmineIssue {
+	data := RedmineIssue{
+		ID:             issue.ID,
+		Project:        r.project(issue.ProjectID),
+		AssignedTo:     r.user(issue.AssignedToID),
+		Category:       r.category(issue.CategoryID),
+		FixedVersion:   r.version(issue.FixedVersionID),
+		Author:         r.user(issue.AuthorID),
+		Subject:        issue.Subject,
+		Description:    issue.Description,
+		StartDate:      issue.StartDate,
+		DueDate:        issue.DueDate,
+		DoneRatio:      issue.DoneRatio,
+		EstimatedHours: issue.EstimatedHours,
+		CreatedOn:      issue.CreatedAt,
+		UpdatedOn:      issue.UpdatedAt,
+	}
+	if tracker, ok := r.trackers[issue.TrackerID]; ok {
+		data.Tracker = &tracker
+	}
+	if priority, ok := r.priorities[issue.PriorityID]; ok {
+		data.Priority = &priority
+	}
+	// Los estados que no figuran en issue_statuses se devuelven con id 0
+	status, ok := r.statuses[issue.Status]
+	if !ok {
+		status = RedmineStatusRef{Name: issue.Status}
+	}
+	data.Status = &status
+	if issue.ParentID != nil {
+		data.Parent = &RedmineIDRef{ID: *issue.ParentID}
+	}
+	return data
+}
+
+// redmineProjectStatus son los códigos de estado de proyecto de Redmine
+var redmineProjectStatus = map[string]int{
+	models.ProjectStatusActive:   1,
+	models.ProjectStatusClosed:   5,
+	models.ProjectStatusArchived: 9,
+}
+
+func (r *redmineRefs) projectData(project models.Project) RedmineProject {
+	data := RedmineProject{
+		ID:          project.ID,
+		Name:        project.Name,
+		Identifier:  project.Identifier,
+		Description: project.Description,
+		Status:      redmineProjectStatus[project.Status],
+		CreatedOn:   project.CreatedOn.Format(time.RFC3339),
+		UpdatedOn:   project.UpdatedOn.Format(time.RFC3339),
+	}
+	if project.ParentID != nil {
+		data.Parent = r.project(*project.ParentID)
+	}
+	return data
+}
+
+func redmineUser(user models.User) RedmineUser {
+	return RedmineUser{
+		ID:        user.ID,
+		Login:     user.Username,
+		Firstname: user.Username,
+		Mail:      user.Email,
+		CreatedOn: user.CreatedAt,
+		UpdatedOn: user.UpdatedAt,
+	}
+}
+
+// redmineIssueFilter traduce los parámetros de /issues.json al filtro de los modelos
+func redmineIssueFilter(c *gin.Context, db *sql.DB) (models.IssueFilter, error) {
+	filter := models.IssueFilter{Status: "open"}
+	filter.Offset, filter.Limit = redmineOffsetLimit(c)
+
+	if value := c.Query("project_id"); value != "" {
+		project, err := redmineProject(db, value)
+		if err != nil {
+			return filter, err
+		}
+		if project == nil {
+			filter.ProjectID = -1 // no coincide con ningún ticket
+		} else {
+			filter.ProjectID = project.ID
+		}
+	}
+
+	filter.TrackerID, _ = strconv.Atoi(c.Query("tracker_id"))
+	filter.CategoryID, _ = strconv.Atoi(c.Query("category_id"))
+	filter.FixedVersionID, _ = strconv.Atoi(c.Query("fixed_version_id"))
+	filter.PriorityID, _ = strconv.Atoi(c.Query("priority_id"))
+	filter.AuthorID, _ = strconv.Atoi(c.Query("author_id"))
+
+	if value := c.Query("assigned_to_id"); value == "me" {
+		if user_id := currentUserID(c); user_id != nil {
+			filter.AssignedToID = *user_id
+		} else {
+			filter.AssignedToID = -1
+		}
+	} else {
+		filter.AssignedToID, _ = strconv.Atoi(value)
+	}
+
+	switch value := c.Query("status_id"); value {
+	case "", "open":
+		filter.Status = "open"
+	case "closed":
+		filter.Status = "closed"
+	case "*":
+		filter.Status = ""
+	default:
+		id, err := strconv.Atoi(value)
+		if err != nil {
+			return filter, err
+		}
+		status, err := models.GetIssueStatusByID(db, id)
+		if err != nil {
+			return filter, err
+		}
+		if status == nil {
+			filter.Status = "-"
+		} else {
+			filter.Status = status.Name
+		}
+	}
+
+	// Redmine admite varias columnas separadas por comas, aquí solo se usa la primera
+	filter.Sort = strings.Split(c.Query("sort"), ",")[0]
+
+	return filter, nil
+}
+
+// @Summary: RedmineGetIssuesHandler
+// @Description: List issues using the Redmine REST API format
+// @Tags: redmine
+// @Produce: json
+// @Param project_id query string false "Project ID or identifier"
+// @Param tracker_id query int false "Tracker ID"
+// @Param status_id query string false "open, closed, * or status ID"
+// @Param assigned_to_id query string false "User ID or me"
+// @Param category_id query int false "Category ID"
+// @Param fixed_version_id query int false "Version ID"
+// @Param priority_id query int false "Priority ID"
+// @Param author_id query int false "Author user ID"
+// @Param sort query string false "Sort column, e.g. updated_on:desc"
+// @Param offset query int false "Offset"
+// @Param limit query int false "Limit (max 100)"
+// @Success 200 {object} RedmineIssuesData
+// @Failure 422 {object} map[string][]string
+// @Failure 500 {object} map[string][]string
+// @Router /issues.json [get]
+// @Security BearerAuth
+func RedmineGetIssuesHandler(cfg *config.Config) gin.HandlerFunc {
+	return func(c *gin.Context) {
+
+		// Inicializar la base de datos
+		db, err := database.InitDBContext(c.Request.Context(), cfg)
+		if err != nil {
+			redmineError(c, http.StatusInternalServerError, err.Error())
+			return
+		}
+		defer db.Close()
+
+		filter, err := redmineIssueFilter(c, db)
+		if err != nil {
+			redmineError(c, http.StatusUnprocessableEntity, err.Error())
+			return
+		}
+		filter.ProjectIDs, err = visibleProjectIDs(c, db)
+		if err != nil {
+			redmineError(c, http.StatusInternalServerError, err.Error())
+			return
+		}
+
+		issues, err := models.GetIssuesFiltered(db, filter)
+		if err != nil {
+			redmineError(c, http.StatusInternalServerError, err.Error())
+			return
+		}
+
+		count, err := models.CountIssuesFiltered(db, filter)
+		if err != nil {
+			redmineError(c, http.StatusInternalServerError, err.Error())
+			return
+		}
+
+		refs, err := newRedmineRefs(db)
+		if err != nil {
+			redmineError(c, http.StatusInternalServerError, err.Error())
+			return
+		}
+
+		data := RedmineIssuesData{
+			Issues:     []RedmineIssue{},
+			TotalCount: count,
+			Offset:     filter.Offset,
+			Limit:      filter.Limit,
+		}
+		for _, issue := range issues {
+			data.Issues = append(data.Issues, refs.issue(issue))
+		}
+
+		c.JSON(http.StatusOK, data)
+	}
+}
+
+// @Summary: RedmineGetIssueHandler
+// @Description: Get an issue using the Redmine REST API format
+// @Tags: redmine
+// @Produce: json
+// @Param id path int true "Issue ID"
+// @Param include query string false "journals"
+// @Success 200 {object} RedmineIssueData
+// @Failure 404 {object} map[string][]string
+// @Failure 500 {object} map[string][]string
+// @Router /issues/{id}.json [get]
+// @Security BearerAuth
+func RedmineGetIssueHandler(cfg *config.Config) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		pid, ok := redmineParam(c, "id")
+		id, err := strconv.Atoi(pid)
+		if !ok || err != nil {
+			redmineError(c, http.StatusNotFound, "Not found")
+			return
+		}
+
+		// Inicializar la base de datos
+		db, err := database.InitDBContext(c.Request.Context(), cfg)
+		if err != nil {
+			redmineError(c, http.StatusInternalServerError, err.Error())
+			return
+		}
+		defer db.Close()
+
+		issue, err := models.GetIssueByID(db, id)
+		if err == sql.ErrNoRows {
+			redmineError(c, http.StatusNotFound, "Not found")
+			return
+		}
+		if err != nil {
+			redmineError(c, http.StatusInternalServerError, err.Error())
+			return
+		}
+		if !redmineCheckVisible(c, db, issue.ProjectID) {
+			return
+		}
+
+		refs, err := newRedmineRefs(db)
+		if err != nil {
+			redmineError(c, http.StatusInternalServerError, err.Error())
+			return
+		}
+
+		data := RedmineIssueData{Issue: refs.issue(*issue)}
+
+		if strings.Contains(c.Query("include"), "journals") {
+			comments, err := models.GetCommentsByIssueID(db, id)
+			if err != nil {
+				redmineError(c, http.StatusInternalServerError, err.Error())
+				return
+			}
+			data.Issue.Journals = []RedmineJournal{}
+			for _, comment := range comments {
+				user_id := comment.UserID
+				data.Issue.Journals = append(data.Issue.Journals, RedmineJournal{
+					ID:        comment.ID,
+					User:      refs.user(&user_id),
+					Notes:     comment.Content,
+					CreatedOn: comment.CreatedAt,
+					Details:   []interface{}{},
+				})
+			}
+		}
+
+		c.JSON(http.StatusOK, data)
+	}
+}
+
+// applyRedmineIssueFields copia sobre issue los campos presentes en el payload
+func applyRedmineIssueFields(db *sql.DB, issue *models.Issue, fields RedmineIssueFields) error {
+	if fields.ProjectID != nil {
+		issue.ProjectID = *fields.ProjectID
+	}
+	if fields.TrackerID != nil {
+		issue.TrackerID = *fields.TrackerID
+	}
+	if fields.Subject != nil {
+		issue.Subject = *fields.Subject
+	}
+	if fields.Description != nil {
+		issue.Description = *fields.Description
+	}
+	if fields.AssignedToID != nil {
+		issue.AssignedToID = fields.AssignedToID
+	}
+	if fields.CategoryID != nil {
+		issue.CategoryID = fields.CategoryID
+	}
+	if fields.FixedVersionID != nil {
+		issue.FixedVersionID = fields.FixedVersionID
+	}
+	if fields.ParentIssueID != nil {
+		issue.ParentID = fields.ParentIssueID
+	}
+	if fields.PriorityID != nil {
+		issue.PriorityID = *fields.PriorityID
+	}
+	if fields.StartDate != nil {
+		issue.StartDate = optionalDate(*fields.StartDate)
+	}
+	if fields.DueDate != nil {
+		issue.DueDate = optionalDate(*fields.DueDate)
+	}
+	if fields.DoneRatio != nil {
+		issue.DoneRatio = *fields.DoneRatio
+	}
+	if fields.EstimatedHours != nil {
+		issue.EstimatedHours = fields.EstimatedHours
+	}
+	if fields.StatusID != nil {
+		status, err := models.GetIssueStatusByID(db, *fields.StatusID)
+		if err != nil {
+			return err
+		}
+		if status == nil {
+			return errRedmineInvalidStatus
+		}
+		issue.Status = status.Name
+	}
+	return nil
+}
+
+// optionalDate traduce la cadena vacía, con la que Redmine borra una fecha, a nil
+func optionalDate(value string) *string {
+	if value == "" {
+		return nil
+	}
+	return &value
+}
+
+type redmineValidationError string
+
+func (e redmineValidationError) Error() string { return string(e) }
+
+const errRedmineInvalidStatus = redmineValidationError("Status is not included in the list")
+
+// validateRedmineIssue devuelve los mensajes de validación al estilo de Redmine
+func validateRedmineIssue(issue *models.Issue) []string {
+	errors := []string{}
+	if issue.ProjectID == 0 {
+		errors = append(errors, "Project cannot be blank")
+	}
+	if issue.TrackerID == 0 {
+		errors = append(errors, "Tracker cannot be blank")
+	}
+	if strings.TrimSpace(issue.Subject) == "" {
+		errors = append(errors, "Subject cannot be blank")
+	}
+	return errors
+}
+
+// @Summary: RedmineCreateIssueHandler
+// @Description: Create an issue using the Redmine REST API format
+// @Tags: redmine
+// @Accept: json
+// @Produce: json
+// @Param issue body RedmineIssuePayload true "Issue"
+// @Success 201 {object} RedmineIssueData
+// @Failure 422 {object} map[string][]string
+// @Failure 500 {object} map[string][]string
+// @Router /issues.json [post]
+// @Security BearerAuth
+func RedmineCreateIssueHandler(cfg *config.Config) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var payload RedmineIssuePayload
+		if err := c.ShouldBindJSON(&payload); err != nil {
+			redmineError(c, http.StatusUnprocessableEntity, err.Error())
+			return
+		}
+
+		// Inicializar la base de datos
+		db, err := database.InitDBContext(c.Request.Context(), cfg)
+		if err != nil {
+			redmineError(c, http.StatusInternalServerError, err.Error())
+			return
+		}
+		defer db.Close()
+
+		// Sin status_id se usa el estado por defecto del tracker (checkTrackerFields)
+		issue := models.Issue{}
+		if err := applyRedmineIssueFields(db, &issue, payload.Issue); err != nil {
+			if verr, ok := err.(redmineValidationError); ok {
+				redmineError(c, http.StatusUnprocessableEntity, verr.Error())
+				return
+			}
+			redmineError(c, http.StatusInternalServerError, err.Error())
+			return
+		}
+
+		if errors := validateRedmineIssue(&issue); len(errors) > 0 {
+			redmineError(c, http.StatusUnprocessableEntity, errors...)
+			return
+		}
+		if issue.ProjectID != 0 && !redmineCheckVisible(c, db, issue.ProjectID) {
+			return
+		}
+
+		// Las mismas comprobaciones que la API propia: proyecto, tracker, campos, categoría, versión y padre
+		if status, msg := validateIssue(db, &issue, nil); status != 0 {
+			redmineError(c, status, msg)
+			return
+		}
+
+		issue.AuthorID = currentUserID(c)
+
+		// El ticket y sus menciones se guardan juntos
+		tx, err := db.Begin()
+		if err != nil {
+			redmineError(c, http.StatusInternalServerError, err.Error())
+			return
+		}
+		defer tx.Rollback()
+
+		id, err := models.CreateIssue(tx, &issue)
+		if err != nil {
+			redmineError(c, http.StatusUnprocessableEntity, err.Error())
+			return
+		}
+
+		if err := recordMentions(c, cfg, db, tx, id, nil, issue.Description); err != nil {
+			redmineError(c, http.StatusInternalServerError, err.Error())
+			return
+		}
+
+		if err := tx.Commit(); err != nil {
+			redmineError(c, http.StatusInternalServerError, err.Error())
+			return
+		}
+
+		created, err := models.GetIssueByID(db, id)
+		if err != nil {
+			redmineError(c, http.StatusInternalServerError, err.Error())
+			return
+		}
+
+		refs, err := newRedmineRefs(db)
+		if err != nil {
+			redmineError(c, http.StatusInternalServerError, err.Error())
+			return
+		}
+
+		c.JSON(http.StatusCreated, RedmineIssueData{Issue: refs.issue(*created)})
+	}
+}
+
+// @Summary: RedmineUpdateIssueHandler
+// @Description: Update an issue using the Redmine REST API format, notes are stored as a comment
+// @Tags: redmine
+// @Accept: json
+// @Param id path int true "Issue ID"
+// @Param issue body RedmineIssuePayload true "Issue"
+// @Success 204
+// @Failure 404 {object} map[string][]string
+// @Failure 422 {object} map[string][]string
+// @Failure 500 {object} map[string][]string
+// @Router /issues/{id}.json [put]
+// @Security BearerAuth
+func RedmineUpdateIssueHandler(cfg *config.Config) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		pid, ok := redmineParam(c, "id")
+		id, err := strconv.Atoi(pid)
+		if !ok || err != nil {
+			redmineError(c, http.StatusNotFound, "Not found")
+			return
+		}
+
+		var payload RedmineIssuePayload
+		if err := c.ShouldBindJSON(&payload); err != nil {
+			redmineError(c, http.StatusUnprocessableEntity, err.Error())
+			return
+		}
+
+		// Inicializar la base de datos
+		db, err := database.InitDBContext(c.Request.Context(), cfg)
+		if err != nil {
+			redmineError(c, http.StatusInternalServerError, err.Error())
+			return
+		}
+		defer db.Close()
+
+		issue, err := models.GetIssueByID(db, id)
+		if err == sql.ErrNoRows {
+			redmineError(c, http.StatusNotFound, "Not found")
+			return
+		}
+		if err != nil {
+			redmineError(c, http.StatusInternalServerError, err.Error())
+			return
+		}
+
+		if !redmineCheckVisible(c, db, issue.ProjectID) {
+			return
+		}
+		if status, msg := checkProjectWritable(db, issue.ProjectID); status != 0 {
+			redmineError(c, status, msg)
+			return
+		}
+
+		before := *issue
+		if err := applyRedmineIssueFields(db, issue, payload.Issue); err != nil {
+			if verr, ok := err.(redmineValidationError); ok {
+				redmineError(c, http.StatusUnprocessableEntity, verr.Error())
+				return
+			}
+			redmineError(c, http.StatusInternalServerError, err.Error())
+			return
+		}
+
+		if errors := validateRedmineIssue(issue); len(errors) > 0 {
+			redmineError(c, http.StatusUnprocessableEntity, errors...)
+			return
+		}
+		// Tampoco se puede mover a un proyecto que no ve
+		if issue.ProjectID != before.ProjectID && !redmineCheckVisible(c, db, issue.ProjectID) {
+			return
+		}
+
+		// Las mismas comprobaciones que la API propia, incluidos los ciclos de ticket padre
+		if status, msg := validateIssue(db, issue, &before); status != 0 {
+			redmineError(c, status, msg)
+			return
+		}
+		if status, msg := checkIssueWorkflow(c, db, &before, issue); status != 0 {
+			redmineError(c, status, msg)
+			return
+		}
+
+		// Las notas de Redmine se guardan como comentario del usuario autenticado: se comprueba
+		// antes de escribir nada para no dejar el ticket cambiado y responder con un error
+		notes := payload.Issue.Notes != nil && strings.TrimSpace(*payload.Issue.Notes) != ""
+		user_id := currentUserID(c)
+		if notes && user_id == nil {
+			redmineError(c, http.StatusUnprocessableEntity, "Notes require an authenticated user")
+			return
+		}
+
+		// El ticket, su historial y el comentario se guardan juntos o no se guarda nada
+		tx, err := db.Begin()
+		if err != nil {
+			redmineError(c, http.StatusInternalServerError, err.Error())
+			return
+		}
+		defer tx.Rollback()
+
+		// La API de Redmine no envía lock_version: solo se detecta una escritura concurrente
+		if err := models.UpdateIssue(tx, issue); err == models.ErrStaleObject {
+			redmineError(c, http.StatusConflict, err.Error())
+			return
+		} else if err != nil {
+			redmineError(c, http.StatusUnprocessableEntity, err.Error())
+			return
+		}
+
+		if err := models.RecordIssueChanges(tx, &before, issue, user_id); err != nil {
+			redmineError(c, http.StatusInternalServerError, err.Error())
+			return
+		}
+
+		if issue.Description != before.Description {
+			if err := recordMentions(c, cfg, db, tx, id, nil, issue.Description); err != nil {
+				redmineError(c, http.StatusInternalServerError, err.Error())
+				return
+			}
+		}
+
+		if notes {
+			comment := models.Comment{IssueID: id, UserID: *user_id, Content: *payload.Issue.Notes}
+			comment_id, err := models.CreateComment(tx, &comment)
+			if err != nil {
+				redmineError(c, http.StatusInternalServerError, err.Error())
+				return
+			}
+			if err := recordMentions(c, cfg, db, tx, id, &comment_id, comment.Content); err != nil {
+				redmineError(c, http.StatusInternalServerError, err.Error())
+				return
+			}
+		}
+
+		if err := tx.Commit(); err != nil {
+			redmineError(c, http.StatusInternalServerError, err.Error())
+			return
+		}
+
+		c.Status(http.StatusNoContent)
+	}
+}
+
+// @Summary: RedmineDeleteIssueHandler
+// @Description: Delete an issue using the Redmine REST API format
+// @Tags: redmine
+// @Param id path int true "Issue ID"
+// @Success 204
+// @Failure 404 {object} map[string][]string
+// @Failure 500 {object} map[string][]string
+// @Router /issues/{id}.json [delete]
+// @Security BearerAuth
+func RedmineDeleteIssueHandler(cfg *config.Config) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		pid, ok := redmineParam(c, "id")
+		id, err := strconv.Atoi(pid)
+		if !ok || err != nil {
+			redmineError(c, http.StatusNotFound, "Not found")
+			return
+		}
+
+		// Inicializar la base de datos
+		db, err := database.InitDBContext(c.Request.Context(), cfg)
+		if err != nil {
+			redmineError(c, http.StatusInternalServerError, err.Error())
+			return
+		}
+		defer db.Close()
+
+		issue, err := models.GetIssueByID(db, id)
+		if err != nil {
+			if err == sql.ErrNoRows {
+				redmineError(c, http.StatusNotFound, "Not found")
+				return
+			}
+			redmineError(c, http.StatusInternalServerError, err.Error())
+			return
+		}
+
+		if !redmineCheckVisible(c, db, issue.ProjectID) {
+			return
+		}
+		if status, msg := checkProjectWritable(db, issue.ProjectID); status != 0 {
+			redmineError(c, status, msg)
+			return
+		}
+
+		if err := models.DeleteIssue(db, id); err != nil {
+			redmineError(c, http.StatusInternalServerError, err.Error())
+			return
+		}
+
+		c.Status(http.StatusNoContent)
+	}
+}
+
+// @Summary: RedmineGetProjectsHandler
+// @Description: List projects using the Redmine REST API format
+// @Tags: redmine
+// @Produce: json
+// @Param offset query int false "Offset"
+// @Param limit query int false "Limit (max 100)"
+// @Success 200 {object} RedmineProjectsData
+// @Failure 500 {object} map[string][]string
+// @Router /projects.json [get]
+// @Security BearerAuth
+func RedmineGetProjectsHandler(cfg *config.Config) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		offset, limit := redmineOffsetLimit(c)
+
+		// Inicializar la base de datos
+		db, err := database.InitDBContext(c.Request.Context(), cfg)
+		if err != nil {
+			redmineError(c, http.StatusInternalServerError, err.Error())
+			return
+		}
+		defer db.Close()
+
+		all, err := models.GetAllProjects(db)
+		if err != nil {
+			redmineError(c, http.StatusInternalServerError, err.Error())
+			return
+		}
+
+		// nil si el usuario puede ver todos los proyectos
+		visible_ids, err := visibleProjectIDs(c, db)
+		if err != nil {
+			redmineError(c, http.StatusInternalServerError, err.Error())
+			return
+		}
+		visible := map[int]bool{}
+		for _, id := range visible_ids {
+			visible[id] = true
+		}
+
+		// Como en Redmine, los proyectos archivados no se listan
+		projects := []models.Project{}
+		for _, project := range all {
+			if project.Status != models.ProjectStatusArchived && (visible_ids == nil || visible[project.ID]) {
+				projects = append(projects, project)
+			}
+		}
+
+		refs, err := newRedmineRefs(db)
+		if err != nil {
+			redmineError(c, http.StatusInternalServerError, err.Error())
+			return
+		}
+
+		data := RedmineProjectsData{
+			Projects:   []RedmineProject{},
+			TotalCount: len(projects),
+			Offset:     offset,
+			Limit:      limit,
+		}
+		start, end := redminePage(len(projects), offset, limit)
+		for _, project := range projects[start:end] {
+			data.Projects = append(data.Projects, refs.projectData(project))
+		}
+
+		c.JSON(http.StatusOK, data)
+	}
+}
+
+// @Summary: RedmineGetProjectHandler
+// @Description: Get a project by ID or identifier using the Redmine REST API format
+// @Tags: redmine
+// @Produce: json
+// @Param id path string true "Project ID or identifier"
+// @Param include query string false "trackers,issue_categories"
+// @Success 200 {object} RedmineProjectData
+// @Failure 404 {object} map[string][]string
+// @Failure 500 {object} map[string][]string
+// @Router /projects/{id}.json [get]
+// @Security BearerAuth
+func RedmineGetProjectHandler(cfg *config.Config) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		pid, ok := redmineParam(c, "id")
+		if !ok {
+			redmineError(c, http.StatusNotFound, "Not found")
+			return
+		}
+
+		// Inicializar la base de datos
+		db, err := database.InitDBContext(c.Request.Context(), cfg)
+		if err != nil {
+			redmineError(c, http.StatusInternalServerError, err.Error())
+			return
+		}
+		defer db.Close()
+
+		project := redmineVisibleProject(c, db, pid)
+		if project == nil {
+			return
+		}
+
+		refs, err := newRedmineRefs(db)
+		if err != nil {
+			redmineError(c, http.StatusInternalServerError, err.Error())
+			return
+		}
+
+		data := RedmineProjectData{Project: refs.projectData(*project)}
+
+		include := c.Query("include")
+		if strings.Contains(include, "trackers") {
+			trackers, err := models.GetTrackersByProjectID(db, project.ID)
+			if err != nil {
+				redmineError(c, http.StatusInternalServerError, err.Error())
+				return
+			}
+			for _, tracker := range trackers {
+				data.Project.Trackers = append(data.Project.Trackers, RedmineRef{ID: tracker.ID, Name: tracker.Name})
+			}
+		}
+		if strings.Contains(include, "issue_categories") {
+			categories, err := models.GetCategoriesByProjectID(db, project.ID)
+			if err != nil {
+				redmineError(c, http.StatusInternalServerError, err.Error())
+				return
+			}
+			for _, category := range categories {
+				data.Project.IssueCategories = append(data.Project.IssueCategories, RedmineRef{ID: category.ID, Name: category.Name})
+			}
+		}
+
+		c.JSON(http.StatusOK, data)
+	}
+}
+
+// @Summary: RedmineGetProjectMembershipsHandler
+// @Description: List the memberships of a project using the Redmine REST API format
+// @Tags: redmine
+// @Produce: json
+// @Param id path string true "Project ID or identifier"
+// @Success 200 {object} RedmineMembershipsData
+// @Failure 404 {object} map[string][]string
+// @Failure 500 {object} map[string][]string
+// @Router /projects/{id}/memberships.json [get]
+// @Security BearerAuth
+func RedmineGetProjectMembershipsHandler(cfg *config.Config) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		offset, limit := redmineOffsetLimit(c)
+
+		// Inicializar la base de datos
+		db, err := database.InitDBContext(c.Request.Context(), cfg)
+		if err != nil {
+			redmineError(c, http.StatusInternalServerError, err.Error())
+			return
+		}
+		defer db.Close()
+
+		project := redmineVisibleProject(c, db, c.Param("id"))
+		if project == nil {
+			return
+		}
+
+		members, err := models.GetMembersByProjectID(db, project.ID)
+		if err != nil {
+			redmineError(c, http.StatusInternalServerError, err.Error())
+			return
+		}
+
+		roles, err := models.GetAllRoles(db)
+		if err != nil {
+			redmineError(c, http.StatusInternalServerError, err.Error())
+			return
+		}
+		role_names := map[int]string{}
+		for _, role := range roles {
+			role_names[role.ID] = role.Name
+		}
+
+		refs, err := newRedmineRefs(db)
+		if err != nil {
+			redmineError(c, http.StatusInternalServerError, err.Error())
+			return
+		}
+
+		data := RedmineMembershipsData{
+			Memberships: []RedmineMembership{},
+			TotalCount:  len(members),
+			Offset:      offset,
+			Limit:       limit,
+		}
+		start, end := redminePage(len(members), offset, limit)
+		for _, member := range members[start:end] {
+			user_id := member.UserID
+			data.Memberships = append(data.Memberships, RedmineMembership{
+				ID:      member.ID,
+				Project: RedmineRef{ID: project.ID, Name: project.Name},
+				User:    refs.user(&user_id),
+				Roles:   []RedmineRef{{ID: member.RoleID, Name: role_names[member.RoleID]}},
+			})
+		}
+
+		c.JSON(http.StatusOK, data)
+	}
+}
+
+// @Summary: RedmineGetProjectIssueCategoriesHandler
+// @Description: List the issue categories of a project using the Redmine REST API format
+// @Tags: redmine
+// @Produce: json
+// @Param id path string true "Project ID or identifier"
+// @Success 200 {object} RedmineIssueCategoriesData
+// @Failure 404 {object} map[string][]string
+// @Failure 500 {object} map[string][]string
+// @Router /projects/{id}/issue_categories.json [get]
+// @Security BearerAuth
+func RedmineGetProjectIssueCategoriesHandler(cfg *config.Config) gin.HandlerFunc {
+	return func(c *gin.Context) {
+
+		// Inicializar la base de datos
+		db, err := database.InitDBContext(c.Request.Context(), cfg)
+		if err != nil {
+			redmineError(c, http.StatusInternalServerError, err.Error())
+			return
+		}
+		defer db.Close()
+
+		project := redmineVisibleProject(c, db, c.Param("id"))
+		if project == nil {
+			return
+		}
+
+		categories, err := models.GetCategoriesByProjectID(db, project.ID)
+		if err != nil {
+			redmineError(c, http.StatusInternalServerError, err.Error())
+			return
+		}
+
+		refs, err := newRedmineRefs(db)
+		if err != nil {
+			redmineError(c, http.StatusInternalServerError, err.Error())
+			return
+		}
+
+		data := RedmineIssueCategoriesData{
+			IssueCategories: []RedmineIssueCategory{},
+			TotalCount:      len(categories),
+		}
+		for _, category := range categories {
+			data.IssueCategories = append(data.IssueCategories, RedmineIssueCategory{
+				ID:         category.ID,
+				Project:    RedmineRef{ID: project.ID, Name: project.Name},
+				Name:       category.Name,
+				AssignedTo: refs.user(category.AssignedToID),
+			})
+		}
+
+		c.JSON(http.StatusOK, data)
+	}
+}
+
+// @Summary: RedmineGetUsersHandler
+// @Description: List users using the Redmine REST API format
+// @Tags: redmine
+// @Produce: json
+// @Param offset query int false "Offset"
+// @Param limit query int false "Limit (max 100)"
+// @Success 200 {object} RedmineUsersData
+// @Failure 500 {object} map[string][]string
+// @Router /users.json [get]
+// @Security BearerAuth
+func RedmineGetUsersHandler(cfg *config.Config) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		offset, limit := redmineOffsetLimit(c)
+
+		// Inicializar la base de datos
+		db, err := database.InitDBContext(c.Request.Context(), cfg)
+		if err != nil {
+			redmineError(c, http.StatusInternalServerError, err.Error())
+			return
+		}
+		defer db.Close()
+
+		users, err := models.GetAllUsers(db)
+		if err != nil {
+			redmineError(c, http.StatusInternalServerError, err.Error())
+			return
+		}
+
+		data := RedmineUsersData{
+			Users:      []RedmineUser{},
+			TotalCount: len(users),
+			Offset:     offset,
+			Limit:      limit,
+		}
+		start, end := redminePage(len(users), offset, limit)
+		for _, user := range users[start:end] {
+			data.Users = append(data.Users, redmineUser(user))
+		}
+
+		c.JSON(http.StatusOK, data)
+	}
+}
+
+// @Summary: RedmineGetUserHandler
+// @Description: Get a user by ID using the Redmine REST API format
+// @Tags: redmine
+// @Produce: json
+// @Param id path int true "User ID"
+// @Success 200 {object} RedmineUserData
+// @Failure 404 {object} map[string][]string
+// @Failure 500 {object} map[string][]string
+// @Router /users/{id}.json [get]
+// @Security BearerAuth
+func RedmineGetUserHandler(cfg *config.Config) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		pid, ok := redmineParam(c, "id")
+		id, err := strconv.Atoi(pid)
+		if !ok || err != nil {
+			redmineError(c, http.StatusNotFound, "Not found")
+			return
+		}
+
+		// Inicializar la base de datos
+		db, err := database.InitDBContext(c.Request.Context(), cfg)
+		if err != nil {
+			redmineError(c, http.StatusInternalServerError, err.Error())
+			return
+		}
+		defer db.Close()
+
+		user, err := models.GetUserByID(db, id)
+		if err == sql.ErrNoRows {
+			redmineError(c, http.StatusNotFound, "Not found")
+			return
+		}
+		if err != nil {
+			redmineError(c, http.StatusInternalServerError, err.Error())
+			return
+		}
+
+		c.JSON(http.StatusOK, RedmineUserData{User: redmineUser(*user)})
+	}
+}
+
+// @Summary: RedmineGetCurrentUserHandler
+// @Description: Get the authenticated user using the Redmine REST API format
+// @Tags: redmine
+// @Produce: json
+// @Success 200 {object} RedmineUserData
+// @Failure 404 {object} map[string][]string
+// @Failure 500 {object} map[string][]string
+// @Router /users/current.json [get]
+// @Security BearerAuth
+func RedmineGetCurrentUserHandler(cfg *config.Config) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		// El token de servicio no está asociado a ningún usuario
+		user_id := currentUserID(c)
+		if user_id == nil {
+			redmineError(c, http.StatusNotFound, "Not found")
+			return
+		}
+
+		// Inicializar la base de datos
+		db, err := database.InitDBContext(c.Request.Context(), cfg)
+		if err != nil {
+			redmineError(c, http.StatusInternalServerError, err.Error())
+			return
+		}
+		defer db.Close()
+
+		user, err := models.GetUserByID(db, *user_id)
+		if err == sql.ErrNoRows {
+			redmineError(c, http.StatusNotFound, "Not found")
+			return
+		}
+		if err != nil {
+			redmineError(c, http.StatusInternalServerError, err.Error())
+			return
+		}
+
+		c.JSON(http.StatusOK, RedmineUserData{User: redmineUser(*user)})
+	}
+}
+
+// @Summary: RedmineGetTrackersHandler
+// @Description: List trackers using the Redmine REST API format
+// @Tags: redmine
+// @Produce: json
+// @Success 200 {object} RedmineTrackersData
+// @Failure 500 {object} map[string][]string
+// @Router /trackers.json [get]
+// @Security BearerAuth
+func RedmineGetTrackersHandler(cfg *config.Config) gin.HandlerFunc {
+	return func(c *gin.Context) {
+
+		// Inicializar la base de datos
+		db, err := database.InitDBContext(c.Request.Context(), cfg)
+		if err != nil {
+			redmineError(c, http.StatusInternalServerError, err.Error())
+			return
+		}
+		defer db.Close()
+
+		trackers, err := models.GetAllTrackers(db)
+		if err != nil {
+			redmineError(c, http.StatusInternalServerError, err.Error())
+			return
+		}
+
+		statuses, err := models.GetAllIssueStatuses(db)
+		if err != nil {
+			redmineError(c, http.StatusInternalServerError, err.Error())
+			return
+		}
+		status_names := map[int]string{}
+		for _, status := range statuses {
+			status_names[status.ID] = status.Name
+		}
+
+		data := RedmineTrackersData{Trackers: []RedmineTracker{}}
+		for _, tracker := range trackers {
+			item := RedmineTracker{
+				ID:                    tracker.ID,
+				Name:                  tracker.Name,
+				Description:           tracker.Description,
+				EnabledStandardFields: tracker.EnabledFields,
+			}
+			if tracker.DefaultStatusID != nil {
+				item.DefaultStatus = &RedmineRef{ID: *tracker.DefaultStatusID, Name: status_names[*tracker.DefaultStatusID]}
+			}
+			data.Trackers = append(data.Trackers, item)
+		}
+
+		c.JSON(http.StatusOK, data)
+	}
+}
+
+// @Summary: RedmineGetIssueStatusesHandler
+// @Description: List issue statuses using the Redmine REST API format
+// @Tags: redmine
+// @Produce: json
+// @Success 200 {object} RedmineIssueStatusesData
+// @Failure 500 {object} map[string][]string
+// @Router /issue_statuses.json [get]
+// @Security BearerAuth
+func RedmineGetIssueStatusesHandler(cfg *config.Config) gin.HandlerFunc {
+	return func(c *gin.Context) {
+
+		// Inicializar la base de datos
+		db, err := database.InitDBContext(c.Request.Context(), cfg)
+		if err != nil {
+			redmineError(c, http.StatusInternalServerError, err.Error())
+			return
+		}
+		defer db.Close()
+
+		statuses, err := models.GetAllIssueStatuses(db)
+		if err != nil {
+			redmineError(c, http.StatusInternalServerError, err.Error())
+			return
+		}
+
+		data := RedmineIssueStatusesData{IssueStatuses: []RedmineStatusRef{}}
+		for _, status := range statuses {
+			data.IssueStatuses = append(data.IssueStatuses, RedmineStatusRef{ID: status.ID, Name: status.Name, IsClosed: status.IsClosed})
+		}
+
+		c.JSON(http.StatusOK, data)
+	}
+}
+
+// @Summary: RedmineGetIssuePrioritiesHandler
+// @Description: List issue priorities using the Redmine REST API format
+// @Tags: redmine
+// @Produce: json
+// @Success 200 {object} RedmineIssuePrioritiesData
+// @Failure 500 {object} map[string][]string
+// @Router /enumerations/issue_priorities.json [get]
+// @Security BearerAuth
+func RedmineGetIssuePrioritiesHandler(cfg *config.Config) gin.HandlerFunc {
+	return func(c *gin.Context) {
+
+		// Inicializar la base de datos
+		db, err := database.InitDBContext(c.Request.Context(), cfg)
+		if err != nil {
+			redmineError(c, http.StatusInternalServerError, err.Error())
+			return
+		}
+		defer db.Close()
+
+		priorities, err := models.GetAllIssuePriorities(db)
+		if err != nil {
+			redmineError(c, http.StatusInternalServerError, err.Error())
+			return
+		}
+
+		data := RedmineIssuePrioritiesData{IssuePriorities: []RedmineIssuePriority{}}
+		for _, priority := range priorities {
+			data.IssuePriorities = append(data.IssuePriorities, RedmineIssuePriority{ID: priority.ID, Name: priority.Name, IsDefault: priority.IsDefault})
+		}
+
+		c.JSON(http.StatusOK, data)
+	}
+}
+
+// @Summary: RedmineGetRolesHandler
+// @Description: List roles using the Redmine REST API format
+// @Tags: redmine
+// @Produce: json
+// @Success 200 {object} RedmineRolesData
+// @Failure 500 {object} map[string][]string
+// @Router /roles.json [get]
+// @Security BearerAuth
+func RedmineGetRolesHandler(cfg *config.Config) gin.HandlerFunc {
+	return func(c *gin.Context) {
+
+		// Inicializar la base de datos
+		db, err := database.InitDBContext(c.Request.Context(), cfg)
+		if err != nil {
+			redmineError(c, http.StatusInternalServerError, err.Error())
+			return
+		}
+		defer db.Close()
+
+		roles, err := models.GetAllRoles(db)
+		if err != nil {
+			redmineError(c, http.StatusInternalServerError, err.Error())
+			return
+		}
+
+		data := RedmineRolesData{Roles: []RedmineRef{}}
+		for _, role := range roles {
+			data.Roles = append(data.Roles, RedmineRef{ID: role.ID, Name: role.Name})
+		}
+
+		c.JSON(http.StatusOK, data)
+	}
+}
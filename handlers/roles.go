@@ -27,7 +27,7 @@ func GetRolesHandler(cfg *config.Config) gin.HandlerFunc {
 	return func(c *gin.Context) {
 
 		// Inicializar la base de datos
-		db, err := database.InitDB(cfg)
+		db, err := database.InitDBContext(c.Request.Context(), cfg)
 		if err != nil {
 			c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 			return
@@ -77,7 +77,7 @@ func GetRoleHandler(cfg *config.Config) gin.HandlerFunc {
 		}
 
 		// Inicializar la base de datos
-		db, err := database.InitDB(cfg)
+		db, err := database.InitDBContext(c.Request.Context(), cfg)
 		if err != nil {
 			c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 			return
@@ -114,7 +114,7 @@ func CreateRoleHandler(cfg *config.Config) gin.HandlerFunc {
 		}
 
 		// Inicializar la base de datos
-		db, err := database.InitDB(cfg)
+		db, err := database.InitDBContext(c.Request.Context(), cfg)
 		if err != nil {
 			c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 			return
@@ -156,7 +156,7 @@ func UpdateRoleHandler(cfg *config.Config) gin.HandlerFunc {
 		}
 
 		// Inicializar la base de datos
-		db, err := database.InitDB(cfg)
+		db, err := database.InitDBContext(c.Request.Context(), cfg)
 		if err != nil {
 			c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 			return
@@ -200,7 +200,7 @@ func DeleteRoleHandler(cfg *config.Config) gin.HandlerFunc {
 		}
 
 		// Inicializar la base de datos
-		db, err := database.InitDB(cfg)
+		db, err := database.InitDBContext(c.Request.Context(), cfg)
 		if err != nil {
 			c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 			return
@@ -0,0 +1,230 @@
+package handlers
+
+import (
+	"database/sql"
+	"go-redmine-ish/config"
+	"go-redmine-ish/database"
+	"go-redmine-ish/models"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+type GetTimeEntriesHandlerData struct {
+	TimeEntries []models.TimeEntry `json:"time_entries"`
+}
+
+// @Summary: GetProjectTimeEntriesHandler
+// @Description: Get the time entries of a project
+// @Tags: time_entries
+// @Produce: json
+// @Param id path int true "Project ID"
+// @Success 200 {object} GetTimeEntriesHandlerData
+// @Failure 400 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Router /project/{id}/time_entries [get]
+// @Security BearerAuth
+func GetProjectTimeEntriesHandler(cfg *config.Config) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		pid := c.Param("id")
+
+		// pasar string id a int id
+		id, err := strconv.Atoi(pid)
+		if err != nil {
+			c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		// Inicializar la base de datos
+		db, err := database.InitDBContext(c.Request.Context(), cfg)
+		if err != nil {
+			c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		defer db.Close()
+
+		allowed, err := canViewProject(c, db, id)
+		if err != nil {
+			c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		if !allowed {
+			c.AbortWithStatusJSON(http.StatusNotFound, gin.H{"error": "Project not found"})
+			return
+		}
+
+		entries, err := models.GetTimeEntriesByProjectID(db, id)
+		if err != nil {
+			c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+
+		c.JSON(http.StatusOK, GetTimeEntriesHandlerData{TimeEntries: entries})
+	}
+}
+
+// @Summary: CreateTimeEntryHandler
+// @Description: Log time on a project or issue. The user defaults to the authenticated one and spent_on to today
+// @Tags: time_entries
+// @Accept: json
+// @Produce: json
+// @Param time_entry body models.TimeEntry true "Time entry"
+// @Success 201 {object} models.TimeEntry
+// @Failure 400 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Router /time_entry [post]
+// @Security BearerAuth
+func CreateTimeEntryHandler(cfg *config.Config) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var entry models.TimeEntry
+		if err := c.ShouldBindJSON(&entry); err != nil {
+			c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		if entry.Hours <= 0 {
+			c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": "hours must be greater than 0"})
+			return
+		}
+		if entry.SpentOn == "" {
+			entry.SpentOn = time.Now().Format("2006-01-02")
+		} else if _, err := time.Parse("2006-01-02", entry.SpentOn); err != nil {
+			c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": "spent_on must be YYYY-MM-DD"})
+			return
+		}
+		if entry.UserID == 0 {
+			user_id := currentUserID(c)
+			if user_id == nil {
+				c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": "user_id is required"})
+				return
+			}
+			entry.UserID = *user_id
+		}
+
+		// Inicializar la base de datos
+		db, err := database.InitDBContext(c.Request.Context(), cfg)
+		if err != nil {
+			c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		defer db.Close()
+
+		// La imputación a un ticket hereda su proyecto
+		if entry.IssueID != nil {
+			issue, err := models.GetIssueByID(db, *entry.IssueID)
+			if err == sql.ErrNoRows {
+				c.AbortWithStatusJSON(http.StatusNotFound, gin.H{"error": "Issue not found"})
+				return
+			}
+			if err != nil {
+				c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+				return
+			}
+			entry.ProjectID = issue.ProjectID
+		}
+
+		allowed, err := canViewProject(c, db, entry.ProjectID)
+		if err != nil {
+			c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		if !allowed {
+			c.AbortWithStatusJSON(http.StatusNotFound, gin.H{"error": "Project not found"})
+			return
+		}
+
+		if status, msg := checkProjectWritable(db, entry.ProjectID); status != 0 {
+			c.AbortWithStatusJSON(status, gin.H{"error": msg})
+			return
+		}
+		if status, msg := checkProjectModule(db, entry.ProjectID, models.ModuleTimeTracking); status != 0 {
+			c.AbortWithStatusJSON(status, gin.H{"error": msg})
+			return
+		}
+
+		id, err := models.CreateTimeEntry(db, &entry)
+		if err != nil {
+			c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+
+		created, err := models.GetTimeEntryByID(db, id)
+		if err != nil {
+			c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+
+		c.JSON(http.StatusCreated, created)
+	}
+}
+
+// @Summary: DeleteTimeEntryHandler
+// @Description: Delete a time entry by ID
+// @Tags: time_entries
+// @Param id path int true "Time entry ID"
+// @Success 204 {object} nil
+// @Failure 400 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Router /time_entry/{id} [delete]
+// @Security BearerAuth
+func DeleteTimeEntryHandler(cfg *config.Config) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		pid := c.Param("id")
+
+		// pasar string id a int id
+		id, err := strconv.Atoi(pid)
+		if err != nil {
+			c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		// Inicializar la base de datos
+		db, err := database.InitDBContext(c.Request.Context(), cfg)
+		if err != nil {
+			c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		defer db.Close()
+
+		entry, err := models.GetTimeEntryByID(db, id)
+		if err != nil {
+			c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		if entry == nil {
+			c.AbortWithStatusJSON(http.StatusNotFound, gin.H{"error": "Time entry not found"})
+			return
+		}
+
+		allowed, err := canViewProject(c, db, entry.ProjectID)
+		if err != nil {
+			c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		if !allowed {
+			c.AbortWithStatusJSON(http.StatusNotFound, gin.H{"error": "Time entry not found"})
+			return
+		}
+
+		if status, msg := checkProjectWritable(db, entry.ProjectID); status != 0 {
+			c.AbortWithStatusJSON(status, gin.H{"error": msg})
+			return
+		}
+		if status, msg := checkProjectModule(db, entry.ProjectID, models.ModuleTimeTracking); status != 0 {
+			c.AbortWithStatusJSON(status, gin.H{"error": msg})
+			return
+		}
+
+		if err := models.DeleteTimeEntry(db, id); err != nil {
+			c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+
+		c.JSON(http.StatusNoContent, nil)
+	}
+}
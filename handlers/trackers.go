@@ -1,10 +1,14 @@
 package handlers
 
 import (
+	"database/sql"
+	"fmt"
 	"go-redmine-ish/config"
 	"go-redmine-ish/database"
 	"go-redmine-ish/models"
 	"net/http"
+	"strconv"
+	"strings"
 
 	"github.com/gin-gonic/gin"
 )
@@ -26,7 +30,7 @@ func GetTrackersHandler(cfg *config.Config) gin.HandlerFunc {
 	return func(c *gin.Context) {
 
 		// Inicializar la base de datos
-		db, err := database.InitDB(cfg)
+		db, err := database.InitDBContext(c.Request.Context(), cfg)
 		if err != nil {
 			c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 			return
@@ -53,3 +57,323 @@ func GetTrackersHandler(cfg *config.Config) gin.HandlerFunc {
 		c.JSON(http.StatusOK, data)
 	}
 }
+
+// validateTracker comprueba el nombre, el estado por defecto y los campos del tracker.
+// Devuelve 0 si es válido, o el código HTTP y el mensaje de error a responder
+func validateTracker(db *sql.DB, tracker *models.Tracker) (int, string) {
+	tracker.Name = strings.TrimSpace(tracker.Name)
+	if tracker.Name == "" {
+		return http.StatusBadRequest, "name is required"
+	}
+	if tracker.Position < 0 {
+		return http.StatusBadRequest, "position must not be negative"
+	}
+
+	existing, err := models.GetTrackerByName(db, tracker.Name)
+	if err != nil && err != sql.ErrNoRows {
+		return http.StatusInternalServerError, err.Error()
+	}
+	if existing != nil && existing.ID != tracker.ID {
+		return http.StatusConflict, "A tracker with this name already exists"
+	}
+
+	if tracker.DefaultStatusID != nil {
+		status, err := models.GetIssueStatusByID(db, *tracker.DefaultStatusID)
+		if err != nil {
+			return http.StatusInternalServerError, err.Error()
+		}
+		if status == nil {
+			return http.StatusBadRequest, "default_status_id does not exist"
+		}
+	}
+
+	for _, field := range tracker.EnabledFields {
+		if !models.IsTrackerCoreField(field) {
+			return http.StatusBadRequest, fmt.Sprintf("unknown field %q, expected one of %s", field, strings.Join(models.TrackerCoreFields, ", "))
+		}
+	}
+
+	return 0, ""
+}
+
+// issueDefaultStatus devuelve el estado inicial de un ticket del tracker: su estado
+// por defecto o, si no tiene, el primero de issue_statuses
+func issueDefaultStatus(db *sql.DB, tracker *models.Tracker) (string, error) {
+	if tracker.DefaultStatusID != nil {
+		status, err := models.GetIssueStatusByID(db, *tracker.DefaultStatusID)
+		if err != nil {
+			return "", err
+		}
+		if status != nil {
+			return status.Name, nil
+		}
+	}
+
+	statuses, err := models.GetAllIssueStatuses(db)
+	if err != nil {
+		return "", err
+	}
+	if len(statuses) == 0 {
+		return "Open", nil
+	}
+	return statuses[0].Name, nil
+}
+
+// checkTrackerFields aplica al ticket los campos que usa su tracker y, al crear
+// (before nil), le asigna el estado por defecto del tracker si no trae ninguno.
+// Devuelve 0 si es válido, o el código HTTP y el mensaje de error a responder
+func checkTrackerFields(db *sql.DB, issue, before *models.Issue) (int, string) {
+	tracker, err := models.GetTrackerByID(db, issue.TrackerID)
+	if err == sql.ErrNoRows {
+		return http.StatusUnprocessableEntity, "Tracker not found"
+	}
+	if err != nil {
+		return http.StatusInternalServerError, err.Error()
+	}
+
+	if rejected := models.ApplyTrackerFields(tracker, issue, before); len(rejected) > 0 {
+		return http.StatusUnprocessableEntity, fmt.Sprintf("Tracker %s does not use the fields: %s", tracker.Name, strings.Join(rejected, ", "))
+	}
+
+	if before == nil && issue.Status == "" {
+		issue.Status, err = issueDefaultStatus(db, tracker)
+		if err != nil {
+			return http.StatusInternalServerError, err.Error()
+		}
+	}
+
+	return 0, ""
+}
+
+// @Summary: GetTrackerHandler
+// @Description: Get a tracker by ID
+// @Tags: trackers
+// @Produce: json
+// @Param id path int true "Tracker ID"
+// @Success 200 {object} models.Tracker
+// @Failure 400 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Router /tracker/{id} [get]
+// @Security BearerAuth
+func GetTrackerHandler(cfg *config.Config) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		pid := c.Param("id")
+
+		// pasar string id a int id
+		id, err := strconv.Atoi(pid)
+		if err != nil {
+			c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		// Inicializar la base de datos
+		db, err := database.InitDBContext(c.Request.Context(), cfg)
+		if err != nil {
+			c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		defer db.Close()
+
+		tracker, err := models.GetTrackerByID(db, id)
+		if err == sql.ErrNoRows {
+			c.AbortWithStatusJSON(http.StatusNotFound, gin.H{"error": "Tracker not found"})
+			return
+		}
+		if err != nil {
+			c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+
+		c.JSON(http.StatusOK, tracker)
+	}
+}
+
+// @Summary: CreateTrackerHandler
+// @Description: Create a new tracker. Without enabled_fields it uses every core field, without position it goes last
+// @Tags: trackers
+// @Accept: json
+// @Produce: json
+// @Param tracker body models.Tracker true "Tracker"
+// @Success 201 {object} models.Tracker
+// @Failure 400 {object} map[string]string
+// @Failure 409 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Router /tracker [post]
+// @Security BearerAuth
+func CreateTrackerHandler(cfg *config.Config) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var tracker models.Tracker
+		if err := c.ShouldBindJSON(&tracker); err != nil {
+			c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		tracker.ID = 0
+
+		// Inicializar la base de datos
+		db, err := database.InitDBContext(c.Request.Context(), cfg)
+		if err != nil {
+			c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		defer db.Close()
+
+		if status, msg := validateTracker(db, &tracker); status != 0 {
+			c.AbortWithStatusJSON(status, gin.H{"error": msg})
+			return
+		}
+
+		id, err := models.CreateTracker(db, &tracker)
+		if err != nil {
+			c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+
+		created, err := models.GetTrackerByID(db, id)
+		if err != nil {
+			c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+
+		c.JSON(http.StatusCreated, created)
+	}
+}
+
+// @Summary: UpdateTrackerHandler
+// @Description: Update a tracker by ID. Omitted position and enabled_fields keep their current values
+// @Tags: trackers
+// @Accept: json
+// @Produce: json
+// @Param id path int true "Tracker ID"
+// @Param tracker body models.Tracker true "Tracker"
+// @Success 200 {object} models.Tracker
+// @Failure 400 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Failure 409 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Router /tracker/{id} [put]
+// @Security BearerAuth
+func UpdateTrackerHandler(cfg *config.Config) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		pid := c.Param("id")
+
+		// pasar string id a int id
+		id, err := strconv.Atoi(pid)
+		if err != nil {
+			c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		var tracker models.Tracker
+		if err := c.ShouldBindJSON(&tracker); err != nil {
+			c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		if id != tracker.ID {
+			c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": "ID in body and URL do not match"})
+			return
+		}
+
+		// Inicializar la base de datos
+		db, err := database.InitDBContext(c.Request.Context(), cfg)
+		if err != nil {
+			c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		defer db.Close()
+
+		current, err := models.GetTrackerByID(db, id)
+		if err == sql.ErrNoRows {
+			c.AbortWithStatusJSON(http.StatusNotFound, gin.H{"error": "Tracker not found"})
+			return
+		}
+		if err != nil {
+			c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		if tracker.Position == 0 {
+			tracker.Position = current.Position
+		}
+		if tracker.EnabledFields == nil {
+			tracker.EnabledFields = current.EnabledFields
+		}
+
+		if status, msg := validateTracker(db, &tracker); status != 0 {
+			c.AbortWithStatusJSON(status, gin.H{"error": msg})
+			return
+		}
+
+		if err := models.UpdateTracker(db, &tracker); err != nil {
+			c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+
+		updated, err := models.GetTrackerByID(db, id)
+		if err != nil {
+			c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+
+		c.JSON(http.StatusOK, updated)
+	}
+}
+
+// @Summary: DeleteTrackerHandler
+// @Description: Delete a tracker by ID. Trackers still used by issues cannot be deleted
+// @Tags: trackers
+// @Produce: json
+// @Param id path int true "Tracker ID"
+// @Success 204
+// @Failure 400 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Failure 409 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Router /tracker/{id} [delete]
+// @Security BearerAuth
+func DeleteTrackerHandler(cfg *config.Config) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		pid := c.Param("id")
+
+		// pasar string id a int id
+		id, err := strconv.Atoi(pid)
+		if err != nil {
+			c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		// Inicializar la base de datos
+		db, err := database.InitDBContext(c.Request.Context(), cfg)
+		if err != nil {
+			c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		defer db.Close()
+
+		if _, err := models.GetTrackerByID(db, id); err == sql.ErrNoRows {
+			c.AbortWithStatusJSON(http.StatusNotFound, gin.H{"error": "Tracker not found"})
+			return
+		} else if err != nil {
+			c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+
+		// issues.tracker_id es ON DELETE RESTRICT
+		count, err := models.CountIssuesByTrackerID(db, id)
+		if err != nil {
+			c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		if count > 0 {
+			c.AbortWithStatusJSON(http.StatusConflict, gin.H{"error": fmt.Sprintf("Tracker is used by %d issues", count)})
+			return
+		}
+
+		if err := models.DeleteTracker(db, id); err != nil {
+			c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+
+		c.JSON(http.StatusNoContent, nil)
+	}
+}
@@ -1,11 +1,13 @@
 package handlers
 
 import (
+	"database/sql"
 	"go-redmine-ish/config"
 	"go-redmine-ish/database"
 	"go-redmine-ish/models"
 	"net/http"
 	"strconv"
+	"strings"
 
 	"github.com/gin-gonic/gin"
 )
@@ -31,7 +33,7 @@ func GetUsersHandler(cfg *config.Config) gin.HandlerFunc {
 	return func(c *gin.Context) {
 
 		// Inicializar la base de datos
-		db, err := database.InitDB(cfg)
+		db, err := database.InitDBContext(c.Request.Context(), cfg)
 		if err != nil {
 			c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 			return
@@ -67,12 +69,13 @@ func GetUsersHandler(cfg *config.Config) gin.HandlerFunc {
 }
 
 type GetUserHandlerData struct {
-	User       models.User       `json:"user"`
-	Trackers   []models.Tracker  `json:"trackers"`
-	Projects   []models.Project  `json:"projects,omitempty"`
-	Roles      []models.Role     `json:"roles,omitempty"`
-	Issues     []models.Issue    `json:"issues,omitempty"`
-	Categories []models.Category `json:"categories,omitempty"`
+	User          models.User       `json:"user"`
+	Trackers      []models.Tracker  `json:"trackers"`
+	Projects      []models.Project  `json:"projects,omitempty"`
+	Roles         []models.Role     `json:"roles,omitempty"`
+	Issues        []models.Issue    `json:"issues,omitempty"`
+	Categories    []models.Category `json:"categories,omitempty"`
+	OverdueIssues []models.Issue    `json:"overdue_issues,omitempty"`
 }
 
 // @Summary: GetUserHandler
@@ -80,8 +83,10 @@ type GetUserHandlerData struct {
 // @Tags: users
 // @Produce: json
 // @Param id path int true "User ID"
+// @Param If-Match header string false "ETag returned by a previous GET"
 // @Success 200 {object} GetUserHandlerData
 // @Failure 400 {object} map[string]string
+// @Failure 412 {object} map[string]string
 // @Failure 500 {object} map[string]string
 // @Router /user/{id} [get]
 // @Security BearerAuth
@@ -97,7 +102,7 @@ func GetUserHandler(cfg *config.Config) gin.HandlerFunc {
 		}
 
 		// Inicializar la base de datos
-		db, err := database.InitDB(cfg)
+		db, err := database.InitDBContext(c.Request.Context(), cfg)
 		if err != nil {
 			c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 			return
@@ -109,6 +114,10 @@ func GetUserHandler(cfg *config.Config) gin.HandlerFunc {
 			return
 		}
 
+		if !checkETag(c, user.LockVersion) {
+			return
+		}
+
 		trackers, err := models.GetAllTrackers(db)
 		if err != nil {
 			c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
@@ -140,6 +149,17 @@ func GetUserHandler(cfg *config.Config) gin.HandlerFunc {
 			data.Issues = issues
 		}
 
+		// Tickets abiertos asignados al usuario con la fecha de fin pasada
+		overdue, err := models.GetIssuesFiltered(db, models.IssueFilter{AssignedToID: id, Overdue: true, Sort: "due_date"})
+		if err != nil {
+			c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+
+		if len(overdue) > 0 {
+			data.OverdueIssues = overdue
+		}
+
 		projects, err := models.GetProjectsByUserID(db, id)
 		if err != nil {
 			c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
@@ -185,13 +205,18 @@ func CreateUserHandler(cfg *config.Config) gin.HandlerFunc {
 		}
 
 		// Inicializar la base de datos
-		db, err := database.InitDB(cfg)
+		db, err := database.InitDBContext(c.Request.Context(), cfg)
 		if err != nil {
 			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 			return
 		}
 		defer db.Close()
 
+		if status, msg := validateUser(&user); status != 0 {
+			c.JSON(status, gin.H{"error": msg})
+			return
+		}
+
 		id, err := models.CreateUser(db, &user)
 		if err != nil {
 			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
@@ -210,9 +235,13 @@ func CreateUserHandler(cfg *config.Config) gin.HandlerFunc {
 // @Accept: json
 // @Produce: json
 // @Param id path int true "User ID"
-// @Param user body models.User true "User"
+// @Param user body models.User true "User, with the lock_version that was read"
+// @Param If-Match header string false "ETag returned by GET, instead of lock_version"
 // @Success 200 {object} models.User
 // @Failure 400 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Failure 409 {object} map[string]interface{}
+// @Failure 428 {object} map[string]string
 // @Failure 500 {object} map[string]string
 // @Router /user/{id} [put]
 // @Security BearerAuth
@@ -238,21 +267,137 @@ func UpdateUserHandler(cfg *config.Config) gin.HandlerFunc {
 		}
 
 		// Inicializar la base de datos
-		db, err := database.InitDB(cfg)
+		db, err := database.InitDBContext(c.Request.Context(), cfg)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		defer db.Close()
+
+		current, err := models.GetUserByID(db, id)
+		if err == sql.ErrNoRows {
+			c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+			return
+		}
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+
+		lock_version, ok := lockVersionFromRequest(c, user.LockVersion)
+		if !ok {
+			return
+		}
+		user.LockVersion = lock_version
+
+		updateUser(c, db, current, &user)
+	}
+}
+
+// @Summary: PatchUserHandler
+// @Description: Partially update a user with a JSON Merge Patch. Only the fields present change
+// @Tags: users
+// @Accept: json
+// @Produce: json
+// @Param id path int true "User ID"
+// @Param user body models.User true "Only the fields to change"
+// @Param If-Match header string false "ETag returned by GET"
+// @Success 200 {object} models.User
+// @Failure 400 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Failure 409 {object} map[string]interface{}
+// @Failure 500 {object} map[string]string
+// @Router /user/{id} [patch]
+// @Security BearerAuth
+func PatchUserHandler(cfg *config.Config) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		pid := c.Param("id")
+		id, err := strconv.Atoi(pid)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		// Inicializar la base de datos
+		db, err := database.InitDBContext(c.Request.Context(), cfg)
 		if err != nil {
 			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 			return
 		}
 		defer db.Close()
 
-		err = models.UpdateUser(db, &user)
+		current, err := models.GetUserByID(db, id)
+		if err == sql.ErrNoRows {
+			c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+			return
+		}
 		if err != nil {
 			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 			return
 		}
 
-		c.JSON(http.StatusOK, user)
+		var user models.User
+		if !bindMergePatch(c, current, &user) {
+			return
+		}
+		if id != user.ID {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "ID in body and URL do not match"})
+			return
+		}
+
+		// Sin lock_version en el parche ni If-Match se aplica sobre la versión que se acaba de leer
+		lock_version, ok := lockVersionFromRequest(c, user.LockVersion)
+		if !ok {
+			return
+		}
+		user.LockVersion = lock_version
+
+		updateUser(c, db, current, &user)
+	}
+}
+
+// validateUser comprueba los campos de un usuario antes de crearlo o modificarlo. Devuelve 0
+// si es válido, o el código HTTP y el mensaje de error a responder
+func validateUser(user *models.User) (int, string) {
+	if strings.TrimSpace(user.Username) == "" {
+		return http.StatusBadRequest, "username is required"
+	}
+	if !strings.Contains(user.Email, "@") {
+		return http.StatusBadRequest, "email is not valid"
+	}
+	return 0, ""
+}
+
+// updateUser guarda los cambios de user sobre current, comprobando su lock_version, y responde
+// con el usuario actualizado. Lo comparten PUT y PATCH
+func updateUser(c *gin.Context, db *sql.DB, current *models.User, user *models.User) {
+	if user.LockVersion != current.LockVersion {
+		respondStale(c, current.LockVersion, current)
+		return
+	}
+
+	if status, msg := validateUser(user); status != 0 {
+		c.JSON(status, gin.H{"error": msg})
+		return
+	}
+
+	err := models.UpdateUser(db, user)
+	if err == models.ErrStaleObject {
+		// Otra petición lo modificó entre la lectura y la escritura
+		if current, err = models.GetUserByID(db, user.ID); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		respondStale(c, current.LockVersion, current)
+		return
 	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.Header("ETag", etag(user.LockVersion))
+	c.JSON(http.StatusOK, user)
 }
 
 // @Summary: UpdateUserHandler
@@ -277,7 +422,7 @@ func DeleteUserHandler(cfg *config.Config) gin.HandlerFunc {
 		}
 
 		// Inicializar la base de datos
-		db, err := database.InitDB(cfg)
+		db, err := database.InitDBContext(c.Request.Context(), cfg)
 		if err != nil {
 			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 			return
@@ -0,0 +1,311 @@
+package handlers
+
+import (
+	"database/sql"
+	"go-redmine-ish/config"
+	"go-redmine-ish/database"
+	"go-redmine-ish/models"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// versionChartMaxDays es el máximo de días de una serie
+const versionChartMaxDays = 366
+
+type GetVersionBurndownHandlerData struct {
+	VersionID int       `json:"version_id"`
+	Metric    string    `json:"metric"` // hours o issues
+	From      string    `json:"from"`
+	To        string    `json:"to"`
+	DueDate   *string   `json:"due_date"`
+	Dates     []string  `json:"dates"`
+	Total     []float64 `json:"total"`
+	Remaining []float64 `json:"remaining"`
+	Ideal     []float64 `json:"ideal"` // de total el primer día a 0 en due_date; null sin due_date
+}
+
+type VersionFlowSeries struct {
+	Status   string `json:"status"`
+	IsClosed bool   `json:"is_closed"`
+	Values   []int  `json:"values"`
+}
+
+type GetVersionCumulativeFlowHandlerData struct {
+	VersionID int                 `json:"version_id"`
+	From      string              `json:"from"`
+	To        string              `json:"to"`
+	Dates     []string            `json:"dates"`
+	Series    []VersionFlowSeries `json:"series"`
+}
+
+// versionChartDays obtiene una versión visible con el roadmap habilitado y su estado al final de
+// cada día del intervalo from/to de la petición. Los días ya terminados salen de la caché y los que
+// faltan se reconstruyen del historial y se guardan; hoy se calcula siempre. Devuelve 0 si todo fue
+// bien, o el código HTTP y el mensaje de error a responder
+func versionChartDays(c *gin.Context, db *sql.DB, id int) (*models.Version, []models.VersionChartDay, int, string) {
+	version, err := models.GetVersionByID(db, id)
+	if err != nil {
+		return nil, nil, http.StatusInternalServerError, err.Error()
+	}
+	if version == nil {
+		return nil, nil, http.StatusNotFound, "Version not found"
+	}
+	visible, err := canViewProject(c, db, version.ProjectID)
+	if err != nil {
+		return nil, nil, http.StatusInternalServerError, err.Error()
+	}
+	if !visible {
+		return nil, nil, http.StatusNotFound, "Version not found"
+	}
+	if status, msg := checkProjectModule(db, version.ProjectID, models.ModuleRoadmap); status != 0 {
+		return nil, nil, status, msg
+	}
+
+	today, err := models.GetCurrentDate(db)
+	if err != nil {
+		return nil, nil, http.StatusInternalServerError, err.Error()
+	}
+	to, _ := time.Parse("2006-01-02", today)
+	if value := c.Query("to"); value != "" {
+		if to, err = time.Parse("2006-01-02", value); err != nil {
+			return nil, nil, http.StatusBadRequest, "invalid to: " + err.Error()
+		}
+	}
+	from := to
+	if len(version.CreatedAt) >= 10 {
+		if created, err := time.Parse("2006-01-02", version.CreatedAt[:10]); err == nil {
+			from = created
+		}
+	}
+	if value := c.Query("from"); value != "" {
+		if from, err = time.Parse("2006-01-02", value); err != nil {
+			return nil, nil, http.StatusBadRequest, "invalid from: " + err.Error()
+		}
+	}
+	if to.Format("2006-01-02") > today {
+		to, _ = time.Parse("2006-01-02", today)
+	}
+	if to.Before(from) {
+		return nil, nil, http.StatusBadRequest, "to must be on or after from"
+	}
+	if to.Sub(from).Hours()/24 >= versionChartMaxDays {
+		return nil, nil, http.StatusBadRequest, "The range cannot be longer than " + strconv.Itoa(versionChartMaxDays) + " days"
+	}
+
+	refresh := false
+	if value := c.Query("refresh"); value != "" {
+		if refresh, err = strconv.ParseBool(value); err != nil {
+			return nil, nil, http.StatusBadRequest, "invalid refresh: " + err.Error()
+		}
+	}
+	if refresh {
+		if err := models.DeleteVersionChartCache(db, id); err != nil {
+			return nil, nil, http.StatusInternalServerError, err.Error()
+		}
+	}
+
+	cached, err := models.GetVersionChartCache(db, id, from.Format("2006-01-02"), to.Format("2006-01-02"))
+	if err != nil {
+		return nil, nil, http.StatusInternalServerError, err.Error()
+	}
+
+	// Se reconstruye desde el primer día que falta: los anteriores están en la caché
+	dates := []string{}
+	missing := -1
+	for day := from; !day.After(to); day = day.AddDate(0, 0, 1) {
+		date := day.Format("2006-01-02")
+		if _, ok := cached[date]; (!ok || date == today) && missing < 0 {
+			missing = len(dates)
+		}
+		dates = append(dates, date)
+	}
+
+	days := make([]models.VersionChartDay, len(dates))
+	if missing < 0 {
+		missing = len(dates)
+	}
+	for i := range dates[:missing] {
+		days[i] = cached[dates[i]]
+	}
+	if missing == len(dates) {
+		return version, days, 0, ""
+	}
+
+	built, err := models.BuildVersionChartDays(db, id, dates[missing:])
+	if err != nil {
+		return nil, nil, http.StatusInternalServerError, err.Error()
+	}
+	copy(days[missing:], built)
+
+	completed := []models.VersionChartDay{}
+	for _, day := range built {
+		if _, ok := cached[day.Date]; !ok && day.Date < today {
+			completed = append(completed, day)
+		}
+	}
+	if err := models.SaveVersionChartDays(db, id, completed); err != nil {
+		return nil, nil, http.StatusInternalServerError, err.Error()
+	}
+
+	return version, days, 0, ""
+}
+
+// @Summary: GetVersionBurndownHandler
+// @Description: Burndown of a version: for each day of the range, the estimated hours (metric=hours) or issues (metric=issues) of the version in total and still in open statuses, and the ideal line from the total of the first day to 0 on the due date. Reconstructed from the issue history; finished days are cached, refresh=true rebuilds them (e.g. after changing which statuses are closed).
+// @Tags: versions
+// @Produce: json
+// @Param id path int true "Version ID"
+// @Param metric query string false "hours (default) or issues"
+// @Param from query string false "First date (YYYY-MM-DD), the version creation by default"
+// @Param to query string false "Last date (YYYY-MM-DD), today by default"
+// @Param refresh query bool false "Rebuild the cached days"
+// @Success 200 {object} GetVersionBurndownHandlerData
+// @Failure 400 {object} map[string]string
+// @Failure 403 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Router /version/{id}/burndown [get]
+// @Security BearerAuth
+func GetVersionBurndownHandler(cfg *config.Config) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		pid := c.Param("id")
+
+		// pasar string id a int id
+		id, err := strconv.Atoi(pid)
+		if err != nil {
+			c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		metric := c.DefaultQuery("metric", "hours")
+		if metric != "hours" && metric != "issues" {
+			c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": "metric must be hours or issues"})
+			return
+		}
+
+		// Inicializar la base de datos
+		db, err := database.InitDBContext(c.Request.Context(), cfg)
+		if err != nil {
+			c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		defer db.Close()
+
+		version, days, status, msg := versionChartDays(c, db, id)
+		if status != 0 {
+			c.AbortWithStatusJSON(status, gin.H{"error": msg})
+			return
+		}
+
+		data := GetVersionBurndownHandlerData{
+			VersionID: id,
+			Metric:    metric,
+			From:      days[0].Date,
+			To:        days[len(days)-1].Date,
+			DueDate:   version.DueDate,
+			Dates:     []string{},
+			Total:     []float64{},
+			Remaining: []float64{},
+		}
+		for _, day := range days {
+			data.Dates = append(data.Dates, day.Date)
+			if metric == "hours" {
+				data.Total = append(data.Total, day.Hours)
+				data.Remaining = append(data.Remaining, day.RemainingHours)
+			} else {
+				data.Total = append(data.Total, float64(day.Issues))
+				data.Remaining = append(data.Remaining, float64(day.OpenIssues))
+			}
+		}
+
+		// La línea ideal baja de forma lineal hasta la fecha prevista y después se queda en 0
+		if version.DueDate != nil {
+			start, _ := time.Parse("2006-01-02", data.From)
+			due, err := time.Parse("2006-01-02", *version.DueDate)
+			if err == nil {
+				span := due.Sub(start).Hours() / 24
+				data.Ideal = []float64{}
+				for i := range days {
+					value := 0.0
+					if span > 0 && float64(i) < span {
+						value = data.Total[0] * (1 - float64(i)/span)
+					}
+					data.Ideal = append(data.Ideal, value)
+				}
+			}
+		}
+
+		c.JSON(http.StatusOK, data)
+	}
+}
+
+// @Summary: GetVersionCumulativeFlowHandler
+// @Description: Cumulative flow of a version: for each status, in workflow order, the number of issues of the version in it at the end of each day of the range. Reconstructed from the issue history; finished days are cached, refresh=true rebuilds them.
+// @Tags: versions
+// @Produce: json
+// @Param id path int true "Version ID"
+// @Param from query string false "First date (YYYY-MM-DD), the version creation by default"
+// @Param to query string false "Last date (YYYY-MM-DD), today by default"
+// @Param refresh query bool false "Rebuild the cached days"
+// @Success 200 {object} GetVersionCumulativeFlowHandlerData
+// @Failure 400 {object} map[string]string
+// @Failure 403 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Router /version/{id}/cumulative_flow [get]
+// @Security BearerAuth
+func GetVersionCumulativeFlowHandler(cfg *config.Config) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		pid := c.Param("id")
+
+		// pasar string id a int id
+		id, err := strconv.Atoi(pid)
+		if err != nil {
+			c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		// Inicializar la base de datos
+		db, err := database.InitDBContext(c.Request.Context(), cfg)
+		if err != nil {
+			c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		defer db.Close()
+
+		_, days, status, msg := versionChartDays(c, db, id)
+		if status != 0 {
+			c.AbortWithStatusJSON(status, gin.H{"error": msg})
+			return
+		}
+
+		statuses, err := models.GetAllIssueStatuses(db)
+		if err != nil {
+			c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+
+		data := GetVersionCumulativeFlowHandlerData{
+			VersionID: id,
+			From:      days[0].Date,
+			To:        days[len(days)-1].Date,
+			Dates:     []string{},
+			Series:    []VersionFlowSeries{},
+		}
+		for _, day := range days {
+			data.Dates = append(data.Dates, day.Date)
+		}
+		for _, status := range models.VersionChartStatuses(statuses, days) {
+			series := VersionFlowSeries{Status: status.Name, IsClosed: status.IsClosed, Values: []int{}}
+			for _, day := range days {
+				series.Values = append(series.Values, day.Statuses[status.Name])
+			}
+			data.Series = append(data.Series, series)
+		}
+
+		c.JSON(http.StatusOK, data)
+	}
+}
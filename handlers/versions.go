@@ -0,0 +1,283 @@
+package handlers
+
+import (
+	"go-redmine-ish/config"
+	"go-redmine-ish/database"
+	"go-redmine-ish/models"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+type GetVersionsHandlerData struct {
+	Versions []models.Version `json:"versions"`
+}
+
+// validateVersion comprueba el estado y la fecha de entrega de una versión
+func validateVersion(version *models.Version) string {
+	if version.Name == "" {
+		return "name is required"
+	}
+	switch version.Status {
+	case "", models.VersionStatusOpen, models.VersionStatusLocked, models.VersionStatusClosed:
+	default:
+		return "status must be open, locked or closed"
+	}
+	if version.DueDate != nil {
+		if _, err := time.Parse("2006-01-02", *version.DueDate); err != nil {
+			return "due_date must be YYYY-MM-DD"
+		}
+	}
+	return ""
+}
+
+// @Summary: GetProjectVersionsHandler
+// @Description: Get the versions of a project
+// @Tags: versions
+// @Produce: json
+// @Param id path int true "Project ID"
+// @Success 200 {object} GetVersionsHandlerData
+// @Failure 400 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Router /project/{id}/versions [get]
+// @Security BearerAuth
+func GetProjectVersionsHandler(cfg *config.Config) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		pid := c.Param("id")
+
+		// pasar string id a int id
+		id, err := strconv.Atoi(pid)
+		if err != nil {
+			c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		// Inicializar la base de datos
+		db, err := database.InitDBContext(c.Request.Context(), cfg)
+		if err != nil {
+			c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		defer db.Close()
+
+		allowed, err := canViewProject(c, db, id)
+		if err != nil {
+			c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		if !allowed {
+			c.AbortWithStatusJSON(http.StatusNotFound, gin.H{"error": "Project not found"})
+			return
+		}
+
+		versions, err := models.GetVersionsByProjectID(db, id)
+		if err != nil {
+			c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+
+		c.JSON(http.StatusOK, GetVersionsHandlerData{Versions: versions})
+	}
+}
+
+// @Summary: CreateVersionHandler
+// @Description: Create a new version in a project
+// @Tags: versions
+// @Accept: json
+// @Produce: json
+// @Param version body models.Version true "Version"
+// @Success 201 {object} models.Version
+// @Failure 400 {object} map[string]string
+// @Failure 403 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Router /version [post]
+// @Security BearerAuth
+func CreateVersionHandler(cfg *config.Config) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var version models.Version
+		if err := c.ShouldBindJSON(&version); err != nil {
+			c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		if msg := validateVersion(&version); msg != "" {
+			c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": msg})
+			return
+		}
+
+		// Inicializar la base de datos
+		db, err := database.InitDBContext(c.Request.Context(), cfg)
+		if err != nil {
+			c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		defer db.Close()
+
+		if status, msg := checkProjectWritable(db, version.ProjectID); status != 0 {
+			c.AbortWithStatusJSON(status, gin.H{"error": msg})
+			return
+		}
+		if status, msg := checkProjectModule(db, version.ProjectID, models.ModuleRoadmap); status != 0 {
+			c.AbortWithStatusJSON(status, gin.H{"error": msg})
+			return
+		}
+
+		id, err := models.CreateVersion(db, &version)
+		if err != nil {
+			c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+
+		created, err := models.GetVersionByID(db, id)
+		if err != nil {
+			c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+
+		c.JSON(http.StatusCreated, created)
+	}
+}
+
+// @Summary: UpdateVersionHandler
+// @Description: Update a version by ID
+// @Tags: versions
+// @Accept: json
+// @Produce: json
+// @Param id path int true "Version ID"
+// @Param version body models.Version true "Version"
+// @Success 200 {object} models.Version
+// @Failure 400 {object} map[string]string
+// @Failure 403 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Router /version/{id} [put]
+// @Security BearerAuth
+func UpdateVersionHandler(cfg *config.Config) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		pid := c.Param("id")
+
+		// pasar string id a int id
+		id, err := strconv.Atoi(pid)
+		if err != nil {
+			c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		var version models.Version
+		if err := c.ShouldBindJSON(&version); err != nil {
+			c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		version.ID = id
+
+		if msg := validateVersion(&version); msg != "" {
+			c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": msg})
+			return
+		}
+		if version.Status == "" {
+			version.Status = models.VersionStatusOpen
+		}
+
+		// Inicializar la base de datos
+		db, err := database.InitDBContext(c.Request.Context(), cfg)
+		if err != nil {
+			c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		defer db.Close()
+
+		existing, err := models.GetVersionByID(db, id)
+		if err != nil {
+			c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		if existing == nil {
+			c.AbortWithStatusJSON(http.StatusNotFound, gin.H{"error": "Version not found"})
+			return
+		}
+
+		if status, msg := checkProjectWritable(db, existing.ProjectID); status != 0 {
+			c.AbortWithStatusJSON(status, gin.H{"error": msg})
+			return
+		}
+		if status, msg := checkProjectModule(db, existing.ProjectID, models.ModuleRoadmap); status != 0 {
+			c.AbortWithStatusJSON(status, gin.H{"error": msg})
+			return
+		}
+
+		if err := models.UpdateVersion(db, &version); err != nil {
+			c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+
+		updated, err := models.GetVersionByID(db, id)
+		if err != nil {
+			c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+
+		c.JSON(http.StatusOK, updated)
+	}
+}
+
+// @Summary: DeleteVersionHandler
+// @Description: Delete a version by ID
+// @Tags: versions
+// @Param id path int true "Version ID"
+// @Success 204 {object} nil
+// @Failure 400 {object} map[string]string
+// @Failure 403 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Router /version/{id} [delete]
+// @Security BearerAuth
+func DeleteVersionHandler(cfg *config.Config) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		pid := c.Param("id")
+
+		// pasar string id a int id
+		id, err := strconv.Atoi(pid)
+		if err != nil {
+			c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		// Inicializar la base de datos
+		db, err := database.InitDBContext(c.Request.Context(), cfg)
+		if err != nil {
+			c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		defer db.Close()
+
+		existing, err := models.GetVersionByID(db, id)
+		if err != nil {
+			c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		if existing == nil {
+			c.AbortWithStatusJSON(http.StatusNotFound, gin.H{"error": "Version not found"})
+			return
+		}
+
+		if status, msg := checkProjectWritable(db, existing.ProjectID); status != 0 {
+			c.AbortWithStatusJSON(status, gin.H{"error": msg})
+			return
+		}
+		if status, msg := checkProjectModule(db, existing.ProjectID, models.ModuleRoadmap); status != 0 {
+			c.AbortWithStatusJSON(status, gin.H{"error": msg})
+			return
+		}
+
+		if err := models.DeleteVersion(db, id); err != nil {
+			c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+
+		c.JSON(http.StatusNoContent, nil)
+	}
+}
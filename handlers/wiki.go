@@ -0,0 +1,739 @@
+package handlers
+
+import (
+	"database/sql"
+	"fmt"
+	"go-redmine-ish/config"
+	"go-redmine-ish/database"
+	"go-redmine-ish/models"
+	"html"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// wikiTitleForbidden son los caracteres no admitidos en los títulos, que forman parte de la URL
+const wikiTitleForbidden = ",./?;|:#%"
+
+// normalizeWikiTitle sustituye los espacios por guiones bajos y valida el título de una página
+func normalizeWikiTitle(title string) (string, error) {
+	title = strings.Join(strings.Fields(title), "_")
+	if title == "" {
+		return "", fmt.Errorf("title is required")
+	}
+	if strings.ContainsAny(title, wikiTitleForbidden) {
+		return "", fmt.Errorf("title must not contain any of %s", wikiTitleForbidden)
+	}
+	if len(title) > 255 {
+		return "", fmt.Errorf("title is too long")
+	}
+	return title, nil
+}
+
+// wikiProjectFromParams lee el proyecto de la URL y comprueba que el usuario es miembro (o admin)
+// y, para escribir, que el proyecto admite cambios
+func wikiProjectFromParams(c *gin.Context, db *sql.DB, write bool) (int, bool) {
+	pid := c.Param("id")
+
+	// pasar string id a int id
+	id, err := strconv.Atoi(pid)
+	if err != nil {
+		c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return 0, false
+	}
+
+	project, err := models.GetProjectByID(db, id)
+	if err != nil {
+		c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return 0, false
+	}
+	allowed, err := canViewProject(c, db, id)
+	if err != nil {
+		c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return 0, false
+	}
+	if project == nil || !allowed {
+		c.AbortWithStatusJSON(http.StatusNotFound, gin.H{"error": "Project not found"})
+		return 0, false
+	}
+
+	if write {
+		if status, msg := checkProjectWritable(db, id); status != 0 {
+			c.AbortWithStatusJSON(status, gin.H{"error": msg})
+			return 0, false
+		}
+	}
+
+	return id, true
+}
+
+// wikiPageFromParams carga la página de la URL. Si el título fue renombrado con redirección
+// y follow es true, responde con una redirección temporal a la misma ruta con el título nuevo.
+// La redirección es relativa para que funcione también detrás de un proxy que reescriba la ruta,
+// y temporal porque el título antiguo puede volver a usarse para otra página
+func wikiPageFromParams(c *gin.Context, db *sql.DB, projectID int, follow bool) (*models.WikiPage, bool) {
+	title := strings.Join(strings.Fields(c.Param("title")), "_")
+	page, err := models.GetWikiPageByTitle(db, projectID, title)
+	if err != nil {
+		c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return nil, false
+	}
+	if page != nil {
+		return page, true
+	}
+
+	if follow {
+		target, err := models.GetWikiRedirect(db, projectID, title)
+		if err != nil {
+			c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return nil, false
+		}
+		if target != nil {
+			// Desde /wiki/:title se sube un nivel por cada segmento tras el título, p. ej. /html
+			suffix := strings.TrimPrefix(c.FullPath(), "/project/:id/wiki/:title")
+			location := "./"
+			if depth := strings.Count(suffix, "/"); depth > 0 {
+				location = strings.Repeat("../", depth)
+			}
+			location += url.PathEscape(target.Title) + suffix
+			if c.Request.URL.RawQuery != "" {
+				location += "?" + c.Request.URL.RawQuery
+			}
+			c.Redirect(http.StatusFound, location)
+			c.Abort()
+			return nil, false
+		}
+	}
+
+	c.AbortWithStatusJSON(http.StatusNotFound, gin.H{"error": "Wiki page not found"})
+	return nil, false
+}
+
+type GetWikiPagesHandlerData struct {
+	Pages []models.WikiPage `json:"pages"`
+}
+
+// @Summary: GetWikiPagesHandler
+// @Description: List the wiki pages of a project, without their content. parent_id gives the page hierarchy
+// @Tags: wiki
+// @Produce: json
+// @Param id path int true "Project ID"
+// @Success 200 {object} GetWikiPagesHandlerData
+// @Failure 400 {object} map[string]string
+// @Failure 403 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Router /project/{id}/wiki [get]
+// @Security BearerAuth
+func GetWikiPagesHandler(cfg *config.Config) gin.HandlerFunc {
+	return func(c *gin.Context) {
+
+		// Inicializar la base de datos
+		db, err := database.InitDBContext(c.Request.Context(), cfg)
+		if err != nil {
+			c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		defer db.Close()
+
+		project_id, ok := wikiProjectFromParams(c, db, false)
+		if !ok {
+			return
+		}
+
+		pages, err := models.GetWikiPagesByProjectID(db, project_id)
+		if err != nil {
+			c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+
+		c.JSON(http.StatusOK, GetWikiPagesHandlerData{Pages: pages})
+	}
+}
+
+// @Summary: GetWikiPageHandler
+// @Description: Get a wiki page, or one of its versions with ?version=. Renamed titles redirect to the new one
+// @Tags: wiki
+// @Produce: json
+// @Param id path int true "Project ID"
+// @Param title path string true "Page title"
+// @Param version query int false "Version number"
+// @Success 200 {object} models.WikiPage
+// @Success 302
+// @Failure 400 {object} map[string]string
+// @Failure 403 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Router /project/{id}/wiki/{title} [get]
+// @Security BearerAuth
+func GetWikiPageHandler(cfg *config.Config) gin.HandlerFunc {
+	return func(c *gin.Context) {
+
+		// Inicializar la base de datos
+		db, err := database.InitDBContext(c.Request.Context(), cfg)
+		if err != nil {
+			c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		defer db.Close()
+
+		project_id, ok := wikiProjectFromParams(c, db, false)
+		if !ok {
+			return
+		}
+		page, ok := wikiPageFromParams(c, db, project_id, true)
+		if !ok {
+			return
+		}
+
+		if value := c.Query("version"); value != "" {
+			version, err := strconv.Atoi(value)
+			if err != nil {
+				c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": "version must be a number"})
+				return
+			}
+			revision, err := models.GetWikiRevision(db, page.ID, version)
+			if err != nil {
+				c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+				return
+			}
+			if revision == nil {
+				c.AbortWithStatusJSON(http.StatusNotFound, gin.H{"error": "Version not found"})
+				return
+			}
+			page.Content = revision.Content
+			page.Version = revision.Version
+			page.AuthorID = revision.AuthorID
+			page.UpdatedAt = revision.CreatedAt
+		}
+
+		c.JSON(http.StatusOK, page)
+	}
+}
+
+// @Summary: GetWikiPageHTMLHandler
+// @Description: Render a wiki page (or one of its versions) as sanitized HTML, linking #123 to issues, @username to users and project:identifier to projects
+// @Tags: wiki
+// @Produce: html
+// @Param id path int true "Project ID"
+// @Param title path string true "Page title"
+// @Param version query int false "Version number"
+// @Success 200 {string} string
+// @Failure 400 {object} map[string]string
+// @Failure 403 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Router /project/{id}/wiki/{title}/html [get]
+// @Security BearerAuth
+func GetWikiPageHTMLHandler(cfg *config.Config) gin.HandlerFunc {
+	return func(c *gin.Context) {
+
+		// Inicializar la base de datos
+		db, err := database.InitDBContext(c.Request.Context(), cfg)
+		if err != nil {
+			c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		defer db.Close()
+
+		project_id, ok := wikiProjectFromParams(c, db, false)
+		if !ok {
+			return
+		}
+		page, ok := wikiPageFromParams(c, db, project_id, true)
+		if !ok {
+			return
+		}
+
+		content := page.Content
+		if value := c.Query("version"); value != "" {
+			version, err := strconv.Atoi(value)
+			if err != nil {
+				c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": "version must be a number"})
+				return
+			}
+			revision, err := models.GetWikiRevision(db, page.ID, version)
+			if err != nil {
+				c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+				return
+			}
+			if revision == nil {
+				c.AbortWithStatusJSON(http.StatusNotFound, gin.H{"error": "Version not found"})
+				return
+			}
+			content = revision.Content
+		}
+
+		body, err := newMarkdownRenderer(cfg, db).render(content)
+		if err != nil {
+			c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+
+		document := fmt.Sprintf("<!DOCTYPE html>\n<html>\n<head>\n<meta charset=\"utf-8\">\n<title>%s</title>\n</head>\n<body>\n<h1>%s</h1>\n%s</body>\n</html>\n",
+			html.EscapeString(page.Title), html.EscapeString(strings.ReplaceAll(page.Title, "_", " ")), body)
+		c.Data(http.StatusOK, "text/html; charset=utf-8", []byte(document))
+	}
+}
+
+// WikiPagePayload es el cuerpo de PUT /project/{id}/wiki/{title}
+type WikiPagePayload struct {
+	Content     string  `json:"content"`
+	Comment     string  `json:"comment"`
+	ParentTitle *string `json:"parent_title"` // "" quita la página padre
+	Version     *int    `json:"version"`      // versión editada, para detectar ediciones concurrentes
+}
+
+// @Summary: SaveWikiPageHandler
+// @Description: Create a wiki page or save a new version of it. Sending the edited version returns 409 if the page changed meanwhile
+// @Tags: wiki
+// @Accept: json
+// @Produce: json
+// @Param id path int true "Project ID"
+// @Param title path string true "Page title"
+// @Param page body WikiPagePayload true "Page"
+// @Success 200 {object} models.WikiPage
+// @Success 201 {object} models.WikiPage
+// @Failure 400 {object} map[string]string
+// @Failure 403 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Failure 409 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Router /project/{id}/wiki/{title} [put]
+// @Security BearerAuth
+func SaveWikiPageHandler(cfg *config.Config) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var payload WikiPagePayload
+		if err := c.ShouldBindJSON(&payload); err != nil {
+			c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		// Inicializar la base de datos
+		db, err := database.InitDBContext(c.Request.Context(), cfg)
+		if err != nil {
+			c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		defer db.Close()
+
+		project_id, ok := wikiProjectFromParams(c, db, true)
+		if !ok {
+			return
+		}
+
+		title, err := normalizeWikiTitle(c.Param("title"))
+		if err != nil {
+			c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		page, err := models.GetWikiPageByTitle(db, project_id, title)
+		if err != nil {
+			c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		if page != nil && payload.Version != nil && *payload.Version != page.Version {
+			c.AbortWithStatusJSON(http.StatusConflict, gin.H{"error": fmt.Sprintf("Page was updated by someone else, current version is %d", page.Version)})
+			return
+		}
+
+		created := page == nil
+		if created {
+			page = &models.WikiPage{ProjectID: project_id, Title: title}
+		}
+
+		parent_id := page.ParentID
+		if payload.ParentTitle != nil {
+			parent_id = nil
+			if *payload.ParentTitle != "" {
+				parent, err := models.GetWikiPageByTitle(db, project_id, strings.Join(strings.Fields(*payload.ParentTitle), "_"))
+				if err != nil {
+					c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+					return
+				}
+				if parent == nil {
+					c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": "parent page does not exist"})
+					return
+				}
+				if !created {
+					cycle, err := models.IsWikiPageAncestor(db, page.ID, parent.ID)
+					if err != nil {
+						c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+						return
+					}
+					if cycle {
+						c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": "parent page cannot be the page itself or one of its children"})
+						return
+					}
+				}
+				parent_id = &parent.ID
+			}
+		}
+
+		tx, err := db.Begin()
+		if err != nil {
+			c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		defer tx.Rollback()
+
+		// Sin cambios en el contenido no se crea una versión nueva
+		switch {
+		case created:
+			page.ParentID = parent_id
+			page.Content = payload.Content
+			page.AuthorID = currentUserID(c)
+			_, err = models.CreateWikiPage(tx, page, payload.Comment)
+		case page.Content != payload.Content:
+			page.ParentID = parent_id
+			page.Content = payload.Content
+			page.AuthorID = currentUserID(c)
+			err = models.UpdateWikiPage(tx, page, payload.Comment)
+		default:
+			err = models.SetWikiPageParent(tx, page.ID, parent_id)
+		}
+		if err != nil {
+			c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+
+		saved, err := models.GetWikiPageByID(tx, page.ID)
+		if err != nil {
+			c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+
+		if err := tx.Commit(); err != nil {
+			c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+
+		if created {
+			c.JSON(http.StatusCreated, saved)
+			return
+		}
+		c.JSON(http.StatusOK, saved)
+	}
+}
+
+// WikiRenamePayload es el cuerpo de POST /project/{id}/wiki/{title}/rename
+type WikiRenamePayload struct {
+	Title    string `json:"title" binding:"required"`
+	Redirect *bool  `json:"redirect"` // por defecto true
+}
+
+// @Summary: RenameWikiPageHandler
+// @Description: Rename a wiki page. By default the old title redirects to the new one
+// @Tags: wiki
+// @Accept: json
+// @Produce: json
+// @Param id path int true "Project ID"
+// @Param title path string true "Page title"
+// @Param rename body WikiRenamePayload true "New title"
+// @Success 200 {object} models.WikiPage
+// @Failure 400 {object} map[string]string
+// @Failure 403 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Failure 409 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Router /project/{id}/wiki/{title}/rename [post]
+// @Security BearerAuth
+func RenameWikiPageHandler(cfg *config.Config) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var payload WikiRenamePayload
+		if err := c.ShouldBindJSON(&payload); err != nil {
+			c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		title, err := normalizeWikiTitle(payload.Title)
+		if err != nil {
+			c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		// Inicializar la base de datos
+		db, err := database.InitDBContext(c.Request.Context(), cfg)
+		if err != nil {
+			c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		defer db.Close()
+
+		project_id, ok := wikiProjectFromParams(c, db, true)
+		if !ok {
+			return
+		}
+		page, ok := wikiPageFromParams(c, db, project_id, false)
+		if !ok {
+			return
+		}
+
+		if title != page.Title {
+			existing, err := models.GetWikiPageByTitle(db, project_id, title)
+			if err != nil {
+				c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+				return
+			}
+			if existing != nil {
+				c.AbortWithStatusJSON(http.StatusConflict, gin.H{"error": "A page with this title already exists"})
+				return
+			}
+
+			tx, err := db.Begin()
+			if err != nil {
+				c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+				return
+			}
+			defer tx.Rollback()
+
+			if err := models.RenameWikiPage(tx, page, title, payload.Redirect == nil || *payload.Redirect); err != nil {
+				c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+				return
+			}
+			if err := tx.Commit(); err != nil {
+				c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+				return
+			}
+		}
+
+		renamed, err := models.GetWikiPageByID(db, page.ID)
+		if err != nil {
+			c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+
+		c.JSON(http.StatusOK, renamed)
+	}
+}
+
+// @Summary: DeleteWikiPageHandler
+// @Description: Delete a wiki page with its history. Its child pages become top-level pages
+// @Tags: wiki
+// @Param id path int true "Project ID"
+// @Param title path string true "Page title"
+// @Success 204
+// @Failure 400 {object} map[string]string
+// @Failure 403 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Router /project/{id}/wiki/{title} [delete]
+// @Security BearerAuth
+func DeleteWikiPageHandler(cfg *config.Config) gin.HandlerFunc {
+	return func(c *gin.Context) {
+
+		// Inicializar la base de datos
+		db, err := database.InitDBContext(c.Request.Context(), cfg)
+		if err != nil {
+			c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		defer db.Close()
+
+		project_id, ok := wikiProjectFromParams(c, db, true)
+		if !ok {
+			return
+		}
+		page, ok := wikiPageFromParams(c, db, project_id, false)
+		if !ok {
+			return
+		}
+
+		if err := models.DeleteWikiPage(db, page.ID); err != nil {
+			c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+
+		c.JSON(http.StatusNoContent, nil)
+	}
+}
+
+type GetWikiRevisionsHandlerData struct {
+	Title     string                `json:"title"`
+	Revisions []models.WikiRevision `json:"revisions"`
+}
+
+// @Summary: GetWikiRevisionsHandler
+// @Description: List the versions of a wiki page with author and comment, newest first
+// @Tags: wiki
+// @Produce: json
+// @Param id path int true "Project ID"
+// @Param title path string true "Page title"
+// @Success 200 {object} GetWikiRevisionsHandlerData
+// @Failure 400 {object} map[string]string
+// @Failure 403 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Router /project/{id}/wiki/{title}/revisions [get]
+// @Security BearerAuth
+func GetWikiRevisionsHandler(cfg *config.Config) gin.HandlerFunc {
+	return func(c *gin.Context) {
+
+		// Inicializar la base de datos
+		db, err := database.InitDBContext(c.Request.Context(), cfg)
+		if err != nil {
+			c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		defer db.Close()
+
+		project_id, ok := wikiProjectFromParams(c, db, false)
+		if !ok {
+			return
+		}
+		page, ok := wikiPageFromParams(c, db, project_id, true)
+		if !ok {
+			return
+		}
+
+		revisions, err := models.GetWikiRevisionsByPageID(db, page.ID)
+		if err != nil {
+			c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+
+		c.JSON(http.StatusOK, GetWikiRevisionsHandlerData{Title: page.Title, Revisions: revisions})
+	}
+}
+
+// WikiDiffLine es una línea del diff entre dos versiones: equal, insert o delete
+type WikiDiffLine struct {
+	Op   string `json:"op"`
+	Text string `json:"text"`
+}
+
+type GetWikiDiffHandlerData struct {
+	Title string         `json:"title"`
+	From  int            `json:"from"`
+	To    int            `json:"to"`
+	Lines []WikiDiffLine `json:"lines"`
+}
+
+// diffLines compara dos textos línea a línea con la subsecuencia común más larga
+func diffLines(from, to string) []WikiDiffLine {
+	a := strings.Split(from, "\n")
+	b := strings.Split(to, "\n")
+
+	// lcs[i][j] es la longitud de la subsecuencia común más larga de a[i:] y b[j:]
+	lcs := make([][]int, len(a)+1)
+	for i := range lcs {
+		lcs[i] = make([]int, len(b)+1)
+	}
+	for i := len(a) - 1; i >= 0; i-- {
+		for j := len(b) - 1; j >= 0; j-- {
+			if a[i] == b[j] {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else if lcs[i+1][j] >= lcs[i][j+1] {
+				lcs[i][j] = lcs[i+1][j]
+			} else {
+				lcs[i][j] = lcs[i][j+1]
+			}
+		}
+	}
+
+	lines := []WikiDiffLine{}
+	i, j := 0, 0
+	for i < len(a) && j < len(b) {
+		switch {
+		case a[i] == b[j]:
+			lines = append(lines, WikiDiffLine{Op: "equal", Text: a[i]})
+			i++
+			j++
+		case lcs[i+1][j] >= lcs[i][j+1]:
+			lines = append(lines, WikiDiffLine{Op: "delete", Text: a[i]})
+			i++
+		default:
+			lines = append(lines, WikiDiffLine{Op: "insert", Text: b[j]})
+			j++
+		}
+	}
+	for ; i < len(a); i++ {
+		lines = append(lines, WikiDiffLine{Op: "delete", Text: a[i]})
+	}
+	for ; j < len(b); j++ {
+		lines = append(lines, WikiDiffLine{Op: "insert", Text: b[j]})
+	}
+
+	return lines
+}
+
+// @Summary: GetWikiDiffHandler
+// @Description: Line diff between two versions of a wiki page. By default compares the last version with the previous one
+// @Tags: wiki
+// @Produce: json
+// @Param id path int true "Project ID"
+// @Param title path string true "Page title"
+// @Param from query int false "Old version, defaults to to - 1"
+// @Param to query int false "New version, defaults to the current one"
+// @Success 200 {object} GetWikiDiffHandlerData
+// @Failure 400 {object} map[string]string
+// @Failure 403 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Router /project/{id}/wiki/{title}/diff [get]
+// @Security BearerAuth
+func GetWikiDiffHandler(cfg *config.Config) gin.HandlerFunc {
+	return func(c *gin.Context) {
+
+		// Inicializar la base de datos
+		db, err := database.InitDBContext(c.Request.Context(), cfg)
+		if err != nil {
+			c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		defer db.Close()
+
+		project_id, ok := wikiProjectFromParams(c, db, false)
+		if !ok {
+			return
+		}
+		page, ok := wikiPageFromParams(c, db, project_id, true)
+		if !ok {
+			return
+		}
+
+		to := page.Version
+		if value := c.Query("to"); value != "" {
+			if to, err = strconv.Atoi(value); err != nil {
+				c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": "to must be a number"})
+				return
+			}
+		}
+		from := to - 1
+		if value := c.Query("from"); value != "" {
+			if from, err = strconv.Atoi(value); err != nil {
+				c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": "from must be a number"})
+				return
+			}
+		}
+
+		// La versión 0 es la página vacía, para comparar la primera versión
+		contents := map[int]string{}
+		for _, version := range []int{from, to} {
+			if version == 0 {
+				contents[version] = ""
+				continue
+			}
+			revision, err := models.GetWikiRevision(db, page.ID, version)
+			if err != nil {
+				c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+				return
+			}
+			if revision == nil {
+				c.AbortWithStatusJSON(http.StatusNotFound, gin.H{"error": fmt.Sprintf("Version %d not found", version)})
+				return
+			}
+			contents[version] = revision.Content
+		}
+
+		c.JSON(http.StatusOK, GetWikiDiffHandlerData{
+			Title: page.Title,
+			From:  from,
+			To:    to,
+			Lines: diffLines(contents[from], contents[to]),
+		})
+	}
+}
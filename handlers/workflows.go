@@ -0,0 +1,188 @@
+package handlers
+
+import (
+	"database/sql"
+	"fmt"
+	"go-redmine-ish/config"
+	"go-redmine-ish/database"
+	"go-redmine-ish/models"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+type TrackerWorkflowData struct {
+	Transitions []models.WorkflowTransition `json:"transitions"`
+}
+
+// @Summary: GetTrackerWorkflowHandler
+// @Description: Get the status transitions allowed per role for a tracker. An empty list means any transition is allowed
+// @Tags: trackers
+// @Produce: json
+// @Param id path int true "Tracker ID"
+// @Success 200 {object} TrackerWorkflowData
+// @Failure 400 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Router /tracker/{id}/workflow [get]
+// @Security BearerAuth
+func GetTrackerWorkflowHandler(cfg *config.Config) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		pid := c.Param("id")
+
+		// pasar string id a int id
+		id, err := strconv.Atoi(pid)
+		if err != nil {
+			c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		// Inicializar la base de datos
+		db, err := database.InitDBContext(c.Request.Context(), cfg)
+		if err != nil {
+			c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		defer db.Close()
+
+		if _, err := models.GetTrackerByID(db, id); err == sql.ErrNoRows {
+			c.AbortWithStatusJSON(http.StatusNotFound, gin.H{"error": "Tracker not found"})
+			return
+		} else if err != nil {
+			c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+
+		transitions, err := models.GetWorkflowTransitionsByTrackerID(db, id)
+		if err != nil {
+			c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+
+		c.JSON(http.StatusOK, TrackerWorkflowData{Transitions: transitions})
+	}
+}
+
+// @Summary: UpdateTrackerWorkflowHandler
+// @Description: Replace the status transitions allowed per role for a tracker
+// @Tags: trackers
+// @Accept: json
+// @Produce: json
+// @Param id path int true "Tracker ID"
+// @Param workflow body TrackerWorkflowData true "Transitions"
+// @Success 200 {object} TrackerWorkflowData
+// @Failure 400 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Router /tracker/{id}/workflow [put]
+// @Security BearerAuth
+func UpdateTrackerWorkflowHandler(cfg *config.Config) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		pid := c.Param("id")
+
+		// pasar string id a int id
+		id, err := strconv.Atoi(pid)
+		if err != nil {
+			c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		var data TrackerWorkflowData
+		if err := c.ShouldBindJSON(&data); err != nil {
+			c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		// Inicializar la base de datos
+		db, err := database.InitDBContext(c.Request.Context(), cfg)
+		if err != nil {
+			c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		defer db.Close()
+
+		if _, err := models.GetTrackerByID(db, id); err == sql.ErrNoRows {
+			c.AbortWithStatusJSON(http.StatusNotFound, gin.H{"error": "Tracker not found"})
+			return
+		} else if err != nil {
+			c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+
+		for _, transition := range data.Transitions {
+			if _, err := models.GetRoleByID(db, transition.RoleID); err == sql.ErrNoRows {
+				c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("Role %d not found", transition.RoleID)})
+				return
+			} else if err != nil {
+				c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+				return
+			}
+			for _, status_id := range []int{transition.OldStatusID, transition.NewStatusID} {
+				status, err := models.GetIssueStatusByID(db, status_id)
+				if err != nil {
+					c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+					return
+				}
+				if status == nil {
+					c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("Issue status %d not found", status_id)})
+					return
+				}
+			}
+		}
+
+		tx, err := db.Begin()
+		if err != nil {
+			c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		defer tx.Rollback()
+
+		if err := models.SetWorkflowTransitions(tx, id, data.Transitions); err != nil {
+			c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		if err := tx.Commit(); err != nil {
+			c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+
+		transitions, err := models.GetWorkflowTransitionsByTrackerID(db, id)
+		if err != nil {
+			c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+
+		c.JSON(http.StatusOK, TrackerWorkflowData{Transitions: transitions})
+	}
+}
+
+// checkIssueWorkflow comprueba que el usuario autenticado puede cambiar el estado del ticket según
+// el flujo de trabajo de su tracker. El token de servicio y los administradores no tienen límites.
+// Devuelve 0 si se permite, o el código HTTP y el mensaje de error a responder
+func checkIssueWorkflow(c *gin.Context, db *sql.DB, before, issue *models.Issue) (int, string) {
+	if issue.Status == before.Status {
+		return 0, ""
+	}
+
+	user_id := currentUserID(c)
+	if user_id == nil {
+		return 0, ""
+	}
+	admin, err := models.IsAdminUser(db, *user_id)
+	if err != nil {
+		return http.StatusInternalServerError, err.Error()
+	}
+	if admin {
+		return 0, ""
+	}
+
+	allowed, err := models.IsStatusTransitionAllowed(db, *user_id, issue.ProjectID, issue.TrackerID, before.Status, issue.Status)
+	if err != nil {
+		return http.StatusInternalServerError, err.Error()
+	}
+	if !allowed {
+		return http.StatusForbidden, fmt.Sprintf("Your roles cannot change the status from %s to %s", before.Status, issue.Status)
+	}
+	return 0, ""
+}
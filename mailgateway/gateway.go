@@ -0,0 +1,519 @@
+package mailgateway
+
+import (
+	"crypto/sha256"
+	"database/sql"
+	"errors"
+	"flag"
+	"fmt"
+	"go-redmine-ish/config"
+	"go-redmine-ish/database"
+	"go-redmine-ish/models"
+	"log/slog"
+	"net/url"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// Resultados del procesado de un correo
+const (
+	ResultCreated   = models.MailMessageCreated
+	ResultCommented = models.MailMessageCommented
+	ResultRejected  = models.MailMessageRejected
+	ResultDuplicate = "duplicate"
+)
+
+// Gateway convierte correos en tickets o, si responden a un correo ya procesado, en comentarios
+type Gateway struct {
+	db *sql.DB
+
+	// DefaultProject es el identificador del proyecto de los correos que no indican ninguno
+	DefaultProject string
+	// Tracker es el nombre del tracker de los tickets nuevos; vacío para el primero del proyecto
+	Tracker string
+	// AllowUnknown permite crear tickets (sin autor) desde direcciones que no son de ningún usuario
+	AllowUnknown bool
+	// MaxAttachmentSize es el tamaño máximo de un adjunto en bytes; los mayores se descartan
+	MaxAttachmentSize int
+
+	Processed map[string]int
+}
+
+// NewGateway crea una pasarela que escribe en db
+func NewGateway(db *sql.DB) *Gateway {
+	return &Gateway{
+		db:                db,
+		MaxAttachmentSize: 10 << 20,
+		Processed:         map[string]int{},
+	}
+}
+
+// subjectProjectPattern reconoce el proyecto al principio del asunto: "[identificador] ..."
+var subjectProjectPattern = regexp.MustCompile(`^\[([a-z0-9_-]+)\]\s*`)
+
+// Run procesa los correos pendientes del buzón. Un correo solo se marca como procesado cuando su
+// resultado está guardado, así que si falla la base de datos se vuelve a intentar en la siguiente
+// pasada; el Message-ID registrado evita duplicados si se cae justo entre las dos cosas
+func (g *Gateway) Run(mailbox Mailbox) error {
+	ids, err := mailbox.Pending()
+	if err != nil {
+		return err
+	}
+
+	for _, id := range ids {
+		raw, err := mailbox.Read(id)
+		if err != nil {
+			return err
+		}
+		result, err := g.Process(raw)
+		if err != nil {
+			// Un error temporal (p. ej. la base de datos caída) detiene la pasada y el correo se
+			// reintenta en la siguiente
+			return fmt.Errorf("message %s: %v", id, err)
+		}
+		g.Processed[result]++
+		if err := mailbox.Done(id); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Process procesa un correo y devuelve su resultado. Los correos que no se pueden aceptar se
+// registran como rechazados; solo devuelve error si hay que reintentarlo
+func (g *Gateway) Process(raw []byte) (string, error) {
+	message, err := ParseMessage(raw)
+	if err != nil {
+		slog.Warn("mail-gateway: invalid message rejected", "error", err)
+		return ResultRejected, nil
+	}
+	// Sin Message-ID se usa un resumen del contenido para no procesarlo dos veces
+	if message.MessageID == "" {
+		message.MessageID = fmt.Sprintf("%x@mail-gateway", sha256.Sum256(raw))
+	}
+	message.MessageID = storedMessageID(message.MessageID)
+	for i, id := range message.InReplyTo {
+		message.InReplyTo[i] = storedMessageID(id)
+	}
+
+	exists, err := models.MailMessageExists(g.db, message.MessageID)
+	if err != nil {
+		return "", err
+	}
+	if exists {
+		return ResultDuplicate, nil
+	}
+
+	record := &models.MailMessage{
+		MessageID:   message.MessageID,
+		FromAddress: truncate(message.From, 255),
+		Subject:     message.Subject,
+	}
+	reason, err := g.deliver(message, record)
+	if models.IsPermanentError(err) {
+		// Un correo que la base de datos no acepta fallaría siempre y bloquearía el buzón
+		reason, err = "cannot be saved: "+err.Error(), nil
+	}
+	if err != nil {
+		return "", err
+	}
+	if reason != "" {
+		slog.Warn("mail-gateway: message rejected", "message_id", message.MessageID, "from", message.From, "reason", reason)
+		record.Status = ResultRejected
+		record.Error = &reason
+		// La transacción fallida pudo dejar puestos el ticket o el comentario que no se guardaron
+		record.IssueID = nil
+		record.CommentID = nil
+		if _, err := models.CreateMailMessage(g.db, record); err != nil {
+			return "", err
+		}
+	}
+
+	return record.Status, nil
+}
+
+// deliver crea el ticket o el comentario del correo y lo registra en la misma transacción.
+// Devuelve el motivo si el correo se rechaza
+func (g *Gateway) deliver(message *Message, record *models.MailMessage) (string, error) {
+	user, err := models.GetUserByEmail(g.db, message.From)
+	if err == sql.ErrNoRows {
+		user = nil
+	} else if err != nil {
+		return "", err
+	}
+
+	// Una respuesta a un correo que creó o comentó un ticket se añade como comentario
+	if len(message.InReplyTo) > 0 {
+		issue_id, err := models.GetIssueIDByMailMessageIDs(g.db, message.InReplyTo)
+		if err != nil {
+			return "", err
+		}
+		if issue_id != nil {
+			issue, err := models.GetIssueByID(g.db, *issue_id)
+			if err != nil && err != sql.ErrNoRows {
+				return "", err
+			}
+			if err == nil {
+				return g.comment(message, record, user, issue)
+			}
+		}
+	}
+
+	return g.createIssue(message, record, user)
+}
+
+// comment añade el correo como comentario del ticket
+func (g *Gateway) comment(message *Message, record *models.MailMessage, user *models.User, issue *models.Issue) (string, error) {
+	if user == nil {
+		return "unknown sender, only users can reply to issues", nil
+	}
+	if reason, err := g.checkProject(user, issue.ProjectID); reason != "" || err != nil {
+		return reason, err
+	}
+
+	content := StripQuotedReply(message.Body)
+	if content == "" && len(message.Attachments) == 0 {
+		return "empty reply", nil
+	}
+	if content == "" {
+		content = "(attachments)"
+	}
+
+	tx, err := g.db.Begin()
+	if err != nil {
+		return "", err
+	}
+	defer tx.Rollback()
+
+	comment_id, err := models.CreateComment(tx, &models.Comment{IssueID: issue.ID, UserID: user.ID, Content: content})
+	if err != nil {
+		return "", err
+	}
+	if err := g.saveAttachments(tx, message, issue.ID, &comment_id, &user.ID); err != nil {
+		return "", err
+	}
+
+	record.Status = ResultCommented
+	record.IssueID = &issue.ID
+	record.CommentID = &comment_id
+	if created, err := models.CreateMailMessage(tx, record); err != nil || !created {
+		// Otra instancia lo ha procesado a la vez: se descarta este comentario
+		record.Status = ResultDuplicate
+		return "", err
+	}
+
+	return "", tx.Commit()
+}
+
+// createIssue crea un ticket con el correo en el proyecto indicado por la dirección, el asunto o
+// el proyecto por defecto
+func (g *Gateway) createIssue(message *Message, record *models.MailMessage, user *models.User) (string, error) {
+	project, subject, err := g.project(message)
+	if err != nil {
+		return "", err
+	}
+	if project == nil {
+		return "no project in the address or the subject and no default project", nil
+	}
+	if user == nil && !g.AllowUnknown {
+		return "unknown sender", nil
+	}
+	if reason, err := g.checkProject(user, project.ID); reason != "" || err != nil {
+		return reason, err
+	}
+
+	issue := models.Issue{
+		ProjectID:   project.ID,
+		Subject:     subject,
+		Description: message.Body,
+	}
+	if user != nil {
+		issue.AuthorID = &user.ID
+	} else {
+		issue.Description = "From: " + message.From + "\n\n" + issue.Description
+	}
+	if issue.Subject == "" {
+		issue.Subject = "(no subject)"
+	}
+	issue.Subject = truncate(issue.Subject, 255)
+
+	tracker, err := g.tracker(project.ID)
+	if err != nil {
+		return "", err
+	}
+	if tracker == nil {
+		return "no tracker enabled for project " + project.Identifier, nil
+	}
+	issue.TrackerID = tracker.ID
+	if issue.Status, err = defaultStatus(g.db, tracker); err != nil {
+		return "", err
+	}
+
+	tx, err := g.db.Begin()
+	if err != nil {
+		return "", err
+	}
+	defer tx.Rollback()
+
+	issue_id, err := models.CreateIssue(tx, &issue)
+	if err != nil {
+		return "", err
+	}
+	if err := g.saveAttachments(tx, message, issue_id, nil, issue.AuthorID); err != nil {
+		return "", err
+	}
+
+	record.Status = ResultCreated
+	record.IssueID = &issue_id
+	if created, err := models.CreateMailMessage(tx, record); err != nil || !created {
+		record.Status = ResultDuplicate
+		return "", err
+	}
+
+	return "", tx.Commit()
+}
+
+// project busca el proyecto del correo: el identificador tras el + de una dirección de destino
+// (soporte+identificador@...), el asunto "[identificador] ..." o el proyecto por defecto. Devuelve
+// también el asunto sin el identificador
+func (g *Gateway) project(message *Message) (*models.Project, string, error) {
+	for _, address := range message.Recipients {
+		local, _, _ := strings.Cut(address, "@")
+		if _, identifier, ok := strings.Cut(local, "+"); ok && identifier != "" {
+			project, err := models.GetProjectByIdentifier(g.db, identifier)
+			if err != nil || project != nil {
+				return project, message.Subject, err
+			}
+		}
+	}
+
+	if match := subjectProjectPattern.FindStringSubmatch(strings.ToLower(message.Subject)); match != nil {
+		project, err := models.GetProjectByIdentifier(g.db, match[1])
+		if err != nil || project != nil {
+			return project, strings.TrimSpace(message.Subject[len(match[0]):]), err
+		}
+	}
+
+	if g.DefaultProject == "" {
+		return nil, message.Subject, nil
+	}
+	project, err := models.GetProjectByIdentifier(g.db, g.DefaultProject)
+	return project, message.Subject, err
+}
+
+// checkProject comprueba que el proyecto admite tickets y que el usuario, si lo hay, es miembro
+// del proyecto o administrador. Devuelve el motivo si no
+func (g *Gateway) checkProject(user *models.User, projectID int) (string, error) {
+	project, err := models.GetProjectByID(g.db, projectID)
+	if err != nil {
+		return "", err
+	}
+	if project == nil {
+		return "project not found", nil
+	}
+	if project.Status != models.ProjectStatusActive {
+		return "project " + project.Identifier + " is " + project.Status, nil
+	}
+	enabled, err := models.IsProjectModuleEnabled(g.db, projectID, models.ModuleIssues)
+	if err != nil {
+		return "", err
+	}
+	if !enabled {
+		return "the issues module is disabled for project " + project.Identifier, nil
+	}
+
+	if user == nil {
+		return "", nil
+	}
+	admin, err := models.IsAdminUser(g.db, user.ID)
+	if err != nil || admin {
+		return "", err
+	}
+	project_ids, err := models.GetProjectIDsByMemberUserID(g.db, user.ID)
+	if err != nil {
+		return "", err
+	}
+	for _, id := range project_ids {
+		if id == projectID {
+			return "", nil
+		}
+	}
+	return user.Email + " is not a member of project " + project.Identifier, nil
+}
+
+// tracker devuelve el tracker de los tickets nuevos del proyecto, o nil si no hay ninguno habilitado
+func (g *Gateway) tracker(projectID int) (*models.Tracker, error) {
+	trackers, err := models.GetTrackersByProjectID(g.db, projectID)
+	if err != nil {
+		return nil, err
+	}
+	for i, tracker := range trackers {
+		if g.Tracker == "" || strings.EqualFold(tracker.Name, g.Tracker) {
+			return &trackers[i], nil
+		}
+	}
+	return nil, nil
+}
+
+// defaultStatus es el estado inicial de los tickets del tracker: el suyo por defecto o el primero
+func defaultStatus(db *sql.DB, tracker *models.Tracker) (string, error) {
+	if tracker.DefaultStatusID != nil {
+		status, err := models.GetIssueStatusByID(db, *tracker.DefaultStatusID)
+		if err != nil {
+			return "", err
+		}
+		if status != nil {
+			return status.Name, nil
+		}
+	}
+
+	statuses, err := models.GetAllIssueStatuses(db)
+	if err != nil {
+		return "", err
+	}
+	if len(statuses) == 0 {
+		return "Open", nil
+	}
+	return statuses[0].Name, nil
+}
+
+// saveAttachments guarda los adjuntos del correo en el ticket, descartando los demasiado grandes
+func (g *Gateway) saveAttachments(tx *sql.Tx, message *Message, issueID int, commentID, authorID *int) error {
+	for _, file := range message.Attachments {
+		if len(file.Content) > g.MaxAttachmentSize {
+			slog.Warn("mail-gateway: attachment too large, skipped", "filename", file.Filename, "message_id", message.MessageID, "size", len(file.Content))
+			continue
+		}
+		content_type := file.ContentType
+		if len(content_type) > 255 {
+			content_type = "application/octet-stream"
+		}
+		attachment := &models.Attachment{
+			IssueID:     issueID,
+			CommentID:   commentID,
+			AuthorID:    authorID,
+			Filename:    truncateFilename(file.Filename, 255),
+			ContentType: content_type,
+		}
+		if _, err := models.CreateAttachment(tx, attachment, file.Content); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// storedMessageID es el Message-ID tal como se guarda en mail_messages: los de más de 255
+// caracteres se sustituyen por su resumen, también al buscarlos desde In-Reply-To / References
+func storedMessageID(id string) string {
+	if len(id) <= 255 {
+		return id
+	}
+	return fmt.Sprintf("%x@mail-gateway", sha256.Sum256([]byte(id)))
+}
+
+// truncate recorta value a max caracteres sin partir ninguno
+func truncate(value string, max int) string {
+	for utf8.RuneCountInString(value) > max {
+		_, size := utf8.DecodeLastRuneInString(value)
+		value = value[:len(value)-size]
+	}
+	return value
+}
+
+// truncateFilename recorta un nombre de fichero a max caracteres conservando la extensión
+func truncateFilename(name string, max int) string {
+	if utf8.RuneCountInString(name) <= max {
+		return name
+	}
+	ext := filepath.Ext(name)
+	if utf8.RuneCountInString(ext) > 16 {
+		ext = ""
+	}
+	return truncate(strings.TrimSuffix(name, ext), max-utf8.RuneCountInString(ext)) + ext
+}
+
+// RunCommand ejecuta el subcomando mail-gateway: lee los correos de un maildir o de un buzón IMAP
+// una vez o, con -poll, cada cierto tiempo como proceso permanente
+func RunCommand(cfg *config.Config, args []string) error {
+	fs := flag.NewFlagSet("mail-gateway", flag.ContinueOnError)
+	maildir := fs.String("maildir", os.Getenv("MAIL_MAILDIR"), "directorio maildir de entrada (MAIL_MAILDIR)")
+	imapURL := fs.String("imap", os.Getenv("MAIL_IMAP_URL"), "buzón IMAP, imaps://usuario@servidor/INBOX (MAIL_IMAP_URL); la contraseña puede ir en MAIL_IMAP_PASSWORD")
+	project := fs.String("project", os.Getenv("MAIL_DEFAULT_PROJECT"), "identificador del proyecto por defecto (MAIL_DEFAULT_PROJECT)")
+	tracker := fs.String("tracker", "", "nombre del tracker de los tickets nuevos, el primero del proyecto por defecto")
+	allowUnknown := fs.Bool("allow-unknown", false, "crear tickets desde direcciones que no son de ningún usuario")
+	maxAttachment := fs.Int("max-attachment", 10<<20, "tamaño máximo de un adjunto en bytes")
+	poll := fs.Duration("poll", 0, "intervalo entre lecturas; 0 para leer una vez y terminar")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	if (*maildir == "") == (*imapURL == "") {
+		return errors.New("indica un origen: -maildir o -imap")
+	}
+	if *imapURL != "" {
+		u, err := url.Parse(*imapURL)
+		if err != nil {
+			return err
+		}
+		if password := os.Getenv("MAIL_IMAP_PASSWORD"); password != "" && u.User != nil {
+			u.User = url.UserPassword(u.User.Username(), password)
+		}
+		*imapURL = u.String()
+	}
+
+	db, err := database.InitDB(cfg)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	if err := models.CreateAttachmentsTable(db); err != nil {
+		return err
+	}
+	if err := models.CreateMailMessagesTable(db); err != nil {
+		return err
+	}
+
+	gateway := NewGateway(db)
+	gateway.DefaultProject = *project
+	gateway.Tracker = *tracker
+	gateway.AllowUnknown = *allowUnknown
+	gateway.MaxAttachmentSize = *maxAttachment
+
+	for {
+		err := runOnce(gateway, *maildir, *imapURL)
+		if *poll == 0 {
+			if err == nil {
+				slog.Info("mail-gateway: done", "processed", gateway.Processed)
+			}
+			return err
+		}
+		// Como proceso permanente un error no lo detiene: se reintenta en la siguiente lectura
+		if err != nil {
+			slog.Error("mail-gateway: run failed", "error", err)
+		}
+		time.Sleep(*poll)
+	}
+}
+
+// runOnce abre el buzón y procesa sus correos pendientes
+func runOnce(gateway *Gateway, maildir, imapURL string) error {
+	var mailbox Mailbox
+	var err error
+	if maildir != "" {
+		mailbox, err = NewMaildir(maildir)
+	} else {
+		mailbox, err = DialIMAP(imapURL)
+	}
+	if err != nil {
+		return err
+	}
+	defer mailbox.Close()
+
+	return gateway.Run(mailbox)
+}
@@ -0,0 +1,269 @@
+package mailgateway
+
+import (
+	"database/sql"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+
+	"go-redmine-ish/database/dbtest"
+	"go-redmine-ish/models"
+)
+
+// testMail compone un correo de texto; inReplyTo puede ir vacío
+func testMail(from, to, subject, messageID, inReplyTo, body string) []byte {
+	headers := []string{
+		"From: " + from,
+		"To: " + to,
+		"Subject: " + subject,
+		"Message-ID: <" + messageID + ">",
+	}
+	if inReplyTo != "" {
+		headers = append(headers, "In-Reply-To: <"+inReplyTo+">", "References: <"+inReplyTo+">")
+	}
+	headers = append(headers, "Content-Type: text/plain; charset=utf-8")
+	return []byte(strings.Join(headers, "\r\n") + "\r\n\r\n" + body + "\r\n")
+}
+
+// deliverTo deja los correos en new/ de un maildir temporal, en el orden en que se pasan
+func deliverTo(t *testing.T, mails ...[]byte) *Maildir {
+	t.Helper()
+	maildir, err := NewMaildir(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i, raw := range mails {
+		name := fmt.Sprintf("17000000%02d.M%dP1.test", i, i)
+		if err := os.WriteFile(filepath.Join(maildir.Path, "new", name), raw, 0o640); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return maildir
+}
+
+// assertAllDone comprueba que el gateway ha pasado todos los correos a cur/
+func assertAllDone(t *testing.T, maildir *Maildir, count int) {
+	t.Helper()
+	pending, err := maildir.Pending()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(pending) != 0 {
+		t.Errorf("correos pendientes tras la pasada: %v", pending)
+	}
+	done, err := os.ReadDir(filepath.Join(maildir.Path, "cur"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(done) != count {
+		t.Errorf("cur/ tiene %d correos, se esperaban %d", len(done), count)
+	}
+}
+
+// createTestProject crea un proyecto con el módulo de tickets y el tracker Bug habilitados
+func createTestProject(t *testing.T, db *sql.DB, identifier string) int {
+	t.Helper()
+	project := &models.Project{Name: identifier, Identifier: identifier}
+	project_id, err := models.CreateProject(db, project)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := models.SetProjectModules(db, project_id, []string{models.ModuleIssues}); err != nil {
+		t.Fatal(err)
+	}
+	tracker, err := models.GetTrackerByName(db, "Bug")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := models.SetProjectTrackers(db, project_id, []int{tracker.ID}); err != nil {
+		t.Fatal(err)
+	}
+	return project_id
+}
+
+// createTestMember crea un usuario miembro de los proyectos indicados
+func createTestMember(t *testing.T, db *sql.DB, username, email string, projectIDs ...int) int {
+	t.Helper()
+	user_id, err := models.CreateUser(db, &models.User{Username: username, Email: email})
+	if err != nil {
+		t.Fatal(err)
+	}
+	role, err := models.GetRoleByName(db, "Developer")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, project_id := range projectIDs {
+		if _, err := models.CreateMember(db, &models.Member{UserID: user_id, ProjectID: project_id, RoleID: role.ID}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return user_id
+}
+
+// projectIssues devuelve los tickets de un proyecto por orden de creación
+func projectIssues(t *testing.T, db *sql.DB, projectID int) []models.Issue {
+	t.Helper()
+	issues, err := models.GetIssuesByProjectID(db, projectID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sort.Slice(issues, func(i, j int) bool { return issues[i].ID < issues[j].ID })
+	return issues
+}
+
+func TestGatewayProjectRouting(t *testing.T) {
+	db := dbtest.Open(t)
+	alpha := createTestProject(t, db, "mg-alpha")
+	beta := createTestProject(t, db, "mg-beta")
+	fallback := createTestProject(t, db, "mg-default")
+	createTestMember(t, db, "mg-routing", "Routing@Example.com", alpha, beta, fallback)
+
+	maildir := deliverTo(t,
+		// El identificador tras el + de la dirección manda sobre el asunto
+		testMail("routing@example.com", "support+mg-alpha@example.com", "[mg-beta] By address", "route-1@example.com", "", "Body"),
+		testMail("routing@example.com", "support@example.com", "[MG-BETA]  By subject", "route-2@example.com", "", "Body"),
+		testMail("routing@example.com", "support@example.com", "By default", "route-3@example.com", "", "Body"),
+		// Un identificador que no existe no se confunde con un proyecto
+		testMail("routing@example.com", "support+nope@example.com", "[nope] Unknown project", "route-4@example.com", "", "Body"),
+	)
+	gateway := NewGateway(db)
+	gateway.DefaultProject = "mg-default"
+	if err := gateway.Run(maildir); err != nil {
+		t.Fatal(err)
+	}
+	assertAllDone(t, maildir, 4)
+	if gateway.Processed[ResultCreated] != 4 {
+		t.Errorf("creados = %v, se esperaban 4", gateway.Processed)
+	}
+
+	expected := map[int][]string{
+		alpha:    {"[mg-beta] By address"},
+		beta:     {"By subject"},
+		fallback: {"By default", "[nope] Unknown project"},
+	}
+	for project_id, subjects := range expected {
+		issues := projectIssues(t, db, project_id)
+		got := []string{}
+		for _, issue := range issues {
+			got = append(got, issue.Subject)
+			if issue.AuthorID == nil {
+				t.Errorf("el ticket %q no tiene autor", issue.Subject)
+			}
+		}
+		if strings.Join(got, "|") != strings.Join(subjects, "|") {
+			t.Errorf("proyecto %d: asuntos %q, se esperaban %q", project_id, got, subjects)
+		}
+	}
+}
+
+func TestGatewayRejectsWithoutProject(t *testing.T) {
+	db := dbtest.Open(t)
+	project_id := createTestProject(t, db, "mg-members")
+	createTestMember(t, db, "mg-member", "member@example.com", project_id)
+
+	maildir := deliverTo(t,
+		testMail("member@example.com", "support@example.com", "No project", "reject-1@example.com", "", "Body"),
+		testMail("stranger@example.com", "support+mg-members@example.com", "Unknown sender", "reject-2@example.com", "", "Body"),
+	)
+	gateway := NewGateway(db)
+	if err := gateway.Run(maildir); err != nil {
+		t.Fatal(err)
+	}
+
+	// Los rechazados también se dan por procesados para no bloquear el buzón
+	assertAllDone(t, maildir, 2)
+	if gateway.Processed[ResultRejected] != 2 {
+		t.Errorf("rechazados = %v, se esperaban 2", gateway.Processed)
+	}
+	if issues := projectIssues(t, db, project_id); len(issues) != 0 {
+		t.Errorf("se han creado %d tickets, se esperaba ninguno", len(issues))
+	}
+	for _, message_id := range []string{"reject-1@example.com", "reject-2@example.com"} {
+		exists, err := models.MailMessageExists(db, message_id)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !exists {
+			t.Errorf("el correo rechazado %s no se ha registrado", message_id)
+		}
+	}
+}
+
+func TestGatewayReplyThreading(t *testing.T) {
+	db := dbtest.Open(t)
+	project_id := createTestProject(t, db, "mg-thread")
+	user_id := createTestMember(t, db, "mg-thread", "thread@example.com", project_id)
+
+	reply := "Still failing after the update.\n\nOn Mon, 1 Jan 2024 at 10:00, Support wrote:\n> Please try again"
+	maildir := deliverTo(t,
+		testMail("thread@example.com", "support+mg-thread@example.com", "Printer on fire", "thread-1@example.com", "", "It is on fire."),
+		testMail("thread@example.com", "support@example.com", "Re: Printer on fire", "thread-2@example.com", "thread-1@example.com", reply),
+		// La respuesta a una respuesta sigue el hilo hasta el mismo ticket
+		testMail("thread@example.com", "support@example.com", "Re: Re: Printer on fire", "thread-3@example.com", "thread-2@example.com", "> quoted only\nFixed now."),
+	)
+	gateway := NewGateway(db)
+	if err := gateway.Run(maildir); err != nil {
+		t.Fatal(err)
+	}
+	assertAllDone(t, maildir, 3)
+
+	issues := projectIssues(t, db, project_id)
+	if len(issues) != 1 {
+		t.Fatalf("se han creado %d tickets, se esperaba 1", len(issues))
+	}
+	comments, err := models.GetCommentsByIssueID(db, issues[0].ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sort.Slice(comments, func(i, j int) bool { return comments[i].ID < comments[j].ID })
+	contents := []string{}
+	for _, comment := range comments {
+		contents = append(contents, comment.Content)
+		if comment.UserID != user_id {
+			t.Errorf("el comentario %q es del usuario %d, se esperaba %d", comment.Content, comment.UserID, user_id)
+		}
+	}
+	expected := []string{"Still failing after the update.", "Fixed now."}
+	if strings.Join(contents, "|") != strings.Join(expected, "|") {
+		t.Errorf("comentarios %q, se esperaban %q", contents, expected)
+	}
+	if gateway.Processed[ResultCreated] != 1 || gateway.Processed[ResultCommented] != 2 {
+		t.Errorf("procesados = %v", gateway.Processed)
+	}
+}
+
+func TestGatewayDeduplication(t *testing.T) {
+	db := dbtest.Open(t)
+	project_id := createTestProject(t, db, "mg-dedup")
+	createTestMember(t, db, "mg-dedup", "dedup@example.com", project_id)
+
+	raw := testMail("dedup@example.com", "support+mg-dedup@example.com", "Delivered twice", "dedup-1@example.com", "", "Body")
+	// Sin Message-ID se reconoce por el contenido
+	anonymous := []byte("From: dedup@example.com\r\nTo: support+mg-dedup@example.com\r\nSubject: No id\r\n\r\nBody\r\n")
+	maildir := deliverTo(t, raw, raw, anonymous, anonymous)
+	gateway := NewGateway(db)
+	if err := gateway.Run(maildir); err != nil {
+		t.Fatal(err)
+	}
+	assertAllDone(t, maildir, 4)
+
+	if issues := projectIssues(t, db, project_id); len(issues) != 2 {
+		t.Errorf("se han creado %d tickets, se esperaban 2", len(issues))
+	}
+	if gateway.Processed[ResultCreated] != 2 || gateway.Processed[ResultDuplicate] != 2 {
+		t.Errorf("procesados = %v", gateway.Processed)
+	}
+
+	// Una segunda pasada sobre el mismo correo, p. ej. tras caerse antes de moverlo a cur/
+	again := deliverTo(t, raw)
+	if err := gateway.Run(again); err != nil {
+		t.Fatal(err)
+	}
+	assertAllDone(t, again, 1)
+	if gateway.Processed[ResultDuplicate] != 3 {
+		t.Errorf("duplicados = %d, se esperaban 3", gateway.Processed[ResultDuplicate])
+	}
+}
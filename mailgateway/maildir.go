@@ -0,0 +1,71 @@
+package mailgateway
+
+import (
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// Mailbox es el origen de los correos: un directorio maildir o un buzón IMAP
+type Mailbox interface {
+	// Pending devuelve los identificadores de los correos por procesar, del más antiguo al más nuevo
+	Pending() ([]string, error)
+	// Read devuelve el correo completo
+	Read(id string) ([]byte, error)
+	// Done marca el correo como procesado para que no vuelva a salir en Pending
+	Done(id string) error
+	Close() error
+}
+
+// Maildir lee los correos de new/ de un directorio maildir y los pasa a cur/ marcados como leídos
+// al procesarlos, como haría un cliente de correo
+type Maildir struct {
+	Path string
+}
+
+// NewMaildir abre un directorio maildir, creando new/, cur/ y tmp/ si no existen
+func NewMaildir(path string) (*Maildir, error) {
+	for _, dir := range []string{"new", "cur", "tmp"} {
+		if err := os.MkdirAll(filepath.Join(path, dir), 0o750); err != nil {
+			return nil, err
+		}
+	}
+	return &Maildir{Path: path}, nil
+}
+
+func (m *Maildir) Pending() ([]string, error) {
+	entries, err := os.ReadDir(filepath.Join(m.Path, "new"))
+	if err != nil {
+		return nil, err
+	}
+
+	// Los nombres de maildir empiezan por la marca de tiempo de la entrega
+	ids := []string{}
+	for _, entry := range entries {
+		if entry.Type().IsRegular() && !strings.HasPrefix(entry.Name(), ".") {
+			ids = append(ids, entry.Name())
+		}
+	}
+	sort.Strings(ids)
+	return ids, nil
+}
+
+func (m *Maildir) Read(id string) ([]byte, error) {
+	return os.ReadFile(filepath.Join(m.Path, "new", filepath.Base(id)))
+}
+
+func (m *Maildir) Done(id string) error {
+	name := filepath.Base(id)
+	if !strings.Contains(name, ":2,") {
+		name += ":2,"
+	}
+	if !strings.Contains(name[strings.Index(name, ":2,"):], "S") {
+		name += "S"
+	}
+	return os.Rename(filepath.Join(m.Path, "new", filepath.Base(id)), filepath.Join(m.Path, "cur", name))
+}
+
+func (m *Maildir) Close() error {
+	return nil
+}
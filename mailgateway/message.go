@@ -0,0 +1,212 @@
+package mailgateway
+
+import (
+	"bytes"
+	"encoding/base64"
+	"fmt"
+	stdhtml "html"
+	"io"
+	"mime"
+	"mime/multipart"
+	"mime/quotedprintable"
+	"net/mail"
+	"regexp"
+	"strings"
+
+	"github.com/microcosm-cc/bluemonday"
+)
+
+// Message es un correo entrante ya decodificado
+type Message struct {
+	MessageID   string   // sin < >
+	From        string   // dirección del remitente, en minúsculas
+	Recipients  []string // To, Cc, Delivered-To y X-Original-To, en minúsculas
+	Subject     string
+	InReplyTo   []string // Message-ID de In-Reply-To y References, sin < >
+	Body        string   // texto del correo; el HTML se pasa a texto si no hay parte text/plain
+	Attachments []Attachment
+}
+
+// Attachment es un fichero adjunto a un correo
+type Attachment struct {
+	Filename    string
+	ContentType string
+	Content     []byte
+}
+
+// messageIDPattern extrae los identificadores <...> de In-Reply-To y References
+var messageIDPattern = regexp.MustCompile(`<([^<>\s]+)>`)
+
+var wordDecoder = &mime.WordDecoder{}
+
+// ParseMessage decodifica un correo en formato RFC 5322
+func ParseMessage(raw []byte) (*Message, error) {
+	parsed, err := mail.ReadMessage(bytes.NewReader(raw))
+	if err != nil {
+		return nil, err
+	}
+	header := parsed.Header
+
+	message := &Message{}
+	from, err := mail.ParseAddress(decodeHeader(header.Get("From")))
+	if err != nil {
+		return nil, fmt.Errorf("invalid From: %v", err)
+	}
+	message.From = strings.ToLower(from.Address)
+
+	for _, name := range []string{"To", "Cc", "Delivered-To", "X-Original-To"} {
+		for _, value := range header[name] {
+			addresses, err := mail.ParseAddressList(decodeHeader(value))
+			if err != nil {
+				continue
+			}
+			for _, address := range addresses {
+				message.Recipients = append(message.Recipients, strings.ToLower(address.Address))
+			}
+		}
+	}
+
+	message.Subject = strings.TrimSpace(decodeHeader(header.Get("Subject")))
+	message.MessageID = strings.Trim(strings.TrimSpace(header.Get("Message-Id")), "<>")
+	for _, name := range []string{"In-Reply-To", "References"} {
+		for _, match := range messageIDPattern.FindAllStringSubmatch(header.Get(name), -1) {
+			message.InReplyTo = append(message.InReplyTo, match[1])
+		}
+	}
+
+	var text, html string
+	if err := readPart(header, parsed.Body, message, &text, &html); err != nil {
+		return nil, err
+	}
+	if text == "" && html != "" {
+		html = strings.NewReplacer("<br>", "\n", "<br/>", "\n", "</p>", "\n\n", "</div>", "\n").Replace(html)
+		text = stdhtml.UnescapeString(bluemonday.StrictPolicy().Sanitize(html))
+	}
+	message.Body = strings.TrimSpace(strings.ReplaceAll(text, "\r\n", "\n"))
+
+	return message, nil
+}
+
+// readPart recorre una parte MIME: guarda la primera parte text/plain y la primera text/html como
+// texto del correo y el resto de partes con nombre de fichero como adjuntos
+func readPart(header interface{ Get(string) string }, body io.Reader, message *Message, text, html *string) error {
+	mediaType, params, err := mime.ParseMediaType(header.Get("Content-Type"))
+	if err != nil {
+		mediaType, params = "text/plain", map[string]string{}
+	}
+
+	if strings.HasPrefix(mediaType, "multipart/") {
+		reader := multipart.NewReader(body, params["boundary"])
+		for {
+			part, err := reader.NextRawPart()
+			if err == io.EOF {
+				return nil
+			}
+			if err != nil {
+				return err
+			}
+			if err := readPart(part.Header, part, message, text, html); err != nil {
+				return err
+			}
+		}
+	}
+
+	content, err := io.ReadAll(decodeTransfer(header.Get("Content-Transfer-Encoding"), body))
+	if err != nil {
+		return err
+	}
+
+	disposition, dispositionParams, _ := mime.ParseMediaType(header.Get("Content-Disposition"))
+	filename := decodeHeader(dispositionParams["filename"])
+	if filename == "" {
+		filename = decodeHeader(params["name"])
+	}
+
+	switch {
+	case disposition != "attachment" && mediaType == "text/plain" && *text == "":
+		*text = toUTF8(content, params["charset"])
+	case disposition != "attachment" && mediaType == "text/html" && *html == "":
+		*html = toUTF8(content, params["charset"])
+	case filename != "" || disposition == "attachment":
+		if filename == "" {
+			filename = "attachment"
+		}
+		message.Attachments = append(message.Attachments, Attachment{
+			Filename:    filename,
+			ContentType: mediaType,
+			Content:     content,
+		})
+	}
+
+	return nil
+}
+
+// decodeTransfer deshace la codificación de transferencia de una parte
+func decodeTransfer(encoding string, body io.Reader) io.Reader {
+	switch strings.ToLower(strings.TrimSpace(encoding)) {
+	case "base64":
+		return base64.NewDecoder(base64.StdEncoding, newlineStripper{body})
+	case "quoted-printable":
+		return quotedprintable.NewReader(body)
+	}
+	return body
+}
+
+// newlineStripper quita los saltos de línea del base64, que el decodificador no admite
+type newlineStripper struct{ r io.Reader }
+
+func (n newlineStripper) Read(p []byte) (int, error) {
+	count, err := n.r.Read(p)
+	kept := 0
+	for _, b := range p[:count] {
+		if b != '\r' && b != '\n' {
+			p[kept] = b
+			kept++
+		}
+	}
+	return kept, err
+}
+
+// decodeHeader decodifica las palabras codificadas (=?utf-8?...?=) de una cabecera
+func decodeHeader(value string) string {
+	decoded, err := wordDecoder.DecodeHeader(value)
+	if err != nil {
+		return value
+	}
+	return decoded
+}
+
+// toUTF8 pasa a UTF-8 el texto en ISO-8859-1, el juego de caracteres más habitual después de
+// UTF-8 y US-ASCII; el resto se deja como está
+func toUTF8(content []byte, charset string) string {
+	switch strings.ToLower(charset) {
+	case "iso-8859-1", "latin1", "windows-1252":
+		runes := make([]rune, len(content))
+		for i, b := range content {
+			runes[i] = rune(b)
+		}
+		return string(runes)
+	}
+	return string(content)
+}
+
+// replyHeaderPattern reconoce la línea con la que los clientes de correo introducen el texto citado
+var replyHeaderPattern = regexp.MustCompile(`(?i)^(on .+ wrote:|el .+ escribió:|-+\s*original message\s*-+)$`)
+
+// StripQuotedReply quita de una respuesta el texto citado del correo anterior
+func StripQuotedReply(body string) string {
+	lines := strings.Split(body, "\n")
+	for i, line := range lines {
+		if replyHeaderPattern.MatchString(strings.TrimSpace(line)) {
+			lines = lines[:i]
+			break
+		}
+	}
+	kept := []string{}
+	for _, line := range lines {
+		if !strings.HasPrefix(line, ">") {
+			kept = append(kept, line)
+		}
+	}
+	return strings.TrimSpace(strings.Join(kept, "\n"))
+}
@@ -0,0 +1,125 @@
+package mailgateway
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"unicode/utf8"
+)
+
+func TestParseMessageThreading(t *testing.T) {
+	raw := "From: \"Ana\" <Ana@Example.com>\r\n" +
+		"To: Support <support+web@example.com>, other@example.com\r\n" +
+		"Cc: cc@example.com\r\n" +
+		"Subject: =?utf-8?q?Re:_Caf=C3=A9?=\r\n" +
+		"Message-ID: <reply@example.com>\r\n" +
+		"In-Reply-To: <parent@example.com>\r\n" +
+		"References: <root@example.com>\r\n <parent@example.com>\r\n" +
+		"\r\n" +
+		"Hello\r\n"
+
+	message, err := ParseMessage([]byte(raw))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if message.From != "ana@example.com" {
+		t.Errorf("From = %q", message.From)
+	}
+	if got := strings.Join(message.Recipients, ","); got != "support+web@example.com,other@example.com,cc@example.com" {
+		t.Errorf("Recipients = %q", got)
+	}
+	if message.Subject != "Re: Café" {
+		t.Errorf("Subject = %q", message.Subject)
+	}
+	if message.MessageID != "reply@example.com" {
+		t.Errorf("MessageID = %q", message.MessageID)
+	}
+	if got := strings.Join(message.InReplyTo, ","); got != "parent@example.com,root@example.com,parent@example.com" {
+		t.Errorf("InReplyTo = %q", got)
+	}
+	if message.Body != "Hello" {
+		t.Errorf("Body = %q", message.Body)
+	}
+}
+
+func TestStripQuotedReply(t *testing.T) {
+	tests := []struct {
+		body     string
+		expected string
+	}{
+		{"Thanks!\n\nOn Tue, 2 Jan 2024, Bob wrote:\n> old text", "Thanks!"},
+		{"Gracias\n\nEl mar, 2 ene 2024, Bob escribió:\n> texto", "Gracias"},
+		{"Done\n----- Original Message -----\nFrom: Bob", "Done"},
+		{"> quoted\nAnswer\n> more quoted", "Answer"},
+		{"> only quoted", ""},
+	}
+	for _, test := range tests {
+		if got := StripQuotedReply(test.body); got != test.expected {
+			t.Errorf("StripQuotedReply(%q) = %q, se esperaba %q", test.body, got, test.expected)
+		}
+	}
+}
+
+func TestStoredMessageID(t *testing.T) {
+	short := "short@example.com"
+	if got := storedMessageID(short); got != short {
+		t.Errorf("storedMessageID(%q) = %q", short, got)
+	}
+
+	long := strings.Repeat("a", 300) + "@example.com"
+	stored := storedMessageID(long)
+	if len(stored) > 255 {
+		t.Errorf("storedMessageID devuelve %d caracteres", len(stored))
+	}
+	// El mismo identificador da siempre el mismo resumen, para encontrarlo desde las respuestas
+	if storedMessageID(long) != stored || storedMessageID(long+"x") == stored {
+		t.Error("storedMessageID no es estable o no distingue identificadores")
+	}
+}
+
+func TestTruncateFilename(t *testing.T) {
+	name := strings.Repeat("ñ", 300) + ".pdf"
+	got := truncateFilename(name, 255)
+	if utf8.RuneCountInString(got) != 255 || !strings.HasSuffix(got, ".pdf") || !utf8.ValidString(got) {
+		t.Errorf("truncateFilename = %q (%d caracteres)", got, utf8.RuneCountInString(got))
+	}
+	if got := truncateFilename("report.pdf", 255); got != "report.pdf" {
+		t.Errorf("truncateFilename = %q", got)
+	}
+}
+
+func TestMaildirPendingAndDone(t *testing.T) {
+	maildir, err := NewMaildir(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, name := range []string{"1700000002.M2P1.host", "1700000001.M1P1.host:2,F", ".hidden"} {
+		if err := os.WriteFile(filepath.Join(maildir.Path, "new", name), []byte("x"), 0o640); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	pending, err := maildir.Pending()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := strings.Join(pending, ","); got != "1700000001.M1P1.host:2,F,1700000002.M2P1.host" {
+		t.Fatalf("Pending = %q", got)
+	}
+
+	for _, id := range pending {
+		if err := maildir.Done(id); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if pending, _ := maildir.Pending(); len(pending) != 0 {
+		t.Errorf("Pending tras Done = %q", pending)
+	}
+	// Se marcan como leídos conservando las marcas que ya tuvieran
+	for _, name := range []string{"1700000001.M1P1.host:2,FS", "1700000002.M2P1.host:2,S"} {
+		if _, err := os.Stat(filepath.Join(maildir.Path, "cur", name)); err != nil {
+			t.Errorf("falta cur/%s: %v", name, err)
+		}
+	}
+}
@@ -13,14 +13,29 @@
 package main
 
 import (
+	"context"
+	"errors"
 	"go-redmine-ish/config"
+	"go-redmine-ish/database"
 	"go-redmine-ish/docs" // docs is generated by Swag CLI, you have to import it.
 	"go-redmine-ish/handlers"
+	"go-redmine-ish/logging"
+	"go-redmine-ish/mailgateway"
 	"go-redmine-ish/middleware"
+	"go-redmine-ish/models"
+	"go-redmine-ish/redmine"
+	"go-redmine-ish/telemetry"
+	"log"
+	"log/slog"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
 	"time"
 
 	"github.com/gin-contrib/cors"
 	"github.com/gin-gonic/gin"
+	"github.com/prometheus/client_golang/prometheus"
 	swaggerFiles "github.com/swaggo/files"
 	ginSwagger "github.com/swaggo/gin-swagger"
 )
@@ -38,15 +53,58 @@ func main() {
 	// Cargar la configuración
 	cfg := config.LoadConfig()
 
+	// Registros en JSON con el nivel de LOG_LEVEL y los secretos ocultos
+	logging.Setup(cfg.LogLevel)
+
+	// Subcomando de importación desde Redmine: ./app import-redmine -url ... -key ...
+	if len(os.Args) > 1 && os.Args[1] == "import-redmine" {
+		if err := redmine.RunImportCommand(cfg, os.Args[2:]); err != nil {
+			log.Fatalf("import-redmine: %v", err)
+		}
+		return
+	}
+
+	// Subcomando de la pasarela de correo: ./app mail-gateway -maildir ... o -imap ...
+	if len(os.Args) > 1 && os.Args[1] == "mail-gateway" {
+		if err := mailgateway.RunCommand(cfg, os.Args[2:]); err != nil {
+			log.Fatalf("mail-gateway: %v", err)
+		}
+		return
+	}
+
+	// Trazas OpenTelemetry por OTLP si hay colector configurado
+	shutdownTracing, err := telemetry.SetupTracing(context.Background(), cfg.OTLPEndpoint)
+	if err != nil {
+		log.Fatalf("tracing: %v", err)
+	}
+
+	// Métricas de Prometheus en su propio puerto
+	var metricsServer *http.Server
+	var issueMetrics *handlers.IssueMetricsCollector
+	if cfg.MetricsAddr != "" {
+		telemetry.RegisterDBPoolStats(database.PoolStats)
+		issueMetrics = handlers.NewIssueMetricsCollector(cfg)
+		prometheus.MustRegister(issueMetrics)
+		metricsServer = telemetry.ServeMetrics(cfg.MetricsAddr)
+	}
+
+	// Planificador de las plantillas de tickets periódicos
+	if cfg.SchedulerInterval > 0 {
+		go handlers.RunIssueTemplateScheduler(cfg, cfg.SchedulerInterval)
+	}
+
 	// Crear un router Gin
-	router := gin.Default()
+	router := gin.New()
+
+	// Trazas y métricas, ID de petición, registro de cada petición y recuperación de pánicos
+	router.Use(middleware.Telemetry(), middleware.RequestLogger(), middleware.Recovery())
 
 	// Configurar CORS
 	router.Use(cors.New(cors.Config{
 		AllowOrigins:     []string{"http://localhost:5173"}, // Origen permitido
-		AllowMethods:     []string{"GET", "POST", "PUT", "DELETE", "OPTIONS"},
-		AllowHeaders:     []string{"Origin", "Content-Type", "Authorization"},
-		ExposeHeaders:    []string{"Content-Length"},
+		AllowMethods:     []string{"GET", "POST", "PUT", "PATCH", "DELETE", "OPTIONS"},
+		AllowHeaders:     []string{"Origin", "Content-Type", "Authorization", "X-Redmine-API-Key", "If-Match", middleware.RequestIDHeader, "traceparent", "tracestate"},
+		ExposeHeaders:    []string{"Content-Length", "ETag", middleware.RequestIDHeader},
 		AllowCredentials: true,
 		MaxAge:           12 * time.Hour, // Tiempo de caché para las opciones preflight
 	}))
@@ -57,7 +115,7 @@ func main() {
 
 	// Grupo de rutas con middleware de autenticación
 	authGroup := router.Group("/")
-	authGroup.Use(middleware.AuthMiddleware(cfg))
+	authGroup.Use(middleware.AuthMiddleware(cfg), middleware.ProjectIdentifierMiddleware(cfg))
 
 	authGroup.GET("/auth", handlers.GetAuthHandler(cfg))
 
@@ -70,12 +128,47 @@ func main() {
 	authGroup.GET("/project/:id", handlers.GetProjectHandler(cfg))
 	authGroup.POST("/project", handlers.CreateProjectHandler(cfg))
 	authGroup.PUT("/project/:id", handlers.UpdateProjectHandler(cfg))
+	authGroup.PATCH("/project/:id", handlers.PatchProjectHandler(cfg))
 	authGroup.DELETE("/project/:id", handlers.DeleteProjectHandler(cfg))
+	authGroup.POST("/project/:id/close", handlers.CloseProjectHandler(cfg))
+	authGroup.POST("/project/:id/reopen", handlers.ReopenProjectHandler(cfg))
+	authGroup.POST("/project/:id/archive", handlers.ArchiveProjectHandler(cfg))
+	authGroup.POST("/project/:id/unarchive", handlers.UnarchiveProjectHandler(cfg))
+	authGroup.POST("/project/:id/copy", handlers.CopyProjectHandler(cfg))
+	authGroup.GET("/project/:id/activity", handlers.GetProjectActivityHandler(cfg))
+	authGroup.POST("/project/:id/member", handlers.CreateProjectMemberHandler(cfg))
+	authGroup.PUT("/project/:id/member/:member_id", handlers.UpdateProjectMemberHandler(cfg))
+	authGroup.DELETE("/project/:id/member/:member_id", handlers.DeleteProjectMemberHandler(cfg))
+	authGroup.GET("/project/:id/time_entries", handlers.RequireProjectModule(cfg, models.ModuleTimeTracking), handlers.GetProjectTimeEntriesHandler(cfg))
+
+	authGroup.GET("/activity", handlers.GetActivityHandler(cfg))
+
+	authGroup.GET("/project/:id/versions", handlers.RequireProjectModule(cfg, models.ModuleRoadmap), handlers.GetProjectVersionsHandler(cfg))
+	authGroup.GET("/project/:id/statistics", handlers.RequireProjectModule(cfg, models.ModuleIssues), handlers.GetProjectStatsHandler(cfg))
+	authGroup.GET("/project/:id/gantt", handlers.RequireProjectModule(cfg, models.ModuleIssues), handlers.GetProjectGanttHandler(cfg))
+	authGroup.GET("/project/:id/calendar", handlers.RequireProjectModule(cfg, models.ModuleIssues), handlers.GetCalendarHandler(cfg))
+	authGroup.GET("/project/:id/board", handlers.RequireProjectModule(cfg, models.ModuleIssues), handlers.GetProjectBoardHandler(cfg))
+	authGroup.PUT("/project/:id/board/wip_limits", handlers.RequireProjectModule(cfg, models.ModuleIssues), handlers.UpdateBoardWIPLimitsHandler(cfg))
+	authGroup.POST("/project/:id/board/move", handlers.RequireProjectModule(cfg, models.ModuleIssues), handlers.MoveBoardIssueHandler(cfg))
+	authGroup.POST("/version", handlers.CreateVersionHandler(cfg))
+	authGroup.PUT("/version/:id", handlers.UpdateVersionHandler(cfg))
+	authGroup.DELETE("/version/:id", handlers.DeleteVersionHandler(cfg))
+	authGroup.GET("/project/:id/issue_templates", handlers.RequireProjectModule(cfg, models.ModuleIssues), handlers.GetProjectIssueTemplatesHandler(cfg))
+	authGroup.POST("/project/:id/issue_templates", handlers.RequireProjectModule(cfg, models.ModuleIssues), handlers.CreateIssueTemplateHandler(cfg))
+	authGroup.GET("/issue_template/:id", handlers.GetIssueTemplateHandler(cfg))
+	authGroup.PUT("/issue_template/:id", handlers.UpdateIssueTemplateHandler(cfg))
+	authGroup.DELETE("/issue_template/:id", handlers.DeleteIssueTemplateHandler(cfg))
+	authGroup.GET("/version/:id/burndown", handlers.GetVersionBurndownHandler(cfg))
+	authGroup.GET("/version/:id/cumulative_flow", handlers.GetVersionCumulativeFlowHandler(cfg))
+
+	authGroup.POST("/time_entry", handlers.CreateTimeEntryHandler(cfg))
+	authGroup.DELETE("/time_entry/:id", handlers.DeleteTimeEntryHandler(cfg))
 
 	authGroup.GET("/users", handlers.GetUsersHandler(cfg))
 	authGroup.GET("/user/:id", handlers.GetUserHandler(cfg))
 	authGroup.POST("/user", handlers.CreateUserHandler(cfg))
 	authGroup.PUT("/user/:id", handlers.UpdateUserHandler(cfg))
+	authGroup.PATCH("/user/:id", handlers.PatchUserHandler(cfg))
 	authGroup.DELETE("/user/:id", handlers.DeleteUserHandler(cfg))
 
 	authGroup.GET("/roles", handlers.GetRolesHandler(cfg))
@@ -84,20 +177,103 @@ func main() {
 	authGroup.PUT("/role/:id", handlers.UpdateRoleHandler(cfg))
 	authGroup.DELETE("/role/:id", handlers.DeleteRoleHandler(cfg))
 
+	wiki := handlers.RequireProjectModule(cfg, models.ModuleWiki)
+	authGroup.GET("/project/:id/wiki", wiki, handlers.GetWikiPagesHandler(cfg))
+	authGroup.GET("/project/:id/wiki/:title", wiki, handlers.GetWikiPageHandler(cfg))
+	authGroup.PUT("/project/:id/wiki/:title", wiki, handlers.SaveWikiPageHandler(cfg))
+	authGroup.DELETE("/project/:id/wiki/:title", wiki, handlers.DeleteWikiPageHandler(cfg))
+	authGroup.GET("/project/:id/wiki/:title/html", wiki, handlers.GetWikiPageHTMLHandler(cfg))
+	authGroup.POST("/project/:id/wiki/:title/rename", wiki, handlers.RenameWikiPageHandler(cfg))
+	authGroup.GET("/project/:id/wiki/:title/revisions", wiki, handlers.GetWikiRevisionsHandler(cfg))
+	authGroup.GET("/project/:id/wiki/:title/diff", wiki, handlers.GetWikiDiffHandler(cfg))
+
 	authGroup.GET("/trackers", handlers.GetTrackersHandler(cfg))
+	authGroup.GET("/tracker/:id", handlers.GetTrackerHandler(cfg))
+	authGroup.POST("/tracker", handlers.CreateTrackerHandler(cfg))
+	authGroup.PUT("/tracker/:id", handlers.UpdateTrackerHandler(cfg))
+	authGroup.DELETE("/tracker/:id", handlers.DeleteTrackerHandler(cfg))
+	authGroup.GET("/tracker/:id/workflow", handlers.GetTrackerWorkflowHandler(cfg))
+	authGroup.PUT("/tracker/:id/workflow", handlers.UpdateTrackerWorkflowHandler(cfg))
 
 	authGroup.GET("/issues", handlers.GetIssuesHandler(cfg))
+	authGroup.GET("/issue_priorities", handlers.GetIssuePrioritiesHandler(cfg))
+	authGroup.GET("/calendar", handlers.GetCalendarHandler(cfg))
+	authGroup.GET("/issues.csv", handlers.GetIssuesCSVHandler(cfg))
+	authGroup.POST("/issues/bulk_update", handlers.BulkUpdateIssuesHandler(cfg))
+	authGroup.POST("/issues/bulk_delete", handlers.BulkDeleteIssuesHandler(cfg))
+	authGroup.POST("/project/:id/issues/import", handlers.RequireProjectModule(cfg, models.ModuleIssues), handlers.ImportIssuesHandler(cfg))
 	authGroup.GET("/issue/:id", handlers.GetIssueHandler(cfg))
 	authGroup.POST("/issue", handlers.CreateIssueHandler(cfg))
 	authGroup.PUT("/issue/:id", handlers.UpdateIssueHandler(cfg))
+	authGroup.PATCH("/issue/:id", handlers.PatchIssueHandler(cfg))
 	authGroup.DELETE("/issue/:id", handlers.DeleteIssueHandler(cfg))
+	authGroup.POST("/issue/:id/copy", handlers.CopyIssueHandler(cfg))
+	authGroup.POST("/issue/:id/move", handlers.MoveIssueHandler(cfg))
+	authGroup.GET("/issue/:id/relations", handlers.GetIssueRelationsHandler(cfg))
+	authGroup.POST("/issue/:id/relations", handlers.CreateIssueRelationHandler(cfg))
+	authGroup.DELETE("/issue/:id/relations/:relation_id", handlers.DeleteIssueRelationHandler(cfg))
+	authGroup.GET("/issue/:id/attachments", handlers.GetIssueAttachmentsHandler(cfg))
+	authGroup.GET("/attachment/:id", handlers.DownloadAttachmentHandler(cfg))
 
 	authGroup.GET("/settings", handlers.GetSettingsHandler(cfg))
 
+	authGroup.GET("/my/feed_key", handlers.GetFeedKeyHandler(cfg))
+	authGroup.POST("/my/feed_key", handlers.ResetFeedKeyHandler(cfg))
+	authGroup.GET("/my/mentions", handlers.GetMyMentionsHandler(cfg))
+	authGroup.POST("/my/mentions/notified", handlers.MarkMyMentionsNotifiedHandler(cfg))
+
+	// Compatibilidad con la API REST de Redmine
+	authGroup.GET("/issues.json", handlers.RedmineGetIssuesHandler(cfg))
+	authGroup.POST("/issues.json", handlers.RedmineCreateIssueHandler(cfg))
+	authGroup.GET("/issues/:id", handlers.RedmineGetIssueHandler(cfg))
+	authGroup.PUT("/issues/:id", handlers.RedmineUpdateIssueHandler(cfg))
+	authGroup.DELETE("/issues/:id", handlers.RedmineDeleteIssueHandler(cfg))
+	authGroup.GET("/projects.json", handlers.RedmineGetProjectsHandler(cfg))
+	authGroup.GET("/projects/:id", handlers.RedmineGetProjectHandler(cfg))
+	authGroup.GET("/projects/:id/memberships.json", handlers.RedmineGetProjectMembershipsHandler(cfg))
+	authGroup.GET("/projects/:id/issue_categories.json", handlers.RedmineGetProjectIssueCategoriesHandler(cfg))
+	authGroup.GET("/users.json", handlers.RedmineGetUsersHandler(cfg))
+	authGroup.GET("/users/current.json", handlers.RedmineGetCurrentUserHandler(cfg))
+	authGroup.GET("/users/:id", handlers.RedmineGetUserHandler(cfg))
+	authGroup.GET("/trackers.json", handlers.RedmineGetTrackersHandler(cfg))
+	authGroup.GET("/issue_statuses.json", handlers.RedmineGetIssueStatusesHandler(cfg))
+	authGroup.GET("/enumerations/issue_priorities.json", handlers.RedmineGetIssuePrioritiesHandler(cfg))
+	authGroup.GET("/roles.json", handlers.RedmineGetRolesHandler(cfg))
+
+	// Feeds Atom e iCalendar, autenticados con la clave de feed del usuario (?key=)
+	feedGroup := router.Group("/")
+	feedGroup.Use(middleware.FeedKeyMiddleware(cfg), middleware.ProjectIdentifierMiddleware(cfg))
+
+	feedGroup.GET("/project/:id/activity.atom", handlers.GetProjectActivityFeedHandler(cfg))
+	feedGroup.GET("/issues.atom", handlers.GetIssuesFeedHandler(cfg))
+	feedGroup.GET("/calendar.ics", handlers.GetCalendarICSHandler(cfg))
+	feedGroup.GET("/project/:id/calendar.ics", handlers.RequireProjectModule(cfg, models.ModuleIssues), handlers.GetCalendarICSHandler(cfg))
+
 	router.GET("/swagger/*any", ginSwagger.WrapHandler(swaggerFiles.Handler))
 
 	// Iniciar el servidor
-	if err := router.Run(":8080"); err != nil {
-		panic(err)
+	server := &http.Server{Addr: ":8080", Handler: router}
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+	go func() {
+		if err := server.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			panic(err)
+		}
+	}()
+
+	// Al parar el pod se dejan terminar las peticiones en curso y después se envían los spans
+	// pendientes antes de salir
+	<-ctx.Done()
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), 25*time.Second)
+	defer cancel()
+	if err := server.Shutdown(shutdownCtx); err != nil {
+		slog.Error("api server shutdown", "error", err)
+	}
+	if metricsServer != nil {
+		if err := metricsServer.Shutdown(shutdownCtx); err != nil {
+			slog.Error("metrics server shutdown", "error", err)
+		}
+		issueMetrics.Close()
 	}
+	shutdownTracing(shutdownCtx)
 }
@@ -0,0 +1,184 @@
+// Package markdown convierte a HTML el Markdown de wikis, tickets y comentarios,
+// enlazando las referencias propias de la aplicación: #123 a tickets, @usuario a
+// usuarios y project:identificador a proyectos
+package markdown
+
+import (
+	"bytes"
+	"regexp"
+	"strconv"
+	"unicode"
+
+	"github.com/microcosm-cc/bluemonday"
+	"github.com/yuin/goldmark"
+	"github.com/yuin/goldmark/ast"
+	"github.com/yuin/goldmark/extension"
+	"github.com/yuin/goldmark/parser"
+	"github.com/yuin/goldmark/text"
+	"github.com/yuin/goldmark/util"
+)
+
+// Links resuelve las referencias del texto a URLs. Una función nil, o que devuelve
+// "", deja la referencia como texto (por ejemplo, si el ticket no existe).
+// User se llama una vez por cada mención encontrada, lo que permite registrarlas
+type Links struct {
+	Issue   func(id int) string
+	User    func(username string) string
+	Project func(identifier string) string
+}
+
+// policy limpia el HTML generado: aunque goldmark ya omite el HTML en bruto y los
+// enlaces peligrosos, se filtra de nuevo con una lista blanca
+var policy = func() *bluemonday.Policy {
+	p := bluemonday.UGCPolicy()
+	p.AllowAttrs("class").Matching(regexp.MustCompile(`^(issue|user|project)$`)).OnElements("a")
+	return p
+}()
+
+// Render convierte source a HTML seguro para incrustar en una página
+func Render(source string, links Links) (string, error) {
+	md := goldmark.New(
+		goldmark.WithExtensions(extension.GFM),
+		goldmark.WithParserOptions(
+			parser.WithInlineParsers(
+				util.Prioritized(&issueRefParser{links: links}, 500),
+				util.Prioritized(&userRefParser{links: links}, 500),
+				util.Prioritized(&projectRefParser{links: links}, 500),
+			),
+		),
+	)
+
+	var buf bytes.Buffer
+	if err := md.Convert([]byte(source), &buf); err != nil {
+		return "", err
+	}
+	return policy.Sanitize(buf.String()), nil
+}
+
+// isWordRune indica si r forma parte de una palabra, para no enlazar "abc#12" ni "#12abc"
+func isWordRune(r rune) bool {
+	return unicode.IsLetter(r) || unicode.IsDigit(r) || r == '_' || r == '&' || r == '/'
+}
+
+// newRefLink crea el enlace de una referencia con el texto original como contenido
+func newRefLink(url, class string, segment text.Segment) *ast.Link {
+	link := ast.NewLink()
+	link.Destination = []byte(url)
+	link.SetAttributeString("class", []byte(class))
+	link.AppendChild(link, ast.NewTextSegment(segment))
+	return link
+}
+
+// issueRefParser enlaza las referencias #123 a tickets
+type issueRefParser struct {
+	links Links
+}
+
+func (p *issueRefParser) Trigger() []byte {
+	return []byte{'#'}
+}
+
+func (p *issueRefParser) Parse(parent ast.Node, block text.Reader, pc parser.Context) ast.Node {
+	if p.links.Issue == nil || pc.IsInLinkLabel() || isWordRune(block.PrecendingCharacter()) {
+		return nil
+	}
+
+	line, segment := block.PeekLine()
+	end := 1
+	for end < len(line) && line[end] >= '0' && line[end] <= '9' {
+		end++
+	}
+	if end == 1 || (end < len(line) && isWordRune(rune(line[end]))) {
+		return nil
+	}
+
+	id, err := strconv.Atoi(string(line[1:end]))
+	if err != nil {
+		return nil
+	}
+	url := p.links.Issue(id)
+	if url == "" {
+		return nil
+	}
+
+	block.Advance(end)
+	return newRefLink(url, "issue", segment.WithStop(segment.Start+end))
+}
+
+// usernamePattern son los caracteres de un nombre de usuario tras la @. El punto final se
+// descarta para que "@ana." al final de una frase enlace a ana
+var usernamePattern = regexp.MustCompile(`^@([A-Za-z0-9_.\-]*[A-Za-z0-9_\-])`)
+
+// userRefParser enlaza las menciones @usuario
+type userRefParser struct {
+	links Links
+}
+
+func (p *userRefParser) Trigger() []byte {
+	return []byte{'@'}
+}
+
+func (p *userRefParser) Parse(parent ast.Node, block text.Reader, pc parser.Context) ast.Node {
+	if p.links.User == nil || pc.IsInLinkLabel() || isWordRune(block.PrecendingCharacter()) {
+		return nil
+	}
+
+	line, segment := block.PeekLine()
+	m := usernamePattern.FindSubmatchIndex(line)
+	if m == nil {
+		return nil
+	}
+
+	url := p.links.User(string(line[m[2]:m[3]]))
+	if url == "" {
+		return nil
+	}
+
+	block.Advance(m[1])
+	return newRefLink(url, "user", segment.WithStop(segment.Start+m[1]))
+}
+
+var projectRefPattern = regexp.MustCompile(`^project:([A-Za-z0-9][A-Za-z0-9_\-]*)`)
+
+// projectRefParser enlaza las referencias project:identificador. Como empiezan por
+// una letra se disparan al principio de línea, tras un espacio o tras un paréntesis
+type projectRefParser struct {
+	links Links
+}
+
+func (p *projectRefParser) Trigger() []byte {
+	return []byte{' ', '('}
+}
+
+func (p *projectRefParser) Parse(parent ast.Node, block text.Reader, pc parser.Context) ast.Node {
+	if p.links.Project == nil || pc.IsInLinkLabel() {
+		return nil
+	}
+
+	line, segment := block.PeekLine()
+	consumes := 0
+	if len(line) > 0 && (line[0] == ' ' || line[0] == '(') {
+		consumes = 1
+	}
+
+	m := projectRefPattern.FindSubmatchIndex(line[consumes:])
+	if m == nil {
+		return nil
+	}
+	if end := consumes + m[1]; end < len(line) && isWordRune(rune(line[end])) {
+		return nil
+	}
+
+	url := p.links.Project(string(line[consumes+m[2] : consumes+m[3]]))
+	if url == "" {
+		return nil
+	}
+
+	// El espacio o paréntesis que dispara el parser se conserva como texto
+	if consumes != 0 {
+		ast.MergeOrAppendTextSegment(parent, segment.WithStop(segment.Start+consumes))
+	}
+	block.Advance(consumes + m[1])
+	start := segment.Start + consumes
+	return newRefLink(url, "project", text.NewSegment(start, start+m[1]))
+}
@@ -1,47 +1,56 @@
 package middleware
 
 import (
-	"fmt"
+	"context"
 	"go-redmine-ish/config"
+	"go-redmine-ish/logging"
 	"net/http"
 	"strings"
 
 	"github.com/gin-gonic/gin"
 )
 
+// UserIDKey es la clave del contexto de gin donde se guarda el ID del usuario autenticado.
+// Con el token de servicio (AUTH_TOKEN) no hay usuario y la clave no se establece.
+const UserIDKey = "user_id"
+
 // Middleware de autenticación
 func AuthMiddleware(cfg *config.Config) gin.HandlerFunc {
 	return func(c *gin.Context) {
 
-		fmt.Println("AuthMiddleware")
+		// Los clientes de la API de Redmine envían la clave en X-Redmine-API-Key
+		token := c.GetHeader("X-Redmine-API-Key")
 
-		// Obtener el header Authorization
-		authHeader := c.GetHeader("Authorization")
-		if authHeader == "" {
-			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "Authorization header is required"})
-			return
-		}
+		if token == "" {
+			// Obtener el header Authorization
+			authHeader := c.GetHeader("Authorization")
+			if authHeader == "" {
+				c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "Authorization header is required"})
+				return
+			}
 
-		fmt.Printf("AuthMiddleware - authHeader %s\n", authHeader)
+			// Verificar que el header tenga el formato correcto: "Bearer <token>"
+			parts := strings.Split(authHeader, " ")
+			if len(parts) != 2 || parts[0] != "Bearer" {
+				c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "Invalid Authorization header format"})
+				return
+			}
 
-		// Verificar que el header tenga el formato correcto: "Bearer <token>"
-		parts := strings.Split(authHeader, " ")
-		if len(parts) != 2 || parts[0] != "Bearer" {
-			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "Invalid Authorization header format"})
-			return
+			// Extraer el token
+			token = parts[1]
 		}
 
-		// Extraer el token
-		token := parts[1]
-
-		fmt.Printf("AuthMiddleware - token %s\n", token)
-
 		// Validar el token
 		if token != cfg.AuthToken {
-			if !oauth_token_autorizado(token) {
+			auth_profile, ok := oauth_token_autorizado(c.Request.Context(), token)
+			if !ok {
 				c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "Invalid token"})
 				return
 			}
+			// Guardar el usuario autenticado para los handlers
+			c.Set(UserIDKey, auth_profile.UserID)
+			// Los registros de la petición llevan el usuario a partir de aquí
+			setLogger(c, Logger(c).With(UserIDKey, auth_profile.UserID))
 		}
 
 		// Si el token es válido, continuar con el siguiente handler
@@ -49,31 +58,31 @@ func AuthMiddleware(cfg *config.Config) gin.HandlerFunc {
 	}
 }
 
-func oauth_token_autorizado(token string) bool {
+// oauth_token_autorizado valida el token con el servicio de autenticación. El token nunca se registra
+func oauth_token_autorizado(ctx context.Context, token string) (*AuthProfileData, bool) {
 
-	auth_profile, err := AuthProfile(token)
+	logger := logging.FromContext(ctx)
+	auth_profile, err := AuthProfile(ctx, token)
 	if err != nil {
-		fmt.Println("oauth_token_autorizado error:", err)
-		return false
+		logger.Warn("token rejected", "reason", err)
+		return nil, false
 	}
 	if auth_profile == nil {
-		fmt.Println("oauth_token_autorizado auth_profile es nil")
-		return false
+		logger.Warn("token rejected", "reason", "empty profile")
+		return nil, false
 	}
 	if auth_profile.ClientID == "" {
-		fmt.Println("oauth_token_autorizado auth_profile.ClientID es nil")
-		return false
+		logger.Warn("token rejected", "reason", "profile without client_id")
+		return nil, false
 	}
 	if auth_profile.UserID != 0 {
 		// autorizaciones de usuario
-		if auth_profile.ClientID == "ISSUES" {
-			fmt.Println("oauth_token_autorizado auth_profile.ClientID es ISSUES")
-			return true
-		} else if auth_profile.ClientID == "CRM" {
-			fmt.Println("oauth_token_autorizado auth_profile.ClientID es CRM")
-			return true
+		if auth_profile.ClientID == "ISSUES" || auth_profile.ClientID == "CRM" {
+			logger.Debug("token accepted", "client_id", auth_profile.ClientID, UserIDKey, auth_profile.UserID)
+			return auth_profile, true
 		}
 	}
 
-	return false
+	logger.Warn("token rejected", "reason", "client not allowed", "client_id", auth_profile.ClientID)
+	return nil, false
 }
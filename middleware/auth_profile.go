@@ -1,12 +1,22 @@
 package middleware
 
 import (
+	"context"
 	"encoding/json"
 	"errors"
 	"fmt"
-	"log"
 	"net/http"
 	"os"
+	"time"
+
+	"go-redmine-ish/logging"
+	"go-redmine-ish/telemetry"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/trace"
 )
 
 // AuthProfile representa la estructura del perfil de autenticación
@@ -17,26 +27,41 @@ type AuthProfileData struct {
 	Attributes map[string]string `json:"attributes"`
 }
 
-// authProfile realiza la solicitud para obtener el perfil de autenticación
-func AuthProfile(token string) (*AuthProfileData, error) {
+// authProfile realiza la solicitud para obtener el perfil de autenticación. La latencia se mide
+// por resultado y la llamada es un span hijo del de la petición, propagado al servicio
+func AuthProfile(ctx context.Context, token string) (*AuthProfileData, error) {
 	authProfileURL := os.Getenv("AUTH_PROFILE_URL")
 	if authProfileURL == "" {
 		return nil, errors.New("la variable de entorno AUTH_PROFILE_URL no está definida")
 	}
 
+	ctx, span := telemetry.Tracer().Start(ctx, "oauth.profile", trace.WithSpanKind(trace.SpanKindClient))
+	defer span.End()
+	result := "error"
+	start := time.Now()
+	defer func() {
+		telemetry.ObserveSince(telemetry.OAuthProfileDuration.WithLabelValues(result), start)
+		span.SetAttributes(attribute.String("oauth.result", result))
+		if result == "error" {
+			span.SetStatus(codes.Error, "auth profile request failed")
+		}
+	}()
+	logger := logging.FromContext(ctx)
+
 	// Crear la solicitud HTTP
-	req, err := http.NewRequest("GET", authProfileURL, nil)
+	req, err := http.NewRequestWithContext(ctx, "GET", authProfileURL, nil)
 	if err != nil {
 		return nil, fmt.Errorf("error creando la solicitud: %v", err)
 	}
 	req.Header.Set("Authorization", "Bearer "+token)
+	otel.GetTextMapPropagator().Inject(ctx, propagation.HeaderCarrier(req.Header))
 
 	// Realizar la solicitud
 	client := &http.Client{}
 	resp, err := client.Do(req)
 	if err != nil {
 		// Manejar errores de conexión
-		log.Printf("Error obteniendo perfil: %v", err)
+		logger.Error("auth profile request failed", "error", err)
 		return nil, errors.New("error de conexión con el servicio de autenticación")
 	}
 	defer resp.Body.Close()
@@ -46,17 +71,18 @@ func AuthProfile(token string) (*AuthProfileData, error) {
 	case http.StatusOK:
 		var profile AuthProfileData
 		if err := json.NewDecoder(resp.Body).Decode(&profile); err != nil {
-			log.Printf("Error parseando perfil: %v", err)
+			logger.Error("auth profile response invalid", "error", err)
 			return nil, errors.New("error procesando la respuesta del servidor")
 		}
+		result = "ok"
 		return &profile, nil
 
 	case http.StatusUnauthorized:
-		log.Println("auth_profile response status: 401 Unauthorized")
+		result = "unauthorized"
 		return nil, errors.New("no autorizado")
 
 	default:
-		log.Printf("auth_profile response status: %d", resp.StatusCode)
+		logger.Error("auth profile unexpected status", "status", resp.StatusCode)
 		return nil, errors.New("error interno del servidor")
 	}
 }
@@ -0,0 +1,46 @@
+package middleware
+
+import (
+	"go-redmine-ish/config"
+	"go-redmine-ish/database"
+	"go-redmine-ish/models"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// FeedKeyMiddleware autentica con la clave de feeds del usuario en ?key=,
+// porque los lectores de feeds y calendarios no pueden enviar el header Authorization
+func FeedKeyMiddleware(cfg *config.Config) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		// Se lee de la URL y no con c.Query para no fijar la caché de la query de gin
+		// antes de que ProjectIdentifierMiddleware sustituya project_id
+		key := c.Request.URL.Query().Get("key")
+		if key == "" {
+			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "key parameter is required"})
+			return
+		}
+
+		// Inicializar la base de datos
+		db, err := database.InitDBContext(c.Request.Context(), cfg)
+		if err != nil {
+			c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+
+		user, err := models.GetUserByFeedKey(db, key)
+		db.Close()
+		if err != nil {
+			c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		if user == nil {
+			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "Invalid key"})
+			return
+		}
+
+		c.Set(UserIDKey, user.ID)
+		setLogger(c, Logger(c).With(UserIDKey, user.ID))
+		c.Next()
+	}
+}
@@ -0,0 +1,109 @@
+package middleware
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"go-redmine-ish/logging"
+	"log/slog"
+	"net/http"
+	"regexp"
+	"runtime/debug"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// RequestIDKey es la clave del contexto de gin donde se guarda el ID de la petición
+const RequestIDKey = "request_id"
+
+// RequestIDHeader es la cabecera con el ID de la petición; se respeta la que envía un proxy o el
+// cliente y se devuelve siempre en la respuesta
+const RequestIDHeader = "X-Request-ID"
+
+// requestIDPattern limita los IDs recibidos para que no se pueda inyectar texto en los registros
+var requestIDPattern = regexp.MustCompile(`^[A-Za-z0-9._-]{1,64}$`)
+
+// Logger devuelve el logger de la petición, con su ID y el usuario autenticado
+func Logger(c *gin.Context) *slog.Logger {
+	return logging.FromContext(c.Request.Context())
+}
+
+// setLogger sustituye el logger de la petición
+func setLogger(c *gin.Context, logger *slog.Logger) {
+	c.Request = c.Request.WithContext(logging.WithContext(c.Request.Context(), logger))
+}
+
+// RequestLogger asigna un ID a cada petición y, al terminar, registra en JSON el método, la ruta,
+// el estado y la latencia. Debe ser el primer middleware para medir la petición completa
+func RequestLogger() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		start := time.Now()
+
+		requestID := c.GetHeader(RequestIDHeader)
+		if !requestIDPattern.MatchString(requestID) {
+			requestID = newRequestID()
+		}
+		c.Set(RequestIDKey, requestID)
+		c.Header(RequestIDHeader, requestID)
+		logger := slog.Default().With(RequestIDKey, requestID)
+		// Con Telemetry delante, los registros se pueden cruzar con la traza
+		if span := trace.SpanContextFromContext(c.Request.Context()); span.IsValid() {
+			logger = logger.With("trace_id", span.TraceID().String())
+		}
+		setLogger(c, logger)
+
+		c.Next()
+
+		status := c.Writer.Status()
+		attrs := []any{
+			"method", c.Request.Method,
+			"route", c.FullPath(),
+			"status", status,
+			"latency_ms", float64(time.Since(start).Microseconds()) / 1000,
+			"bytes", max(c.Writer.Size(), 0),
+			"client_ip", c.ClientIP(),
+		}
+		// Sin ruta (404) se registra la ruta pedida, sin la query, que puede llevar la clave ?key=
+		if c.FullPath() == "" {
+			attrs = append(attrs, "path", c.Request.URL.Path)
+		}
+		if len(c.Errors) > 0 {
+			attrs = append(attrs, "errors", c.Errors.String())
+		}
+
+		level := slog.LevelInfo
+		switch {
+		case status >= http.StatusInternalServerError:
+			level = slog.LevelError
+		case status >= http.StatusBadRequest:
+			level = slog.LevelWarn
+		}
+		// El logger de la petición ya lleva el usuario si AuthMiddleware lo ha autenticado
+		Logger(c).Log(c.Request.Context(), level, "request", attrs...)
+	}
+}
+
+// Recovery responde 500 a los pánicos de los handlers y los registra con la traza. A diferencia de
+// gin.Recovery no vuelca las cabeceras de la petición, que llevan los tokens
+func Recovery() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		defer func() {
+			if recovered := recover(); recovered != nil {
+				Logger(c).Error("panic", "error", fmt.Sprint(recovered), "stack", string(debug.Stack()))
+				c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": "Internal server error"})
+			}
+		}()
+		c.Next()
+	}
+}
+
+// newRequestID genera un ID de petición aleatorio
+func newRequestID() string {
+	buf := make([]byte, 16)
+	if _, err := rand.Read(buf); err != nil {
+		return time.Now().UTC().Format("20060102T150405.000000000")
+	}
+	return hex.EncodeToString(buf)
+}
@@ -0,0 +1,138 @@
+package middleware
+
+import (
+	"bytes"
+	"encoding/json"
+	"go-redmine-ish/config"
+	"go-redmine-ish/database"
+	"go-redmine-ish/models"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ProjectIdentifierMiddleware permite usar el identificador del proyecto (su slug) en lugar del ID
+// numérico en las rutas /project/:id/... , en el parámetro project_id de la query y en el campo
+// project_id de primer nivel de los cuerpos JSON (tickets, versiones, plantillas, copia...). El
+// identificador se sustituye por el ID antes de llegar al handler, así que los handlers solo ven IDs
+// numéricos.
+func ProjectIdentifierMiddleware(cfg *config.Config) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		param := ""
+		if strings.HasPrefix(c.FullPath(), "/project/:id") {
+			param = c.Param("id")
+		}
+		query := c.Request.URL.Query()
+		project_id := query.Get("project_id")
+
+		body, body_project_id, err := bodyProjectIdentifier(c)
+		if err != nil {
+			c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		if !isProjectIdentifier(param) && !isProjectIdentifier(project_id) && body_project_id == "" {
+			c.Next()
+			return
+		}
+
+		// Inicializar la base de datos
+		db, err := database.InitDBContext(c.Request.Context(), cfg)
+		if err != nil {
+			c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		defer db.Close()
+
+		resolve := func(identifier string) (string, bool) {
+			project, err := models.GetProjectByIdentifier(db, identifier)
+			if err != nil {
+				c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+				return "", false
+			}
+			if project == nil {
+				c.AbortWithStatusJSON(http.StatusNotFound, gin.H{"error": "Project not found"})
+				return "", false
+			}
+			return strconv.Itoa(project.ID), true
+		}
+
+		if isProjectIdentifier(param) {
+			id, ok := resolve(param)
+			if !ok {
+				return
+			}
+			for i := range c.Params {
+				if c.Params[i].Key == "id" {
+					c.Params[i].Value = id
+				}
+			}
+		}
+
+		if isProjectIdentifier(project_id) {
+			id, ok := resolve(project_id)
+			if !ok {
+				return
+			}
+			query.Set("project_id", id)
+			c.Request.URL.RawQuery = query.Encode()
+		}
+
+		if body_project_id != "" {
+			id, ok := resolve(body_project_id)
+			if !ok {
+				return
+			}
+			body["project_id"] = json.RawMessage(id)
+			encoded, err := json.Marshal(body)
+			if err != nil {
+				c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+				return
+			}
+			c.Request.Body = io.NopCloser(bytes.NewReader(encoded))
+			c.Request.ContentLength = int64(len(encoded))
+		}
+
+		c.Next()
+	}
+}
+
+// isProjectIdentifier indica si el valor es un identificador de proyecto y no un ID numérico
+func isProjectIdentifier(value string) bool {
+	if value == "" {
+		return false
+	}
+	_, err := strconv.Atoi(value)
+	return err != nil
+}
+
+// bodyProjectIdentifier lee el cuerpo JSON de la petición y, si su project_id es un identificador,
+// lo devuelve junto con el cuerpo decodificado para sustituirlo. El cuerpo se deja como estaba
+// para el handler, que es quien responde si no es JSON válido
+func bodyProjectIdentifier(c *gin.Context) (map[string]json.RawMessage, string, error) {
+	if c.Request.Body == nil || !strings.Contains(c.ContentType(), "json") {
+		return nil, "", nil
+	}
+	raw, err := io.ReadAll(c.Request.Body)
+	if err != nil {
+		return nil, "", err
+	}
+	c.Request.Body = io.NopCloser(bytes.NewReader(raw))
+	if !bytes.Contains(raw, []byte(`"project_id"`)) {
+		return nil, "", nil
+	}
+
+	var body map[string]json.RawMessage
+	if err := json.Unmarshal(raw, &body); err != nil {
+		return nil, "", nil
+	}
+	var identifier string
+	if err := json.Unmarshal(body["project_id"], &identifier); err != nil || !isProjectIdentifier(identifier) {
+		return nil, "", nil
+	}
+
+	return body, identifier, nil
+}
@@ -0,0 +1,62 @@
+package middleware
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"go-redmine-ish/telemetry"
+
+	"github.com/gin-gonic/gin"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// Telemetry abre el span de cada petición, continuando la traza de la cabecera traceparent si la
+// hay, y registra las métricas de peticiones. Debe ir antes de RequestLogger para que los registros
+// lleven el trace_id
+func Telemetry() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		start := time.Now()
+
+		// La ruta de gin (/issue/:id) y no la pedida, para no disparar el número de series
+		route := c.FullPath()
+		if route == "" {
+			route = "unmatched"
+		}
+
+		ctx := otel.GetTextMapPropagator().Extract(c.Request.Context(), propagation.HeaderCarrier(c.Request.Header))
+		ctx, span := telemetry.Tracer().Start(ctx, c.Request.Method+" "+route,
+			trace.WithSpanKind(trace.SpanKindServer),
+			trace.WithAttributes(
+				attribute.String("http.request.method", c.Request.Method),
+				attribute.String("http.route", route),
+				attribute.String("url.path", c.Request.URL.Path),
+			),
+		)
+		defer span.End()
+		c.Request = c.Request.WithContext(ctx)
+
+		telemetry.HTTPInFlight.Inc()
+		defer telemetry.HTTPInFlight.Dec()
+
+		c.Next()
+
+		status := c.Writer.Status()
+		span.SetAttributes(attribute.Int("http.response.status_code", status))
+		if value, ok := c.Get(UserIDKey); ok {
+			if user_id, ok := value.(int); ok {
+				span.SetAttributes(attribute.Int("enduser.id", user_id))
+			}
+		}
+		if status >= http.StatusInternalServerError {
+			span.SetStatus(codes.Error, http.StatusText(status))
+		}
+
+		telemetry.HTTPRequests.WithLabelValues(c.Request.Method, route, strconv.Itoa(status)).Inc()
+		telemetry.ObserveSince(telemetry.HTTPDuration.WithLabelValues(c.Request.Method, route), start)
+	}
+}
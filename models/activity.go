@@ -0,0 +1,126 @@
+package models
+
+import (
+	"database/sql"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+// Tipos de evento de la actividad de un proyecto
+const (
+	ActivityIssue      = "issue"
+	ActivityStatus     = "status"
+	ActivityComment    = "comment"
+	ActivityTimeEntry  = "time_entry"
+	ActivityMembership = "membership"
+)
+
+var ActivityTypes = []string{ActivityIssue, ActivityStatus, ActivityComment, ActivityTimeEntry, ActivityMembership}
+
+// ActivityEvent es un evento de la actividad: el alta de un ticket, un cambio de estado,
+// un comentario, una imputación de horas o un cambio de miembros
+type ActivityEvent struct {
+	Type      string    `json:"type"`
+	ID        int       `json:"id"` // ID en la tabla de origen del evento
+	CreatedAt time.Time `json:"created_at"`
+	ProjectID int       `json:"project_id"`
+	UserID    *int      `json:"user_id"`
+	IssueID   *int      `json:"issue_id,omitempty"`
+	Title     string    `json:"title"`
+	Detail    string    `json:"detail,omitempty"`
+}
+
+// ActivityCursor es la posición del último evento devuelto; la página siguiente empieza justo después
+type ActivityCursor struct {
+	CreatedAt time.Time
+	Type      string
+	ID        int
+}
+
+// ActivityFilter restringe los eventos devueltos por GetActivity
+type ActivityFilter struct {
+	ProjectIDs []int // nil para todos los proyectos
+	Types      []string
+	UserID     int
+	After      *ActivityCursor
+	Limit      int
+}
+
+// activityQuery une todas las fuentes de eventos con las mismas columnas
+const activityQuery = `
+	SELECT 'issue' AS type, i.id, i.created_at, i.project_id, i.author_id AS user_id, i.id AS issue_id,
+		i.subject AS title, '' AS detail
+	FROM issues i
+	UNION ALL
+	SELECT 'status', ch.id, ch.created_at, i.project_id, ch.user_id, i.id,
+		i.subject, COALESCE(ch.old_value, '') || ' -> ' || COALESCE(ch.new_value, '')
+	FROM issue_changes ch JOIN issues i ON i.id = ch.issue_id
+	WHERE ch.field = 'status'
+	UNION ALL
+	SELECT 'comment', cm.id, cm.created_at, i.project_id, cm.user_id, i.id,
+		i.subject, cm.content
+	FROM comments cm JOIN issues i ON i.id = cm.issue_id
+	UNION ALL
+	SELECT 'time_entry', te.id, te.created_at, te.project_id, te.user_id, te.issue_id,
+		COALESCE(i.subject, ''), te.hours::text || 'h ' || COALESCE(te.comments, '')
+	FROM time_entries te LEFT JOIN issues i ON i.id = te.issue_id
+	UNION ALL
+	SELECT 'membership', mc.id, mc.created_at, mc.project_id, mc.author_id, NULL::int,
+		u.username, mc.action || COALESCE(' (' || r.name || ')', '')
+	FROM member_changes mc
+	JOIN users u ON u.id = mc.user_id
+	LEFT JOIN roles r ON r.id = mc.role_id`
+
+// GetActivity obtiene los eventos que cumplen el filtro, del más reciente al más antiguo
+func GetActivity(db *sql.DB, f ActivityFilter) ([]ActivityEvent, error) {
+	conditions := []string{}
+	args := []interface{}{}
+	arg := func(value interface{}) string {
+		args = append(args, value)
+		return fmt.Sprintf("$%d", len(args))
+	}
+
+	if f.ProjectIDs != nil {
+		conditions = append(conditions, "ev.project_id = ANY("+arg(pq.Array(f.ProjectIDs))+")")
+	}
+	if len(f.Types) > 0 {
+		conditions = append(conditions, "ev.type = ANY("+arg(pq.Array(f.Types))+")")
+	}
+	if f.UserID > 0 {
+		conditions = append(conditions, "ev.user_id = "+arg(f.UserID))
+	}
+	if f.After != nil {
+		conditions = append(conditions, fmt.Sprintf("(ev.created_at, ev.type, ev.id) < (%s, %s, %s)",
+			arg(f.After.CreatedAt), arg(f.After.Type), arg(f.After.ID)))
+	}
+
+	query := `SELECT ev.type, ev.id, ev.created_at, ev.project_id, ev.user_id, ev.issue_id, ev.title, ev.detail FROM (` + activityQuery + `) ev`
+	if len(conditions) > 0 {
+		query += " WHERE " + strings.Join(conditions, " AND ")
+	}
+	query += " ORDER BY ev.created_at DESC, ev.type DESC, ev.id DESC"
+	if f.Limit > 0 {
+		query += " LIMIT " + arg(f.Limit)
+	}
+
+	rows, err := db.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	events := []ActivityEvent{}
+	for rows.Next() {
+		event := ActivityEvent{}
+		err := rows.Scan(&event.Type, &event.ID, &event.CreatedAt, &event.ProjectID, &event.UserID, &event.IssueID, &event.Title, &event.Detail)
+		if err != nil {
+			return nil, err
+		}
+		events = append(events, event)
+	}
+
+	return events, nil
+}
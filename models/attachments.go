@@ -0,0 +1,153 @@
+package models
+
+import (
+	"database/sql"
+)
+
+/*
+CREATE TABLE IF NOT EXISTS attachments (
+	id SERIAL PRIMARY KEY,
+	issue_id INT NOT NULL,              -- Ticket al que pertenece
+	comment_id INT,                     -- Comentario con el que llegó, si lo hay
+	author_id INT,                      -- Usuario que lo subió (NULL si fue el token de servicio)
+	filename VARCHAR(255) NOT NULL,
+	content_type VARCHAR(255) NOT NULL,
+	size INT NOT NULL,
+	content BYTEA NOT NULL,
+	created_at TIMESTAMP DEFAULT NOW(),
+	FOREIGN KEY (issue_id) REFERENCES issues(id) ON DELETE CASCADE,
+	FOREIGN KEY (comment_id) REFERENCES comments(id) ON DELETE SET NULL,
+	FOREIGN KEY (author_id) REFERENCES users(id) ON DELETE SET NULL
+);
+*/
+
+// Attachment es un fichero adjunto a un ticket. El contenido se lee aparte con GetAttachmentContent
+type Attachment struct {
+	ID          int    `json:"id"`
+	IssueID     int    `json:"issue_id"`
+	CommentID   *int   `json:"comment_id"`
+	AuthorID    *int   `json:"author_id"`
+	Filename    string `json:"filename"`
+	ContentType string `json:"content_type"`
+	Size        int    `json:"size"`
+	CreatedAt   string `json:"created_at"`
+}
+
+const attachmentColumns = `id, issue_id, comment_id, author_id, filename, content_type, size, created_at`
+
+func scanAttachment(row interface{ Scan(...interface{}) error }, attachment *Attachment) error {
+	return row.Scan(&attachment.ID, &attachment.IssueID, &attachment.CommentID, &attachment.AuthorID,
+		&attachment.Filename, &attachment.ContentType, &attachment.Size, &attachment.CreatedAt)
+}
+
+// CreateAttachment guarda un adjunto con su contenido
+func CreateAttachment(db DBTX, attachment *Attachment, content []byte) (int, error) {
+	query := `
+	INSERT INTO attachments (issue_id, comment_id, author_id, filename, content_type, size, content)
+	VALUES ($1, $2, $3, $4, $5, $6, $7)
+	RETURNING id`
+
+	attachment.Size = len(content)
+
+	var id int
+	err := db.QueryRow(query, attachment.IssueID, attachment.CommentID, attachment.AuthorID,
+		attachment.Filename, attachment.ContentType, attachment.Size, content).Scan(&id)
+	if err != nil {
+		return 0, err
+	}
+
+	return id, nil
+}
+
+// CopyAttachment duplica un adjunto, con su contenido, en otro ticket y opcionalmente otro
+// comentario. Conserva el autor y la fecha de subida del original
+func CopyAttachment(db DBTX, id int, issueID int, commentID *int) (int, error) {
+	query := `
+	INSERT INTO attachments (issue_id, comment_id, author_id, filename, content_type, size, content, created_at)
+	SELECT $2, $3, author_id, filename, content_type, size, content, created_at
+	FROM attachments WHERE id = $1
+	RETURNING id`
+
+	var new_id int
+	if err := db.QueryRow(query, id, issueID, commentID).Scan(&new_id); err != nil {
+		return 0, err
+	}
+
+	return new_id, nil
+}
+
+// GetAttachmentByID obtiene un adjunto sin su contenido, o nil si no existe
+func GetAttachmentByID(db *sql.DB, id int) (*Attachment, error) {
+	query := `SELECT ` + attachmentColumns + ` FROM attachments WHERE id = $1`
+
+	var attachment Attachment
+	err := scanAttachment(db.QueryRow(query, id), &attachment)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return &attachment, nil
+}
+
+// GetAttachmentsByIssueID obtiene los adjuntos de un ticket en orden de subida
+func GetAttachmentsByIssueID(db *sql.DB, issueID int) ([]Attachment, error) {
+	query := `SELECT ` + attachmentColumns + ` FROM attachments WHERE issue_id = $1 ORDER BY created_at, id`
+
+	rows, err := db.Query(query, issueID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	attachments := []Attachment{}
+	for rows.Next() {
+		var attachment Attachment
+		if err := scanAttachment(rows, &attachment); err != nil {
+			return nil, err
+		}
+		attachments = append(attachments, attachment)
+	}
+
+	return attachments, nil
+}
+
+// GetAttachmentContent obtiene el contenido de un adjunto
+func GetAttachmentContent(db *sql.DB, id int) ([]byte, error) {
+	query := `SELECT content FROM attachments WHERE id = $1`
+
+	var content []byte
+	if err := db.QueryRow(query, id).Scan(&content); err != nil {
+		return nil, err
+	}
+
+	return content, nil
+}
+
+func CreateAttachmentsTable(db *sql.DB) error {
+	query := `
+	CREATE TABLE IF NOT EXISTS attachments (
+		id SERIAL PRIMARY KEY,
+		issue_id INT NOT NULL,
+		comment_id INT,
+		author_id INT,
+		filename VARCHAR(255) NOT NULL,
+		content_type VARCHAR(255) NOT NULL,
+		size INT NOT NULL,
+		content BYTEA NOT NULL,
+		created_at TIMESTAMP DEFAULT NOW(),
+		FOREIGN KEY (issue_id) REFERENCES issues(id) ON DELETE CASCADE,
+		FOREIGN KEY (comment_id) REFERENCES comments(id) ON DELETE SET NULL,
+		FOREIGN KEY (author_id) REFERENCES users(id) ON DELETE SET NULL
+	)`
+	_, err := db.Exec(query)
+	return err
+}
+
+func DropAttachmentsTable(db *sql.DB) error {
+	query := `DROP TABLE IF EXISTS attachments`
+	_, err := db.Exec(query)
+	return err
+}
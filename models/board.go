@@ -0,0 +1,123 @@
+package models
+
+import (
+	"database/sql"
+
+	"github.com/lib/pq"
+)
+
+/*
+CREATE TABLE IF NOT EXISTS board_wip_limits (
+	project_id INT NOT NULL,            -- Proyecto del tablero
+	status_id INT NOT NULL,             -- Columna del tablero
+	wip_limit INT NOT NULL CHECK (wip_limit > 0), -- Máximo de tickets en la columna
+	PRIMARY KEY (project_id, status_id)
+);
+*/
+
+// BoardWIPLimit es el máximo de tickets en curso de una columna (estado) del tablero de un proyecto
+type BoardWIPLimit struct {
+	StatusID int `json:"status_id"`
+	WIPLimit int `json:"wip_limit"`
+}
+
+// GetBoardWIPLimits obtiene los límites del tablero de un proyecto, por ID de estado
+func GetBoardWIPLimits(db *sql.DB, projectID int) (map[int]int, error) {
+	query := `SELECT status_id, wip_limit FROM board_wip_limits WHERE project_id = $1`
+
+	rows, err := db.Query(query, projectID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	limits := map[int]int{}
+	for rows.Next() {
+		var limit BoardWIPLimit
+		if err := rows.Scan(&limit.StatusID, &limit.WIPLimit); err != nil {
+			return nil, err
+		}
+		limits[limit.StatusID] = limit.WIPLimit
+	}
+
+	return limits, nil
+}
+
+// SetBoardWIPLimits sustituye los límites del tablero de un proyecto
+func SetBoardWIPLimits(db DBTX, projectID int, limits []BoardWIPLimit) error {
+	if _, err := db.Exec(`DELETE FROM board_wip_limits WHERE project_id = $1`, projectID); err != nil {
+		return err
+	}
+
+	for _, limit := range limits {
+		query := `INSERT INTO board_wip_limits (project_id, status_id, wip_limit) VALUES ($1, $2, $3)`
+		if _, err := db.Exec(query, projectID, limit.StatusID, limit.WIPLimit); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// GetBoardColumnIssueIDs obtiene los tickets de una columna del tablero en su orden, bloqueándolos
+// hasta el final de la transacción para que dos movimientos no se pisen al renumerar
+func GetBoardColumnIssueIDs(db DBTX, projectID int, status string) ([]int, error) {
+	query := `
+	SELECT id FROM issues
+	WHERE project_id = $1 AND status = $2
+	ORDER BY position NULLS LAST, id
+	FOR UPDATE`
+
+	rows, err := db.Query(query, projectID, status)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	ids := []int{}
+	for rows.Next() {
+		var id int
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		ids = append(ids, id)
+	}
+
+	return ids, nil
+}
+
+// SetIssuePositions numera los tickets en el orden dado, desde 1. La posición no es un cambio
+// del ticket: no modifica updated_at ni lock_version
+func SetIssuePositions(db DBTX, ids []int) error {
+	if len(ids) == 0 {
+		return nil
+	}
+
+	query := `
+	UPDATE issues SET position = ordered.position
+	FROM unnest($1::int[]) WITH ORDINALITY AS ordered(id, position)
+	WHERE issues.id = ordered.id`
+
+	_, err := db.Exec(query, pq.Array(ids))
+	return err
+}
+
+func CreateBoardWIPLimitsTable(db *sql.DB) error {
+	query := `
+	CREATE TABLE IF NOT EXISTS board_wip_limits (
+		project_id INT NOT NULL,
+		status_id INT NOT NULL,
+		wip_limit INT NOT NULL CHECK (wip_limit > 0),
+		PRIMARY KEY (project_id, status_id),
+		FOREIGN KEY (project_id) REFERENCES projects(id) ON DELETE CASCADE,
+		FOREIGN KEY (status_id) REFERENCES issue_statuses(id) ON DELETE CASCADE
+	)`
+	_, err := db.Exec(query)
+	return err
+}
+
+func DropBoardWIPLimitsTable(db *sql.DB) error {
+	query := `DROP TABLE IF EXISTS board_wip_limits`
+	_, err := db.Exec(query)
+	return err
+}
@@ -12,7 +12,7 @@ type Category struct {
 }
 
 // CreateCategory crea una nueva categoría
-func CreateCategory(db *sql.DB, category *Category) (int, error) {
+func CreateCategory(db DBTX, category *Category) (int, error) {
 	query := `
 	INSERT INTO categories (project_id, name, assigned_to_id)
 	VALUES ($1, $2, $3)
@@ -148,3 +148,21 @@ func SampleCategories(db *sql.DB) error {
 	_, err := db.Exec(query)
 	return err
 }
+
+// GetCategoryByProjectAndName obtiene la categoría de un proyecto con ese nombre, o nil si no existe
+func GetCategoryByProjectAndName(db *sql.DB, projectID int, name string) (*Category, error) {
+	query := `
+	SELECT id, project_id, name, assigned_to_id, created_at, updated_at
+	FROM categories
+	WHERE project_id = $1 AND name = $2`
+	category := &Category{}
+	err := db.QueryRow(query, projectID, name).Scan(&category.ID, &category.ProjectID, &category.Name, &category.AssignedToID, &category.CreatedAt, &category.UpdatedAt)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return category, nil
+}
@@ -22,10 +22,13 @@ type Comment struct {
 	Content   string `json:"content"`
 	CreatedAt string `json:"created_at"`
 	UpdatedAt string `json:"updated_at"`
+
+	// ContentHTML es el contenido convertido a HTML, solo con ?render=html
+	ContentHTML string `json:"content_html,omitempty"`
 }
 
 // CreateComment crea un nuevo comentario
-func CreateComment(db *sql.DB, comment *Comment) (int, error) {
+func CreateComment(db DBTX, comment *Comment) (int, error) {
 	query := `
 	INSERT INTO comments (issue_id, user_id, content)
 	VALUES ($1, $2, $3)
@@ -93,6 +96,13 @@ func UpdateComment(db *sql.DB, comment *Comment) error {
 	return nil
 }
 
+// SetCommentTimestamps fija las fechas de creación y actualización de un comentario (importaciones)
+func SetCommentTimestamps(db DBTX, id int, createdAt, updatedAt string) error {
+	query := `UPDATE comments SET created_at = $1, updated_at = $2 WHERE id = $3`
+	_, err := db.Exec(query, createdAt, updatedAt, id)
+	return err
+}
+
 // DeleteComment elimina un comentario
 func DeleteComment(db *sql.DB, id int) error {
 	query := `DELETE FROM comments WHERE id = $1`
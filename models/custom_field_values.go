@@ -1,6 +1,10 @@
 package models
 
-import "database/sql"
+import (
+	"database/sql"
+
+	"github.com/lib/pq"
+)
 
 /*
 CREATE TABLE IF NOT EXISTS custom_field_values (
@@ -27,7 +31,7 @@ type CustomFieldValue struct {
 }
 
 // CreateCustomFieldValue crea un nuevo valor de campo personalizado
-func CreateCustomFieldValue(db *sql.DB, customFieldValue *CustomFieldValue) (int, error) {
+func CreateCustomFieldValue(db DBTX, customFieldValue *CustomFieldValue) (int, error) {
 	query := `
 	INSERT INTO custom_field_values (custom_field_id, entity_type, entity_id, value)
 	VALUES ($1, $2, $3, $4)
@@ -90,6 +94,24 @@ func UpdateCustomFieldValue(db *sql.DB, customFieldValue *CustomFieldValue) erro
 	return err
 }
 
+// SetCustomFieldValue fija el valor de un campo personalizado de una entidad, creándolo si no tenía
+func SetCustomFieldValue(db DBTX, customFieldValue *CustomFieldValue) error {
+	query := `
+	UPDATE custom_field_values
+	SET value = $1, updated_at = NOW()
+	WHERE custom_field_id = $2 AND entity_type = $3 AND entity_id = $4`
+	result, err := db.Exec(query, customFieldValue.Value, customFieldValue.CustomFieldID, customFieldValue.EntityType, customFieldValue.EntityID)
+	if err != nil {
+		return err
+	}
+	if updated, err := result.RowsAffected(); err != nil || updated > 0 {
+		return err
+	}
+
+	_, err = CreateCustomFieldValue(db, customFieldValue)
+	return err
+}
+
 // DeleteCustomFieldValue elimina un valor de campo personalizado
 func DeleteCustomFieldValue(db *sql.DB, id int) error {
 	query := `DELETE FROM custom_field_values WHERE id = $1`
@@ -165,3 +187,52 @@ func DropCustomFieldValuesTable(db *sql.DB) error {
 	_, err := db.Exec(query)
 	return err
 }
+
+// GetCustomFieldValuesByEntityType obtiene todos los valores de campo personalizado de un tipo de entidad
+func GetCustomFieldValuesByEntityType(db *sql.DB, entityType string) ([]CustomFieldValue, error) {
+	query := `
+	SELECT id, custom_field_id, entity_type, entity_id, value, created_at, updated_at
+	FROM custom_field_values
+	WHERE entity_type = $1`
+	rows, err := db.Query(query, entityType)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	customFieldValues := []CustomFieldValue{}
+	for rows.Next() {
+		var customFieldValue CustomFieldValue
+		if err := rows.Scan(&customFieldValue.ID, &customFieldValue.CustomFieldID, &customFieldValue.EntityType, &customFieldValue.EntityID, &customFieldValue.Value, &customFieldValue.CreatedAt, &customFieldValue.UpdatedAt); err != nil {
+			return nil, err
+		}
+		customFieldValues = append(customFieldValues, customFieldValue)
+	}
+
+	return customFieldValues, nil
+}
+
+// GetCustomFieldValuesByEntityIDs obtiene los valores de campo personalizado de varias entidades
+// de un tipo, p. ej. los de los tickets de una exportación
+func GetCustomFieldValuesByEntityIDs(db *sql.DB, entityType string, entityIDs []int) ([]CustomFieldValue, error) {
+	query := `
+	SELECT id, custom_field_id, entity_type, entity_id, value, created_at, updated_at
+	FROM custom_field_values
+	WHERE entity_type = $1 AND entity_id = ANY($2)`
+	rows, err := db.Query(query, entityType, pq.Array(entityIDs))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	customFieldValues := []CustomFieldValue{}
+	for rows.Next() {
+		var customFieldValue CustomFieldValue
+		if err := rows.Scan(&customFieldValue.ID, &customFieldValue.CustomFieldID, &customFieldValue.EntityType, &customFieldValue.EntityID, &customFieldValue.Value, &customFieldValue.CreatedAt, &customFieldValue.UpdatedAt); err != nil {
+			return nil, err
+		}
+		customFieldValues = append(customFieldValues, customFieldValue)
+	}
+
+	return customFieldValues, nil
+}
@@ -0,0 +1,11 @@
+package models
+
+import "database/sql"
+
+// DBTX es la interfaz común de *sql.DB y *sql.Tx, para las funciones que
+// también deben poder ejecutarse dentro de una transacción
+type DBTX interface {
+	Exec(query string, args ...interface{}) (sql.Result, error)
+	Query(query string, args ...interface{}) (*sql.Rows, error)
+	QueryRow(query string, args ...interface{}) *sql.Row
+}
@@ -2,39 +2,109 @@ package models
 
 import (
 	"database/sql"
+	"errors"
 	"fmt"
-	"log"
+	"strings"
+
+	"github.com/lib/pq"
 )
 
 // Issue representa un ticket o incidencia
 type Issue struct {
-	ID           int    `json:"id"`
-	Subject      string `json:"subject"`
-	Description  string `json:"description"`
-	TrackerID    int    `json:"tracker_id"`
-	ProjectID    int    `json:"project_id"`
-	AssignedToID *int   `json:"assigned_to_id"`
-	Status       string `json:"status"`
-	CategoryID   *int   `json:"category_id"`
-	CreatedAt    string `json:"created_at"`
-	UpdatedAt    string `json:"updated_at"`
+	ID             int      `json:"id"`
+	Subject        string   `json:"subject"`
+	Description    string   `json:"description"`
+	TrackerID      int      `json:"tracker_id"`
+	ProjectID      int      `json:"project_id"`
+	AssignedToID   *int     `json:"assigned_to_id"`
+	Status         string   `json:"status"`
+	CategoryID     *int     `json:"category_id"`
+	FixedVersionID *int     `json:"fixed_version_id"` // versión del proyecto en la que se planifica
+	ParentID       *int     `json:"parent_id"`        // ticket padre, del que este es una subtarea
+	PriorityID     int      `json:"priority_id"`      // 0 al crear usa la prioridad por defecto
+	AuthorID       *int     `json:"author_id"`        // usuario que lo creó, no se modifica al actualizar
+	StartDate      *string  `json:"start_date"`       // YYYY-MM-DD
+	DueDate        *string  `json:"due_date"`         // YYYY-MM-DD, no anterior a start_date
+	DoneRatio      int      `json:"done_ratio"`       // porcentaje realizado, de 0 a 100
+	EstimatedHours *float64 `json:"estimated_hours"`  // horas estimadas, no negativas
+	Overdue        bool     `json:"overdue"`          // calculado: vencido y sin cerrar
+	Position       *int     `json:"position"`         // orden en su columna del tablero, solo lo cambia MoveBoardIssue
+	CreatedAt      string   `json:"created_at"`
+	UpdatedAt      string   `json:"updated_at"`
+	LockVersion    int      `json:"lock_version"` // versión leída, para detectar ediciones concurrentes
+
+	// DescriptionHTML es la descripción convertida a HTML, solo con ?render=html
+	DescriptionHTML string `json:"description_html,omitempty"`
+}
+
+// ErrStaleObject indica que el registro cambió desde que se leyó (lock_version distinta)
+var ErrStaleObject = errors.New("the record was modified by someone else")
+
+// IsPermanentError indica si un error de PostgreSQL se repetiría al reintentar con los mismos
+// datos: datos no válidos (clase 22) o restricciones incumplidas (clase 23)
+func IsPermanentError(err error) bool {
+	var pqErr *pq.Error
+	if !errors.As(err, &pqErr) {
+		return false
+	}
+	class := pqErr.Code.Class()
+	return class == "22" || class == "23"
+}
+
+// issueColumns son las columnas que lee scanIssue, en orden
+const issueColumns = `
+			id, subject, description, tracker_id, project_id,
+			assigned_to_id, status, category_id, fixed_version_id, parent_id,
+			COALESCE(priority_id, 0), author_id,
+			to_char(start_date, 'YYYY-MM-DD'), to_char(due_date, 'YYYY-MM-DD'), done_ratio, estimated_hours,
+			COALESCE(due_date < CURRENT_DATE AND status NOT IN (SELECT name FROM issue_statuses WHERE is_closed), FALSE),
+			position, created_at, updated_at, lock_version`
+
+func scanIssue(row interface{ Scan(...interface{}) error }, issue *Issue) error {
+	return row.Scan(
+		&issue.ID,
+		&issue.Subject,
+		&issue.Description,
+		&issue.TrackerID,
+		&issue.ProjectID,
+		&issue.AssignedToID,
+		&issue.Status,
+		&issue.CategoryID,
+		&issue.FixedVersionID,
+		&issue.ParentID,
+		&issue.PriorityID,
+		&issue.AuthorID,
+		&issue.StartDate,
+		&issue.DueDate,
+		&issue.DoneRatio,
+		&issue.EstimatedHours,
+		&issue.Overdue,
+		&issue.Position,
+		&issue.CreatedAt,
+		&issue.UpdatedAt,
+		&issue.LockVersion,
+	)
 }
 
 // CreateIssue crea un nuevo ticket
-func CreateIssue(db *sql.DB, issue *Issue) (int, error) {
+func CreateIssue(db DBTX, issue *Issue) (int, error) {
 	query := `
 		INSERT INTO issues (
 			subject, description, tracker_id, project_id, 
-			assigned_to_id, status, category_id
+			assigned_to_id, status, category_id, fixed_version_id, parent_id,
+			priority_id, author_id, start_date, due_date, done_ratio, estimated_hours
 		) VALUES (
-		 	$1, $2, $3, $4, $5, $6, $7
-		) RETURNING id`
+		 	$1, $2, $3, $4, $5, $6, $7, $8, $9,
+			COALESCE(NULLIF($10, 0), (SELECT id FROM issue_priorities WHERE is_default ORDER BY position LIMIT 1)),
+			$11, $12, $13, $14, $15
+		) RETURNING id, COALESCE(priority_id, 0)`
 
 	var id int
 	err := db.QueryRow(query,
 		issue.Subject, issue.Description, issue.TrackerID, issue.ProjectID,
-		issue.AssignedToID, issue.Status, issue.CategoryID,
-	).Scan(&id)
+		issue.AssignedToID, issue.Status, issue.CategoryID, issue.FixedVersionID, issue.ParentID,
+		issue.PriorityID, issue.AuthorID, issue.StartDate, issue.DueDate, issue.DoneRatio, issue.EstimatedHours,
+	).Scan(&id, &issue.PriorityID)
 	if err != nil {
 		return 0, err
 	}
@@ -45,18 +115,12 @@ func CreateIssue(db *sql.DB, issue *Issue) (int, error) {
 // GetIssueByID obtiene un ticket por su ID
 func GetIssueByID(db *sql.DB, id int) (*Issue, error) {
 	query := `
-		SELECT
-			id, subject, description, tracker_id, project_id,
-			assigned_to_id, status, category_id,
-			created_at, updated_at
+		SELECT ` + issueColumns + `
 		FROM issues
 			WHERE id = $1`
 
 	issue := &Issue{}
-	err := db.QueryRow(query, id).Scan(
-		&issue.ID, &issue.Subject, &issue.Description, &issue.TrackerID, &issue.ProjectID,
-		&issue.AssignedToID, &issue.Status, &issue.CategoryID,
-		&issue.CreatedAt, &issue.UpdatedAt)
+	err := scanIssue(db.QueryRow(query, id), issue)
 	if err != nil {
 		return nil, err
 	}
@@ -67,10 +131,7 @@ func GetIssueByID(db *sql.DB, id int) (*Issue, error) {
 // GetIssuesByProjectID obtiene todos los tickets de un proyecto
 func GetIssuesByProjectID(db *sql.DB, projectID int) ([]Issue, error) {
 	query := `
-	SELECT
-		id, subject, description, tracker_id, project_id,
-		assigned_to_id, status, category_id,
-		created_at, updated_at
+	SELECT ` + issueColumns + `
 	FROM issues
 		WHERE project_id = $1`
 
@@ -83,18 +144,7 @@ func GetIssuesByProjectID(db *sql.DB, projectID int) ([]Issue, error) {
 	var issues []Issue
 	for rows.Next() {
 		var issue Issue
-		err := rows.Scan(
-			&issue.ID,
-			&issue.Subject,
-			&issue.Description,
-			&issue.TrackerID,
-			&issue.ProjectID,
-			&issue.AssignedToID,
-			&issue.Status,
-			&issue.CategoryID,
-			&issue.CreatedAt,
-			&issue.UpdatedAt,
-		)
+		err := scanIssue(rows, &issue)
 		if err != nil {
 			return nil, err
 		}
@@ -107,15 +157,11 @@ func GetIssuesByProjectID(db *sql.DB, projectID int) ([]Issue, error) {
 
 func GetIssuesByCategoryID(db *sql.DB, categoryID int) ([]Issue, error) {
 	query := fmt.Sprintf(`
-		SELECT 
-			id, subject, description, tracker_id, project_id,
-			assigned_to_id, status, category_id,
-			created_at, updated_at
+		SELECT `+issueColumns+`
 		FROM issues where category_id = %d`, categoryID)
 
 	rows, err := db.Query(query)
 	if err != nil {
-		log.Printf("Error GetIssuesByCategoryID querying issues by category ID %v %v\n", query, err)
 		return nil, err
 	}
 	defer rows.Close()
@@ -123,18 +169,7 @@ func GetIssuesByCategoryID(db *sql.DB, categoryID int) ([]Issue, error) {
 	var issues []Issue
 	for rows.Next() {
 		var issue Issue
-		err := rows.Scan(
-			&issue.ID,
-			&issue.Subject,
-			&issue.Description,
-			&issue.TrackerID,
-			&issue.ProjectID,
-			&issue.AssignedToID,
-			&issue.Status,
-			&issue.CategoryID,
-			&issue.CreatedAt,
-			&issue.UpdatedAt,
-		)
+		err := scanIssue(rows, &issue)
 		if err != nil {
 			return nil, err
 		}
@@ -147,37 +182,21 @@ func GetIssuesByCategoryID(db *sql.DB, categoryID int) ([]Issue, error) {
 
 func GetIssuesByProjectWhereCategoryIsNull(db *sql.DB, projectID int) ([]Issue, error) {
 	query := fmt.Sprintf(`
-		SELECT 
-			id, subject, description, tracker_id, project_id,
-			assigned_to_id, status,
-			created_at, updated_at
+		SELECT `+issueColumns+`
 		FROM issues
 		where project_id = %d
 		AND category_id IS NULL`, projectID)
 
 	rows, err := db.Query(query)
 	if err != nil {
-		log.Printf("Error GetIssuesByProjectWhereCategoryIsNull executing query %v %v\n", query, err)
 		return nil, err
-	} else {
-		log.Printf("GetIssuesByProjectWhereCategoryIsNull executed query %v\n", query)
 	}
 	defer rows.Close()
 
 	var issues []Issue
 	for rows.Next() {
 		var issue Issue
-		err := rows.Scan(
-			&issue.ID,
-			&issue.Subject,
-			&issue.Description,
-			&issue.TrackerID,
-			&issue.ProjectID,
-			&issue.AssignedToID,
-			&issue.Status,
-			&issue.CreatedAt,
-			&issue.UpdatedAt,
-		)
+		err := scanIssue(rows, &issue)
 		if err != nil {
 			return nil, err
 		}
@@ -185,8 +204,6 @@ func GetIssuesByProjectWhereCategoryIsNull(db *sql.DB, projectID int) ([]Issue,
 		issues = append(issues, issue)
 	}
 
-	log.Printf("GetIssuesByProjectWhereCategoryIsNull found %d issues for project ID %d\n", len(issues), projectID)
-
 	return issues, nil
 }
 
@@ -201,10 +218,7 @@ func GetIssuesWhereProjectIsNull(db *sql.DB) ([]Issue, error) {
 
 	rows, err := db.Query(query)
 	if err != nil {
-		log.Printf("Error GetIssuesWhereProjectIsNull executing query %v %v\n", query, err)
 		return nil, err
-	} else {
-		log.Printf("GetIssuesWhereProjectIsNull executed query %v\n", query)
 	}
 	defer rows.Close()
 
@@ -228,17 +242,12 @@ func GetIssuesWhereProjectIsNull(db *sql.DB) ([]Issue, error) {
 		issues = append(issues, issue)
 	}
 
-	log.Printf("GetIssuesWhereProjectIsNull found %d issues where project is null\n", len(issues))
-
 	return issues, nil
 }
 
 func GetIssuesByUserID(db *sql.DB, userID int) ([]Issue, error) {
 	query := `
-		SELECT 
-			id, subject, description, tracker_id, project_id,
-			assigned_to_id, status, category_id,
-			created_at, updated_at
+		SELECT ` + issueColumns + `
 		FROM issues where assigned_to_id = $1`
 
 	rows, err := db.Query(query, userID)
@@ -250,18 +259,7 @@ func GetIssuesByUserID(db *sql.DB, userID int) ([]Issue, error) {
 	var issues []Issue
 	for rows.Next() {
 		var issue Issue
-		err := rows.Scan(
-			&issue.ID,
-			&issue.Subject,
-			&issue.Description,
-			&issue.TrackerID,
-			&issue.ProjectID,
-			&issue.AssignedToID,
-			&issue.Status,
-			&issue.CategoryID,
-			&issue.CreatedAt,
-			&issue.UpdatedAt,
-		)
+		err := scanIssue(rows, &issue)
 		if err != nil {
 			return nil, err
 		}
@@ -272,20 +270,28 @@ func GetIssuesByUserID(db *sql.DB, userID int) ([]Issue, error) {
 	return issues, nil
 }
 
-// UpdateIssue actualiza un ticket existente en la base de datos
-func UpdateIssue(db *sql.DB, issue *Issue) error {
+// UpdateIssue actualiza un ticket existente en la base de datos si su lock_version
+// sigue siendo la leída, e incrementa la versión. Devuelve ErrStaleObject si no lo es
+func UpdateIssue(db DBTX, issue *Issue) error {
 	query := `
 		UPDATE
 			issues
 		SET
 			subject = $1, description = $2, tracker_id = $3, project_id = $4,
-			assigned_to_id = $5, status = $6, category_id = $7,
-			updated_at = NOW() WHERE id = $8`
+			assigned_to_id = $5, status = $6, category_id = $7, fixed_version_id = $8, parent_id = $9,
+			priority_id = COALESCE(NULLIF($10, 0), priority_id), start_date = $11, due_date = $12, done_ratio = $13,
+			estimated_hours = $14, updated_at = NOW(), lock_version = lock_version + 1
+		WHERE id = $15 AND lock_version = $16
+		RETURNING lock_version, COALESCE(priority_id, 0)`
 
-	_, err := db.Exec(query,
+	err := db.QueryRow(query,
 		issue.Subject, issue.Description, issue.TrackerID, issue.ProjectID,
-		issue.AssignedToID, issue.Status, issue.CategoryID,
-		issue.ID)
+		issue.AssignedToID, issue.Status, issue.CategoryID, issue.FixedVersionID, issue.ParentID,
+		issue.PriorityID, issue.StartDate, issue.DueDate, issue.DoneRatio, issue.EstimatedHours,
+		issue.ID, issue.LockVersion).Scan(&issue.LockVersion, &issue.PriorityID)
+	if err == sql.ErrNoRows {
+		return ErrStaleObject
+	}
 	if err != nil {
 		return err
 	}
@@ -293,8 +299,57 @@ func UpdateIssue(db *sql.DB, issue *Issue) error {
 	return nil
 }
 
+// GetIssuesByParentID obtiene las subtareas directas de un ticket
+func GetIssuesByParentID(db *sql.DB, parentID int) ([]Issue, error) {
+	query := `
+		SELECT ` + issueColumns + `
+		FROM issues
+		WHERE parent_id = $1
+		ORDER BY id`
+
+	rows, err := db.Query(query, parentID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	issues := []Issue{}
+	for rows.Next() {
+		var issue Issue
+		if err := scanIssue(rows, &issue); err != nil {
+			return nil, err
+		}
+		issues = append(issues, issue)
+	}
+
+	return issues, nil
+}
+
+// IsIssueAncestor indica si ancestorID es issueID o uno de sus padres, para evitar ciclos
+func IsIssueAncestor(db *sql.DB, ancestorID, issueID int) (bool, error) {
+	query := `
+	WITH RECURSIVE ancestors AS (
+		SELECT id, parent_id FROM issues WHERE id = $2
+		UNION
+		SELECT i.id, i.parent_id FROM issues i JOIN ancestors a ON i.id = a.parent_id
+	)
+	SELECT EXISTS (SELECT 1 FROM ancestors WHERE id = $1)`
+
+	var exists bool
+	err := db.QueryRow(query, ancestorID, issueID).Scan(&exists)
+	return exists, err
+}
+
+// SetIssueTimestamps fija las fechas de creación y actualización de un ticket (importaciones)
+func SetIssueTimestamps(db DBTX, id int, createdAt, updatedAt string) error {
+	query := `UPDATE issues SET created_at = $1, updated_at = $2 WHERE id = $3`
+
+	_, err := db.Exec(query, createdAt, updatedAt, id)
+	return err
+}
+
 // DeleteIssue elimina un ticket
-func DeleteIssue(db *sql.DB, id int) error {
+func DeleteIssue(db DBTX, id int) error {
 	query := `DELETE FROM issues WHERE id = $1`
 
 	_, err := db.Exec(query, id)
@@ -308,10 +363,7 @@ func DeleteIssue(db *sql.DB, id int) error {
 // GetAllIssues obtiene todos los tickets
 func GetAllIssues(db *sql.DB) ([]Issue, error) {
 	query := `
-	SELECT
-		id, subject, description, tracker_id, project_id, 
-		assigned_to_id, status, category_id,
-		created_at, updated_at
+	SELECT ` + issueColumns + `
 	FROM issues`
 
 	rows, err := db.Query(query)
@@ -323,18 +375,7 @@ func GetAllIssues(db *sql.DB) ([]Issue, error) {
 	var issues []Issue
 	for rows.Next() {
 		var issue Issue
-		err := rows.Scan(
-			&issue.ID,
-			&issue.Subject,
-			&issue.Description,
-			&issue.TrackerID,
-			&issue.ProjectID,
-			&issue.AssignedToID,
-			&issue.Status,
-			&issue.CategoryID,
-			&issue.CreatedAt,
-			&issue.UpdatedAt,
-		)
+		err := scanIssue(rows, &issue)
 		if err != nil {
 			return nil, err
 		}
@@ -369,11 +410,25 @@ func CreateIssuesTable(db *sql.DB) error {
 		assigned_to_id INT,
 		status VARCHAR(50) DEFAULT 'Open',
 		category_id INT,
+		fixed_version_id INT,
+		parent_id INT,
+		priority_id INT,
+		author_id INT,
+		start_date DATE,
+		due_date DATE,
+		done_ratio INT NOT NULL DEFAULT 0 CHECK (done_ratio BETWEEN 0 AND 100),
+		estimated_hours DOUBLE PRECISION CHECK (estimated_hours >= 0),
+		position INT,
 		created_at TIMESTAMP DEFAULT NOW(),
 		updated_at TIMESTAMP DEFAULT NOW(),
-		FOREIGN KEY (tracker_id) REFERENCES trackers(id) ON DELETE SET NULL,
-		FOREIGN KEY (project_id) REFERENCES projects(id) ON DELETE SET NULL,
-		FOREIGN KEY (assigned_to_id) REFERENCES users(id) ON DELETE SET NULL
+		lock_version INT NOT NULL DEFAULT 1,
+		FOREIGN KEY (tracker_id) REFERENCES trackers(id) ON DELETE RESTRICT,
+		FOREIGN KEY (project_id) REFERENCES projects(id) ON DELETE CASCADE,
+		FOREIGN KEY (assigned_to_id) REFERENCES users(id) ON DELETE SET NULL,
+		FOREIGN KEY (parent_id) REFERENCES issues(id) ON DELETE CASCADE,
+		FOREIGN KEY (priority_id) REFERENCES issue_priorities(id) ON DELETE SET NULL,
+		FOREIGN KEY (author_id) REFERENCES users(id) ON DELETE SET NULL,
+		CHECK (due_date >= start_date)
 	)`
 
 	_, err := db.Exec(query)
@@ -501,3 +556,190 @@ func CountIssuesByCategoryWhereProject(db *sql.DB, projectID int) ([]CategoryNum
 
 	return categories, nil
 }
+
+// IssueFilter reúne los filtros y la paginación de los listados de tickets
+type IssueFilter struct {
+	ProjectID      int
+	ProjectIDs     []int // si no es nil, limita el listado a estos proyectos
+	TrackerID      int
+	Status         string // nombre del estado, "open", "closed" o vacío para todos
+	AssignedToID   int
+	CategoryID     int
+	FixedVersionID int
+	PriorityID     int
+	AuthorID       int
+	StartDateFrom  string // fechas YYYY-MM-DD, límites incluidos
+	StartDateTo    string
+	DueDateFrom    string
+	DueDateTo      string
+	DoneRatioMin   *int
+	DoneRatioMax   *int
+	Overdue        bool   // solo tickets abiertos con la fecha de fin pasada
+	DatesFrom      string // tickets cuyas fechas de inicio a fin se solapan con [DatesFrom, DatesTo]
+	DatesTo        string
+	Offset         int
+	Limit          int
+	Sort           string // clave de issueSortColumns, con sufijo ":desc" opcional
+}
+
+// issueSortColumns lista las columnas por las que se puede ordenar un listado
+var issueSortColumns = map[string]string{
+	"id":              "id",
+	"subject":         "subject",
+	"status":          "status",
+	"tracker":         "tracker_id",
+	"project":         "project_id",
+	"assigned_to":     "assigned_to_id",
+	"category":        "category_id",
+	"fixed_version":   "fixed_version_id",
+	"position":        "position",
+	"priority":        "(SELECT position FROM issue_priorities WHERE issue_priorities.id = priority_id)",
+	"author":          "author_id",
+	"start_date":      "start_date",
+	"due_date":        "due_date",
+	"done_ratio":      "done_ratio",
+	"estimated_hours": "estimated_hours",
+	"created_on":      "created_at",
+	"updated_on":      "updated_at",
+}
+
+// where construye la cláusula WHERE y sus argumentos a partir del filtro
+func (f IssueFilter) where() (string, []interface{}) {
+	conds := []string{}
+	args := []interface{}{}
+
+	add := func(cond string, arg interface{}) {
+		args = append(args, arg)
+		conds = append(conds, fmt.Sprintf(cond, len(args)))
+	}
+
+	if f.ProjectID != 0 {
+		add("project_id = $%d", f.ProjectID)
+	}
+	if f.ProjectIDs != nil {
+		add("project_id = ANY($%d)", pq.Array(f.ProjectIDs))
+	}
+	if f.TrackerID != 0 {
+		add("tracker_id = $%d", f.TrackerID)
+	}
+	if f.AssignedToID != 0 {
+		add("assigned_to_id = $%d", f.AssignedToID)
+	}
+	if f.CategoryID != 0 {
+		add("category_id = $%d", f.CategoryID)
+	}
+	if f.FixedVersionID != 0 {
+		add("fixed_version_id = $%d", f.FixedVersionID)
+	}
+	if f.PriorityID != 0 {
+		add("priority_id = $%d", f.PriorityID)
+	}
+	if f.AuthorID != 0 {
+		add("author_id = $%d", f.AuthorID)
+	}
+	if f.StartDateFrom != "" {
+		add("start_date >= $%d", f.StartDateFrom)
+	}
+	if f.StartDateTo != "" {
+		add("start_date <= $%d", f.StartDateTo)
+	}
+	if f.DueDateFrom != "" {
+		add("due_date >= $%d", f.DueDateFrom)
+	}
+	if f.DueDateTo != "" {
+		add("due_date <= $%d", f.DueDateTo)
+	}
+	if f.DoneRatioMin != nil {
+		add("done_ratio >= $%d", *f.DoneRatioMin)
+	}
+	if f.DoneRatioMax != nil {
+		add("done_ratio <= $%d", *f.DoneRatioMax)
+	}
+	// Un ticket con una sola fecha ocupa ese día
+	if f.DatesFrom != "" {
+		add("COALESCE(due_date, start_date) >= $%d", f.DatesFrom)
+	}
+	if f.DatesTo != "" {
+		add("COALESCE(start_date, due_date) <= $%d", f.DatesTo)
+	}
+	if f.Overdue {
+		conds = append(conds, "due_date < CURRENT_DATE AND status NOT IN (SELECT name FROM issue_statuses WHERE is_closed)")
+	}
+	switch f.Status {
+	case "":
+	case "open":
+		conds = append(conds, "status NOT IN (SELECT name FROM issue_statuses WHERE is_closed)")
+	case "closed":
+		conds = append(conds, "status IN (SELECT name FROM issue_statuses WHERE is_closed)")
+	default:
+		add("status = $%d", f.Status)
+	}
+
+	if len(conds) == 0 {
+		return "", args
+	}
+	return " WHERE " + strings.Join(conds, " AND "), args
+}
+
+// orderBy traduce el criterio de ordenación a SQL, por defecto por id
+func (f IssueFilter) orderBy() string {
+	key, dir := f.Sort, "ASC"
+	if strings.HasSuffix(key, ":desc") {
+		key, dir = strings.TrimSuffix(key, ":desc"), "DESC"
+	}
+	column, ok := issueSortColumns[key]
+	if !ok {
+		column = "id"
+	}
+	return fmt.Sprintf(" ORDER BY %s %s, id %s", column, dir, dir)
+}
+
+// GetIssuesFiltered obtiene los tickets que cumplen el filtro, paginados
+func GetIssuesFiltered(db *sql.DB, f IssueFilter) ([]Issue, error) {
+	where, args := f.where()
+	query := `
+	SELECT ` + issueColumns + `
+	FROM issues` + where + f.orderBy()
+
+	if f.Limit > 0 {
+		args = append(args, f.Limit)
+		query += fmt.Sprintf(" LIMIT $%d", len(args))
+	}
+	if f.Offset > 0 {
+		args = append(args, f.Offset)
+		query += fmt.Sprintf(" OFFSET $%d", len(args))
+	}
+
+	rows, err := db.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	issues := []Issue{}
+	for rows.Next() {
+		var issue Issue
+		err := scanIssue(rows, &issue)
+		if err != nil {
+			return nil, err
+		}
+
+		issues = append(issues, issue)
+	}
+
+	return issues, nil
+}
+
+// CountIssuesFiltered cuenta los tickets que cumplen el filtro, sin paginar
+func CountIssuesFiltered(db *sql.DB, f IssueFilter) (int, error) {
+	where, args := f.where()
+	query := `SELECT COUNT(*) FROM issues` + where
+
+	var count int
+	err := db.QueryRow(query, args...).Scan(&count)
+	if err != nil {
+		return 0, err
+	}
+
+	return count, nil
+}
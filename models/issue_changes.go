@@ -0,0 +1,165 @@
+package models
+
+import (
+	"database/sql"
+	"strconv"
+)
+
+/*
+CREATE TABLE IF NOT EXISTS issue_changes (
+	id SERIAL PRIMARY KEY,
+	issue_id INT NOT NULL,              -- Ticket modificado
+	user_id INT,                        -- Usuario que hizo el cambio (NULL si fue el token de servicio)
+	field VARCHAR(50) NOT NULL,         -- Campo modificado: status, assigned_to_id...
+	old_value TEXT,
+	new_value TEXT,
+	created_at TIMESTAMP DEFAULT NOW(),
+	FOREIGN KEY (issue_id) REFERENCES issues(id) ON DELETE CASCADE,
+	FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE SET NULL
+);
+*/
+
+// IssueChange es el cambio de un campo de un ticket
+type IssueChange struct {
+	ID        int     `json:"id"`
+	IssueID   int     `json:"issue_id"`
+	UserID    *int    `json:"user_id"`
+	Field     string  `json:"field"`
+	OldValue  *string `json:"old_value"`
+	NewValue  *string `json:"new_value"`
+	CreatedAt string  `json:"created_at"`
+}
+
+// CreateIssueChange registra un cambio de un ticket
+func CreateIssueChange(db DBTX, change *IssueChange) (int, error) {
+	query := `
+	INSERT INTO issue_changes (issue_id, user_id, field, old_value, new_value)
+	VALUES ($1, $2, $3, $4, $5)
+	RETURNING id`
+
+	var id int
+	err := db.QueryRow(query, change.IssueID, change.UserID, change.Field, change.OldValue, change.NewValue).Scan(&id)
+	if err != nil {
+		return 0, err
+	}
+
+	return id, nil
+}
+
+// GetIssueChangesByIssueID obtiene el historial de un ticket en orden cronológico
+func GetIssueChangesByIssueID(db *sql.DB, issueID int) ([]IssueChange, error) {
+	query := `
+	SELECT id, issue_id, user_id, field, old_value, new_value, created_at
+	FROM issue_changes
+	WHERE issue_id = $1
+	ORDER BY created_at, id`
+
+	rows, err := db.Query(query, issueID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	changes := []IssueChange{}
+	for rows.Next() {
+		change := IssueChange{}
+		err := rows.Scan(&change.ID, &change.IssueID, &change.UserID, &change.Field, &change.OldValue, &change.NewValue, &change.CreatedAt)
+		if err != nil {
+			return nil, err
+		}
+		changes = append(changes, change)
+	}
+
+	return changes, nil
+}
+
+func optionalIntValue(value *int) *string {
+	if value == nil {
+		return nil
+	}
+	s := strconv.Itoa(*value)
+	return &s
+}
+
+func optionalFloatValue(value *float64) *string {
+	if value == nil {
+		return nil
+	}
+	s := strconv.FormatFloat(*value, 'f', -1, 64)
+	return &s
+}
+
+func sameOptionalValue(a, b *string) bool {
+	if a == nil || b == nil {
+		return a == nil && b == nil
+	}
+	return *a == *b
+}
+
+// RecordIssueChanges compara dos versiones de un ticket y registra un cambio por cada campo distinto
+func RecordIssueChanges(db DBTX, old, updated *Issue, userID *int) error {
+	tracker_old, tracker_new := strconv.Itoa(old.TrackerID), strconv.Itoa(updated.TrackerID)
+	project_old, project_new := strconv.Itoa(old.ProjectID), strconv.Itoa(updated.ProjectID)
+	priority_old, priority_new := strconv.Itoa(old.PriorityID), strconv.Itoa(updated.PriorityID)
+	done_old, done_new := strconv.Itoa(old.DoneRatio), strconv.Itoa(updated.DoneRatio)
+
+	fields := []struct {
+		name     string
+		old, new *string
+	}{
+		{"subject", &old.Subject, &updated.Subject},
+		{"tracker_id", &tracker_old, &tracker_new},
+		{"project_id", &project_old, &project_new},
+		{"status", &old.Status, &updated.Status},
+		{"assigned_to_id", optionalIntValue(old.AssignedToID), optionalIntValue(updated.AssignedToID)},
+		{"category_id", optionalIntValue(old.CategoryID), optionalIntValue(updated.CategoryID)},
+		{"fixed_version_id", optionalIntValue(old.FixedVersionID), optionalIntValue(updated.FixedVersionID)},
+		{"parent_id", optionalIntValue(old.ParentID), optionalIntValue(updated.ParentID)},
+		{"priority_id", &priority_old, &priority_new},
+		{"start_date", old.StartDate, updated.StartDate},
+		{"due_date", old.DueDate, updated.DueDate},
+		{"done_ratio", &done_old, &done_new},
+		{"estimated_hours", optionalFloatValue(old.EstimatedHours), optionalFloatValue(updated.EstimatedHours)},
+	}
+
+	for _, field := range fields {
+		if sameOptionalValue(field.old, field.new) {
+			continue
+		}
+		change := &IssueChange{
+			IssueID:  updated.ID,
+			UserID:   userID,
+			Field:    field.name,
+			OldValue: field.old,
+			NewValue: field.new,
+		}
+		if _, err := CreateIssueChange(db, change); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func CreateIssueChangesTable(db *sql.DB) error {
+	query := `
+	CREATE TABLE IF NOT EXISTS issue_changes (
+		id SERIAL PRIMARY KEY,
+		issue_id INT NOT NULL,
+		user_id INT,
+		field VARCHAR(50) NOT NULL,
+		old_value TEXT,
+		new_value TEXT,
+		created_at TIMESTAMP DEFAULT NOW(),
+		FOREIGN KEY (issue_id) REFERENCES issues(id) ON DELETE CASCADE,
+		FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE SET NULL
+	)`
+	_, err := db.Exec(query)
+	return err
+}
+
+func DropIssueChangesTable(db *sql.DB) error {
+	query := `DROP TABLE IF EXISTS issue_changes`
+	_, err := db.Exec(query)
+	return err
+}
@@ -0,0 +1,107 @@
+package models
+
+import "database/sql"
+
+/*
+CREATE TABLE IF NOT EXISTS issue_priorities (
+	id SERIAL PRIMARY KEY,
+	name VARCHAR(50) UNIQUE NOT NULL,   -- Nombre de la prioridad
+	position INT DEFAULT 0,             -- Orden, de menor a mayor prioridad
+	is_default BOOLEAN DEFAULT FALSE    -- Prioridad de los tickets nuevos que no indican otra
+);
+*/
+
+// IssuePriority es un valor de la enumeración de prioridades de ticket
+type IssuePriority struct {
+	ID        int    `json:"id"`
+	Name      string `json:"name"`
+	Position  int    `json:"position"`
+	IsDefault bool   `json:"is_default"`
+}
+
+// GetAllIssuePriorities obtiene las prioridades de menor a mayor
+func GetAllIssuePriorities(db *sql.DB) ([]IssuePriority, error) {
+	query := `SELECT id, name, position, is_default FROM issue_priorities ORDER BY position, id`
+
+	rows, err := db.Query(query)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	priorities := []IssuePriority{}
+	for rows.Next() {
+		priority := IssuePriority{}
+		if err := rows.Scan(&priority.ID, &priority.Name, &priority.Position, &priority.IsDefault); err != nil {
+			return nil, err
+		}
+		priorities = append(priorities, priority)
+	}
+
+	return priorities, nil
+}
+
+// GetIssuePriorityByID obtiene una prioridad por su ID
+func GetIssuePriorityByID(db *sql.DB, id int) (*IssuePriority, error) {
+	query := `SELECT id, name, position, is_default FROM issue_priorities WHERE id = $1`
+
+	priority := &IssuePriority{}
+	err := db.QueryRow(query, id).Scan(&priority.ID, &priority.Name, &priority.Position, &priority.IsDefault)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	return priority, nil
+}
+
+// GetIssuePriorityByName obtiene una prioridad por su nombre
+func GetIssuePriorityByName(db *sql.DB, name string) (*IssuePriority, error) {
+	query := `SELECT id, name, position, is_default FROM issue_priorities WHERE name = $1`
+
+	priority := &IssuePriority{}
+	err := db.QueryRow(query, name).Scan(&priority.ID, &priority.Name, &priority.Position, &priority.IsDefault)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	return priority, nil
+}
+
+func CreateIssuePrioritiesTable(db *sql.DB) error {
+	query := `
+	CREATE TABLE IF NOT EXISTS issue_priorities (
+		id SERIAL PRIMARY KEY,
+		name VARCHAR(50) UNIQUE NOT NULL,
+		position INT DEFAULT 0,
+		is_default BOOLEAN DEFAULT FALSE
+	)`
+	_, err := db.Exec(query)
+	return err
+}
+
+func DropIssuePrioritiesTable(db *sql.DB) error {
+	query := `DROP TABLE IF EXISTS issue_priorities`
+	_, err := db.Exec(query)
+	return err
+}
+
+// SeedIssuePriorities crea las prioridades por defecto, con Normal como predeterminada
+func SeedIssuePriorities(db *sql.DB) error {
+	query := `
+	INSERT INTO issue_priorities (name, position, is_default)
+	VALUES
+		('Low', 1, FALSE),
+		('Normal', 2, TRUE),
+		('High', 3, FALSE),
+		('Urgent', 4, FALSE),
+		('Immediate', 5, FALSE)
+	ON CONFLICT (name) DO NOTHING`
+	_, err := db.Exec(query)
+	return err
+}
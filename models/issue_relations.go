@@ -0,0 +1,201 @@
+package models
+
+import (
+	"database/sql"
+
+	"github.com/lib/pq"
+)
+
+/*
+CREATE TABLE IF NOT EXISTS issue_relations (
+	id SERIAL PRIMARY KEY,
+	issue_from_id INT NOT NULL,         -- Ticket de origen
+	issue_to_id INT NOT NULL,           -- Ticket relacionado
+	relation_type VARCHAR(20) NOT NULL, -- Tipo visto desde el origen, p. ej. copied_to
+	delay INT,                          -- Solo precedes: días entre el fin del origen y el inicio del relacionado
+	created_at TIMESTAMP DEFAULT NOW(),
+	UNIQUE (issue_from_id, issue_to_id, relation_type)
+);
+*/
+
+// Tipos de relación entre tickets. Cada relación se guarda una sola vez desde el ticket de
+// origen; desde el otro ticket se ve con el tipo inverso
+const (
+	IssueRelationRelates  = "relates"
+	IssueRelationCopiedTo = "copied_to"
+	IssueRelationPrecedes = "precedes"
+)
+
+// issueRelationInverses son los tipos vistos desde el ticket relacionado
+var issueRelationInverses = map[string]string{
+	IssueRelationRelates:  IssueRelationRelates,
+	IssueRelationCopiedTo: "copied_from",
+	IssueRelationPrecedes: "follows",
+}
+
+// IssueRelation es una relación entre dos tickets
+type IssueRelation struct {
+	ID           int    `json:"id"`
+	IssueFromID  int    `json:"issue_id"`
+	IssueToID    int    `json:"issue_to_id"`
+	RelationType string `json:"relation_type"`
+	Delay        *int   `json:"delay"`
+	CreatedAt    string `json:"created_at"`
+}
+
+// IsIssueRelationType indica si el tipo de relación es conocido
+func IsIssueRelationType(relationType string) bool {
+	_, ok := issueRelationInverses[relationType]
+	return ok
+}
+
+const issueRelationColumns = `id, issue_from_id, issue_to_id, relation_type, delay, created_at`
+
+func scanIssueRelation(row interface{ Scan(...interface{}) error }, relation *IssueRelation) error {
+	return row.Scan(&relation.ID, &relation.IssueFromID, &relation.IssueToID, &relation.RelationType, &relation.Delay, &relation.CreatedAt)
+}
+
+// InverseIssueRelationType devuelve el tipo visto desde el ticket relacionado, o "" si no es
+// un tipo inverso conocido. Sirve para aceptar p. ej. follows guardándolo como precedes
+func InverseIssueRelationType(relationType string) string {
+	for stored, inverse := range issueRelationInverses {
+		if inverse == relationType {
+			return stored
+		}
+	}
+	return ""
+}
+
+// CreateIssueRelation crea una relación entre dos tickets
+func CreateIssueRelation(db DBTX, relation *IssueRelation) (int, error) {
+	query := `
+	INSERT INTO issue_relations (issue_from_id, issue_to_id, relation_type, delay)
+	VALUES ($1, $2, $3, $4)
+	RETURNING id`
+
+	var id int
+	err := db.QueryRow(query, relation.IssueFromID, relation.IssueToID, relation.RelationType, relation.Delay).Scan(&id)
+	if err != nil {
+		return 0, err
+	}
+
+	return id, nil
+}
+
+// GetIssueRelationsByIssueID obtiene las relaciones de un ticket vistas desde él: en las que es
+// el ticket relacionado se intercambian los extremos y se devuelve el tipo inverso
+func GetIssueRelationsByIssueID(db *sql.DB, issueID int) ([]IssueRelation, error) {
+	query := `
+	SELECT ` + issueRelationColumns + `
+	FROM issue_relations
+	WHERE issue_from_id = $1 OR issue_to_id = $1
+	ORDER BY id`
+
+	rows, err := db.Query(query, issueID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	relations := []IssueRelation{}
+	for rows.Next() {
+		var relation IssueRelation
+		if err := scanIssueRelation(rows, &relation); err != nil {
+			return nil, err
+		}
+		if relation.IssueFromID != issueID {
+			relation.IssueFromID, relation.IssueToID = relation.IssueToID, relation.IssueFromID
+			relation.RelationType = issueRelationInverses[relation.RelationType]
+		}
+		relations = append(relations, relation)
+	}
+
+	return relations, nil
+}
+
+// GetIssueRelationByID obtiene una relación tal y como está guardada, desde su ticket de origen
+func GetIssueRelationByID(db *sql.DB, id int) (*IssueRelation, error) {
+	query := `SELECT ` + issueRelationColumns + ` FROM issue_relations WHERE id = $1`
+
+	relation := &IssueRelation{}
+	if err := scanIssueRelation(db.QueryRow(query, id), relation); err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	return relation, nil
+}
+
+// GetIssueRelationsBetween obtiene las relaciones de un tipo cuyos dos extremos están en issueIDs
+func GetIssueRelationsBetween(db *sql.DB, issueIDs []int, relationType string) ([]IssueRelation, error) {
+	query := `
+	SELECT ` + issueRelationColumns + `
+	FROM issue_relations
+	WHERE relation_type = $1 AND issue_from_id = ANY($2) AND issue_to_id = ANY($2)
+	ORDER BY id`
+
+	rows, err := db.Query(query, relationType, pq.Array(issueIDs))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	relations := []IssueRelation{}
+	for rows.Next() {
+		var relation IssueRelation
+		if err := scanIssueRelation(rows, &relation); err != nil {
+			return nil, err
+		}
+		relations = append(relations, relation)
+	}
+
+	return relations, nil
+}
+
+// IssuePrecedes indica si fromID precede a toID, directamente o a través de otros tickets
+func IssuePrecedes(db *sql.DB, fromID, toID int) (bool, error) {
+	query := `
+	WITH RECURSIVE following AS (
+		SELECT issue_to_id AS id FROM issue_relations WHERE issue_from_id = $1 AND relation_type = $3
+		UNION
+		SELECT r.issue_to_id FROM issue_relations r JOIN following f ON r.issue_from_id = f.id
+		WHERE r.relation_type = $3
+	)
+	SELECT EXISTS (SELECT 1 FROM following WHERE id = $2)`
+
+	var precedes bool
+	err := db.QueryRow(query, fromID, toID, IssueRelationPrecedes).Scan(&precedes)
+	return precedes, err
+}
+
+// DeleteIssueRelation borra una relación
+func DeleteIssueRelation(db *sql.DB, id int) error {
+	query := `DELETE FROM issue_relations WHERE id = $1`
+	_, err := db.Exec(query, id)
+	return err
+}
+
+func CreateIssueRelationsTable(db *sql.DB) error {
+	query := `
+	CREATE TABLE IF NOT EXISTS issue_relations (
+		id SERIAL PRIMARY KEY,
+		issue_from_id INT NOT NULL,
+		issue_to_id INT NOT NULL,
+		relation_type VARCHAR(20) NOT NULL,
+		delay INT,
+		created_at TIMESTAMP DEFAULT NOW(),
+		UNIQUE (issue_from_id, issue_to_id, relation_type),
+		FOREIGN KEY (issue_from_id) REFERENCES issues(id) ON DELETE CASCADE,
+		FOREIGN KEY (issue_to_id) REFERENCES issues(id) ON DELETE CASCADE
+	)`
+	_, err := db.Exec(query)
+	return err
+}
+
+func DropIssueRelationsTable(db *sql.DB) error {
+	query := `DROP TABLE IF EXISTS issue_relations`
+	_, err := db.Exec(query)
+	return err
+}
@@ -0,0 +1,121 @@
+package models
+
+import "database/sql"
+
+/*
+CREATE TABLE IF NOT EXISTS issue_statuses (
+	id SERIAL PRIMARY KEY,
+	name VARCHAR(50) UNIQUE NOT NULL,   -- Nombre del estado, coincide con issues.status
+	is_closed BOOLEAN DEFAULT FALSE,    -- Indica si el estado cierra el ticket
+	position INT DEFAULT 0              -- Orden de presentación
+);
+*/
+
+// IssueStatus representa un estado posible de un ticket
+type IssueStatus struct {
+	ID       int    `json:"id"`
+	Name     string `json:"name"`
+	IsClosed bool   `json:"is_closed"`
+	Position int    `json:"position"`
+}
+
+// GetAllIssueStatuses obtiene todos los estados ordenados por posición
+func GetAllIssueStatuses(db *sql.DB) ([]IssueStatus, error) {
+	query := `SELECT id, name, is_closed, position FROM issue_statuses ORDER BY position, id`
+
+	rows, err := db.Query(query)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	statuses := []IssueStatus{}
+	for rows.Next() {
+		status := IssueStatus{}
+		if err := rows.Scan(&status.ID, &status.Name, &status.IsClosed, &status.Position); err != nil {
+			return nil, err
+		}
+		statuses = append(statuses, status)
+	}
+
+	return statuses, nil
+}
+
+// GetIssueStatusByID obtiene un estado por su ID
+func GetIssueStatusByID(db *sql.DB, id int) (*IssueStatus, error) {
+	query := `SELECT id, name, is_closed, position FROM issue_statuses WHERE id = $1`
+
+	status := &IssueStatus{}
+	err := db.QueryRow(query, id).Scan(&status.ID, &status.Name, &status.IsClosed, &status.Position)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	return status, nil
+}
+
+// GetIssueStatusByName obtiene un estado por su nombre
+func GetIssueStatusByName(db *sql.DB, name string) (*IssueStatus, error) {
+	query := `SELECT id, name, is_closed, position FROM issue_statuses WHERE name = $1`
+
+	status := &IssueStatus{}
+	err := db.QueryRow(query, name).Scan(&status.ID, &status.Name, &status.IsClosed, &status.Position)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	return status, nil
+}
+
+func CreateIssueStatusesTable(db *sql.DB) error {
+	query := `
+	CREATE TABLE IF NOT EXISTS issue_statuses (
+		id SERIAL PRIMARY KEY,
+		name VARCHAR(50) UNIQUE NOT NULL,
+		is_closed BOOLEAN DEFAULT FALSE,
+		position INT DEFAULT 0
+	)`
+	_, err := db.Exec(query)
+	return err
+}
+
+func DropIssueStatusesTable(db *sql.DB) error {
+	query := `DROP TABLE IF EXISTS issue_statuses`
+	_, err := db.Exec(query)
+	return err
+}
+
+// SeedIssueStatuses inserta los estados por defecto, el primero coincide con el DEFAULT de issues.status
+func SeedIssueStatuses(db *sql.DB) error {
+	query := `
+	INSERT INTO issue_statuses (name, is_closed, position)
+	VALUES
+		('Open', FALSE, 1),
+		('In Progress', FALSE, 2),
+		('Resolved', FALSE, 3),
+		('Feedback', FALSE, 4),
+		('Closed', TRUE, 5),
+		('Rejected', TRUE, 6)
+	ON CONFLICT (name) DO NOTHING`
+	_, err := db.Exec(query)
+	return err
+}
+
+// CreateIssueStatus crea un nuevo estado
+func CreateIssueStatus(db *sql.DB, status *IssueStatus) (int, error) {
+	query := `INSERT INTO issue_statuses (name, is_closed, position) VALUES ($1, $2, $3) RETURNING id`
+
+	var id int
+	err := db.QueryRow(query, status.Name, status.IsClosed, status.Position).Scan(&id)
+	if err != nil {
+		return 0, err
+	}
+
+	return id, nil
+}
@@ -0,0 +1,364 @@
+package models
+
+import (
+	"database/sql"
+	"encoding/json"
+)
+
+/*
+CREATE TABLE IF NOT EXISTS issue_templates (
+	id SERIAL PRIMARY KEY,
+	project_id INT NOT NULL,            -- Proyecto en el que se crean los tickets
+	tracker_id INT NOT NULL,
+	name VARCHAR(255) NOT NULL,
+	subject VARCHAR(255) NOT NULL,      -- {date} se sustituye por la fecha de la ejecución
+	description TEXT,
+	assigned_to_id INT,
+	category_id INT,
+	priority_id INT,
+	custom_fields JSONB NOT NULL DEFAULT '{}', -- Valores por ID de campo personalizado
+	schedule VARCHAR(100) NOT NULL,     -- Expresión cron o "every N days|weeks|months"
+	starts_at TIMESTAMP NOT NULL DEFAULT (NOW() AT TIME ZONE 'UTC'),
+	next_run_at TIMESTAMP,              -- Próxima ejecución, NULL si no hay más
+	last_run_at TIMESTAMP,
+	last_error TEXT,                    -- Error de la última ejecución
+	enabled BOOLEAN NOT NULL DEFAULT TRUE,
+	created_at TIMESTAMP DEFAULT NOW(),
+	updated_at TIMESTAMP DEFAULT NOW(),
+	FOREIGN KEY (project_id) REFERENCES projects(id) ON DELETE CASCADE,
+	FOREIGN KEY (tracker_id) REFERENCES trackers(id) ON DELETE CASCADE,
+	FOREIGN KEY (assigned_to_id) REFERENCES users(id) ON DELETE SET NULL,
+	FOREIGN KEY (category_id) REFERENCES categories(id) ON DELETE SET NULL,
+	FOREIGN KEY (priority_id) REFERENCES issue_priorities(id) ON DELETE SET NULL
+);
+
+CREATE TABLE IF NOT EXISTS issue_template_runs (
+	id SERIAL PRIMARY KEY,
+	template_id INT NOT NULL,
+	scheduled_at TIMESTAMP NOT NULL,    -- Ejecución programada que creó el ticket
+	issue_id INT UNIQUE,                -- Ticket creado, NULL si falló o se borró
+	error TEXT,
+	created_at TIMESTAMP DEFAULT NOW(),
+	UNIQUE (template_id, scheduled_at),
+	FOREIGN KEY (template_id) REFERENCES issue_templates(id) ON DELETE CASCADE,
+	FOREIGN KEY (issue_id) REFERENCES issues(id) ON DELETE SET NULL
+);
+*/
+
+// IssueTemplate es una plantilla de ticket que se crea de forma periódica
+type IssueTemplate struct {
+	ID           int               `json:"id"`
+	ProjectID    int               `json:"project_id"`
+	TrackerID    int               `json:"tracker_id"`
+	Name         string            `json:"name"`
+	Subject      string            `json:"subject"`
+	Description  string            `json:"description"`
+	AssignedToID *int              `json:"assigned_to_id"`
+	CategoryID   *int              `json:"category_id"`
+	PriorityID   *int              `json:"priority_id"`
+	CustomFields map[string]string `json:"custom_fields"` // valor por ID de campo personalizado
+	Schedule     string            `json:"schedule"`
+	StartsAt     string            `json:"starts_at"`
+	NextRunAt    *string           `json:"next_run_at"`
+	LastRunAt    *string           `json:"last_run_at"`
+	LastError    *string           `json:"last_error"`
+	Enabled      bool              `json:"enabled"`
+	CreatedAt    string            `json:"created_at"`
+	UpdatedAt    string            `json:"updated_at"`
+}
+
+// IssueTemplateRun es una ejecución de una plantilla y el ticket que creó
+type IssueTemplateRun struct {
+	ID          int     `json:"id"`
+	TemplateID  int     `json:"template_id"`
+	ScheduledAt string  `json:"scheduled_at"`
+	IssueID     *int    `json:"issue_id"`
+	Error       *string `json:"error"`
+	CreatedAt   string  `json:"created_at"`
+}
+
+// issueTemplateTime es el formato de las fechas de las plantillas, en UTC
+const issueTemplateTime = `'YYYY-MM-DD"T"HH24:MI:SS"Z"'`
+
+const issueTemplateColumns = `id, project_id, tracker_id, name, subject, COALESCE(description, ''),
+	assigned_to_id, category_id, priority_id, custom_fields, schedule,
+	to_char(starts_at, ` + issueTemplateTime + `), to_char(next_run_at, ` + issueTemplateTime + `),
+	to_char(last_run_at, ` + issueTemplateTime + `), last_error, enabled, created_at, updated_at`
+
+func scanIssueTemplate(row interface{ Scan(...interface{}) error }, template *IssueTemplate) error {
+	var customFields []byte
+	err := row.Scan(
+		&template.ID,
+		&template.ProjectID,
+		&template.TrackerID,
+		&template.Name,
+		&template.Subject,
+		&template.Description,
+		&template.AssignedToID,
+		&template.CategoryID,
+		&template.PriorityID,
+		&customFields,
+		&template.Schedule,
+		&template.StartsAt,
+		&template.NextRunAt,
+		&template.LastRunAt,
+		&template.LastError,
+		&template.Enabled,
+		&template.CreatedAt,
+		&template.UpdatedAt,
+	)
+	if err != nil {
+		return err
+	}
+	template.CustomFields = map[string]string{}
+	return json.Unmarshal(customFields, &template.CustomFields)
+}
+
+// CreateIssueTemplate crea una plantilla. next_run_at se calcula antes a partir de la programación
+func CreateIssueTemplate(db DBTX, template *IssueTemplate) (int, error) {
+	query := `
+	INSERT INTO issue_templates (
+		project_id, tracker_id, name, subject, description, assigned_to_id, category_id, priority_id,
+		custom_fields, schedule, starts_at, next_run_at, enabled
+	) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13)
+	RETURNING id`
+
+	customFields, err := json.Marshal(template.CustomFields)
+	if err != nil {
+		return 0, err
+	}
+
+	var id int
+	err = db.QueryRow(query,
+		template.ProjectID, template.TrackerID, template.Name, template.Subject, template.Description,
+		template.AssignedToID, template.CategoryID, template.PriorityID,
+		customFields, template.Schedule, template.StartsAt, template.NextRunAt, template.Enabled,
+	).Scan(&id)
+	if err != nil {
+		return 0, err
+	}
+
+	return id, nil
+}
+
+// GetIssueTemplateByID obtiene una plantilla por su ID, o nil si no existe
+func GetIssueTemplateByID(db *sql.DB, id int) (*IssueTemplate, error) {
+	query := `SELECT ` + issueTemplateColumns + ` FROM issue_templates WHERE id = $1`
+
+	var template IssueTemplate
+	err := scanIssueTemplate(db.QueryRow(query, id), &template)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return &template, nil
+}
+
+// GetIssueTemplatesByProjectID obtiene las plantillas de un proyecto
+func GetIssueTemplatesByProjectID(db *sql.DB, projectID int) ([]IssueTemplate, error) {
+	query := `SELECT ` + issueTemplateColumns + ` FROM issue_templates WHERE project_id = $1 ORDER BY name, id`
+
+	rows, err := db.Query(query, projectID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	templates := []IssueTemplate{}
+	for rows.Next() {
+		var template IssueTemplate
+		if err := scanIssueTemplate(rows, &template); err != nil {
+			return nil, err
+		}
+		templates = append(templates, template)
+	}
+
+	return templates, nil
+}
+
+// UpdateIssueTemplate modifica una plantilla, incluida su próxima ejecución
+func UpdateIssueTemplate(db DBTX, template *IssueTemplate) error {
+	query := `
+	UPDATE issue_templates
+	SET tracker_id = $1, name = $2, subject = $3, description = $4, assigned_to_id = $5,
+		category_id = $6, priority_id = $7, custom_fields = $8, schedule = $9, starts_at = $10,
+		next_run_at = $11, enabled = $12, updated_at = NOW()
+	WHERE id = $13`
+
+	customFields, err := json.Marshal(template.CustomFields)
+	if err != nil {
+		return err
+	}
+
+	_, err = db.Exec(query,
+		template.TrackerID, template.Name, template.Subject, template.Description, template.AssignedToID,
+		template.CategoryID, template.PriorityID, customFields, template.Schedule, template.StartsAt,
+		template.NextRunAt, template.Enabled, template.ID)
+	return err
+}
+
+// DeleteIssueTemplate borra una plantilla y su historial de ejecuciones
+func DeleteIssueTemplate(db *sql.DB, id int) error {
+	query := `DELETE FROM issue_templates WHERE id = $1`
+	_, err := db.Exec(query, id)
+	return err
+}
+
+// ClaimDueIssueTemplate bloquea, dentro de la transacción, una plantilla activa cuya próxima
+// ejecución ya ha llegado, o devuelve nil si no hay ninguna. SKIP LOCKED hace que cada réplica
+// tome una plantilla distinta
+func ClaimDueIssueTemplate(tx *sql.Tx) (*IssueTemplate, error) {
+	query := `
+	SELECT ` + issueTemplateColumns + `
+	FROM issue_templates
+	WHERE enabled AND next_run_at <= NOW() AT TIME ZONE 'UTC'
+	ORDER BY next_run_at, id
+	LIMIT 1
+	FOR UPDATE SKIP LOCKED`
+
+	var template IssueTemplate
+	err := scanIssueTemplate(tx.QueryRow(query), &template)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return &template, nil
+}
+
+// CreateIssueTemplateRun registra la ejecución programada de una plantilla. Devuelve false si ya
+// estaba registrada, en cuyo caso no hay que volver a crear el ticket
+func CreateIssueTemplateRun(tx *sql.Tx, run *IssueTemplateRun) (bool, error) {
+	query := `
+	INSERT INTO issue_template_runs (template_id, scheduled_at, issue_id, error)
+	VALUES ($1, $2, $3, $4)
+	ON CONFLICT (template_id, scheduled_at) DO NOTHING
+	RETURNING id`
+
+	err := tx.QueryRow(query, run.TemplateID, run.ScheduledAt, run.IssueID, run.Error).Scan(&run.ID)
+	if err == sql.ErrNoRows {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+
+	return true, nil
+}
+
+// SetIssueTemplateRunResult guarda el ticket creado o el error de una ejecución
+func SetIssueTemplateRunResult(tx *sql.Tx, runID int, issueID *int, runError *string) error {
+	query := `UPDATE issue_template_runs SET issue_id = $1, error = $2 WHERE id = $3`
+	_, err := tx.Exec(query, issueID, runError, runID)
+	return err
+}
+
+// FinishIssueTemplateRun pasa la plantilla a su siguiente ejecución y guarda el resultado de la última
+func FinishIssueTemplateRun(tx *sql.Tx, templateID int, nextRunAt *string, runError *string) error {
+	query := `
+	UPDATE issue_templates
+	SET next_run_at = $1, last_run_at = NOW() AT TIME ZONE 'UTC', last_error = $2
+	WHERE id = $3`
+	_, err := tx.Exec(query, nextRunAt, runError, templateID)
+	return err
+}
+
+// GetIssueTemplateRuns obtiene las ejecuciones de una plantilla, de la más reciente a la más antigua
+func GetIssueTemplateRuns(db *sql.DB, templateID int) ([]IssueTemplateRun, error) {
+	query := `
+	SELECT id, template_id, to_char(scheduled_at, ` + issueTemplateTime + `), issue_id, error, created_at
+	FROM issue_template_runs
+	WHERE template_id = $1
+	ORDER BY scheduled_at DESC, id DESC`
+
+	rows, err := db.Query(query, templateID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	runs := []IssueTemplateRun{}
+	for rows.Next() {
+		var run IssueTemplateRun
+		if err := rows.Scan(&run.ID, &run.TemplateID, &run.ScheduledAt, &run.IssueID, &run.Error, &run.CreatedAt); err != nil {
+			return nil, err
+		}
+		runs = append(runs, run)
+	}
+
+	return runs, nil
+}
+
+// GetIssueTemplateRunByIssueID obtiene la ejecución que creó un ticket, o nil si no lo creó una plantilla
+func GetIssueTemplateRunByIssueID(db *sql.DB, issueID int) (*IssueTemplateRun, error) {
+	query := `
+	SELECT id, template_id, to_char(scheduled_at, ` + issueTemplateTime + `), issue_id, error, created_at
+	FROM issue_template_runs
+	WHERE issue_id = $1`
+
+	var run IssueTemplateRun
+	err := db.QueryRow(query, issueID).Scan(&run.ID, &run.TemplateID, &run.ScheduledAt, &run.IssueID, &run.Error, &run.CreatedAt)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return &run, nil
+}
+
+func CreateIssueTemplatesTable(db *sql.DB) error {
+	query := `
+	CREATE TABLE IF NOT EXISTS issue_templates (
+		id SERIAL PRIMARY KEY,
+		project_id INT NOT NULL,
+		tracker_id INT NOT NULL,
+		name VARCHAR(255) NOT NULL,
+		subject VARCHAR(255) NOT NULL,
+		description TEXT,
+		assigned_to_id INT,
+		category_id INT,
+		priority_id INT,
+		custom_fields JSONB NOT NULL DEFAULT '{}',
+		schedule VARCHAR(100) NOT NULL,
+		starts_at TIMESTAMP NOT NULL DEFAULT (NOW() AT TIME ZONE 'UTC'),
+		next_run_at TIMESTAMP,
+		last_run_at TIMESTAMP,
+		last_error TEXT,
+		enabled BOOLEAN NOT NULL DEFAULT TRUE,
+		created_at TIMESTAMP DEFAULT NOW(),
+		updated_at TIMESTAMP DEFAULT NOW(),
+		FOREIGN KEY (project_id) REFERENCES projects(id) ON DELETE CASCADE,
+		FOREIGN KEY (tracker_id) REFERENCES trackers(id) ON DELETE CASCADE,
+		FOREIGN KEY (assigned_to_id) REFERENCES users(id) ON DELETE SET NULL,
+		FOREIGN KEY (category_id) REFERENCES categories(id) ON DELETE SET NULL,
+		FOREIGN KEY (priority_id) REFERENCES issue_priorities(id) ON DELETE SET NULL
+	);
+	CREATE INDEX IF NOT EXISTS issue_templates_next_run_at ON issue_templates (next_run_at) WHERE enabled;
+
+	CREATE TABLE IF NOT EXISTS issue_template_runs (
+		id SERIAL PRIMARY KEY,
+		template_id INT NOT NULL,
+		scheduled_at TIMESTAMP NOT NULL,
+		issue_id INT UNIQUE,
+		error TEXT,
+		created_at TIMESTAMP DEFAULT NOW(),
+		UNIQUE (template_id, scheduled_at),
+		FOREIGN KEY (template_id) REFERENCES issue_templates(id) ON DELETE CASCADE,
+		FOREIGN KEY (issue_id) REFERENCES issues(id) ON DELETE SET NULL
+	)`
+	_, err := db.Exec(query)
+	return err
+}
+
+func DropIssueTemplatesTable(db *sql.DB) error {
+	query := `DROP TABLE IF EXISTS issue_template_runs, issue_templates`
+	_, err := db.Exec(query)
+	return err
+}
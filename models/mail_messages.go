@@ -0,0 +1,119 @@
+package models
+
+import (
+	"database/sql"
+
+	"github.com/lib/pq"
+)
+
+/*
+CREATE TABLE IF NOT EXISTS mail_messages (
+	id SERIAL PRIMARY KEY,
+	message_id VARCHAR(255) NOT NULL UNIQUE, -- Cabecera Message-ID, sin < >
+	from_address VARCHAR(255) NOT NULL,
+	subject TEXT,
+	issue_id INT,                       -- Ticket creado o comentado
+	comment_id INT,                     -- Comentario creado, si era una respuesta
+	status VARCHAR(20) NOT NULL,        -- created, commented o rejected
+	error TEXT,                         -- Motivo del rechazo
+	created_at TIMESTAMP DEFAULT NOW(),
+	FOREIGN KEY (issue_id) REFERENCES issues(id) ON DELETE SET NULL,
+	FOREIGN KEY (comment_id) REFERENCES comments(id) ON DELETE SET NULL
+);
+*/
+
+// MailMessage es un correo procesado por la pasarela de correo. Sirve para no procesar dos veces
+// el mismo correo y para encontrar el ticket de las respuestas por In-Reply-To / References
+type MailMessage struct {
+	ID          int     `json:"id"`
+	MessageID   string  `json:"message_id"`
+	FromAddress string  `json:"from_address"`
+	Subject     string  `json:"subject"`
+	IssueID     *int    `json:"issue_id"`
+	CommentID   *int    `json:"comment_id"`
+	Status      string  `json:"status"`
+	Error       *string `json:"error"`
+	CreatedAt   string  `json:"created_at"`
+}
+
+const (
+	MailMessageCreated   = "created"
+	MailMessageCommented = "commented"
+	MailMessageRejected  = "rejected"
+)
+
+// CreateMailMessage registra un correo procesado. Devuelve false si ese Message-ID ya estaba
+// registrado, p. ej. porque otra instancia de la pasarela lo procesó a la vez
+func CreateMailMessage(db DBTX, message *MailMessage) (bool, error) {
+	query := `
+	INSERT INTO mail_messages (message_id, from_address, subject, issue_id, comment_id, status, error)
+	VALUES ($1, $2, $3, $4, $5, $6, $7)
+	ON CONFLICT (message_id) DO NOTHING
+	RETURNING id`
+
+	err := db.QueryRow(query, message.MessageID, message.FromAddress, message.Subject,
+		message.IssueID, message.CommentID, message.Status, message.Error).Scan(&message.ID)
+	if err == sql.ErrNoRows {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+
+	return true, nil
+}
+
+// MailMessageExists indica si un Message-ID ya se procesó
+func MailMessageExists(db *sql.DB, messageID string) (bool, error) {
+	query := `SELECT EXISTS (SELECT 1 FROM mail_messages WHERE message_id = $1)`
+
+	var exists bool
+	err := db.QueryRow(query, messageID).Scan(&exists)
+	return exists, err
+}
+
+// GetIssueIDByMailMessageIDs obtiene el ticket del más reciente de los correos procesados con
+// esos Message-ID, o nil si ninguno tiene ticket
+func GetIssueIDByMailMessageIDs(db *sql.DB, messageIDs []string) (*int, error) {
+	query := `
+	SELECT issue_id FROM mail_messages
+	WHERE message_id = ANY($1) AND issue_id IS NOT NULL
+	ORDER BY created_at DESC, id DESC
+	LIMIT 1`
+
+	var id int
+	err := db.QueryRow(query, pq.Array(messageIDs)).Scan(&id)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return &id, nil
+}
+
+func CreateMailMessagesTable(db *sql.DB) error {
+	query := `
+	CREATE TABLE IF NOT EXISTS mail_messages (
+		id SERIAL PRIMARY KEY,
+		message_id VARCHAR(255) NOT NULL UNIQUE,
+		from_address VARCHAR(255) NOT NULL,
+		subject TEXT,
+		issue_id INT,
+		comment_id INT,
+		status VARCHAR(20) NOT NULL,
+		error TEXT,
+		created_at TIMESTAMP DEFAULT NOW(),
+		FOREIGN KEY (issue_id) REFERENCES issues(id) ON DELETE SET NULL,
+		FOREIGN KEY (comment_id) REFERENCES comments(id) ON DELETE SET NULL
+	)`
+	_, err := db.Exec(query)
+	return err
+}
+
+func DropMailMessagesTable(db *sql.DB) error {
+	query := `DROP TABLE IF EXISTS mail_messages`
+	_, err := db.Exec(query)
+	return err
+}
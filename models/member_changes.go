@@ -0,0 +1,77 @@
+package models
+
+import "database/sql"
+
+/*
+CREATE TABLE IF NOT EXISTS member_changes (
+	id SERIAL PRIMARY KEY,
+	project_id INT NOT NULL,            -- Proyecto afectado
+	user_id INT NOT NULL,               -- Usuario que entra, sale o cambia de rol
+	role_id INT,                        -- Rol tras el cambio (el anterior si sale)
+	action VARCHAR(20) NOT NULL,        -- added, updated o removed
+	author_id INT,                      -- Usuario que hizo el cambio (NULL si fue el token de servicio)
+	created_at TIMESTAMP DEFAULT NOW(),
+	FOREIGN KEY (project_id) REFERENCES projects(id) ON DELETE CASCADE,
+	FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE,
+	FOREIGN KEY (role_id) REFERENCES roles(id) ON DELETE SET NULL,
+	FOREIGN KEY (author_id) REFERENCES users(id) ON DELETE SET NULL
+);
+*/
+
+const (
+	MemberChangeAdded   = "added"
+	MemberChangeUpdated = "updated"
+	MemberChangeRemoved = "removed"
+)
+
+// MemberChange es un alta, baja o cambio de rol de un miembro de un proyecto
+type MemberChange struct {
+	ID        int    `json:"id"`
+	ProjectID int    `json:"project_id"`
+	UserID    int    `json:"user_id"`
+	RoleID    *int   `json:"role_id"`
+	Action    string `json:"action"`
+	AuthorID  *int   `json:"author_id"`
+	CreatedAt string `json:"created_at"`
+}
+
+// CreateMemberChange registra un cambio en los miembros de un proyecto
+func CreateMemberChange(db DBTX, change *MemberChange) (int, error) {
+	query := `
+	INSERT INTO member_changes (project_id, user_id, role_id, action, author_id)
+	VALUES ($1, $2, $3, $4, $5)
+	RETURNING id`
+
+	var id int
+	err := db.QueryRow(query, change.ProjectID, change.UserID, change.RoleID, change.Action, change.AuthorID).Scan(&id)
+	if err != nil {
+		return 0, err
+	}
+
+	return id, nil
+}
+
+func CreateMemberChangesTable(db *sql.DB) error {
+	query := `
+	CREATE TABLE IF NOT EXISTS member_changes (
+		id SERIAL PRIMARY KEY,
+		project_id INT NOT NULL,
+		user_id INT NOT NULL,
+		role_id INT,
+		action VARCHAR(20) NOT NULL,
+		author_id INT,
+		created_at TIMESTAMP DEFAULT NOW(),
+		FOREIGN KEY (project_id) REFERENCES projects(id) ON DELETE CASCADE,
+		FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE,
+		FOREIGN KEY (role_id) REFERENCES roles(id) ON DELETE SET NULL,
+		FOREIGN KEY (author_id) REFERENCES users(id) ON DELETE SET NULL
+	)`
+	_, err := db.Exec(query)
+	return err
+}
+
+func DropMemberChangesTable(db *sql.DB) error {
+	query := `DROP TABLE IF EXISTS member_changes`
+	_, err := db.Exec(query)
+	return err
+}
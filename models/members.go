@@ -8,8 +8,8 @@ CREATE TABLE IF NOT EXISTS members (
 	user_id INT,
 	project_id INT,
 	role_id INT,
-	created_at TIMESTAMP,
-	updated_at TIMESTAMP
+	created_at TIMESTAMP DEFAULT NOW(),
+	updated_at TIMESTAMP DEFAULT NOW()
 );
 */
 
@@ -108,7 +108,7 @@ func GetMembersByUserID(db *sql.DB, userID int) ([]Member, error) {
 }
 
 // CreateMember crea un nuevo miembro
-func CreateMember(db *sql.DB, member *Member) (int, error) {
+func CreateMember(db DBTX, member *Member) (int, error) {
 	query := `INSERT INTO members (user_id, project_id, role_id) VALUES ($1, $2, $3) RETURNING id`
 
 	var id int
@@ -121,7 +121,7 @@ func CreateMember(db *sql.DB, member *Member) (int, error) {
 }
 
 // UpdateMember actualiza un miembro
-func UpdateMember(db *sql.DB, member *Member) error {
+func UpdateMember(db DBTX, member *Member) error {
 	query := `UPDATE members SET user_id = $1, project_id = $2, role_id = $3, updated_at = CURRENT_TIMESTAMP WHERE id = $4`
 
 	_, err := db.Exec(query, member.UserID, member.ProjectID, member.RoleID, member.ID)
@@ -129,7 +129,7 @@ func UpdateMember(db *sql.DB, member *Member) error {
 }
 
 // DeleteMember elimina un miembro
-func DeleteMember(db *sql.DB, id int) error {
+func DeleteMember(db DBTX, id int) error {
 	query := `DELETE FROM members WHERE id = $1`
 
 	_, err := db.Exec(query, id)
@@ -152,6 +152,53 @@ func DeleteMembersByUserID(db *sql.DB, userID int) error {
 	return err
 }
 
+// GetMemberByProjectAndUserID obtiene el miembro de un proyecto para un usuario
+func GetMemberByProjectAndUserID(db *sql.DB, projectID, userID int) (*Member, error) {
+	query := `SELECT id, user_id, project_id, role_id, created_at, updated_at FROM members WHERE project_id = $1 AND user_id = $2`
+
+	var member Member
+	err := db.QueryRow(query, projectID, userID).Scan(&member.ID, &member.UserID, &member.ProjectID, &member.RoleID, &member.CreatedAt, &member.UpdatedAt)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	return &member, nil
+}
+
+// IsProjectMember indica si el usuario es miembro del proyecto con cualquier rol
+func IsProjectMember(db *sql.DB, userID, projectID int) (bool, error) {
+	query := `SELECT EXISTS (SELECT 1 FROM members WHERE user_id = $1 AND project_id = $2)`
+
+	var exists bool
+	err := db.QueryRow(query, userID, projectID).Scan(&exists)
+	return exists, err
+}
+
+// GetProjectIDsByMemberUserID obtiene los IDs de los proyectos de los que el usuario es miembro
+func GetProjectIDsByMemberUserID(db *sql.DB, userID int) ([]int, error) {
+	query := `SELECT DISTINCT project_id FROM members WHERE user_id = $1 ORDER BY project_id`
+
+	rows, err := db.Query(query, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	ids := []int{}
+	for rows.Next() {
+		var id int
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		ids = append(ids, id)
+	}
+
+	return ids, nil
+}
+
 func CreateMembersTable(db *sql.DB) error {
 	query := `
 	CREATE TABLE IF NOT EXISTS members (
@@ -159,8 +206,8 @@ func CreateMembersTable(db *sql.DB) error {
 		user_id INT,
 		project_id INT,
 		role_id INT,
-		created_at TIMESTAMP,
-		updated_at TIMESTAMP,
+		created_at TIMESTAMP DEFAULT NOW(),
+		updated_at TIMESTAMP DEFAULT NOW(),
 		FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE,
 		FOREIGN KEY (project_id) REFERENCES projects(id) ON DELETE CASCADE,
 		FOREIGN KEY (role_id) REFERENCES roles(id) ON DELETE CASCADE
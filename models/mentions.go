@@ -0,0 +1,107 @@
+package models
+
+import "database/sql"
+
+/*
+CREATE TABLE IF NOT EXISTS mentions (
+	id SERIAL PRIMARY KEY,
+	user_id INT NOT NULL,               -- Usuario mencionado con @usuario
+	issue_id INT NOT NULL,              -- Ticket cuya descripción o comentario le menciona
+	comment_id INT,                     -- Comentario, NULL si es la descripción del ticket
+	author_id INT,                      -- Usuario que escribió la mención
+	created_at TIMESTAMP DEFAULT NOW(),
+	notified_at TIMESTAMP               -- Cuándo se avisó al usuario, NULL si está pendiente
+);
+*/
+
+// Mention es una mención @usuario en la descripción de un ticket o en un comentario
+type Mention struct {
+	ID         int     `json:"id"`
+	UserID     int     `json:"user_id"`
+	IssueID    int     `json:"issue_id"`
+	CommentID  *int    `json:"comment_id"`
+	AuthorID   *int    `json:"author_id"`
+	CreatedAt  string  `json:"created_at"`
+	NotifiedAt *string `json:"notified_at"`
+}
+
+// CreateMentions registra las menciones de los usuarios. Una mención ya registrada
+// para el mismo texto no se repite, así que editar el texto solo añade las nuevas
+func CreateMentions(db DBTX, issueID int, commentID *int, userIDs []int, authorID *int) error {
+	query := `
+	INSERT INTO mentions (user_id, issue_id, comment_id, author_id)
+	VALUES ($1, $2, $3, $4)
+	ON CONFLICT DO NOTHING`
+
+	for _, user_id := range userIDs {
+		if authorID != nil && *authorID == user_id {
+			continue
+		}
+		if _, err := db.Exec(query, user_id, issueID, commentID, authorID); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// GetMentionsByUserID obtiene las menciones de un usuario, las más recientes primero.
+// Con pending solo devuelve las que no se han notificado
+func GetMentionsByUserID(db *sql.DB, userID int, pending bool) ([]Mention, error) {
+	query := `
+	SELECT id, user_id, issue_id, comment_id, author_id, created_at, notified_at
+	FROM mentions
+	WHERE user_id = $1 AND (NOT $2 OR notified_at IS NULL)
+	ORDER BY created_at DESC, id DESC`
+
+	rows, err := db.Query(query, userID, pending)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	mentions := []Mention{}
+	for rows.Next() {
+		var mention Mention
+		if err := rows.Scan(&mention.ID, &mention.UserID, &mention.IssueID, &mention.CommentID,
+			&mention.AuthorID, &mention.CreatedAt, &mention.NotifiedAt); err != nil {
+			return nil, err
+		}
+		mentions = append(mentions, mention)
+	}
+
+	return mentions, nil
+}
+
+// MarkMentionsNotified marca como notificadas las menciones pendientes de un usuario
+func MarkMentionsNotified(db *sql.DB, userID int) error {
+	query := `UPDATE mentions SET notified_at = NOW() WHERE user_id = $1 AND notified_at IS NULL`
+	_, err := db.Exec(query, userID)
+	return err
+}
+
+func CreateMentionsTable(db *sql.DB) error {
+	query := `
+	CREATE TABLE IF NOT EXISTS mentions (
+		id SERIAL PRIMARY KEY,
+		user_id INT NOT NULL,
+		issue_id INT NOT NULL,
+		comment_id INT,
+		author_id INT,
+		created_at TIMESTAMP DEFAULT NOW(),
+		notified_at TIMESTAMP,
+		FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE,
+		FOREIGN KEY (issue_id) REFERENCES issues(id) ON DELETE CASCADE,
+		FOREIGN KEY (comment_id) REFERENCES comments(id) ON DELETE CASCADE,
+		FOREIGN KEY (author_id) REFERENCES users(id) ON DELETE SET NULL
+	);
+	CREATE UNIQUE INDEX IF NOT EXISTS mentions_unique ON mentions (user_id, issue_id, COALESCE(comment_id, 0))`
+	_, err := db.Exec(query)
+	return err
+}
+
+func DropMentionsTable(db *sql.DB) error {
+	query := `DROP TABLE IF EXISTS mentions`
+	_, err := db.Exec(query)
+	return err
+}
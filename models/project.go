@@ -14,17 +14,30 @@ type Project struct {
 	Name        string    `json:"name"`
 	Identifier  string    `json:"identifier"`
 	Description string    `json:"description"`
+	Status      string    `json:"status"`       // active, closed o archived
+	LockVersion int       `json:"lock_version"` // versión leída, para detectar ediciones concurrentes
 	CreatedOn   time.Time `json:"created_on"`
 	UpdatedOn   time.Time `json:"updated_on"`
 }
 
+// Estados de un proyecto: los cerrados son de solo lectura y los archivados además se ocultan
+const (
+	ProjectStatusActive   = "active"
+	ProjectStatusClosed   = "closed"
+	ProjectStatusArchived = "archived"
+)
+
 // CreateProject inserta un nuevo proyecto en la base de datos
-func CreateProject(db *sql.DB, project *Project) (int, error) {
+func CreateProject(db DBTX, project *Project) (int, error) {
 	query := `
-	INSERT INTO projects (name, identifier, description, parent_id, created_on, updated_on)
-	VALUES ($1, $2, $3, $4, $5, $6)
+	INSERT INTO projects (name, identifier, description, parent_id, created_on, updated_on, status)
+	VALUES ($1, $2, $3, $4, $5, $6, $7)
 	RETURNING id`
 
+	if project.Status == "" {
+		project.Status = ProjectStatusActive
+	}
+
 	err := db.QueryRow(
 		query,
 		project.Name,
@@ -33,6 +46,7 @@ func CreateProject(db *sql.DB, project *Project) (int, error) {
 		project.ParentID,
 		time.Now(),
 		time.Now(),
+		project.Status,
 	).Scan(&project.ID)
 
 	if err != nil {
@@ -46,7 +60,7 @@ func CreateProject(db *sql.DB, project *Project) (int, error) {
 // GetProjectByID obtiene un proyecto por su ID
 func GetProjectByID(db *sql.DB, id int) (*Project, error) {
 	query := `
-	SELECT id, name, identifier, description, parent_id, created_on, updated_on
+	SELECT id, name, identifier, description, parent_id, created_on, updated_on, status, lock_version
 	FROM projects
 	WHERE id = $1`
 
@@ -60,6 +74,8 @@ func GetProjectByID(db *sql.DB, id int) (*Project, error) {
 		&project.ParentID,
 		&project.CreatedOn,
 		&project.UpdatedOn,
+		&project.Status,
+		&project.LockVersion,
 	)
 
 	if err != nil {
@@ -75,7 +91,7 @@ func GetProjectByID(db *sql.DB, id int) (*Project, error) {
 
 func GetProjectsByUserID(db *sql.DB, userID int) ([]Project, error) {
 	query := `
-	SELECT id, name, identifier, description, parent_id, created_on, updated_on
+	SELECT id, name, identifier, description, parent_id, created_on, updated_on, status, lock_version
 	FROM projects
 	WHERE id IN (
 		SELECT project_id
@@ -107,6 +123,8 @@ func GetProjectsByUserID(db *sql.DB, userID int) ([]Project, error) {
 			&project.ParentID,
 			&project.CreatedOn,
 			&project.UpdatedOn,
+			&project.Status,
+			&project.LockVersion,
 		)
 		if err != nil {
 			log.Printf("Error al escanear el proyecto: %v", err)
@@ -119,14 +137,16 @@ func GetProjectsByUserID(db *sql.DB, userID int) ([]Project, error) {
 	return projects, nil
 }
 
-// UpdateProject actualiza un proyecto existente en la base de datos
-func UpdateProject(db *sql.DB, project *Project) error {
+// UpdateProject actualiza un proyecto existente en la base de datos si su lock_version
+// sigue siendo la leída, e incrementa la versión. Devuelve ErrStaleObject si no lo es
+func UpdateProject(db DBTX, project *Project) error {
 	query := `
 	UPDATE projects
-	SET name = $1, identifier = $2, description = $3, parent_id = $4, updated_on = $5
-	WHERE id = $6`
+	SET name = $1, identifier = $2, description = $3, parent_id = $4, updated_on = $5, lock_version = lock_version + 1
+	WHERE id = $6 AND lock_version = $7
+	RETURNING lock_version`
 
-	_, err := db.Exec(
+	err := db.QueryRow(
 		query,
 		project.Name,
 		project.Identifier,
@@ -134,8 +154,12 @@ func UpdateProject(db *sql.DB, project *Project) error {
 		project.ParentID,
 		time.Now(),
 		project.ID,
-	)
+		project.LockVersion,
+	).Scan(&project.LockVersion)
 
+	if err == sql.ErrNoRows {
+		return ErrStaleObject
+	}
 	if err != nil {
 		log.Printf("Error al actualizar el proyecto: %v", err)
 		return err
@@ -147,7 +171,7 @@ func UpdateProject(db *sql.DB, project *Project) error {
 // GetProjects obtiene todos los proyectos de la base de datos
 func GetAllProjects(db *sql.DB) ([]Project, error) {
 	query := `
-	SELECT id, name, identifier, description, parent_id, created_on, updated_on
+	SELECT id, name, identifier, description, parent_id, created_on, updated_on, status, lock_version
 	FROM projects
 	ORDER BY id`
 
@@ -170,6 +194,8 @@ func GetAllProjects(db *sql.DB) ([]Project, error) {
 			&project.ParentID,
 			&project.CreatedOn,
 			&project.UpdatedOn,
+			&project.Status,
+			&project.LockVersion,
 		)
 		if err != nil {
 			log.Printf("Error al escanear el proyecto: %v", err)
@@ -182,6 +208,19 @@ func GetAllProjects(db *sql.DB) ([]Project, error) {
 	return projects, nil
 }
 
+// SetProjectStatus cambia el estado de un proyecto
+func SetProjectStatus(db *sql.DB, id int, status string) error {
+	query := `UPDATE projects SET status = $1, updated_on = $2, lock_version = lock_version + 1 WHERE id = $3`
+
+	_, err := db.Exec(query, status, time.Now(), id)
+	if err != nil {
+		log.Printf("Error al cambiar el estado del proyecto: %v", err)
+		return err
+	}
+
+	return nil
+}
+
 func CountProjects(db *sql.DB) (int, error) {
 	query := `SELECT COUNT(*) FROM projects`
 
@@ -218,6 +257,8 @@ func CreateProjectsTable(db *sql.DB) error {
 		created_on TIMESTAMP DEFAULT NOW(),
 		updated_on TIMESTAMP DEFAULT NOW(),
 		parent_id INT,
+		status VARCHAR(20) NOT NULL DEFAULT 'active' CHECK (status IN ('active', 'closed', 'archived')),
+		lock_version INT NOT NULL DEFAULT 1,
 		FOREIGN KEY (parent_id) REFERENCES projects(id) ON DELETE SET NULL
 	);`
 
@@ -344,3 +385,164 @@ func SampleProjects(db *sql.DB) error {
 
 	return nil
 }
+
+// GetProjectByIdentifier obtiene un proyecto por su identificador
+func GetProjectByIdentifier(db *sql.DB, identifier string) (*Project, error) {
+	query := `
+	SELECT id, name, identifier, description, parent_id, created_on, updated_on, status, lock_version
+	FROM projects
+	WHERE identifier = $1`
+
+	project := &Project{}
+
+	err := db.QueryRow(query, identifier).Scan(
+		&project.ID,
+		&project.Name,
+		&project.Identifier,
+		&project.Description,
+		&project.ParentID,
+		&project.CreatedOn,
+		&project.UpdatedOn,
+		&project.Status,
+		&project.LockVersion,
+	)
+
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil // Proyecto no encontrado
+		}
+		log.Printf("Error al obtener el proyecto: %v", err)
+		return nil, err
+	}
+
+	return project, nil
+}
+
+// GetProjectDescendantIDs obtiene el ID del proyecto y los de todos sus subproyectos
+func GetProjectDescendantIDs(db *sql.DB, id int) ([]int, error) {
+	query := `
+	WITH RECURSIVE tree AS (
+		SELECT id FROM projects WHERE id = $1
+		UNION
+		SELECT p.id FROM projects p JOIN tree t ON p.parent_id = t.id
+	)
+	SELECT id FROM tree ORDER BY id`
+
+	rows, err := db.Query(query, id)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	ids := []int{}
+	for rows.Next() {
+		var child int
+		if err := rows.Scan(&child); err != nil {
+			return nil, err
+		}
+		ids = append(ids, child)
+	}
+
+	return ids, nil
+}
+
+// ProjectRollup son los totales de un proyecto sumando los de todos sus subproyectos
+type ProjectRollup struct {
+	ProjectID        int `json:"project_id"`
+	IssueCount       int `json:"issue_count"`        // Tickets del propio proyecto
+	TotalIssueCount  int `json:"total_issue_count"`  // Tickets del proyecto y sus subproyectos
+	OpenIssueCount   int `json:"open_issue_count"`   // Tickets abiertos del proyecto y sus subproyectos
+	TotalMemberCount int `json:"total_member_count"` // Usuarios distintos miembros del proyecto o sus subproyectos
+}
+
+// GetProjectRollups calcula los totales de cada proyecto incluyendo sus subproyectos
+func GetProjectRollups(db *sql.DB) (map[int]ProjectRollup, error) {
+	query := `
+	WITH RECURSIVE tree AS (
+		SELECT id AS root_id, id AS project_id FROM projects
+		UNION
+		SELECT t.root_id, p.id FROM projects p JOIN tree t ON p.parent_id = t.project_id
+	),
+	issue_counts AS (
+		SELECT i.project_id,
+			COUNT(*) AS total,
+			COUNT(*) FILTER (WHERE NOT COALESCE(s.is_closed, FALSE)) AS open
+		FROM issues i
+		LEFT JOIN issue_statuses s ON s.name = i.status
+		GROUP BY i.project_id
+	)
+	SELECT t.root_id,
+		COALESCE(SUM(ic.total) FILTER (WHERE t.project_id = t.root_id), 0),
+		COALESCE(SUM(ic.total), 0),
+		COALESCE(SUM(ic.open), 0),
+		(SELECT COUNT(DISTINCT m.user_id) FROM tree t2 JOIN members m ON m.project_id = t2.project_id WHERE t2.root_id = t.root_id)
+	FROM tree t
+	LEFT JOIN issue_counts ic ON ic.project_id = t.project_id
+	GROUP BY t.root_id`
+
+	rows, err := db.Query(query)
+	if err != nil {
+		log.Printf("Error al calcular los totales de los proyectos: %v", err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	rollups := map[int]ProjectRollup{}
+	for rows.Next() {
+		rollup := ProjectRollup{}
+		err := rows.Scan(&rollup.ProjectID, &rollup.IssueCount, &rollup.TotalIssueCount, &rollup.OpenIssueCount, &rollup.TotalMemberCount)
+		if err != nil {
+			return nil, err
+		}
+		rollups[rollup.ProjectID] = rollup
+	}
+
+	return rollups, nil
+}
+
+// GetMembersByProjectTree obtiene los miembros del proyecto y de todos sus subproyectos
+func GetMembersByProjectTree(db *sql.DB, projectID int) ([]Member, error) {
+	query := `
+	WITH RECURSIVE tree AS (
+		SELECT id FROM projects WHERE id = $1
+		UNION
+		SELECT p.id FROM projects p JOIN tree t ON p.parent_id = t.id
+	)
+	SELECT m.id, m.user_id, m.project_id, m.role_id, m.created_at, m.updated_at
+	FROM members m
+	JOIN tree t ON t.id = m.project_id
+	ORDER BY m.project_id, m.id`
+
+	rows, err := db.Query(query, projectID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	members := []Member{}
+	for rows.Next() {
+		var member Member
+		err := rows.Scan(&member.ID, &member.UserID, &member.ProjectID, &member.RoleID, &member.CreatedAt, &member.UpdatedAt)
+		if err != nil {
+			return nil, err
+		}
+		members = append(members, member)
+	}
+
+	return members, nil
+}
+
+// IsProjectAncestor indica si ancestorID es el propio proyecto o uno de sus antecesores
+func IsProjectAncestor(db *sql.DB, ancestorID, projectID int) (bool, error) {
+	query := `
+	WITH RECURSIVE ancestors AS (
+		SELECT id, parent_id FROM projects WHERE id = $1
+		UNION
+		SELECT p.id, p.parent_id FROM projects p JOIN ancestors a ON p.id = a.parent_id
+	)
+	SELECT EXISTS (SELECT 1 FROM ancestors WHERE id = $2)`
+
+	var exists bool
+	err := db.QueryRow(query, projectID, ancestorID).Scan(&exists)
+	return exists, err
+}
@@ -0,0 +1,113 @@
+package models
+
+import (
+	"database/sql"
+
+	"github.com/lib/pq"
+)
+
+/*
+CREATE TABLE IF NOT EXISTS enabled_modules (
+	project_id INT NOT NULL,            -- Proyecto
+	name VARCHAR(30) NOT NULL,          -- Módulo habilitado: issues, time_tracking, wiki, files o roadmap
+	PRIMARY KEY (project_id, name),
+	FOREIGN KEY (project_id) REFERENCES projects(id) ON DELETE CASCADE
+);
+*/
+
+// Módulos que se pueden habilitar por proyecto
+const (
+	ModuleIssues       = "issues"
+	ModuleTimeTracking = "time_tracking"
+	ModuleWiki         = "wiki"
+	ModuleFiles        = "files"
+	ModuleRoadmap      = "roadmap"
+)
+
+// ProjectModules son todos los módulos, habilitados por defecto en los proyectos nuevos
+var ProjectModules = []string{ModuleIssues, ModuleTimeTracking, ModuleWiki, ModuleFiles, ModuleRoadmap}
+
+// IsProjectModule indica si name es un módulo conocido
+func IsProjectModule(name string) bool {
+	for _, module := range ProjectModules {
+		if module == name {
+			return true
+		}
+	}
+	return false
+}
+
+// GetProjectModules obtiene los módulos habilitados en un proyecto
+func GetProjectModules(db *sql.DB, projectID int) ([]string, error) {
+	query := `SELECT name FROM enabled_modules WHERE project_id = $1 ORDER BY name`
+
+	rows, err := db.Query(query, projectID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	modules := []string{}
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			return nil, err
+		}
+		modules = append(modules, name)
+	}
+
+	return modules, nil
+}
+
+// IsProjectModuleEnabled indica si el módulo está habilitado en el proyecto
+func IsProjectModuleEnabled(db *sql.DB, projectID int, module string) (bool, error) {
+	query := `SELECT EXISTS (SELECT 1 FROM enabled_modules WHERE project_id = $1 AND name = $2)`
+
+	var exists bool
+	err := db.QueryRow(query, projectID, module).Scan(&exists)
+	return exists, err
+}
+
+// SetProjectModules sustituye los módulos habilitados en un proyecto
+func SetProjectModules(db DBTX, projectID int, modules []string) error {
+	if _, err := db.Exec(`DELETE FROM enabled_modules WHERE project_id = $1`, projectID); err != nil {
+		return err
+	}
+
+	for _, module := range modules {
+		query := `INSERT INTO enabled_modules (project_id, name) VALUES ($1, $2) ON CONFLICT DO NOTHING`
+		if _, err := db.Exec(query, projectID, module); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func CreateEnabledModulesTable(db *sql.DB) error {
+	query := `
+	CREATE TABLE IF NOT EXISTS enabled_modules (
+		project_id INT NOT NULL,
+		name VARCHAR(30) NOT NULL,
+		PRIMARY KEY (project_id, name),
+		FOREIGN KEY (project_id) REFERENCES projects(id) ON DELETE CASCADE
+	)`
+	_, err := db.Exec(query)
+	return err
+}
+
+func DropEnabledModulesTable(db *sql.DB) error {
+	query := `DROP TABLE IF EXISTS enabled_modules`
+	_, err := db.Exec(query)
+	return err
+}
+
+// SampleEnabledModules habilita todos los módulos en los proyectos existentes
+func SampleEnabledModules(db *sql.DB) error {
+	query := `
+	INSERT INTO enabled_modules (project_id, name)
+	SELECT p.id, m.name FROM projects p CROSS JOIN unnest($1::text[]) AS m(name)
+	ON CONFLICT DO NOTHING`
+	_, err := db.Exec(query, pq.Array(ProjectModules))
+	return err
+}
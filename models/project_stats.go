@@ -0,0 +1,238 @@
+package models
+
+import (
+	"database/sql"
+
+	"github.com/lib/pq"
+)
+
+// IssueCount es el número de tickets de un valor de una dimensión (estado, tracker...). ID es nil
+// para los tickets sin valor, p. ej. sin asignar
+type IssueCount struct {
+	ID     *int   `json:"id"`
+	Name   string `json:"name"`
+	Total  int    `json:"total"`
+	Open   int    `json:"open"`
+	Closed int    `json:"closed"`
+}
+
+// IssueWeek es la evolución de una semana: tickets creados, cerrados y reabiertos en ella, y los
+// abiertos al terminarla. Week es el lunes de la semana
+type IssueWeek struct {
+	Week     string `json:"week"`
+	Created  int    `json:"created"`
+	Closed   int    `json:"closed"`
+	Reopened int    `json:"reopened"`
+	Open     int    `json:"open"`
+}
+
+// ProjectStats son las estadísticas de tickets de uno o varios proyectos en un intervalo
+type ProjectStats struct {
+	Total        int          `json:"total"`
+	Open         int          `json:"open"`
+	Closed       int          `json:"closed"`
+	ByStatus     []IssueCount `json:"by_status"`
+	ByTracker    []IssueCount `json:"by_tracker"`
+	ByAssignee   []IssueCount `json:"by_assignee"`
+	ByPriority   []IssueCount `json:"by_priority"`
+	ByVersion    []IssueCount `json:"by_version"`
+	Weeks        []IssueWeek  `json:"weeks"`
+	ClosedCount  int          `json:"closed_count"`   // tickets cerrados en el intervalo
+	AvgCloseDays *float64     `json:"avg_close_days"` // media de días desde la creación hasta el cierre
+}
+
+// statsDimensions son la clave, el nombre, los JOIN y el orden de cada desglose
+var statsDimensions = map[string]struct{ key, name, join, order string }{
+	"status":   {"st.id", "i.status", "", "MIN(st.position)"},
+	"tracker":  {"i.tracker_id", "t.name", "LEFT JOIN trackers t ON t.id = i.tracker_id", "2"},
+	"assignee": {"i.assigned_to_id", "u.username", "LEFT JOIN users u ON u.id = i.assigned_to_id", "2"},
+	"priority": {"i.priority_id", "p.name", "LEFT JOIN issue_priorities p ON p.id = i.priority_id", "MIN(p.position)"},
+	"version":  {"i.fixed_version_id", "v.name", "LEFT JOIN versions v ON v.id = i.fixed_version_id", "MIN(v.due_date)"},
+}
+
+// statsClosedStatuses son los nombres de los estados que cierran un ticket
+const statsClosedStatuses = `(SELECT name FROM issue_statuses WHERE is_closed)`
+
+// countIssuesBy agrupa por una dimensión los tickets de los proyectos creados en [from, to]
+func countIssuesBy(db *sql.DB, dimension string, projectIDs []int, from, to string) ([]IssueCount, error) {
+	d := statsDimensions[dimension]
+	query := `
+	SELECT ` + d.key + `, COALESCE(` + d.name + `, ''),
+		COUNT(*), COUNT(*) FILTER (WHERE NOT COALESCE(st.is_closed, FALSE))
+	FROM issues i
+	LEFT JOIN issue_statuses st ON st.name = i.status
+	` + d.join + `
+	WHERE i.project_id = ANY($1) AND i.created_at >= $2::date AND i.created_at < $3::date + 1
+	GROUP BY 1, 2
+	ORDER BY ` + d.order + ` NULLS LAST, 2`
+
+	rows, err := db.Query(query, pq.Array(projectIDs), from, to)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	counts := []IssueCount{}
+	for rows.Next() {
+		var count IssueCount
+		if err := rows.Scan(&count.ID, &count.Name, &count.Total, &count.Open); err != nil {
+			return nil, err
+		}
+		count.Closed = count.Total - count.Open
+		counts = append(counts, count)
+	}
+
+	return counts, nil
+}
+
+// GetIssueWeeks calcula la evolución semanal de los tickets de los proyectos entre from y to.
+// Los cierres y las reaperturas salen del historial de cambios de estado
+func GetIssueWeeks(db *sql.DB, projectIDs []int, from, to string) ([]IssueWeek, error) {
+	// Todo lo anterior a la primera semana se acumula en la semana previa, que da los abiertos
+	// de partida y no se devuelve
+	query := `
+	WITH transitions AS (
+		SELECT c.created_at AS at,
+			c.new_value IN ` + statsClosedStatuses + ` AS to_closed,
+			COALESCE(c.old_value IN ` + statsClosedStatuses + `, FALSE) AS from_closed
+		FROM issue_changes c
+		JOIN issues i ON i.id = c.issue_id
+		WHERE i.project_id = ANY($1) AND c.field = 'status'
+	),
+	events AS (
+		SELECT created_at AS at, 1 AS created, 0 AS closed, 0 AS reopened FROM issues WHERE project_id = ANY($1)
+		UNION ALL
+		SELECT at, 0, 1, 0 FROM transitions WHERE to_closed AND NOT from_closed
+		UNION ALL
+		SELECT at, 0, 0, 1 FROM transitions WHERE from_closed AND NOT to_closed
+	),
+	buckets AS (
+		SELECT GREATEST(date_trunc('week', at), date_trunc('week', $2::date) - interval '1 week') AS week,
+			SUM(created) AS created, SUM(closed) AS closed, SUM(reopened) AS reopened
+		FROM events
+		WHERE at < date_trunc('week', $3::date) + interval '1 week'
+		GROUP BY 1
+	),
+	series AS (
+		SELECT w.week, COALESCE(b.created, 0) AS created, COALESCE(b.closed, 0) AS closed, COALESCE(b.reopened, 0) AS reopened
+		FROM generate_series(date_trunc('week', $2::date) - interval '1 week', date_trunc('week', $3::date), interval '1 week') AS w(week)
+		LEFT JOIN buckets b ON b.week = w.week
+	)
+	SELECT to_char(week, 'YYYY-MM-DD'), created, closed, reopened,
+		SUM(created - closed + reopened) OVER (ORDER BY week)
+	FROM series
+	ORDER BY week`
+
+	rows, err := db.Query(query, pq.Array(projectIDs), from, to)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	weeks := []IssueWeek{}
+	first := true
+	for rows.Next() {
+		var week IssueWeek
+		if err := rows.Scan(&week.Week, &week.Created, &week.Closed, &week.Reopened, &week.Open); err != nil {
+			return nil, err
+		}
+		if first {
+			first = false
+			continue
+		}
+		weeks = append(weeks, week)
+	}
+
+	return weeks, nil
+}
+
+// GetProjectStats calcula las estadísticas de los tickets de los proyectos creados en [from, to],
+// la evolución semanal y el tiempo medio de cierre de los cerrados en el intervalo. Las fechas
+// son YYYY-MM-DD
+func GetProjectStats(db *sql.DB, projectIDs []int, from, to string) (*ProjectStats, error) {
+	stats := &ProjectStats{}
+
+	query := `
+	SELECT COUNT(*), COUNT(*) FILTER (WHERE i.status NOT IN ` + statsClosedStatuses + `)
+	FROM issues i
+	WHERE i.project_id = ANY($1) AND i.created_at >= $2::date AND i.created_at < $3::date + 1`
+	if err := db.QueryRow(query, pq.Array(projectIDs), from, to).Scan(&stats.Total, &stats.Open); err != nil {
+		return nil, err
+	}
+	stats.Closed = stats.Total - stats.Open
+
+	var err error
+	for dimension, target := range map[string]*[]IssueCount{
+		"status":   &stats.ByStatus,
+		"tracker":  &stats.ByTracker,
+		"assignee": &stats.ByAssignee,
+		"priority": &stats.ByPriority,
+		"version":  &stats.ByVersion,
+	} {
+		if *target, err = countIssuesBy(db, dimension, projectIDs, from, to); err != nil {
+			return nil, err
+		}
+	}
+
+	if stats.Weeks, err = GetIssueWeeks(db, projectIDs, from, to); err != nil {
+		return nil, err
+	}
+
+	// El cierre es el último paso a un estado cerrado; sin historial se usa la última modificación
+	query = `
+	SELECT COUNT(*), AVG(EXTRACT(EPOCH FROM (closed.at - i.created_at)) / 86400)
+	FROM issues i
+	CROSS JOIN LATERAL (
+		SELECT COALESCE(MAX(c.created_at), i.updated_at) AS at
+		FROM issue_changes c
+		WHERE c.issue_id = i.id AND c.field = 'status' AND c.new_value IN ` + statsClosedStatuses + `
+	) closed
+	WHERE i.project_id = ANY($1) AND i.status IN ` + statsClosedStatuses + `
+	AND closed.at >= $2::date AND closed.at < $3::date + 1`
+	var avg sql.NullFloat64
+	if err := db.QueryRow(query, pq.Array(projectIDs), from, to).Scan(&stats.ClosedCount, &avg); err != nil {
+		return nil, err
+	}
+	if avg.Valid {
+		stats.AvgCloseDays = &avg.Float64
+	}
+
+	return stats, nil
+}
+
+// ProjectIssueGauge son los tickets abiertos y vencidos de un proyecto, para las métricas
+type ProjectIssueGauge struct {
+	Identifier string
+	Open       int
+	Overdue    int
+}
+
+// GetProjectIssueGauges cuenta los tickets abiertos y los vencidos de cada proyecto no archivado
+func GetProjectIssueGauges(db *sql.DB) ([]ProjectIssueGauge, error) {
+	query := `
+	SELECT p.identifier,
+		COUNT(i.id) FILTER (WHERE NOT COALESCE(st.is_closed, FALSE)),
+		COUNT(i.id) FILTER (WHERE NOT COALESCE(st.is_closed, FALSE) AND i.due_date < CURRENT_DATE)
+	FROM projects p
+	LEFT JOIN issues i ON i.project_id = p.id
+	LEFT JOIN issue_statuses st ON st.name = i.status
+	WHERE p.status <> $1
+	GROUP BY p.id, p.identifier
+	ORDER BY p.identifier`
+
+	rows, err := db.Query(query, ProjectStatusArchived)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	gauges := []ProjectIssueGauge{}
+	for rows.Next() {
+		var gauge ProjectIssueGauge
+		if err := rows.Scan(&gauge.Identifier, &gauge.Open, &gauge.Overdue); err != nil {
+			return nil, err
+		}
+		gauges = append(gauges, gauge)
+	}
+	return gauges, rows.Err()
+}
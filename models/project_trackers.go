@@ -0,0 +1,104 @@
+package models
+
+import "database/sql"
+
+/*
+CREATE TABLE IF NOT EXISTS project_trackers (
+	project_id INT NOT NULL,            -- Proyecto
+	tracker_id INT NOT NULL,            -- Tracker habilitado en el proyecto
+	PRIMARY KEY (project_id, tracker_id),
+	FOREIGN KEY (project_id) REFERENCES projects(id) ON DELETE CASCADE,
+	FOREIGN KEY (tracker_id) REFERENCES trackers(id) ON DELETE CASCADE
+);
+*/
+
+// GetTrackersByProjectID obtiene los trackers habilitados en un proyecto
+func GetTrackersByProjectID(db *sql.DB, projectID int) ([]Tracker, error) {
+	query := `
+	SELECT ` + trackerColumns + `
+	FROM trackers
+	WHERE id IN (SELECT tracker_id FROM project_trackers WHERE project_id = $1)
+	ORDER BY position, id`
+
+	rows, err := db.Query(query, projectID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	trackers := []Tracker{}
+	for rows.Next() {
+		tracker := Tracker{}
+		if err := scanTracker(rows, &tracker); err != nil {
+			return nil, err
+		}
+		trackers = append(trackers, tracker)
+	}
+
+	return trackers, nil
+}
+
+// IsTrackerEnabledForProject indica si el tracker está habilitado en el proyecto
+func IsTrackerEnabledForProject(db DBTX, projectID, trackerID int) (bool, error) {
+	query := `SELECT EXISTS (SELECT 1 FROM project_trackers WHERE project_id = $1 AND tracker_id = $2)`
+
+	var exists bool
+	err := db.QueryRow(query, projectID, trackerID).Scan(&exists)
+	return exists, err
+}
+
+// SetProjectTrackers sustituye los trackers habilitados en un proyecto
+func SetProjectTrackers(db DBTX, projectID int, trackerIDs []int) error {
+	if _, err := db.Exec(`DELETE FROM project_trackers WHERE project_id = $1`, projectID); err != nil {
+		return err
+	}
+
+	for _, trackerID := range trackerIDs {
+		query := `INSERT INTO project_trackers (project_id, tracker_id) VALUES ($1, $2) ON CONFLICT DO NOTHING`
+		if _, err := db.Exec(query, projectID, trackerID); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// EnableAllTrackersForProject habilita todos los trackers en un proyecto, lo que se hace al crearlo
+func EnableAllTrackersForProject(db DBTX, projectID int) error {
+	query := `
+	INSERT INTO project_trackers (project_id, tracker_id)
+	SELECT $1, id FROM trackers
+	ON CONFLICT DO NOTHING`
+
+	_, err := db.Exec(query, projectID)
+	return err
+}
+
+func CreateProjectTrackersTable(db *sql.DB) error {
+	query := `
+	CREATE TABLE IF NOT EXISTS project_trackers (
+		project_id INT NOT NULL,
+		tracker_id INT NOT NULL,
+		PRIMARY KEY (project_id, tracker_id),
+		FOREIGN KEY (project_id) REFERENCES projects(id) ON DELETE CASCADE,
+		FOREIGN KEY (tracker_id) REFERENCES trackers(id) ON DELETE CASCADE
+	)`
+	_, err := db.Exec(query)
+	return err
+}
+
+func DropProjectTrackersTable(db *sql.DB) error {
+	query := `DROP TABLE IF EXISTS project_trackers`
+	_, err := db.Exec(query)
+	return err
+}
+
+// SampleProjectTrackers habilita todos los trackers en los proyectos existentes
+func SampleProjectTrackers(db *sql.DB) error {
+	query := `
+	INSERT INTO project_trackers (project_id, tracker_id)
+	SELECT p.id, t.id FROM projects p CROSS JOIN trackers t
+	ON CONFLICT DO NOTHING`
+	_, err := db.Exec(query)
+	return err
+}
@@ -0,0 +1,80 @@
+package models
+
+import "database/sql"
+
+/*
+CREATE TABLE IF NOT EXISTS redmine_id_map (
+	entity_type VARCHAR(50) NOT NULL,   -- Tipo de entidad importada (por ejemplo, "project", "issue")
+	redmine_id INT NOT NULL,            -- ID en la instancia de Redmine de origen
+	local_id INT NOT NULL,              -- ID de la entidad en esta base de datos
+	imported_at TIMESTAMP DEFAULT NOW(),
+	PRIMARY KEY (entity_type, redmine_id)
+);
+*/
+
+// Tipos de entidad de la tabla de correspondencias de la importación de Redmine
+const (
+	RedmineEntityProject     = "project"
+	RedmineEntityTracker     = "tracker"
+	RedmineEntityIssueStatus = "issue_status"
+	RedmineEntityUser        = "user"
+	RedmineEntityRole        = "role"
+	RedmineEntityCategory    = "category"
+	RedmineEntityIssue       = "issue"
+	RedmineEntityJournal     = "journal"
+)
+
+// RedmineIDMap relaciona un ID de Redmine con el ID local de la entidad importada
+type RedmineIDMap struct {
+	EntityType string `json:"entity_type"`
+	RedmineID  int    `json:"redmine_id"`
+	LocalID    int    `json:"local_id"`
+	ImportedAt string `json:"imported_at"`
+}
+
+// GetRedmineLocalID devuelve el ID local de una entidad de Redmine ya importada
+func GetRedmineLocalID(db *sql.DB, entityType string, redmineID int) (int, bool, error) {
+	query := `SELECT local_id FROM redmine_id_map WHERE entity_type = $1 AND redmine_id = $2`
+
+	var localID int
+	err := db.QueryRow(query, entityType, redmineID).Scan(&localID)
+	if err == sql.ErrNoRows {
+		return 0, false, nil
+	}
+	if err != nil {
+		return 0, false, err
+	}
+
+	return localID, true, nil
+}
+
+// SaveRedmineIDMap guarda o actualiza la correspondencia de una entidad importada
+func SaveRedmineIDMap(db DBTX, entityType string, redmineID, localID int) error {
+	query := `
+	INSERT INTO redmine_id_map (entity_type, redmine_id, local_id)
+	VALUES ($1, $2, $3)
+	ON CONFLICT (entity_type, redmine_id)
+	DO UPDATE SET local_id = EXCLUDED.local_id, imported_at = NOW()`
+
+	_, err := db.Exec(query, entityType, redmineID, localID)
+	return err
+}
+
+func CreateRedmineIDMapTable(db *sql.DB) error {
+	query := `
+	CREATE TABLE IF NOT EXISTS redmine_id_map (
+		entity_type VARCHAR(50) NOT NULL,
+		redmine_id INT NOT NULL,
+		local_id INT NOT NULL,
+		imported_at TIMESTAMP DEFAULT NOW(),
+		PRIMARY KEY (entity_type, redmine_id)
+	)`
+	_, err := db.Exec(query)
+	return err
+}
+
+func DropRedmineIDMapTable(db *sql.DB) error {
+	query := `DROP TABLE IF EXISTS redmine_id_map`
+	_, err := db.Exec(query)
+	return err
+}
@@ -294,7 +294,7 @@ func RemoveAllRolesFromUser(db *sql.DB, userID int) error {
 // GetUsersByRoleID obtiene los usuarios con un rol
 func GetUsersByRoleID(db *sql.DB, roleID int) ([]*User, error) {
 	query := `
-	SELECT u.id, u.username, u.email, u.password_hash, u.created_at, u.updated_at
+	SELECT u.id, u.username, u.email, u.password_hash, u.created_at, u.updated_at, u.lock_version
 	FROM users u
 	JOIN user_roles ur ON u.id = ur.user_id
 	WHERE ur.role_id = $1`
@@ -309,7 +309,7 @@ func GetUsersByRoleID(db *sql.DB, roleID int) ([]*User, error) {
 	for rows.Next() {
 		user := &User{}
 
-		err := rows.Scan(&user.ID, &user.Username, &user.Email, &user.PasswordHash, &user.CreatedAt, &user.UpdatedAt)
+		err := rows.Scan(&user.ID, &user.Username, &user.Email, &user.PasswordHash, &user.CreatedAt, &user.UpdatedAt, &user.LockVersion)
 		if err != nil {
 			return nil, err
 		}
@@ -392,7 +392,7 @@ func RemoveAllRolesFromProject(db *sql.DB, projectID int) error {
 // GetProjectsByRoleID obtiene los proyectos con un rol
 func GetProjectsByRoleID(db *sql.DB, roleID int) ([]*Project, error) {
 	query := `
-	SELECT p.id, p.name, p.identifier, p.description, p.parent_id, p.created_on, p.updated_on
+	SELECT p.id, p.name, p.identifier, p.description, p.parent_id, p.created_on, p.updated_on, p.status, p.lock_version
 	FROM projects p
 	JOIN project_roles pr ON p.id = pr.project_id
 	WHERE pr.role_id = $1`
@@ -415,6 +415,8 @@ func GetProjectsByRoleID(db *sql.DB, roleID int) ([]*Project, error) {
 			&project.ParentID,
 			&project.CreatedOn,
 			&project.UpdatedOn,
+			&project.Status,
+			&project.LockVersion,
 		)
 		if err != nil {
 			return nil, err
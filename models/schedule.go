@@ -0,0 +1,170 @@
+package models
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Schedule es la programación de una plantilla: una expresión cron de cinco campos (minuto,
+// hora, día del mes, mes y día de la semana, 0 = domingo) o "every N days|weeks|months", que
+// cuenta desde la fecha de inicio. Las horas son UTC
+type Schedule struct {
+	every  int    // N de "every N ...", 0 para cron
+	unit   string // day, week o month
+	fields [5]map[int]bool
+	domAll bool // día del mes "*"
+	dowAll bool // día de la semana "*"
+}
+
+// cronRanges son los valores admitidos por cada campo de una expresión cron
+var cronRanges = [5]struct{ min, max int }{{0, 59}, {0, 23}, {1, 31}, {1, 12}, {0, 7}}
+
+// ParseSchedule interpreta una programación
+func ParseSchedule(expr string) (*Schedule, error) {
+	parts := strings.Fields(strings.ToLower(expr))
+
+	if len(parts) == 3 && parts[0] == "every" {
+		n, err := strconv.Atoi(parts[1])
+		if err != nil || n <= 0 {
+			return nil, fmt.Errorf("invalid schedule %q: N must be a positive number", expr)
+		}
+		unit := strings.TrimSuffix(parts[2], "s")
+		if unit != "day" && unit != "week" && unit != "month" {
+			return nil, fmt.Errorf("invalid schedule %q: the unit must be days, weeks or months", expr)
+		}
+		return &Schedule{every: n, unit: unit}, nil
+	}
+
+	if len(parts) != 5 {
+		return nil, fmt.Errorf("invalid schedule %q: use a cron expression (minute hour day month weekday) or \"every N days|weeks|months\"", expr)
+	}
+	schedule := &Schedule{domAll: parts[2] == "*", dowAll: parts[4] == "*"}
+	for i, part := range parts {
+		values, err := parseCronField(part, cronRanges[i].min, cronRanges[i].max)
+		if err != nil {
+			return nil, fmt.Errorf("invalid schedule %q: %v", expr, err)
+		}
+		schedule.fields[i] = values
+	}
+	// El domingo puede ser 0 o 7
+	if schedule.fields[4][7] {
+		schedule.fields[4][0] = true
+	}
+
+	return schedule, nil
+}
+
+// parseCronField interpreta un campo cron: *, N, N-M, con /paso opcional, separados por comas
+func parseCronField(field string, min, max int) (map[int]bool, error) {
+	values := map[int]bool{}
+	for _, item := range strings.Split(field, ",") {
+		step := 1
+		if base, value, ok := strings.Cut(item, "/"); ok {
+			var err error
+			if step, err = strconv.Atoi(value); err != nil || step <= 0 {
+				return nil, fmt.Errorf("invalid step in %q", item)
+			}
+			item = base
+		}
+
+		from, to := min, max
+		if item != "*" {
+			first, last, isRange := strings.Cut(item, "-")
+			var err error
+			if from, err = strconv.Atoi(first); err != nil {
+				return nil, fmt.Errorf("invalid value %q", item)
+			}
+			to = from
+			if isRange {
+				if to, err = strconv.Atoi(last); err != nil {
+					return nil, fmt.Errorf("invalid value %q", item)
+				}
+			} else if step > 1 {
+				to = max
+			}
+		}
+		if from < min || to > max || from > to {
+			return nil, fmt.Errorf("%q is out of range %d-%d", item, min, max)
+		}
+
+		for value := from; value <= to; value += step {
+			values[value] = true
+		}
+	}
+	return values, nil
+}
+
+// Next devuelve la primera ejecución posterior a after. start es el inicio de la programación: las
+// de tipo every cuentan desde él y ninguna ejecución es anterior. Devuelve false si no hay ninguna
+// en los próximos cinco años (p. ej. "0 0 30 2 *")
+func (s *Schedule) Next(start, after time.Time) (time.Time, bool) {
+	start, after = start.UTC(), after.UTC()
+	if after.Before(start) {
+		after = start.Add(-time.Minute)
+	}
+
+	if s.every > 0 {
+		// Se cuenta siempre desde start para que los meses cortos no desplacen las siguientes
+		next := start
+		for k := 1; !next.After(after); k++ {
+			switch s.unit {
+			case "day":
+				next = start.AddDate(0, 0, k*s.every)
+			case "week":
+				next = start.AddDate(0, 0, 7*k*s.every)
+			case "month":
+				next = addMonths(start, k*s.every)
+			}
+		}
+		return next, true
+	}
+
+	t := after.Truncate(time.Minute).Add(time.Minute)
+	limit := after.AddDate(5, 0, 0)
+	for t.Before(limit) {
+		if !s.fields[3][int(t.Month())] {
+			t = time.Date(t.Year(), t.Month()+1, 1, 0, 0, 0, 0, time.UTC)
+			continue
+		}
+		if !s.matchDay(t) {
+			t = time.Date(t.Year(), t.Month(), t.Day()+1, 0, 0, 0, 0, time.UTC)
+			continue
+		}
+		if !s.fields[1][t.Hour()] {
+			t = time.Date(t.Year(), t.Month(), t.Day(), t.Hour()+1, 0, 0, 0, time.UTC)
+			continue
+		}
+		if !s.fields[0][t.Minute()] {
+			t = t.Add(time.Minute)
+			continue
+		}
+		return t, true
+	}
+	return time.Time{}, false
+}
+
+// addMonths suma meses a t quedándose en el último día del mes si el día no existe (31 de enero
+// más un mes es el 28 o 29 de febrero, no el 3 de marzo)
+func addMonths(t time.Time, months int) time.Time {
+	first := time.Date(t.Year(), t.Month()+time.Month(months), 1, t.Hour(), t.Minute(), t.Second(), 0, time.UTC)
+	last := first.AddDate(0, 1, -1).Day()
+	return first.AddDate(0, 0, min(t.Day(), last)-1)
+}
+
+// matchDay aplica la regla de cron: si se restringen el día del mes y el de la semana basta con
+// que coincida uno de los dos
+func (s *Schedule) matchDay(t time.Time) bool {
+	dom := s.fields[2][t.Day()]
+	dow := s.fields[4][int(t.Weekday())]
+	switch {
+	case s.domAll && s.dowAll:
+		return true
+	case s.domAll:
+		return dow
+	case s.dowAll:
+		return dom
+	}
+	return dom || dow
+}
@@ -0,0 +1,126 @@
+package models
+
+import "database/sql"
+
+/*
+CREATE TABLE IF NOT EXISTS time_entries (
+	id SERIAL PRIMARY KEY,
+	project_id INT NOT NULL,            -- Proyecto al que se imputa el tiempo
+	issue_id INT,                       -- Ticket opcional
+	user_id INT NOT NULL,               -- Usuario que dedicó el tiempo
+	hours NUMERIC(8,2) NOT NULL,        -- Horas dedicadas
+	spent_on DATE NOT NULL,             -- Día en que se dedicaron
+	comments TEXT,
+	created_at TIMESTAMP DEFAULT NOW(),
+	updated_at TIMESTAMP DEFAULT NOW(),
+	FOREIGN KEY (project_id) REFERENCES projects(id) ON DELETE CASCADE,
+	FOREIGN KEY (issue_id) REFERENCES issues(id) ON DELETE SET NULL,
+	FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
+);
+*/
+
+// TimeEntry es una imputación de horas a un proyecto o ticket
+type TimeEntry struct {
+	ID        int     `json:"id"`
+	ProjectID int     `json:"project_id"`
+	IssueID   *int    `json:"issue_id"`
+	UserID    int     `json:"user_id"`
+	Hours     float64 `json:"hours"`
+	SpentOn   string  `json:"spent_on"`
+	Comments  string  `json:"comments"`
+	CreatedAt string  `json:"created_at"`
+	UpdatedAt string  `json:"updated_at"`
+}
+
+const timeEntryColumns = `id, project_id, issue_id, user_id, hours, to_char(spent_on, 'YYYY-MM-DD'), COALESCE(comments, ''), created_at, updated_at`
+
+func scanTimeEntry(row interface{ Scan(...interface{}) error }, entry *TimeEntry) error {
+	return row.Scan(&entry.ID, &entry.ProjectID, &entry.IssueID, &entry.UserID, &entry.Hours, &entry.SpentOn, &entry.Comments, &entry.CreatedAt, &entry.UpdatedAt)
+}
+
+// CreateTimeEntry crea una nueva imputación de horas
+func CreateTimeEntry(db DBTX, entry *TimeEntry) (int, error) {
+	query := `
+	INSERT INTO time_entries (project_id, issue_id, user_id, hours, spent_on, comments)
+	VALUES ($1, $2, $3, $4, $5, $6)
+	RETURNING id`
+
+	var id int
+	err := db.QueryRow(query, entry.ProjectID, entry.IssueID, entry.UserID, entry.Hours, entry.SpentOn, entry.Comments).Scan(&id)
+	if err != nil {
+		return 0, err
+	}
+
+	return id, nil
+}
+
+// GetTimeEntryByID obtiene una imputación por su ID
+func GetTimeEntryByID(db *sql.DB, id int) (*TimeEntry, error) {
+	query := `SELECT ` + timeEntryColumns + ` FROM time_entries WHERE id = $1`
+
+	entry := &TimeEntry{}
+	if err := scanTimeEntry(db.QueryRow(query, id), entry); err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	return entry, nil
+}
+
+// GetTimeEntriesByProjectID obtiene las imputaciones de un proyecto, las más recientes primero
+func GetTimeEntriesByProjectID(db *sql.DB, projectID int) ([]TimeEntry, error) {
+	query := `SELECT ` + timeEntryColumns + ` FROM time_entries WHERE project_id = $1 ORDER BY spent_on DESC, id DESC`
+
+	rows, err := db.Query(query, projectID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	entries := []TimeEntry{}
+	for rows.Next() {
+		entry := TimeEntry{}
+		if err := scanTimeEntry(rows, &entry); err != nil {
+			return nil, err
+		}
+		entries = append(entries, entry)
+	}
+
+	return entries, nil
+}
+
+// DeleteTimeEntry elimina una imputación
+func DeleteTimeEntry(db *sql.DB, id int) error {
+	query := `DELETE FROM time_entries WHERE id = $1`
+
+	_, err := db.Exec(query, id)
+	return err
+}
+
+func CreateTimeEntriesTable(db *sql.DB) error {
+	query := `
+	CREATE TABLE IF NOT EXISTS time_entries (
+		id SERIAL PRIMARY KEY,
+		project_id INT NOT NULL,
+		issue_id INT,
+		user_id INT NOT NULL,
+		hours NUMERIC(8,2) NOT NULL,
+		spent_on DATE NOT NULL,
+		comments TEXT,
+		created_at TIMESTAMP DEFAULT NOW(),
+		updated_at TIMESTAMP DEFAULT NOW(),
+		FOREIGN KEY (project_id) REFERENCES projects(id) ON DELETE CASCADE,
+		FOREIGN KEY (issue_id) REFERENCES issues(id) ON DELETE SET NULL,
+		FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
+	)`
+	_, err := db.Exec(query)
+	return err
+}
+
+func DropTimeEntriesTable(db *sql.DB) error {
+	query := `DROP TABLE IF EXISTS time_entries`
+	_, err := db.Exec(query)
+	return err
+}
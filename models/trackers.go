@@ -3,21 +3,87 @@ package models
 import (
 	"database/sql"
 	"fmt"
+
+	"github.com/lib/pq"
+)
+
+// Campos del ticket que cada tracker puede usar o no
+const (
+	TrackerFieldAssignedTo     = "assigned_to_id"
+	TrackerFieldCategory       = "category_id"
+	TrackerFieldDescription    = "description"
+	TrackerFieldFixedVersion   = "fixed_version_id"
+	TrackerFieldStartDate      = "start_date"
+	TrackerFieldDueDate        = "due_date"
+	TrackerFieldDoneRatio      = "done_ratio"
+	TrackerFieldEstimatedHours = "estimated_hours"
 )
 
+// TrackerCoreFields son los campos configurables por tracker, en orden de presentación
+var TrackerCoreFields = []string{
+	TrackerFieldAssignedTo, TrackerFieldCategory, TrackerFieldFixedVersion,
+	TrackerFieldStartDate, TrackerFieldDueDate, TrackerFieldDoneRatio, TrackerFieldEstimatedHours,
+	TrackerFieldDescription,
+}
+
+// IsTrackerCoreField indica si el campo es configurable por tracker
+func IsTrackerCoreField(field string) bool {
+	for _, f := range TrackerCoreFields {
+		if f == field {
+			return true
+		}
+	}
+	return false
+}
+
 // Tracker representa un tipo de ticket o incidencia
 type Tracker struct {
-	ID          int    `json:"id"`
-	Name        string `json:"name"`
-	Description string `json:"description"`
+	ID              int      `json:"id"`
+	Name            string   `json:"name"`
+	Description     string   `json:"description"`
+	Position        int      `json:"position"`
+	DefaultStatusID *int     `json:"default_status_id"`
+	EnabledFields   []string `json:"enabled_fields"`
 }
 
-// CreateTracker crea un nuevo tracker
+// FieldEnabled indica si los tickets del tracker usan el campo
+func (t *Tracker) FieldEnabled(field string) bool {
+	for _, f := range t.EnabledFields {
+		if f == field {
+			return true
+		}
+	}
+	return false
+}
+
+const trackerColumns = `id, name, description, position, default_status_id, enabled_fields`
+
+func scanTracker(row interface{ Scan(...interface{}) error }, tracker *Tracker) error {
+	return row.Scan(
+		&tracker.ID,
+		&tracker.Name,
+		&tracker.Description,
+		&tracker.Position,
+		&tracker.DefaultStatusID,
+		pq.Array(&tracker.EnabledFields),
+	)
+}
+
+// CreateTracker crea un nuevo tracker. Sin campos indicados usa todos, y sin
+// posición se coloca al final
 func CreateTracker(db *sql.DB, tracker *Tracker) (int, error) {
-	query := `INSERT INTO trackers (name, description) VALUES ($1, $2) RETURNING id`
+	query := `
+	INSERT INTO trackers (name, description, position, default_status_id, enabled_fields)
+	VALUES ($1, $2, CASE WHEN $3 > 0 THEN $3 ELSE (SELECT COALESCE(MAX(position), 0) + 1 FROM trackers) END, $4, $5)
+	RETURNING id`
+
+	fields := tracker.EnabledFields
+	if fields == nil {
+		fields = TrackerCoreFields
+	}
 
 	var id int
-	err := db.QueryRow(query, tracker.Name, tracker.Description).Scan(&id)
+	err := db.QueryRow(query, tracker.Name, tracker.Description, tracker.Position, tracker.DefaultStatusID, pq.Array(fields)).Scan(&id)
 	if err != nil {
 		return 0, err
 	}
@@ -27,10 +93,10 @@ func CreateTracker(db *sql.DB, tracker *Tracker) (int, error) {
 
 // GetTrackerByID obtiene un tracker por su ID
 func GetTrackerByID(db *sql.DB, id int) (*Tracker, error) {
-	query := `SELECT id, name, description FROM trackers WHERE id = $1`
+	query := `SELECT ` + trackerColumns + ` FROM trackers WHERE id = $1`
 
 	tracker := &Tracker{}
-	err := db.QueryRow(query, id).Scan(&tracker.ID, &tracker.Name, &tracker.Description)
+	err := scanTracker(db.QueryRow(query, id), tracker)
 	if err != nil {
 		return nil, err
 	}
@@ -40,10 +106,10 @@ func GetTrackerByID(db *sql.DB, id int) (*Tracker, error) {
 
 // GetTrackerByName obtiene un tracker por su nombre
 func GetTrackerByName(db *sql.DB, name string) (*Tracker, error) {
-	query := `SELECT id, name, description FROM trackers WHERE name = $1`
+	query := `SELECT ` + trackerColumns + ` FROM trackers WHERE name = $1`
 
 	tracker := &Tracker{}
-	err := db.QueryRow(query, name).Scan(&tracker.ID, &tracker.Name, &tracker.Description)
+	err := scanTracker(db.QueryRow(query, name), tracker)
 	if err != nil {
 		return nil, err
 	}
@@ -51,9 +117,9 @@ func GetTrackerByName(db *sql.DB, name string) (*Tracker, error) {
 	return tracker, nil
 }
 
-// GetAllTrackers obtiene todos los trackers
+// GetAllTrackers obtiene todos los trackers ordenados por posición
 func GetAllTrackers(db *sql.DB) ([]Tracker, error) {
-	query := `SELECT id, name, description FROM trackers`
+	query := `SELECT ` + trackerColumns + ` FROM trackers ORDER BY position, id`
 
 	rows, err := db.Query(query)
 	if err != nil {
@@ -65,11 +131,7 @@ func GetAllTrackers(db *sql.DB) ([]Tracker, error) {
 	for rows.Next() {
 		tracker := Tracker{}
 
-		err := rows.Scan(
-			&tracker.ID,
-			&tracker.Name,
-			&tracker.Description,
-		)
+		err := scanTracker(rows, &tracker)
 		if err != nil {
 			return nil, err
 		}
@@ -80,6 +142,103 @@ func GetAllTrackers(db *sql.DB) ([]Tracker, error) {
 	return trackers, nil
 }
 
+// CountIssuesByTrackerID cuenta los tickets de un tracker
+func CountIssuesByTrackerID(db *sql.DB, trackerID int) (int, error) {
+	query := `SELECT COUNT(*) FROM issues WHERE tracker_id = $1`
+
+	var count int
+	err := db.QueryRow(query, trackerID).Scan(&count)
+	if err != nil {
+		return 0, err
+	}
+
+	return count, nil
+}
+
+// issueFieldValue devuelve el valor de un campo configurable del ticket, o nil si está vacío
+func issueFieldValue(issue *Issue, field string) interface{} {
+	switch field {
+	case TrackerFieldAssignedTo:
+		if issue.AssignedToID != nil {
+			return *issue.AssignedToID
+		}
+	case TrackerFieldCategory:
+		if issue.CategoryID != nil {
+			return *issue.CategoryID
+		}
+	case TrackerFieldFixedVersion:
+		if issue.FixedVersionID != nil {
+			return *issue.FixedVersionID
+		}
+	case TrackerFieldDescription:
+		if issue.Description != "" {
+			return issue.Description
+		}
+	case TrackerFieldStartDate:
+		if issue.StartDate != nil {
+			return *issue.StartDate
+		}
+	case TrackerFieldDueDate:
+		if issue.DueDate != nil {
+			return *issue.DueDate
+		}
+	case TrackerFieldDoneRatio:
+		// 0 es el valor de un ticket sin porcentaje
+		if issue.DoneRatio != 0 {
+			return issue.DoneRatio
+		}
+	case TrackerFieldEstimatedHours:
+		if issue.EstimatedHours != nil {
+			return *issue.EstimatedHours
+		}
+	}
+	return nil
+}
+
+// clearIssueField vacía un campo configurable del ticket
+func clearIssueField(issue *Issue, field string) {
+	switch field {
+	case TrackerFieldAssignedTo:
+		issue.AssignedToID = nil
+	case TrackerFieldCategory:
+		issue.CategoryID = nil
+	case TrackerFieldFixedVersion:
+		issue.FixedVersionID = nil
+	case TrackerFieldDescription:
+		issue.Description = ""
+	case TrackerFieldStartDate:
+		issue.StartDate = nil
+	case TrackerFieldDueDate:
+		issue.DueDate = nil
+	case TrackerFieldDoneRatio:
+		issue.DoneRatio = 0
+	case TrackerFieldEstimatedHours:
+		issue.EstimatedHours = nil
+	}
+}
+
+// ApplyTrackerFields ajusta el ticket a los campos que usa su tracker. Los campos
+// deshabilitados que conservan el valor de before (nil al crear) se vacían, y se
+// devuelven los que el ticket intenta fijar, que el llamador debe rechazar
+func ApplyTrackerFields(tracker *Tracker, issue, before *Issue) []string {
+	rejected := []string{}
+	for _, field := range TrackerCoreFields {
+		if tracker.FieldEnabled(field) {
+			continue
+		}
+		value := issueFieldValue(issue, field)
+		if value == nil {
+			continue
+		}
+		if before != nil && issueFieldValue(before, field) == value {
+			clearIssueField(issue, field)
+			continue
+		}
+		rejected = append(rejected, field)
+	}
+	return rejected
+}
+
 // DropTrackersTable elimina la tabla de trackers
 func DropTrackersTable(db *sql.DB) error {
 	query := `DROP TABLE IF EXISTS trackers`
@@ -107,9 +266,12 @@ func CountTrackers(db *sql.DB) (int, error) {
 
 // UpdateTracker actualiza un tracker
 func UpdateTracker(db *sql.DB, tracker *Tracker) error {
-	query := `UPDATE trackers SET name = $1, description = $2 WHERE id = $3`
+	query := `
+	UPDATE trackers
+	SET name = $1, description = $2, position = $3, default_status_id = $4, enabled_fields = $5
+	WHERE id = $6`
 
-	_, err := db.Exec(query, tracker.Name, tracker.Description, tracker.ID)
+	_, err := db.Exec(query, tracker.Name, tracker.Description, tracker.Position, tracker.DefaultStatusID, pq.Array(tracker.EnabledFields), tracker.ID)
 	if err != nil {
 		return err
 	}
@@ -135,7 +297,10 @@ func CreateTrackersTable(db *sql.DB) error {
 	CREATE TABLE IF NOT EXISTS trackers (
 		id SERIAL PRIMARY KEY,
 		name VARCHAR(255) UNIQUE NOT NULL,
-		description TEXT
+		description TEXT,
+		position INT NOT NULL DEFAULT 0,
+		default_status_id INT,              -- issue_statuses se crea después, se valida en la API
+		enabled_fields TEXT[] NOT NULL DEFAULT ARRAY['assigned_to_id', 'category_id', 'fixed_version_id', 'start_date', 'due_date', 'done_ratio', 'estimated_hours', 'description']
 	);`
 
 	_, err := db.Exec(createTableQuery)
@@ -160,7 +325,8 @@ func SeedTrackers(db *sql.DB) error {
 		{Name: "Performance", Description: "Rendimiento"},
 	}
 
-	for _, tracker := range trackers {
+	for i, tracker := range trackers {
+		tracker.Position = i + 1
 		_, err := CreateTracker(db, tracker)
 		if err != nil {
 			return err
@@ -1,7 +1,9 @@
 package models
 
 import (
+	"crypto/rand"
 	"database/sql"
+	"encoding/hex"
 	"fmt"
 )
 
@@ -13,6 +15,7 @@ type User struct {
 	PasswordHash string `json:"password_hash"`
 	CreatedAt    string `json:"created_at"`
 	UpdatedAt    string `json:"updated_at"`
+	LockVersion  int    `json:"lock_version"` // versión leída, para detectar ediciones concurrentes
 }
 
 // CreateUser crea un nuevo usuario
@@ -30,10 +33,10 @@ func CreateUser(db *sql.DB, user *User) (int, error) {
 
 // GetUserByID obtiene un usuario por su ID
 func GetUserByID(db *sql.DB, id int) (*User, error) {
-	query := `SELECT id, username, email, password_hash, created_at, updated_at FROM users WHERE id = $1`
+	query := `SELECT id, username, email, password_hash, created_at, updated_at, lock_version FROM users WHERE id = $1`
 
 	user := &User{}
-	err := db.QueryRow(query, id).Scan(&user.ID, &user.Username, &user.Email, &user.PasswordHash, &user.CreatedAt, &user.UpdatedAt)
+	err := db.QueryRow(query, id).Scan(&user.ID, &user.Username, &user.Email, &user.PasswordHash, &user.CreatedAt, &user.UpdatedAt, &user.LockVersion)
 	if err != nil {
 		return nil, err
 	}
@@ -43,10 +46,10 @@ func GetUserByID(db *sql.DB, id int) (*User, error) {
 
 // GetUserByUsername obtiene un usuario por su nombre de usuario
 func GetUserByUsername(db *sql.DB, username string) (*User, error) {
-	query := `SELECT id, username, email, password_hash, created_at, updated_at FROM users WHERE username = $1`
+	query := `SELECT id, username, email, password_hash, created_at, updated_at, lock_version FROM users WHERE username = $1`
 
 	user := &User{}
-	err := db.QueryRow(query, username).Scan(&user.ID, &user.Username, &user.Email, &user.PasswordHash, &user.CreatedAt, &user.UpdatedAt)
+	err := db.QueryRow(query, username).Scan(&user.ID, &user.Username, &user.Email, &user.PasswordHash, &user.CreatedAt, &user.UpdatedAt, &user.LockVersion)
 	if err != nil {
 		return nil, err
 	}
@@ -54,12 +57,17 @@ func GetUserByUsername(db *sql.DB, username string) (*User, error) {
 	return user, nil
 }
 
-// GetUserByEmail obtiene un usuario por su correo electrónico
+// GetUserByEmail obtiene un usuario por su correo electrónico, sin distinguir mayúsculas. Si hay
+// varios que solo se diferencian en eso, se prefiere el que coincide exactamente
 func GetUserByEmail(db *sql.DB, email string) (*User, error) {
-	query := `SELECT id, username, email, password_hash, created_at, updated_at FROM users WHERE email = $1`
+	query := `
+	SELECT id, username, email, password_hash, created_at, updated_at, lock_version FROM users
+	WHERE lower(email) = lower($1)
+	ORDER BY email = $1 DESC, id
+	LIMIT 1`
 
 	user := &User{}
-	err := db.QueryRow(query, email).Scan(&user.ID, &user.Username, &user.Email, &user.PasswordHash, &user.CreatedAt, &user.UpdatedAt)
+	err := db.QueryRow(query, email).Scan(&user.ID, &user.Username, &user.Email, &user.PasswordHash, &user.CreatedAt, &user.UpdatedAt, &user.LockVersion)
 	if err != nil {
 		return nil, err
 	}
@@ -67,11 +75,19 @@ func GetUserByEmail(db *sql.DB, email string) (*User, error) {
 	return user, nil
 }
 
-// UpdateUser actualiza un usuario
+// UpdateUser actualiza un usuario si su lock_version sigue siendo la leída, e
+// incrementa la versión. Devuelve ErrStaleObject si no lo es
 func UpdateUser(db *sql.DB, user *User) error {
-	query := `UPDATE users SET username = $1, email = $2, password_hash = $3, updated_at = NOW() WHERE id = $4`
-
-	_, err := db.Exec(query, user.Username, user.Email, user.PasswordHash, user.ID)
+	query := `
+	UPDATE users
+	SET username = $1, email = $2, password_hash = $3, updated_at = NOW(), lock_version = lock_version + 1
+	WHERE id = $4 AND lock_version = $5
+	RETURNING lock_version`
+
+	err := db.QueryRow(query, user.Username, user.Email, user.PasswordHash, user.ID, user.LockVersion).Scan(&user.LockVersion)
+	if err == sql.ErrNoRows {
+		return ErrStaleObject
+	}
 	if err != nil {
 		return err
 	}
@@ -106,7 +122,7 @@ func CountUsers(db *sql.DB) (int, error) {
 
 // GetUsers obtiene todos los usuarios
 func GetAllUsers(db *sql.DB) ([]User, error) {
-	query := `SELECT id, username, email, password_hash, created_at, updated_at FROM users`
+	query := `SELECT id, username, email, password_hash, created_at, updated_at, lock_version FROM users`
 
 	rows, err := db.Query(query)
 	if err != nil {
@@ -118,7 +134,7 @@ func GetAllUsers(db *sql.DB) ([]User, error) {
 	for rows.Next() {
 		user := User{}
 
-		err := rows.Scan(&user.ID, &user.Username, &user.Email, &user.PasswordHash, &user.CreatedAt, &user.UpdatedAt)
+		err := rows.Scan(&user.ID, &user.Username, &user.Email, &user.PasswordHash, &user.CreatedAt, &user.UpdatedAt, &user.LockVersion)
 		if err != nil {
 			return nil, err
 		}
@@ -149,8 +165,10 @@ func CreateUsersTable(db *sql.DB) error {
 		username VARCHAR(255) UNIQUE NOT NULL,
 		email VARCHAR(255) UNIQUE NOT NULL,
 		password_hash VARCHAR(255) NOT NULL,
+		feed_key VARCHAR(64) UNIQUE,
 		created_at TIMESTAMP DEFAULT NOW(),
-		updated_at TIMESTAMP DEFAULT NOW()
+		updated_at TIMESTAMP DEFAULT NOW(),
+		lock_version INT NOT NULL DEFAULT 1
 	);`
 
 	_, err := db.Exec(query)
@@ -264,7 +282,7 @@ func SampleUsers(db *sql.DB) error {
 
 func GetUsersByIssueID(db *sql.DB, issue_id int) ([]User, error) {
 	query := `
-	SELECT id, username, email, password_hash, created_at, updated_at
+	SELECT id, username, email, password_hash, created_at, updated_at, lock_version
 	FROM users
 	WHERE id IN (
 		SELECT user_id
@@ -287,7 +305,7 @@ func GetUsersByIssueID(db *sql.DB, issue_id int) ([]User, error) {
 	for rows.Next() {
 		user := User{}
 
-		err := rows.Scan(&user.ID, &user.Username, &user.Email, &user.PasswordHash, &user.CreatedAt, &user.UpdatedAt)
+		err := rows.Scan(&user.ID, &user.Username, &user.Email, &user.PasswordHash, &user.CreatedAt, &user.UpdatedAt, &user.LockVersion)
 		if err != nil {
 			return nil, err
 		}
@@ -300,7 +318,7 @@ func GetUsersByIssueID(db *sql.DB, issue_id int) ([]User, error) {
 
 func GetUsersByProjectID(db *sql.DB, project_id int) ([]User, error) {
 	query := `
-	SELECT id, username, email, password_hash, created_at, updated_at
+	SELECT id, username, email, password_hash, created_at, updated_at, lock_version
 	FROM users
 	WHERE id IN (
 		select assigned_to_id
@@ -323,7 +341,7 @@ func GetUsersByProjectID(db *sql.DB, project_id int) ([]User, error) {
 	for rows.Next() {
 		user := User{}
 
-		err := rows.Scan(&user.ID, &user.Username, &user.Email, &user.PasswordHash, &user.CreatedAt, &user.UpdatedAt)
+		err := rows.Scan(&user.ID, &user.Username, &user.Email, &user.PasswordHash, &user.CreatedAt, &user.UpdatedAt, &user.LockVersion)
 		if err != nil {
 			return nil, err
 		}
@@ -338,7 +356,7 @@ func GetUsersByCategoryID(db *sql.DB, category_id int) ([]User, error) {
 	query := `
 	SELECT
 		id, username, email, password_hash,
-		created_at, updated_at
+		created_at, updated_at, lock_version
 	FROM users
 	WHERE id IN (
 		select assigned_to_id
@@ -363,7 +381,7 @@ func GetUsersByCategoryID(db *sql.DB, category_id int) ([]User, error) {
 
 		err := rows.Scan(
 			&user.ID, &user.Username, &user.Email, &user.PasswordHash,
-			&user.CreatedAt, &user.UpdatedAt)
+			&user.CreatedAt, &user.UpdatedAt, &user.LockVersion)
 		if err != nil {
 			return nil, err
 		}
@@ -373,3 +391,58 @@ func GetUsersByCategoryID(db *sql.DB, category_id int) ([]User, error) {
 
 	return users, nil
 }
+
+// newFeedKey genera una clave aleatoria para los feeds
+func newFeedKey() (string, error) {
+	b := make([]byte, 20)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
+
+// GetUserByFeedKey obtiene el usuario dueño de una clave de feeds, o nil si no existe
+func GetUserByFeedKey(db *sql.DB, key string) (*User, error) {
+	query := `SELECT id, username, email, password_hash, created_at, updated_at, lock_version FROM users WHERE feed_key = $1`
+
+	user := &User{}
+	err := db.QueryRow(query, key).Scan(&user.ID, &user.Username, &user.Email, &user.PasswordHash, &user.CreatedAt, &user.UpdatedAt, &user.LockVersion)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return user, nil
+}
+
+// GetOrCreateUserFeedKey devuelve la clave de feeds del usuario, generándola si aún no tiene
+func GetOrCreateUserFeedKey(db *sql.DB, userID int) (string, error) {
+	query := `SELECT COALESCE(feed_key, '') FROM users WHERE id = $1`
+
+	var key string
+	if err := db.QueryRow(query, userID).Scan(&key); err != nil {
+		return "", err
+	}
+	if key != "" {
+		return key, nil
+	}
+
+	return ResetUserFeedKey(db, userID)
+}
+
+// ResetUserFeedKey genera una nueva clave de feeds, invalidando la anterior
+func ResetUserFeedKey(db *sql.DB, userID int) (string, error) {
+	key, err := newFeedKey()
+	if err != nil {
+		return "", err
+	}
+
+	query := `UPDATE users SET feed_key = $1 WHERE id = $2`
+	if _, err := db.Exec(query, key, userID); err != nil {
+		return "", err
+	}
+
+	return key, nil
+}
@@ -46,6 +46,20 @@ func GetUserRolesByUserID(db *sql.DB, userID int) ([]*Role, error) {
 	return roles, nil
 }
 
+// IsAdminUser indica si el usuario tiene el rol global Admin
+func IsAdminUser(db *sql.DB, userID int) (bool, error) {
+	query := `
+	SELECT EXISTS (
+		SELECT 1 FROM user_roles ur
+		JOIN roles r ON r.id = ur.role_id
+		WHERE ur.user_id = $1 AND r.name = 'Admin'
+	)`
+
+	var admin bool
+	err := db.QueryRow(query, userID).Scan(&admin)
+	return admin, err
+}
+
 func GetAllUsersRoles(db *sql.DB) ([]UserRole, error) {
 	query := `SELECT user_id, role_id FROM user_roles`
 
@@ -0,0 +1,290 @@
+package models
+
+import (
+	"database/sql"
+	"encoding/json"
+	"sort"
+	"strconv"
+
+	"github.com/lib/pq"
+)
+
+/*
+CREATE TABLE IF NOT EXISTS version_chart_days (
+	version_id INT NOT NULL,            -- Versión
+	day DATE NOT NULL,                  -- Día ya terminado
+	data JSONB NOT NULL,                -- VersionChartDay al final del día
+	created_at TIMESTAMP DEFAULT NOW(),
+	PRIMARY KEY (version_id, day),
+	FOREIGN KEY (version_id) REFERENCES versions(id) ON DELETE CASCADE
+);
+*/
+
+// VersionChartDay es el estado de los tickets de una versión al final de un día: tickets y horas
+// estimadas en total y pendientes (en estados abiertos), y tickets por estado
+type VersionChartDay struct {
+	Date           string         `json:"date"`
+	Issues         int            `json:"issues"`
+	OpenIssues     int            `json:"open_issues"`
+	Hours          float64        `json:"hours"`
+	RemainingHours float64        `json:"remaining_hours"`
+	Statuses       map[string]int `json:"statuses"`
+}
+
+// versionChartIssue es el estado de un ticket que se va deshaciendo hacia atrás en el tiempo
+type versionChartIssue struct {
+	createdDay     string
+	status         string
+	versionID      *int
+	estimatedHours *float64
+}
+
+// GetVersionChartCache obtiene los días guardados de una versión entre from y to, por fecha
+func GetVersionChartCache(db *sql.DB, versionID int, from, to string) (map[string]VersionChartDay, error) {
+	query := `
+	SELECT to_char(day, 'YYYY-MM-DD'), data
+	FROM version_chart_days
+	WHERE version_id = $1 AND day BETWEEN $2::date AND $3::date`
+
+	rows, err := db.Query(query, versionID, from, to)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	days := map[string]VersionChartDay{}
+	for rows.Next() {
+		var date string
+		var data []byte
+		if err := rows.Scan(&date, &data); err != nil {
+			return nil, err
+		}
+		var day VersionChartDay
+		if err := json.Unmarshal(data, &day); err != nil {
+			return nil, err
+		}
+		day.Date = date
+		days[date] = day
+	}
+
+	return days, nil
+}
+
+// GetCurrentDate devuelve el día de hoy (YYYY-MM-DD) según PostgreSQL. created_at se guarda con
+// NOW() en la zona de la sesión y los días se agrupan con to_char, así que hoy tiene que salir de la
+// misma zona y no del reloj de la aplicación
+func GetCurrentDate(db *sql.DB) (string, error) {
+	var today string
+	err := db.QueryRow(`SELECT to_char(CURRENT_DATE, 'YYYY-MM-DD')`).Scan(&today)
+	return today, err
+}
+
+// SaveVersionChartDays guarda (o sustituye) días ya terminados de una versión
+func SaveVersionChartDays(db DBTX, versionID int, days []VersionChartDay) error {
+	query := `
+	INSERT INTO version_chart_days (version_id, day, data)
+	VALUES ($1, $2, $3)
+	ON CONFLICT (version_id, day) DO UPDATE SET data = EXCLUDED.data, created_at = NOW()`
+
+	for _, day := range days {
+		data, err := json.Marshal(day)
+		if err != nil {
+			return err
+		}
+		if _, err := db.Exec(query, versionID, day.Date, data); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// DeleteVersionChartCache borra los días guardados de una versión
+func DeleteVersionChartCache(db DBTX, versionID int) error {
+	query := `DELETE FROM version_chart_days WHERE version_id = $1`
+	_, err := db.Exec(query, versionID)
+	return err
+}
+
+// BuildVersionChartDays reconstruye el estado de una versión al final de cada uno de los días
+// (YYYY-MM-DD, en orden) a partir del estado actual de los tickets, deshaciendo hacia atrás los
+// cambios de estado, versión y horas estimadas posteriores a cada día
+func BuildVersionChartDays(db *sql.DB, versionID int, days []string) ([]VersionChartDay, error) {
+	if len(days) == 0 {
+		return []VersionChartDay{}, nil
+	}
+	first := days[0]
+	version := strconv.Itoa(versionID)
+
+	// Tickets que están en la versión o que entraron o salieron de ella después del primer día
+	query := `
+	SELECT id, to_char(created_at, 'YYYY-MM-DD'), status, fixed_version_id, estimated_hours
+	FROM issues
+	WHERE fixed_version_id = $1 OR id IN (
+		SELECT issue_id FROM issue_changes
+		WHERE field = 'fixed_version_id' AND (old_value = $2 OR new_value = $2)
+		AND created_at >= $3::date + 1
+	)`
+	rows, err := db.Query(query, versionID, version, first)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	issues := map[int]*versionChartIssue{}
+	ids := []int{}
+	for rows.Next() {
+		var id int
+		issue := &versionChartIssue{}
+		if err := rows.Scan(&id, &issue.createdDay, &issue.status, &issue.versionID, &issue.estimatedHours); err != nil {
+			return nil, err
+		}
+		issues[id] = issue
+		ids = append(ids, id)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	// Cambios posteriores al primer día, del más reciente al más antiguo
+	query = `
+	SELECT issue_id, field, old_value, to_char(created_at, 'YYYY-MM-DD')
+	FROM issue_changes
+	WHERE issue_id = ANY($1) AND field IN ('status', 'fixed_version_id', 'estimated_hours')
+	AND created_at >= $2::date + 1
+	ORDER BY created_at DESC, id DESC`
+	changeRows, err := db.Query(query, pq.Array(ids), first)
+	if err != nil {
+		return nil, err
+	}
+	defer changeRows.Close()
+
+	type change struct {
+		issueID  int
+		field    string
+		oldValue *string
+		day      string
+	}
+	changes := []change{}
+	for changeRows.Next() {
+		var ch change
+		if err := changeRows.Scan(&ch.issueID, &ch.field, &ch.oldValue, &ch.day); err != nil {
+			return nil, err
+		}
+		changes = append(changes, ch)
+	}
+	if err := changeRows.Err(); err != nil {
+		return nil, err
+	}
+
+	closed := map[string]bool{}
+	statuses, err := GetAllIssueStatuses(db)
+	if err != nil {
+		return nil, err
+	}
+	for _, status := range statuses {
+		closed[status.Name] = status.IsClosed
+	}
+
+	result := make([]VersionChartDay, len(days))
+	next := 0
+	for i := len(days) - 1; i >= 0; i-- {
+		date := days[i]
+
+		// Deshacer los cambios hechos después de este día
+		for ; next < len(changes) && changes[next].day > date; next++ {
+			ch := changes[next]
+			issue := issues[ch.issueID]
+			switch ch.field {
+			case "status":
+				if ch.oldValue != nil {
+					issue.status = *ch.oldValue
+				}
+			case "fixed_version_id":
+				issue.versionID = nil
+				if ch.oldValue != nil {
+					if id, err := strconv.Atoi(*ch.oldValue); err == nil {
+						issue.versionID = &id
+					}
+				}
+			case "estimated_hours":
+				issue.estimatedHours = nil
+				if ch.oldValue != nil {
+					if hours, err := strconv.ParseFloat(*ch.oldValue, 64); err == nil {
+						issue.estimatedHours = &hours
+					}
+				}
+			}
+		}
+
+		day := VersionChartDay{Date: date, Statuses: map[string]int{}}
+		for _, issue := range issues {
+			if issue.createdDay > date || issue.versionID == nil || *issue.versionID != versionID {
+				continue
+			}
+			hours := 0.0
+			if issue.estimatedHours != nil {
+				hours = *issue.estimatedHours
+			}
+			day.Issues++
+			day.Hours += hours
+			if !closed[issue.status] {
+				day.OpenIssues++
+				day.RemainingHours += hours
+			}
+			day.Statuses[issue.status]++
+		}
+		result[i] = day
+	}
+
+	return result, nil
+}
+
+// VersionChartStatuses devuelve los estados que aparecen en los días, en el orden de los estados
+// y después los que ya no existen por nombre
+func VersionChartStatuses(statuses []IssueStatus, days []VersionChartDay) []IssueStatus {
+	seen := map[string]bool{}
+	for _, day := range days {
+		for name := range day.Statuses {
+			seen[name] = true
+		}
+	}
+
+	result := []IssueStatus{}
+	for _, status := range statuses {
+		if seen[status.Name] {
+			result = append(result, status)
+			delete(seen, status.Name)
+		}
+	}
+	unknown := []string{}
+	for name := range seen {
+		unknown = append(unknown, name)
+	}
+	sort.Strings(unknown)
+	for _, name := range unknown {
+		result = append(result, IssueStatus{Name: name})
+	}
+
+	return result
+}
+
+func CreateVersionChartDaysTable(db *sql.DB) error {
+	query := `
+	CREATE TABLE IF NOT EXISTS version_chart_days (
+		version_id INT NOT NULL,
+		day DATE NOT NULL,
+		data JSONB NOT NULL,
+		created_at TIMESTAMP DEFAULT NOW(),
+		PRIMARY KEY (version_id, day),
+		FOREIGN KEY (version_id) REFERENCES versions(id) ON DELETE CASCADE
+	)`
+	_, err := db.Exec(query)
+	return err
+}
+
+func DropVersionChartDaysTable(db *sql.DB) error {
+	query := `DROP TABLE IF EXISTS version_chart_days`
+	_, err := db.Exec(query)
+	return err
+}
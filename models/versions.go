@@ -0,0 +1,190 @@
+package models
+
+import (
+	"database/sql"
+	"fmt"
+
+	"github.com/lib/pq"
+)
+
+/*
+CREATE TABLE IF NOT EXISTS versions (
+	id SERIAL PRIMARY KEY,
+	project_id INT NOT NULL,            -- Proyecto de la versión
+	name VARCHAR(255) NOT NULL,
+	description TEXT,
+	status VARCHAR(20) NOT NULL DEFAULT 'open', -- open, locked o closed
+	due_date DATE,                      -- Fecha prevista de entrega
+	created_at TIMESTAMP DEFAULT NOW(),
+	updated_at TIMESTAMP DEFAULT NOW(),
+	UNIQUE (project_id, name),
+	FOREIGN KEY (project_id) REFERENCES projects(id) ON DELETE CASCADE
+);
+*/
+
+// Version es una versión (hito) de un proyecto
+type Version struct {
+	ID          int     `json:"id"`
+	ProjectID   int     `json:"project_id"`
+	Name        string  `json:"name"`
+	Description string  `json:"description"`
+	Status      string  `json:"status"`
+	DueDate     *string `json:"due_date"`
+	CreatedAt   string  `json:"created_at"`
+	UpdatedAt   string  `json:"updated_at"`
+}
+
+const (
+	VersionStatusOpen   = "open"
+	VersionStatusLocked = "locked"
+	VersionStatusClosed = "closed"
+)
+
+const versionColumns = `id, project_id, name, COALESCE(description, ''), status, to_char(due_date, 'YYYY-MM-DD'), created_at, updated_at`
+
+func scanVersion(row interface{ Scan(...interface{}) error }, version *Version) error {
+	return row.Scan(&version.ID, &version.ProjectID, &version.Name, &version.Description, &version.Status, &version.DueDate, &version.CreatedAt, &version.UpdatedAt)
+}
+
+// CreateVersion crea una nueva versión
+func CreateVersion(db DBTX, version *Version) (int, error) {
+	query := `
+	INSERT INTO versions (project_id, name, description, status, due_date)
+	VALUES ($1, $2, $3, $4, $5)
+	RETURNING id`
+
+	if version.Status == "" {
+		version.Status = VersionStatusOpen
+	}
+
+	var id int
+	err := db.QueryRow(query, version.ProjectID, version.Name, version.Description, version.Status, version.DueDate).Scan(&id)
+	if err != nil {
+		return 0, err
+	}
+
+	return id, nil
+}
+
+// GetVersionByID obtiene una versión por su ID
+func GetVersionByID(db *sql.DB, id int) (*Version, error) {
+	query := `SELECT ` + versionColumns + ` FROM versions WHERE id = $1`
+
+	version := &Version{}
+	if err := scanVersion(db.QueryRow(query, id), version); err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	return version, nil
+}
+
+// GetVersionsByProjectID obtiene las versiones de un proyecto por fecha de entrega
+func GetVersionsByProjectID(db *sql.DB, projectID int) ([]Version, error) {
+	query := `SELECT ` + versionColumns + ` FROM versions WHERE project_id = $1 ORDER BY due_date NULLS LAST, name`
+
+	rows, err := db.Query(query, projectID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	versions := []Version{}
+	for rows.Next() {
+		version := Version{}
+		if err := scanVersion(rows, &version); err != nil {
+			return nil, err
+		}
+		versions = append(versions, version)
+	}
+
+	return versions, nil
+}
+
+// GetVersionsDueBetween obtiene las versiones con fecha de entrega en [from, to] de los proyectos
+// indicados, o de todos si projectIDs es nil. Un límite vacío no acota
+func GetVersionsDueBetween(db *sql.DB, projectIDs []int, from, to string) ([]Version, error) {
+	query := `SELECT ` + versionColumns + ` FROM versions WHERE due_date IS NOT NULL`
+	args := []interface{}{}
+
+	if projectIDs != nil {
+		args = append(args, pq.Array(projectIDs))
+		query += fmt.Sprintf(" AND project_id = ANY($%d)", len(args))
+	}
+	if from != "" {
+		args = append(args, from)
+		query += fmt.Sprintf(" AND due_date >= $%d", len(args))
+	}
+	if to != "" {
+		args = append(args, to)
+		query += fmt.Sprintf(" AND due_date <= $%d", len(args))
+	}
+	query += " ORDER BY due_date, name"
+
+	rows, err := db.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	versions := []Version{}
+	for rows.Next() {
+		version := Version{}
+		if err := scanVersion(rows, &version); err != nil {
+			return nil, err
+		}
+		versions = append(versions, version)
+	}
+
+	return versions, nil
+}
+
+// UpdateVersion actualiza una versión
+func UpdateVersion(db *sql.DB, version *Version) error {
+	query := `
+	UPDATE versions
+	SET name = $1, description = $2, status = $3, due_date = $4, updated_at = NOW()
+	WHERE id = $5`
+
+	_, err := db.Exec(query, version.Name, version.Description, version.Status, version.DueDate, version.ID)
+	return err
+}
+
+// DeleteVersion elimina una versión
+func DeleteVersion(db *sql.DB, id int) error {
+	// Los tickets planificados en la versión quedan sin versión
+	if _, err := db.Exec(`UPDATE issues SET fixed_version_id = NULL WHERE fixed_version_id = $1`, id); err != nil {
+		return err
+	}
+
+	query := `DELETE FROM versions WHERE id = $1`
+
+	_, err := db.Exec(query, id)
+	return err
+}
+
+func CreateVersionsTable(db *sql.DB) error {
+	query := `
+	CREATE TABLE IF NOT EXISTS versions (
+		id SERIAL PRIMARY KEY,
+		project_id INT NOT NULL,
+		name VARCHAR(255) NOT NULL,
+		description TEXT,
+		status VARCHAR(20) NOT NULL DEFAULT 'open',
+		due_date DATE,
+		created_at TIMESTAMP DEFAULT NOW(),
+		updated_at TIMESTAMP DEFAULT NOW(),
+		UNIQUE (project_id, name),
+		FOREIGN KEY (project_id) REFERENCES projects(id) ON DELETE CASCADE
+	)`
+	_, err := db.Exec(query)
+	return err
+}
+
+func DropVersionsTable(db *sql.DB) error {
+	query := `DROP TABLE IF EXISTS versions`
+	_, err := db.Exec(query)
+	return err
+}
@@ -0,0 +1,337 @@
+package models
+
+import "database/sql"
+
+/*
+CREATE TABLE IF NOT EXISTS wiki_pages (
+	id SERIAL PRIMARY KEY,
+	project_id INT NOT NULL,            -- Proyecto de la wiki
+	title VARCHAR(255) NOT NULL,        -- Título, único en el proyecto y usado en la URL
+	parent_id INT,                      -- Página padre
+	content TEXT NOT NULL DEFAULT '',   -- Contenido Markdown de la última versión
+	version INT NOT NULL DEFAULT 1,     -- Número de la última versión
+	author_id INT,                      -- Autor de la última versión
+	created_at TIMESTAMP DEFAULT NOW(),
+	updated_at TIMESTAMP DEFAULT NOW(),
+	UNIQUE (project_id, title)
+);
+
+CREATE TABLE IF NOT EXISTS wiki_revisions (
+	id SERIAL PRIMARY KEY,
+	page_id INT NOT NULL,               -- Página
+	version INT NOT NULL,               -- Número de versión, empieza en 1
+	content TEXT NOT NULL,              -- Contenido Markdown de la versión
+	comment TEXT NOT NULL DEFAULT '',   -- Comentario del cambio
+	author_id INT,                      -- Autor del cambio, NULL con el token de servicio
+	created_at TIMESTAMP DEFAULT NOW(),
+	UNIQUE (page_id, version)
+);
+
+CREATE TABLE IF NOT EXISTS wiki_redirects (
+	project_id INT NOT NULL,            -- Proyecto
+	title VARCHAR(255) NOT NULL,        -- Título antiguo
+	page_id INT NOT NULL,               -- Página a la que redirige
+	PRIMARY KEY (project_id, title)
+);
+*/
+
+// WikiPage es una página de la wiki de un proyecto con su última versión
+type WikiPage struct {
+	ID        int    `json:"id"`
+	ProjectID int    `json:"project_id"`
+	Title     string `json:"title"`
+	ParentID  *int   `json:"parent_id"`
+	Content   string `json:"content,omitempty"`
+	Version   int    `json:"version"`
+	AuthorID  *int   `json:"author_id"`
+	CreatedAt string `json:"created_at"`
+	UpdatedAt string `json:"updated_at"`
+}
+
+// WikiRevision es una versión de una página de la wiki
+type WikiRevision struct {
+	ID        int    `json:"id"`
+	PageID    int    `json:"page_id"`
+	Version   int    `json:"version"`
+	Content   string `json:"content,omitempty"`
+	Comment   string `json:"comment"`
+	AuthorID  *int   `json:"author_id"`
+	CreatedAt string `json:"created_at"`
+}
+
+func scanWikiPage(row interface{ Scan(...interface{}) error }, page *WikiPage) error {
+	return row.Scan(&page.ID, &page.ProjectID, &page.Title, &page.ParentID, &page.Content,
+		&page.Version, &page.AuthorID, &page.CreatedAt, &page.UpdatedAt)
+}
+
+// GetWikiPageByTitle obtiene una página por su título, o nil si no existe
+func GetWikiPageByTitle(db DBTX, projectID int, title string) (*WikiPage, error) {
+	query := `
+	SELECT id, project_id, title, parent_id, content, version, author_id, created_at, updated_at
+	FROM wiki_pages
+	WHERE project_id = $1 AND title = $2`
+
+	page := &WikiPage{}
+	err := scanWikiPage(db.QueryRow(query, projectID, title), page)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return page, nil
+}
+
+// GetWikiPageByID obtiene una página por su ID, o nil si no existe
+func GetWikiPageByID(db DBTX, id int) (*WikiPage, error) {
+	query := `
+	SELECT id, project_id, title, parent_id, content, version, author_id, created_at, updated_at
+	FROM wiki_pages
+	WHERE id = $1`
+
+	page := &WikiPage{}
+	err := scanWikiPage(db.QueryRow(query, id), page)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return page, nil
+}
+
+// GetWikiPagesByProjectID obtiene las páginas de la wiki de un proyecto, sin su contenido
+func GetWikiPagesByProjectID(db *sql.DB, projectID int) ([]WikiPage, error) {
+	query := `
+	SELECT id, project_id, title, parent_id, '', version, author_id, created_at, updated_at
+	FROM wiki_pages
+	WHERE project_id = $1
+	ORDER BY title`
+
+	rows, err := db.Query(query, projectID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	pages := []WikiPage{}
+	for rows.Next() {
+		var page WikiPage
+		if err := scanWikiPage(rows, &page); err != nil {
+			return nil, err
+		}
+		pages = append(pages, page)
+	}
+
+	return pages, nil
+}
+
+// GetWikiRedirect devuelve la página a la que redirige un título antiguo, o nil si no hay redirección
+func GetWikiRedirect(db *sql.DB, projectID int, title string) (*WikiPage, error) {
+	var pageID int
+	query := `SELECT page_id FROM wiki_redirects WHERE project_id = $1 AND title = $2`
+	err := db.QueryRow(query, projectID, title).Scan(&pageID)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return GetWikiPageByID(db, pageID)
+}
+
+// CreateWikiPage crea la página con su primera versión
+func CreateWikiPage(db DBTX, page *WikiPage, comment string) (int, error) {
+	query := `
+	INSERT INTO wiki_pages (project_id, title, parent_id, content, version, author_id)
+	VALUES ($1, $2, $3, $4, 1, $5)
+	RETURNING id`
+
+	var id int
+	err := db.QueryRow(query, page.ProjectID, page.Title, page.ParentID, page.Content, page.AuthorID).Scan(&id)
+	if err != nil {
+		return 0, err
+	}
+
+	// Una página nueva ocupa el título de una redirección anterior
+	if _, err := db.Exec(`DELETE FROM wiki_redirects WHERE project_id = $1 AND title = $2`, page.ProjectID, page.Title); err != nil {
+		return 0, err
+	}
+
+	page.ID = id
+	page.Version = 1
+	return id, createWikiRevision(db, page, comment)
+}
+
+// UpdateWikiPage guarda el contenido de page como una nueva versión y actualiza su padre
+func UpdateWikiPage(db DBTX, page *WikiPage, comment string) error {
+	query := `
+	UPDATE wiki_pages
+	SET parent_id = $1, content = $2, version = version + 1, author_id = $3, updated_at = NOW()
+	WHERE id = $4
+	RETURNING version`
+
+	if err := db.QueryRow(query, page.ParentID, page.Content, page.AuthorID, page.ID).Scan(&page.Version); err != nil {
+		return err
+	}
+
+	return createWikiRevision(db, page, comment)
+}
+
+// SetWikiPageParent cambia la página padre sin crear una versión
+func SetWikiPageParent(db DBTX, pageID int, parentID *int) error {
+	query := `UPDATE wiki_pages SET parent_id = $1, updated_at = NOW() WHERE id = $2`
+	_, err := db.Exec(query, parentID, pageID)
+	return err
+}
+
+func createWikiRevision(db DBTX, page *WikiPage, comment string) error {
+	query := `
+	INSERT INTO wiki_revisions (page_id, version, content, comment, author_id)
+	VALUES ($1, $2, $3, $4, $5)`
+	_, err := db.Exec(query, page.ID, page.Version, page.Content, comment, page.AuthorID)
+	return err
+}
+
+// RenameWikiPage cambia el título de la página. Con redirect, el título antiguo redirige a la página
+func RenameWikiPage(db DBTX, page *WikiPage, title string, redirect bool) error {
+	query := `UPDATE wiki_pages SET title = $1, updated_at = NOW() WHERE id = $2`
+	if _, err := db.Exec(query, title, page.ID); err != nil {
+		return err
+	}
+
+	if _, err := db.Exec(`DELETE FROM wiki_redirects WHERE project_id = $1 AND title = $2`, page.ProjectID, title); err != nil {
+		return err
+	}
+
+	if redirect {
+		query = `
+		INSERT INTO wiki_redirects (project_id, title, page_id) VALUES ($1, $2, $3)
+		ON CONFLICT (project_id, title) DO UPDATE SET page_id = EXCLUDED.page_id`
+		if _, err := db.Exec(query, page.ProjectID, page.Title, page.ID); err != nil {
+			return err
+		}
+	}
+
+	page.Title = title
+	return nil
+}
+
+// DeleteWikiPage elimina la página con sus versiones y redirecciones. Las páginas hijas quedan sin padre
+func DeleteWikiPage(db *sql.DB, id int) error {
+	_, err := db.Exec(`DELETE FROM wiki_pages WHERE id = $1`, id)
+	return err
+}
+
+// IsWikiPageAncestor indica si ancestorID es pageID o uno de sus antecesores
+func IsWikiPageAncestor(db *sql.DB, ancestorID, pageID int) (bool, error) {
+	query := `
+	WITH RECURSIVE ancestors AS (
+		SELECT id, parent_id FROM wiki_pages WHERE id = $2
+		UNION
+		SELECT p.id, p.parent_id FROM wiki_pages p JOIN ancestors a ON p.id = a.parent_id
+	)
+	SELECT EXISTS (SELECT 1 FROM ancestors WHERE id = $1)`
+
+	var exists bool
+	err := db.QueryRow(query, ancestorID, pageID).Scan(&exists)
+	return exists, err
+}
+
+// GetWikiRevisionsByPageID obtiene las versiones de una página, de la más reciente a la más antigua, sin su contenido
+func GetWikiRevisionsByPageID(db *sql.DB, pageID int) ([]WikiRevision, error) {
+	query := `
+	SELECT id, page_id, version, comment, author_id, created_at
+	FROM wiki_revisions
+	WHERE page_id = $1
+	ORDER BY version DESC`
+
+	rows, err := db.Query(query, pageID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	revisions := []WikiRevision{}
+	for rows.Next() {
+		var revision WikiRevision
+		if err := rows.Scan(&revision.ID, &revision.PageID, &revision.Version, &revision.Comment, &revision.AuthorID, &revision.CreatedAt); err != nil {
+			return nil, err
+		}
+		revisions = append(revisions, revision)
+	}
+
+	return revisions, nil
+}
+
+// GetWikiRevision obtiene una versión de una página, o nil si no existe
+func GetWikiRevision(db *sql.DB, pageID, version int) (*WikiRevision, error) {
+	query := `
+	SELECT id, page_id, version, content, comment, author_id, created_at
+	FROM wiki_revisions
+	WHERE page_id = $1 AND version = $2`
+
+	revision := &WikiRevision{}
+	err := db.QueryRow(query, pageID, version).Scan(&revision.ID, &revision.PageID, &revision.Version,
+		&revision.Content, &revision.Comment, &revision.AuthorID, &revision.CreatedAt)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return revision, nil
+}
+
+func CreateWikiTables(db *sql.DB) error {
+	query := `
+	CREATE TABLE IF NOT EXISTS wiki_pages (
+		id SERIAL PRIMARY KEY,
+		project_id INT NOT NULL,
+		title VARCHAR(255) NOT NULL,
+		parent_id INT,
+		content TEXT NOT NULL DEFAULT '',
+		version INT NOT NULL DEFAULT 1,
+		author_id INT,
+		created_at TIMESTAMP DEFAULT NOW(),
+		updated_at TIMESTAMP DEFAULT NOW(),
+		UNIQUE (project_id, title),
+		FOREIGN KEY (project_id) REFERENCES projects(id) ON DELETE CASCADE,
+		FOREIGN KEY (parent_id) REFERENCES wiki_pages(id) ON DELETE SET NULL,
+		FOREIGN KEY (author_id) REFERENCES users(id) ON DELETE SET NULL
+	);
+
+	CREATE TABLE IF NOT EXISTS wiki_revisions (
+		id SERIAL PRIMARY KEY,
+		page_id INT NOT NULL,
+		version INT NOT NULL,
+		content TEXT NOT NULL,
+		comment TEXT NOT NULL DEFAULT '',
+		author_id INT,
+		created_at TIMESTAMP DEFAULT NOW(),
+		UNIQUE (page_id, version),
+		FOREIGN KEY (page_id) REFERENCES wiki_pages(id) ON DELETE CASCADE,
+		FOREIGN KEY (author_id) REFERENCES users(id) ON DELETE SET NULL
+	);
+
+	CREATE TABLE IF NOT EXISTS wiki_redirects (
+		project_id INT NOT NULL,
+		title VARCHAR(255) NOT NULL,
+		page_id INT NOT NULL,
+		PRIMARY KEY (project_id, title),
+		FOREIGN KEY (project_id) REFERENCES projects(id) ON DELETE CASCADE,
+		FOREIGN KEY (page_id) REFERENCES wiki_pages(id) ON DELETE CASCADE
+	)`
+	_, err := db.Exec(query)
+	return err
+}
+
+func DropWikiTables(db *sql.DB) error {
+	query := `DROP TABLE IF EXISTS wiki_redirects, wiki_revisions, wiki_pages`
+	_, err := db.Exec(query)
+	return err
+}
@@ -0,0 +1,115 @@
+package models
+
+import "database/sql"
+
+/*
+CREATE TABLE IF NOT EXISTS workflows (
+	id SERIAL PRIMARY KEY,
+	tracker_id INT NOT NULL,            -- Tracker al que se aplica la transición
+	role_id INT NOT NULL,               -- Rol del miembro del proyecto que puede hacerla
+	old_status_id INT NOT NULL,         -- Estado de partida
+	new_status_id INT NOT NULL,         -- Estado al que se puede pasar
+	UNIQUE (tracker_id, role_id, old_status_id, new_status_id)
+);
+*/
+
+// WorkflowTransition es un cambio de estado permitido a un rol en los tickets de un tracker.
+// Un tracker sin transiciones no restringe los cambios de estado
+type WorkflowTransition struct {
+	ID          int `json:"id"`
+	TrackerID   int `json:"tracker_id"`
+	RoleID      int `json:"role_id"`
+	OldStatusID int `json:"old_status_id"`
+	NewStatusID int `json:"new_status_id"`
+}
+
+// GetWorkflowTransitionsByTrackerID obtiene las transiciones de un tracker
+func GetWorkflowTransitionsByTrackerID(db *sql.DB, trackerID int) ([]WorkflowTransition, error) {
+	query := `
+	SELECT id, tracker_id, role_id, old_status_id, new_status_id
+	FROM workflows
+	WHERE tracker_id = $1
+	ORDER BY role_id, old_status_id, new_status_id`
+
+	rows, err := db.Query(query, trackerID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	transitions := []WorkflowTransition{}
+	for rows.Next() {
+		var transition WorkflowTransition
+		if err := rows.Scan(&transition.ID, &transition.TrackerID, &transition.RoleID,
+			&transition.OldStatusID, &transition.NewStatusID); err != nil {
+			return nil, err
+		}
+		transitions = append(transitions, transition)
+	}
+
+	return transitions, nil
+}
+
+// SetWorkflowTransitions sustituye las transiciones de un tracker
+func SetWorkflowTransitions(db DBTX, trackerID int, transitions []WorkflowTransition) error {
+	if _, err := db.Exec(`DELETE FROM workflows WHERE tracker_id = $1`, trackerID); err != nil {
+		return err
+	}
+
+	query := `
+	INSERT INTO workflows (tracker_id, role_id, old_status_id, new_status_id)
+	VALUES ($1, $2, $3, $4)
+	ON CONFLICT DO NOTHING`
+	for _, transition := range transitions {
+		if _, err := db.Exec(query, trackerID, transition.RoleID, transition.OldStatusID, transition.NewStatusID); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// IsStatusTransitionAllowed indica si el usuario puede pasar un ticket del tracker de un estado
+// a otro en el proyecto: el tracker no tiene transiciones, o alguno de sus roles como miembro
+// del proyecto tiene la transición
+func IsStatusTransitionAllowed(db *sql.DB, userID, projectID, trackerID int, oldStatus, newStatus string) (bool, error) {
+	query := `
+	SELECT NOT EXISTS (SELECT 1 FROM workflows WHERE tracker_id = $1)
+		OR EXISTS (
+			SELECT 1
+			FROM workflows w
+			JOIN issue_statuses o ON o.id = w.old_status_id
+			JOIN issue_statuses n ON n.id = w.new_status_id
+			JOIN members m ON m.role_id = w.role_id
+			WHERE w.tracker_id = $1 AND o.name = $2 AND n.name = $3
+				AND m.project_id = $4 AND m.user_id = $5
+		)`
+
+	var allowed bool
+	err := db.QueryRow(query, trackerID, oldStatus, newStatus, projectID, userID).Scan(&allowed)
+	return allowed, err
+}
+
+func CreateWorkflowsTable(db *sql.DB) error {
+	query := `
+	CREATE TABLE IF NOT EXISTS workflows (
+		id SERIAL PRIMARY KEY,
+		tracker_id INT NOT NULL,
+		role_id INT NOT NULL,
+		old_status_id INT NOT NULL,
+		new_status_id INT NOT NULL,
+		UNIQUE (tracker_id, role_id, old_status_id, new_status_id),
+		FOREIGN KEY (tracker_id) REFERENCES trackers(id) ON DELETE CASCADE,
+		FOREIGN KEY (role_id) REFERENCES roles(id) ON DELETE CASCADE,
+		FOREIGN KEY (old_status_id) REFERENCES issue_statuses(id) ON DELETE CASCADE,
+		FOREIGN KEY (new_status_id) REFERENCES issue_statuses(id) ON DELETE CASCADE
+	)`
+	_, err := db.Exec(query)
+	return err
+}
+
+func DropWorkflowsTable(db *sql.DB) error {
+	query := `DROP TABLE IF EXISTS workflows`
+	_, err := db.Exec(query)
+	return err
+}
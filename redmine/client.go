@@ -0,0 +1,259 @@
+package redmine
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Ref es la referencia {id, name} que Redmine anida en sus respuestas
+type Ref struct {
+	ID   int    `json:"id"`
+	Name string `json:"name"`
+}
+
+type Project struct {
+	ID          int    `json:"id"`
+	Name        string `json:"name"`
+	Identifier  string `json:"identifier"`
+	Description string `json:"description"`
+	Parent      *Ref   `json:"parent"`
+	CreatedOn   string `json:"created_on"`
+	UpdatedOn   string `json:"updated_on"`
+}
+
+type Tracker struct {
+	ID          int    `json:"id"`
+	Name        string `json:"name"`
+	Description string `json:"description"`
+}
+
+type IssueStatus struct {
+	ID       int    `json:"id"`
+	Name     string `json:"name"`
+	IsClosed bool   `json:"is_closed"`
+}
+
+type User struct {
+	ID        int    `json:"id"`
+	Login     string `json:"login"`
+	Firstname string `json:"firstname"`
+	Lastname  string `json:"lastname"`
+	Mail      string `json:"mail"`
+}
+
+type Role struct {
+	ID   int    `json:"id"`
+	Name string `json:"name"`
+}
+
+type Membership struct {
+	ID      int   `json:"id"`
+	Project Ref   `json:"project"`
+	User    *Ref  `json:"user"`
+	Group   *Ref  `json:"group"`
+	Roles   []Ref `json:"roles"`
+}
+
+type IssueCategory struct {
+	ID         int    `json:"id"`
+	Project    Ref    `json:"project"`
+	Name       string `json:"name"`
+	AssignedTo *Ref   `json:"assigned_to"`
+}
+
+type Journal struct {
+	ID        int    `json:"id"`
+	User      *Ref   `json:"user"`
+	Notes     string `json:"notes"`
+	CreatedOn string `json:"created_on"`
+}
+
+type Issue struct {
+	ID             int       `json:"id"`
+	Project        Ref       `json:"project"`
+	Tracker        Ref       `json:"tracker"`
+	Status         Ref       `json:"status"`
+	AssignedTo     *Ref      `json:"assigned_to"`
+	Category       *Ref      `json:"category"`
+	Priority       *Ref      `json:"priority"`
+	Author         *Ref      `json:"author"`
+	Subject        string    `json:"subject"`
+	Description    string    `json:"description"`
+	StartDate      *string   `json:"start_date"`
+	DueDate        *string   `json:"due_date"`
+	DoneRatio      int       `json:"done_ratio"`
+	EstimatedHours *float64  `json:"estimated_hours"`
+	CreatedOn      string    `json:"created_on"`
+	UpdatedOn      string    `json:"updated_on"`
+	Journals       []Journal `json:"journals"`
+}
+
+// Client lee la API REST de una instancia de Redmine
+type Client struct {
+	BaseURL string
+	APIKey  string
+	HTTP    *http.Client
+}
+
+// pageSize es el máximo de elementos por página que admite Redmine
+const pageSize = 100
+
+// NewClient crea un cliente para la instancia de Redmine en baseURL
+func NewClient(baseURL, apiKey string) *Client {
+	return &Client{
+		BaseURL: strings.TrimRight(baseURL, "/"),
+		APIKey:  apiKey,
+		HTTP:    &http.Client{Timeout: 60 * time.Second},
+	}
+}
+
+// StatusError es la respuesta de Redmine con un código distinto de 200
+type StatusError struct {
+	Path       string
+	StatusCode int
+}
+
+func (e *StatusError) Error() string {
+	return fmt.Sprintf("redmine respondió %d a %s", e.StatusCode, e.Path)
+}
+
+// get hace una petición GET y decodifica la respuesta JSON en out
+func (c *Client) get(path string, query url.Values, out interface{}) error {
+	u := c.BaseURL + path
+	if len(query) > 0 {
+		u += "?" + query.Encode()
+	}
+
+	req, err := http.NewRequest("GET", u, nil)
+	if err != nil {
+		return fmt.Errorf("error creando la solicitud: %v", err)
+	}
+	if c.APIKey != "" {
+		req.Header.Set("X-Redmine-API-Key", c.APIKey)
+	}
+	req.Header.Set("Accept", "application/json")
+
+	resp, err := c.HTTP.Do(req)
+	if err != nil {
+		return fmt.Errorf("error de conexión con redmine: %v", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return &StatusError{Path: path, StatusCode: resp.StatusCode}
+	}
+
+	if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+		return fmt.Errorf("error procesando la respuesta de %s: %v", path, err)
+	}
+
+	return nil
+}
+
+// getAll recorre todas las páginas de un listado de Redmine y devuelve los elementos de key
+func getAll[T any](c *Client, path, key string, query url.Values) ([]T, error) {
+	if query == nil {
+		query = url.Values{}
+	}
+
+	items := []T{}
+	for offset := 0; ; offset += pageSize {
+		query.Set("offset", strconv.Itoa(offset))
+		query.Set("limit", strconv.Itoa(pageSize))
+
+		var page map[string]json.RawMessage
+		if err := c.get(path, query, &page); err != nil {
+			return nil, err
+		}
+
+		var chunk []T
+		if raw, ok := page[key]; ok {
+			if err := json.Unmarshal(raw, &chunk); err != nil {
+				return nil, fmt.Errorf("error procesando %s de %s: %v", key, path, err)
+			}
+		}
+		items = append(items, chunk...)
+
+		// Los listados sin paginar (trackers, roles...) no traen total_count
+		var total int
+		if raw, ok := page["total_count"]; !ok || json.Unmarshal(raw, &total) != nil {
+			break
+		}
+		if len(chunk) == 0 || offset+len(chunk) >= total {
+			break
+		}
+	}
+
+	return items, nil
+}
+
+func (c *Client) Projects() ([]Project, error) {
+	return getAll[Project](c, "/projects.json", "projects", nil)
+}
+
+func (c *Client) Trackers() ([]Tracker, error) {
+	return getAll[Tracker](c, "/trackers.json", "trackers", nil)
+}
+
+func (c *Client) IssueStatuses() ([]IssueStatus, error) {
+	return getAll[IssueStatus](c, "/issue_statuses.json", "issue_statuses", nil)
+}
+
+// Users necesita una clave de administrador en Redmine
+func (c *Client) Users() ([]User, error) {
+	return getAll[User](c, "/users.json", "users", nil)
+}
+
+func (c *Client) User(id int) (*User, error) {
+	var data struct {
+		User User `json:"user"`
+	}
+	if err := c.get(fmt.Sprintf("/users/%d.json", id), nil, &data); err != nil {
+		return nil, err
+	}
+	return &data.User, nil
+}
+
+func (c *Client) Roles() ([]Role, error) {
+	return getAll[Role](c, "/roles.json", "roles", nil)
+}
+
+func (c *Client) Memberships(projectID int) ([]Membership, error) {
+	return getAll[Membership](c, fmt.Sprintf("/projects/%d/memberships.json", projectID), "memberships", nil)
+}
+
+func (c *Client) IssueCategories(projectID int) ([]IssueCategory, error) {
+	return getAll[IssueCategory](c, fmt.Sprintf("/projects/%d/issue_categories.json", projectID), "issue_categories", nil)
+}
+
+// Issues obtiene los tickets de un proyecto (sin subproyectos), abiertos y cerrados.
+// Si since no está vacío solo se piden los actualizados desde esa fecha (YYYY-MM-DD).
+func (c *Client) Issues(projectID int, since string) ([]Issue, error) {
+	query := url.Values{}
+	query.Set("project_id", strconv.Itoa(projectID))
+	query.Set("subproject_id", "!*")
+	query.Set("status_id", "*")
+	query.Set("sort", "id")
+	if since != "" {
+		query.Set("updated_on", ">="+since)
+	}
+	return getAll[Issue](c, "/issues.json", "issues", query)
+}
+
+// Journals obtiene el historial de un ticket
+func (c *Client) Journals(issueID int) ([]Journal, error) {
+	var data struct {
+		Issue Issue `json:"issue"`
+	}
+	query := url.Values{}
+	query.Set("include", "journals")
+	if err := c.get(fmt.Sprintf("/issues/%d.json", issueID), query, &data); err != nil {
+		return nil, err
+	}
+	return data.Issue.Journals, nil
+}
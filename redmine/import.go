@@ -0,0 +1,775 @@
+package redmine
+
+import (
+	"database/sql"
+	"errors"
+	"flag"
+	"fmt"
+	"go-redmine-ish/config"
+	"go-redmine-ish/database"
+	"go-redmine-ish/models"
+	"log"
+	"net/http"
+	"os"
+	"sort"
+)
+
+// Importer vuelca una instancia de Redmine sobre la base de datos local.
+// Cada entidad importada se registra en redmine_id_map, así que volver a
+// ejecutarlo actualiza lo ya importado y solo crea lo nuevo.
+type Importer struct {
+	db     *sql.DB
+	client *Client
+
+	// Since limita los tickets a los actualizados desde esa fecha (YYYY-MM-DD)
+	Since string
+	// Project limita la importación al proyecto con ese identificador y sus subproyectos
+	Project string
+
+	Created map[string]int
+	Updated map[string]int
+	Skipped map[string]int
+}
+
+// NewImporter crea un importador que lee de client y escribe en db
+func NewImporter(db *sql.DB, client *Client) *Importer {
+	return &Importer{
+		db:      db,
+		client:  client,
+		Created: map[string]int{},
+		Updated: map[string]int{},
+		Skipped: map[string]int{},
+	}
+}
+
+// Run importa estados, trackers, roles, usuarios, proyectos con sus miembros y
+// categorías, y por último los tickets con sus notas como comentarios
+func (im *Importer) Run() error {
+	if err := models.CreateRedmineIDMapTable(im.db); err != nil {
+		return err
+	}
+
+	steps := []struct {
+		name string
+		fn   func() error
+	}{
+		{"estados", im.importIssueStatuses},
+		{"trackers", im.importTrackers},
+		{"roles", im.importRoles},
+		{"usuarios", im.importUsers},
+	}
+	for _, step := range steps {
+		log.Printf("import-redmine: importando %s", step.name)
+		if err := step.fn(); err != nil {
+			return fmt.Errorf("error importando %s: %v", step.name, err)
+		}
+	}
+
+	log.Println("import-redmine: importando proyectos")
+	projects, err := im.importProjects()
+	if err != nil {
+		return fmt.Errorf("error importando proyectos: %v", err)
+	}
+
+	for _, project := range projects {
+		log.Printf("import-redmine: importando miembros, categorías y tickets de %s", project.Identifier)
+		if err := im.importMemberships(project); err != nil {
+			return fmt.Errorf("error importando miembros de %s: %v", project.Identifier, err)
+		}
+		if err := im.importCategories(project); err != nil {
+			return fmt.Errorf("error importando categorías de %s: %v", project.Identifier, err)
+		}
+		if err := im.importIssues(project); err != nil {
+			return fmt.Errorf("error importando tickets de %s: %v", project.Identifier, err)
+		}
+	}
+
+	return nil
+}
+
+func (im *Importer) importIssueStatuses() error {
+	statuses, err := im.client.IssueStatuses()
+	if err != nil {
+		return err
+	}
+
+	for i, status := range statuses {
+		if _, ok, err := models.GetRedmineLocalID(im.db, models.RedmineEntityIssueStatus, status.ID); err != nil {
+			return err
+		} else if ok {
+			im.Skipped[models.RedmineEntityIssueStatus]++
+			continue
+		}
+
+		local, err := models.GetIssueStatusByName(im.db, status.Name)
+		if err != nil {
+			return err
+		}
+
+		var localID int
+		if local != nil {
+			localID = local.ID
+			im.Skipped[models.RedmineEntityIssueStatus]++
+		} else {
+			localID, err = models.CreateIssueStatus(im.db, &models.IssueStatus{
+				Name:     status.Name,
+				IsClosed: status.IsClosed,
+				Position: i + 1,
+			})
+			if err != nil {
+				return err
+			}
+			im.Created[models.RedmineEntityIssueStatus]++
+		}
+
+		if err := models.SaveRedmineIDMap(im.db, models.RedmineEntityIssueStatus, status.ID, localID); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (im *Importer) importTrackers() error {
+	trackers, err := im.client.Trackers()
+	if err != nil {
+		return err
+	}
+
+	for _, tracker := range trackers {
+		localID, ok, err := models.GetRedmineLocalID(im.db, models.RedmineEntityTracker, tracker.ID)
+		if err != nil {
+			return err
+		}
+
+		if ok {
+			// Se conservan la posición, el estado por defecto y los campos configurados localmente
+			local, err := models.GetTrackerByID(im.db, localID)
+			if err != nil {
+				return err
+			}
+			local.Name = tracker.Name
+			local.Description = tracker.Description
+			if err := models.UpdateTracker(im.db, local); err != nil {
+				return err
+			}
+			im.Updated[models.RedmineEntityTracker]++
+			continue
+		}
+
+		// Un tracker con el mismo nombre se reutiliza
+		if local, err := models.GetTrackerByName(im.db, tracker.Name); err == nil {
+			localID = local.ID
+			im.Skipped[models.RedmineEntityTracker]++
+		} else if err == sql.ErrNoRows {
+			localID, err = models.CreateTracker(im.db, &models.Tracker{Name: tracker.Name, Description: tracker.Description})
+			if err != nil {
+				return err
+			}
+			im.Created[models.RedmineEntityTracker]++
+		} else {
+			return err
+		}
+
+		if err := models.SaveRedmineIDMap(im.db, models.RedmineEntityTracker, tracker.ID, localID); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (im *Importer) importRoles() error {
+	roles, err := im.client.Roles()
+	if err != nil {
+		return err
+	}
+
+	for _, role := range roles {
+		if _, ok, err := models.GetRedmineLocalID(im.db, models.RedmineEntityRole, role.ID); err != nil {
+			return err
+		} else if ok {
+			im.Skipped[models.RedmineEntityRole]++
+			continue
+		}
+
+		var localID int
+		if local, err := models.GetRoleByName(im.db, role.Name); err == nil {
+			localID = local.ID
+			im.Skipped[models.RedmineEntityRole]++
+		} else if err == sql.ErrNoRows {
+			localID, err = models.CreateRole(im.db, &models.Role{Name: role.Name, Description: "Importado de Redmine"})
+			if err != nil {
+				return err
+			}
+			im.Created[models.RedmineEntityRole]++
+		} else {
+			return err
+		}
+
+		if err := models.SaveRedmineIDMap(im.db, models.RedmineEntityRole, role.ID, localID); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (im *Importer) importUsers() error {
+	users, err := im.client.Users()
+	if err != nil {
+		// Sin clave de administrador /users.json no está disponible; los usuarios
+		// se resuelven uno a uno a medida que aparecen en miembros y tickets
+		var statusErr *StatusError
+		if errors.As(err, &statusErr) && (statusErr.StatusCode == http.StatusForbidden || statusErr.StatusCode == http.StatusUnauthorized) {
+			log.Printf("import-redmine: %v, los usuarios se importarán bajo demanda", err)
+			return nil
+		}
+		return err
+	}
+
+	for _, user := range users {
+		if _, err := im.importUser(user); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// importUser crea el usuario local o reutiliza el que tenga el mismo login o correo
+func (im *Importer) importUser(user User) (int, error) {
+	localID, ok, err := models.GetRedmineLocalID(im.db, models.RedmineEntityUser, user.ID)
+	if err != nil {
+		return 0, err
+	}
+	if ok {
+		im.Skipped[models.RedmineEntityUser]++
+		return localID, nil
+	}
+
+	// Los usuarios sin correo visible reciben una dirección no enrutable
+	email := user.Mail
+	if email == "" {
+		email = fmt.Sprintf("%s@redmine.invalid", user.Login)
+	}
+
+	if local, err := models.GetUserByUsername(im.db, user.Login); err == nil {
+		localID = local.ID
+		im.Skipped[models.RedmineEntityUser]++
+	} else if err != sql.ErrNoRows {
+		return 0, err
+	} else if local, err := models.GetUserByEmail(im.db, email); err == nil {
+		localID = local.ID
+		im.Skipped[models.RedmineEntityUser]++
+	} else if err != sql.ErrNoRows {
+		return 0, err
+	} else {
+		// La autenticación es externa (OAuth), el usuario importado no tiene contraseña local
+		localID, err = models.CreateUser(im.db, &models.User{Username: user.Login, Email: email})
+		if err != nil {
+			return 0, err
+		}
+		im.Created[models.RedmineEntityUser]++
+	}
+
+	if err := models.SaveRedmineIDMap(im.db, models.RedmineEntityUser, user.ID, localID); err != nil {
+		return 0, err
+	}
+
+	return localID, nil
+}
+
+// userID resuelve la referencia a un usuario de Redmine; si aún no está importado
+// se pide a /users/{id}.json. Devuelve nil si no se puede resolver.
+func (im *Importer) userID(ref *Ref) (*int, error) {
+	if ref == nil {
+		return nil, nil
+	}
+
+	localID, ok, err := models.GetRedmineLocalID(im.db, models.RedmineEntityUser, ref.ID)
+	if err != nil {
+		return nil, err
+	}
+	if ok {
+		return &localID, nil
+	}
+
+	user, err := im.client.User(ref.ID)
+	if err != nil {
+		// Grupos, usuarios bloqueados o sin permisos para verlos
+		log.Printf("import-redmine: no se puede resolver el usuario %d (%s): %v", ref.ID, ref.Name, err)
+		return nil, nil
+	}
+
+	localID, err = im.importUser(*user)
+	if err != nil {
+		return nil, err
+	}
+
+	return &localID, nil
+}
+
+// selectProjects filtra los proyectos por im.Project (incluidos los subproyectos)
+// y los ordena para que cada padre se importe antes que sus hijos
+func (im *Importer) selectProjects(projects []Project) []Project {
+	byID := map[int]Project{}
+	for _, project := range projects {
+		byID[project.ID] = project
+	}
+
+	selected := map[int]bool{}
+	for _, project := range projects {
+		if im.Project == "" {
+			selected[project.ID] = true
+			continue
+		}
+		for p, ok := project, true; ok; {
+			if p.Identifier == im.Project {
+				selected[project.ID] = true
+				break
+			}
+			if p.Parent == nil {
+				break
+			}
+			p, ok = byID[p.Parent.ID]
+		}
+	}
+
+	depth := func(project Project) int {
+		d := 0
+		for p, ok := project, true; ok && p.Parent != nil; d++ {
+			p, ok = byID[p.Parent.ID]
+		}
+		return d
+	}
+
+	ordered := []Project{}
+	for _, project := range projects {
+		if selected[project.ID] {
+			ordered = append(ordered, project)
+		}
+	}
+	sort.SliceStable(ordered, func(i, j int) bool {
+		return depth(ordered[i]) < depth(ordered[j])
+	})
+
+	return ordered
+}
+
+// importProjects importa la jerarquía de proyectos y devuelve los proyectos locales
+// con el ID de Redmine en ParentID sustituido por el local
+func (im *Importer) importProjects() ([]importedProject, error) {
+	projects, err := im.client.Projects()
+	if err != nil {
+		return nil, err
+	}
+
+	imported := []importedProject{}
+	for _, project := range im.selectProjects(projects) {
+		var parentID *int
+		if project.Parent != nil {
+			id, ok, err := models.GetRedmineLocalID(im.db, models.RedmineEntityProject, project.Parent.ID)
+			if err != nil {
+				return nil, err
+			}
+			if ok {
+				parentID = &id
+			}
+		}
+
+		localID, ok, err := models.GetRedmineLocalID(im.db, models.RedmineEntityProject, project.ID)
+		if err != nil {
+			return nil, err
+		}
+
+		var local *models.Project
+		if ok {
+			local, err = models.GetProjectByID(im.db, localID)
+		} else {
+			local, err = models.GetProjectByIdentifier(im.db, project.Identifier)
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		if local != nil {
+			local.Name = project.Name
+			local.Identifier = project.Identifier
+			local.Description = project.Description
+			local.ParentID = parentID
+			if err := models.UpdateProject(im.db, local); err != nil {
+				return nil, err
+			}
+			im.Updated[models.RedmineEntityProject]++
+		} else {
+			local = &models.Project{
+				Name:        project.Name,
+				Identifier:  project.Identifier,
+				Description: project.Description,
+				ParentID:    parentID,
+			}
+			if _, err := models.CreateProject(im.db, local); err != nil {
+				return nil, err
+			}
+			// Como al crear un proyecto desde la API, con todos los trackers y módulos
+			if err := models.EnableAllTrackersForProject(im.db, local.ID); err != nil {
+				return nil, err
+			}
+			if err := models.SetProjectModules(im.db, local.ID, models.ProjectModules); err != nil {
+				return nil, err
+			}
+			im.Created[models.RedmineEntityProject]++
+		}
+
+		if err := models.SaveRedmineIDMap(im.db, models.RedmineEntityProject, project.ID, local.ID); err != nil {
+			return nil, err
+		}
+
+		imported = append(imported, importedProject{RedmineID: project.ID, LocalID: local.ID, Identifier: project.Identifier})
+	}
+
+	return imported, nil
+}
+
+// importedProject relaciona un proyecto de Redmine con el proyecto local
+type importedProject struct {
+	RedmineID  int
+	LocalID    int
+	Identifier string
+}
+
+func (im *Importer) importMemberships(project importedProject) error {
+	memberships, err := im.client.Memberships(project.RedmineID)
+	if err != nil {
+		return err
+	}
+
+	existing, err := models.GetMembersByProjectID(im.db, project.LocalID)
+	if err != nil {
+		return err
+	}
+	exists := map[[2]int]bool{}
+	for _, member := range existing {
+		exists[[2]int{member.UserID, member.RoleID}] = true
+	}
+
+	for _, membership := range memberships {
+		// Los grupos no tienen equivalente local
+		if membership.User == nil {
+			im.Skipped["member"]++
+			continue
+		}
+
+		userID, err := im.userID(membership.User)
+		if err != nil {
+			return err
+		}
+		if userID == nil {
+			im.Skipped["member"]++
+			continue
+		}
+
+		for _, role := range membership.Roles {
+			roleID, ok, err := models.GetRedmineLocalID(im.db, models.RedmineEntityRole, role.ID)
+			if err != nil {
+				return err
+			}
+			if !ok || exists[[2]int{*userID, roleID}] {
+				im.Skipped["member"]++
+				continue
+			}
+
+			member := &models.Member{UserID: *userID, ProjectID: project.LocalID, RoleID: roleID}
+			if _, err := models.CreateMember(im.db, member); err != nil {
+				return err
+			}
+			exists[[2]int{*userID, roleID}] = true
+			im.Created["member"]++
+		}
+	}
+
+	return nil
+}
+
+func (im *Importer) importCategories(project importedProject) error {
+	categories, err := im.client.IssueCategories(project.RedmineID)
+	if err != nil {
+		return err
+	}
+
+	for _, category := range categories {
+		assignedToID, err := im.userID(category.AssignedTo)
+		if err != nil {
+			return err
+		}
+
+		localID, ok, err := models.GetRedmineLocalID(im.db, models.RedmineEntityCategory, category.ID)
+		if err != nil {
+			return err
+		}
+
+		if !ok {
+			local, err := models.GetCategoryByProjectAndName(im.db, project.LocalID, category.Name)
+			if err != nil {
+				return err
+			}
+			if local != nil {
+				localID, ok = local.ID, true
+			}
+		}
+
+		if ok {
+			local := &models.Category{ID: localID, ProjectID: project.LocalID, Name: category.Name, AssignedToID: assignedToID}
+			if err := models.UpdateCategory(im.db, local); err != nil {
+				return err
+			}
+			im.Updated[models.RedmineEntityCategory]++
+		} else {
+			local := &models.Category{ProjectID: project.LocalID, Name: category.Name, AssignedToID: assignedToID}
+			localID, err = models.CreateCategory(im.db, local)
+			if err != nil {
+				return err
+			}
+			im.Created[models.RedmineEntityCategory]++
+		}
+
+		if err := models.SaveRedmineIDMap(im.db, models.RedmineEntityCategory, category.ID, localID); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (im *Importer) importIssues(project importedProject) error {
+	issues, err := im.client.Issues(project.RedmineID, im.Since)
+	if err != nil {
+		return err
+	}
+
+	for _, issue := range issues {
+		trackerID, ok, err := models.GetRedmineLocalID(im.db, models.RedmineEntityTracker, issue.Tracker.ID)
+		if err != nil {
+			return err
+		}
+		if !ok {
+			log.Printf("import-redmine: ticket %d omitido, tracker %q sin importar", issue.ID, issue.Tracker.Name)
+			im.Skipped[models.RedmineEntityIssue]++
+			continue
+		}
+
+		assignedToID, err := im.userID(issue.AssignedTo)
+		if err != nil {
+			return err
+		}
+
+		var categoryID *int
+		if issue.Category != nil {
+			id, ok, err := models.GetRedmineLocalID(im.db, models.RedmineEntityCategory, issue.Category.ID)
+			if err != nil {
+				return err
+			}
+			if ok {
+				categoryID = &id
+			}
+		}
+
+		authorID, err := im.userID(issue.Author)
+		if err != nil {
+			return err
+		}
+
+		// Las prioridades se corresponden por nombre; si no existe se usa la predeterminada
+		var priorityID int
+		if issue.Priority != nil {
+			priority, err := models.GetIssuePriorityByName(im.db, issue.Priority.Name)
+			if err != nil {
+				return err
+			}
+			if priority != nil {
+				priorityID = priority.ID
+			}
+		}
+
+		local := &models.Issue{
+			Subject:        issue.Subject,
+			Description:    issue.Description,
+			TrackerID:      trackerID,
+			ProjectID:      project.LocalID,
+			AssignedToID:   assignedToID,
+			Status:         issue.Status.Name,
+			CategoryID:     categoryID,
+			PriorityID:     priorityID,
+			AuthorID:       authorID,
+			StartDate:      issue.StartDate,
+			DueDate:        issue.DueDate,
+			DoneRatio:      issue.DoneRatio,
+			EstimatedHours: issue.EstimatedHours,
+		}
+
+		if err := im.saveIssue(issue, local); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// saveIssue crea o actualiza el ticket local de un ticket de Redmine con sus notas. Todo se guarda
+// en una transacción junto con la correspondencia de IDs: si la importación se corta a medias, el
+// ticket no queda creado sin correspondencia y la siguiente pasada no lo duplica
+func (im *Importer) saveIssue(issue Issue, local *models.Issue) error {
+	// Las notas se piden antes de abrir la transacción para no tenerla abierta durante la petición
+	journals, err := im.client.Journals(issue.ID)
+	if err != nil {
+		return err
+	}
+
+	localID, ok, err := models.GetRedmineLocalID(im.db, models.RedmineEntityIssue, issue.ID)
+	if err != nil {
+		return err
+	}
+	var current *models.Issue
+	if ok {
+		current, err = models.GetIssueByID(im.db, localID)
+		if err == sql.ErrNoRows {
+			// El ticket local se ha borrado: se vuelve a crear
+			current = nil
+		} else if err != nil {
+			return err
+		}
+	}
+
+	tx, err := im.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if current != nil {
+		// Redmine manda sobre la copia local: se actualiza sobre la versión actual
+		local.ID = localID
+		local.LockVersion = current.LockVersion
+		// Las versiones y subtareas no se importan: se conserva la planificación local
+		local.FixedVersionID = current.FixedVersionID
+		local.ParentID = current.ParentID
+		if err := models.UpdateIssue(tx, local); err != nil {
+			return err
+		}
+	} else {
+		localID, err = models.CreateIssue(tx, local)
+		if err != nil {
+			return err
+		}
+	}
+
+	// Conservar las fechas originales de Redmine
+	if err := models.SetIssueTimestamps(tx, localID, issue.CreatedOn, issue.UpdatedOn); err != nil {
+		return err
+	}
+
+	if err := models.SaveRedmineIDMap(tx, models.RedmineEntityIssue, issue.ID, localID); err != nil {
+		return err
+	}
+
+	created, skipped, err := im.importJournals(tx, journals, localID, current == nil)
+	if err != nil {
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+
+	// Los contadores solo cuentan lo que ha quedado guardado
+	if current != nil {
+		im.Updated[models.RedmineEntityIssue]++
+	} else {
+		im.Created[models.RedmineEntityIssue]++
+	}
+	im.Created[models.RedmineEntityJournal] += created
+	im.Skipped[models.RedmineEntityJournal] += skipped
+
+	return nil
+}
+
+// importJournals importa en tx las notas del historial de un ticket como comentarios y devuelve
+// cuántas ha creado y omitido. Las entradas sin notas (solo cambios de campos) no tienen
+// equivalente local. En un ticket recién creado se importan todas: las correspondencias que
+// hubiera eran de los comentarios de un ticket local ya borrado
+func (im *Importer) importJournals(tx *sql.Tx, journals []Journal, issueID int, newIssue bool) (int, int, error) {
+	created, skipped := 0, 0
+	for _, journal := range journals {
+		if journal.Notes == "" {
+			continue
+		}
+
+		if !newIssue {
+			if _, ok, err := models.GetRedmineLocalID(im.db, models.RedmineEntityJournal, journal.ID); err != nil {
+				return 0, 0, err
+			} else if ok {
+				skipped++
+				continue
+			}
+		}
+
+		userID, err := im.userID(journal.User)
+		if err != nil {
+			return 0, 0, err
+		}
+		if userID == nil {
+			skipped++
+			continue
+		}
+
+		comment := &models.Comment{IssueID: issueID, UserID: *userID, Content: journal.Notes}
+		commentID, err := models.CreateComment(tx, comment)
+		if err != nil {
+			return 0, 0, err
+		}
+		if err := models.SetCommentTimestamps(tx, commentID, journal.CreatedOn, journal.CreatedOn); err != nil {
+			return 0, 0, err
+		}
+		if err := models.SaveRedmineIDMap(tx, models.RedmineEntityJournal, journal.ID, commentID); err != nil {
+			return 0, 0, err
+		}
+		created++
+	}
+
+	return created, skipped, nil
+}
+
+// RunImportCommand ejecuta el subcomando import-redmine con los argumentos de la línea de comandos
+func RunImportCommand(cfg *config.Config, args []string) error {
+	fs := flag.NewFlagSet("import-redmine", flag.ContinueOnError)
+	baseURL := fs.String("url", os.Getenv("REDMINE_URL"), "URL base de la instancia de Redmine (REDMINE_URL)")
+	apiKey := fs.String("key", os.Getenv("REDMINE_API_KEY"), "clave de la API de Redmine (REDMINE_API_KEY)")
+	since := fs.String("since", "", "solo tickets actualizados desde esta fecha (YYYY-MM-DD)")
+	project := fs.String("project", "", "identificador del proyecto a importar, con sus subproyectos")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	if *baseURL == "" {
+		return errors.New("falta la URL de Redmine (-url o REDMINE_URL)")
+	}
+
+	db, err := database.InitDB(cfg)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	importer := NewImporter(db, NewClient(*baseURL, *apiKey))
+	importer.Since = *since
+	importer.Project = *project
+
+	if err := importer.Run(); err != nil {
+		return err
+	}
+
+	log.Printf("import-redmine: creados %v, actualizados %v, omitidos %v", importer.Created, importer.Updated, importer.Skipped)
+
+	return nil
+}
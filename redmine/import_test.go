@@ -0,0 +1,376 @@
+package redmine
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+
+	"go-redmine-ish/database/dbtest"
+	"go-redmine-ish/models"
+)
+
+// fakeRedmine sirve la API REST de Redmine con los datos de sus campos. Los listados se paginan
+// con offset/limit y total_count como en Redmine
+type fakeRedmine struct {
+	statuses    []IssueStatus
+	trackers    []Tracker
+	roles       []Role
+	users       map[int]User // solo /users/{id}.json: /users.json responde 403 como sin clave de administrador
+	projects    []Project
+	memberships map[int][]Membership
+	categories  map[int][]IssueCategory
+	issues      map[int][]Issue
+}
+
+func (f *fakeRedmine) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	path := r.URL.Path
+	switch {
+	case path == "/issue_statuses.json":
+		writeList(w, r, "issue_statuses", f.statuses)
+	case path == "/trackers.json":
+		writeList(w, r, "trackers", f.trackers)
+	case path == "/roles.json":
+		writeList(w, r, "roles", f.roles)
+	case path == "/users.json":
+		w.WriteHeader(http.StatusForbidden)
+	case path == "/projects.json":
+		writeList(w, r, "projects", f.projects)
+	case path == "/issues.json":
+		project_id, _ := strconv.Atoi(r.URL.Query().Get("project_id"))
+		writeList(w, r, "issues", f.issues[project_id])
+	case strings.HasPrefix(path, "/users/"):
+		id, _ := strconv.Atoi(strings.TrimSuffix(strings.TrimPrefix(path, "/users/"), ".json"))
+		user, ok := f.users[id]
+		if !ok {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		json.NewEncoder(w).Encode(map[string]User{"user": user})
+	case strings.HasPrefix(path, "/issues/"):
+		id, _ := strconv.Atoi(strings.TrimSuffix(strings.TrimPrefix(path, "/issues/"), ".json"))
+		for _, issues := range f.issues {
+			for _, issue := range issues {
+				if issue.ID == id {
+					json.NewEncoder(w).Encode(map[string]Issue{"issue": issue})
+					return
+				}
+			}
+		}
+		w.WriteHeader(http.StatusNotFound)
+	default:
+		var project_id int
+		var resource string
+		if _, err := fmt.Sscanf(path, "/projects/%d/%s", &project_id, &resource); err != nil {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		switch resource {
+		case "memberships.json":
+			writeList(w, r, "memberships", f.memberships[project_id])
+		case "issue_categories.json":
+			writeList(w, r, "issue_categories", f.categories[project_id])
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}
+}
+
+// writeList responde una página de items como los listados de Redmine
+func writeList[T any](w http.ResponseWriter, r *http.Request, key string, items []T) {
+	offset, _ := strconv.Atoi(r.URL.Query().Get("offset"))
+	limit, err := strconv.Atoi(r.URL.Query().Get("limit"))
+	if err != nil || limit <= 0 {
+		limit = 25
+	}
+	page := []T{}
+	if offset < len(items) {
+		page = items[offset:min(offset+limit, len(items))]
+	}
+	json.NewEncoder(w).Encode(map[string]any{key: page, "total_count": len(items), "offset": offset, "limit": limit})
+}
+
+func TestClientPagination(t *testing.T) {
+	fake := &fakeRedmine{issues: map[int][]Issue{1: {}}}
+	for i := 1; i <= 2*pageSize+5; i++ {
+		fake.issues[1] = append(fake.issues[1], Issue{ID: i, Subject: fmt.Sprintf("Issue %d", i)})
+	}
+	server := httptest.NewServer(fake)
+	defer server.Close()
+
+	issues, err := NewClient(server.URL+"/", "secret").Issues(1, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(issues) != 2*pageSize+5 {
+		t.Fatalf("se han leído %d tickets, se esperaban %d", len(issues), 2*pageSize+5)
+	}
+	for i, issue := range issues {
+		if issue.ID != i+1 {
+			t.Fatalf("el ticket %d tiene el ID %d", i, issue.ID)
+		}
+	}
+
+	// Sin clave de administrador el importador reconoce el 403 y sigue sin la lista de usuarios
+	var statusErr *StatusError
+	if _, err := NewClient(server.URL, "").Users(); !errors.As(err, &statusErr) || statusErr.StatusCode != http.StatusForbidden {
+		t.Errorf("Users() = %v, se esperaba un *StatusError 403", err)
+	}
+}
+
+func TestSelectProjects(t *testing.T) {
+	projects := []Project{
+		{ID: 3, Identifier: "grandchild", Parent: &Ref{ID: 2}},
+		{ID: 2, Identifier: "child", Parent: &Ref{ID: 1}},
+		{ID: 4, Identifier: "other"},
+		{ID: 1, Identifier: "root"},
+	}
+	identifiers := func(projects []Project) string {
+		names := []string{}
+		for _, project := range projects {
+			names = append(names, project.Identifier)
+		}
+		return strings.Join(names, ",")
+	}
+
+	// Cada padre antes que sus hijos
+	im := &Importer{}
+	if got := identifiers(im.selectProjects(projects)); got != "other,root,child,grandchild" {
+		t.Errorf("selectProjects = %s", got)
+	}
+
+	// Un proyecto con sus subproyectos, sin los demás
+	im.Project = "child"
+	if got := identifiers(im.selectProjects(projects)); got != "child,grandchild" {
+		t.Errorf("selectProjects(child) = %s", got)
+	}
+}
+
+// newFakeRedmine devuelve una instancia con dos proyectos (uno hijo del otro), un miembro, una
+// categoría y dos tickets, uno de ellos de un tracker que no se importa
+func newFakeRedmine() *fakeRedmine {
+	return &fakeRedmine{
+		statuses: []IssueStatus{{ID: 1, Name: "Open"}, {ID: 7, Name: "Waiting"}},
+		trackers: []Tracker{{ID: 1, Name: "Bug"}, {ID: 20, Name: "Epic", Description: "Grandes funcionalidades"}},
+		roles:    []Role{{ID: 3, Name: "Developer"}, {ID: 4, Name: "Manager"}},
+		users: map[int]User{
+			5: {ID: 5, Login: "jdoe", Mail: "jdoe@example.com"},
+		},
+		// El hijo se lista antes que el padre
+		projects: []Project{
+			{ID: 11, Name: "Child", Identifier: "rm-child", Parent: &Ref{ID: 10}},
+			{ID: 10, Name: "Root", Identifier: "rm-root"},
+		},
+		memberships: map[int][]Membership{
+			10: {
+				{ID: 1, Project: Ref{ID: 10}, User: &Ref{ID: 5}, Roles: []Ref{{ID: 3}, {ID: 4}}},
+				{ID: 2, Project: Ref{ID: 10}, Group: &Ref{ID: 9}, Roles: []Ref{{ID: 3}}},
+			},
+		},
+		categories: map[int][]IssueCategory{
+			10: {{ID: 30, Project: Ref{ID: 10}, Name: "Backend", AssignedTo: &Ref{ID: 5}}},
+		},
+		issues: map[int][]Issue{
+			10: {
+				{
+					ID: 100, Project: Ref{ID: 10}, Tracker: Ref{ID: 1, Name: "Bug"}, Status: Ref{ID: 7, Name: "Waiting"},
+					Author: &Ref{ID: 5}, AssignedTo: &Ref{ID: 6, Name: "Locked user"}, Category: &Ref{ID: 30},
+					Priority: &Ref{ID: 3, Name: "High"}, Subject: "First", DoneRatio: 40,
+					CreatedOn: "2020-01-02T10:00:00Z", UpdatedOn: "2020-01-03T10:00:00Z",
+					Journals: []Journal{
+						{ID: 500, User: &Ref{ID: 5}, Notes: "A note", CreatedOn: "2020-01-02T11:00:00Z"},
+						{ID: 501, User: &Ref{ID: 5}, CreatedOn: "2020-01-02T12:00:00Z"},
+					},
+				},
+				{
+					ID: 101, Project: Ref{ID: 10}, Tracker: Ref{ID: 99, Name: "Unknown"}, Status: Ref{ID: 1, Name: "Open"},
+					Subject: "Skipped", CreatedOn: "2020-01-02T10:00:00Z", UpdatedOn: "2020-01-02T10:00:00Z",
+				},
+			},
+		},
+	}
+}
+
+// localID devuelve el ID local de una entidad de Redmine, que tiene que estar importada
+func localID(t *testing.T, im *Importer, entityType string, redmineID int) int {
+	t.Helper()
+	id, ok, err := models.GetRedmineLocalID(im.db, entityType, redmineID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !ok {
+		t.Fatalf("%s %d de Redmine sin importar", entityType, redmineID)
+	}
+	return id
+}
+
+func TestImporterIDMappingAndRerun(t *testing.T) {
+	db := dbtest.Open(t)
+	fake := newFakeRedmine()
+	server := httptest.NewServer(fake)
+	defer server.Close()
+
+	first := NewImporter(db, NewClient(server.URL, "secret"))
+	if err := first.Run(); err != nil {
+		t.Fatal(err)
+	}
+
+	// Los que ya existían con el mismo nombre se reutilizan, el resto se crea
+	bug, err := models.GetTrackerByName(db, "Bug")
+	if err != nil {
+		t.Fatal(err)
+	}
+	epic, err := models.GetTrackerByName(db, "Epic")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := localID(t, first, models.RedmineEntityTracker, 1); got != bug.ID {
+		t.Errorf("tracker 1 -> %d, se esperaba el Bug local %d", got, bug.ID)
+	}
+	if got := localID(t, first, models.RedmineEntityTracker, 20); got != epic.ID {
+		t.Errorf("tracker 20 -> %d, se esperaba %d", got, epic.ID)
+	}
+	if first.Created[models.RedmineEntityTracker] != 1 || first.Created[models.RedmineEntityRole] != 1 || first.Created[models.RedmineEntityIssueStatus] != 1 {
+		t.Errorf("creados = %v", first.Created)
+	}
+
+	// El padre se importa antes y el hijo apunta a su ID local
+	root := localID(t, first, models.RedmineEntityProject, 10)
+	child, err := models.GetProjectByID(db, localID(t, first, models.RedmineEntityProject, 11))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if child.ParentID == nil || *child.ParentID != root {
+		t.Errorf("el proyecto hijo tiene padre %v, se esperaba %d", child.ParentID, root)
+	}
+
+	// El usuario se resuelve bajo demanda; el que no se puede leer queda sin asignar
+	jdoe := localID(t, first, models.RedmineEntityUser, 5)
+	category := localID(t, first, models.RedmineEntityCategory, 30)
+	issue_id := localID(t, first, models.RedmineEntityIssue, 100)
+	issue, err := models.GetIssueByID(db, issue_id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	high, err := models.GetIssuePriorityByName(db, "High")
+	if err != nil {
+		t.Fatal(err)
+	}
+	switch {
+	case issue.ProjectID != root:
+		t.Errorf("ticket en el proyecto %d, se esperaba %d", issue.ProjectID, root)
+	case issue.TrackerID != bug.ID:
+		t.Errorf("ticket con tracker %d, se esperaba %d", issue.TrackerID, bug.ID)
+	case issue.AuthorID == nil || *issue.AuthorID != jdoe:
+		t.Errorf("ticket con autor %v, se esperaba %d", issue.AuthorID, jdoe)
+	case issue.AssignedToID != nil:
+		t.Errorf("ticket asignado a %d, se esperaba sin asignar", *issue.AssignedToID)
+	case issue.CategoryID == nil || *issue.CategoryID != category:
+		t.Errorf("ticket con categoría %v, se esperaba %d", issue.CategoryID, category)
+	case issue.PriorityID != high.ID:
+		t.Errorf("ticket con prioridad %d, se esperaba %d", issue.PriorityID, high.ID)
+	case issue.Status != "Waiting" || issue.DoneRatio != 40:
+		t.Errorf("ticket con estado %q y %d%%", issue.Status, issue.DoneRatio)
+	}
+	if _, ok, err := models.GetRedmineLocalID(db, models.RedmineEntityIssue, 101); err != nil || ok {
+		t.Errorf("el ticket de un tracker sin importar se ha importado (%v)", err)
+	}
+	if first.Skipped[models.RedmineEntityIssue] != 1 || first.Skipped["member"] != 1 {
+		t.Errorf("omitidos = %v", first.Skipped)
+	}
+
+	// Segunda pasada: Redmine ha cambiado el ticket, tiene una nota nueva y un ticket nuevo
+	fake.issues[10][0].Subject = "First (edited)"
+	fake.issues[10][0].Journals = append(fake.issues[10][0].Journals,
+		Journal{ID: 502, User: &Ref{ID: 5}, Notes: "Second note", CreatedOn: "2020-01-04T10:00:00Z"})
+	fake.issues[10] = append(fake.issues[10], Issue{
+		ID: 102, Project: Ref{ID: 10}, Tracker: Ref{ID: 20, Name: "Epic"}, Status: Ref{ID: 1, Name: "Open"},
+		Author: &Ref{ID: 5}, Subject: "New", CreatedOn: "2020-01-05T10:00:00Z", UpdatedOn: "2020-01-05T10:00:00Z",
+	})
+
+	second := NewImporter(db, NewClient(server.URL, "secret"))
+	if err := second.Run(); err != nil {
+		t.Fatal(err)
+	}
+
+	// Nada se duplica: los IDs locales se mantienen y solo se crea lo nuevo
+	for entity, created := range map[string]int{
+		models.RedmineEntityIssueStatus: 0,
+		models.RedmineEntityTracker:     0,
+		models.RedmineEntityRole:        0,
+		models.RedmineEntityUser:        0,
+		models.RedmineEntityProject:     0,
+		models.RedmineEntityCategory:    0,
+		models.RedmineEntityIssue:       1,
+		models.RedmineEntityJournal:     1,
+		"member":                        0,
+	} {
+		if second.Created[entity] != created {
+			t.Errorf("segunda pasada: %d %s creados, se esperaban %d", second.Created[entity], entity, created)
+		}
+	}
+	if second.Updated[models.RedmineEntityIssue] != 1 || second.Updated[models.RedmineEntityProject] != 2 {
+		t.Errorf("segunda pasada: actualizados = %v", second.Updated)
+	}
+	if got := localID(t, second, models.RedmineEntityIssue, 100); got != issue_id {
+		t.Errorf("el ticket 100 ha pasado de %d a %d", issue_id, got)
+	}
+	if got := localID(t, second, models.RedmineEntityUser, 5); got != jdoe {
+		t.Errorf("el usuario 5 ha pasado de %d a %d", jdoe, got)
+	}
+
+	issue, err = models.GetIssueByID(db, issue_id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if issue.Subject != "First (edited)" {
+		t.Errorf("asunto %q, se esperaba el actualizado", issue.Subject)
+	}
+	comments, err := models.GetCommentsByIssueID(db, issue_id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(comments) != 2 {
+		t.Errorf("el ticket tiene %d comentarios, se esperaban 2", len(comments))
+	}
+	members, err := models.GetMembersByProjectID(db, root)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(members) != 2 {
+		t.Errorf("el proyecto tiene %d miembros, se esperaban 2 (un usuario con dos roles)", len(members))
+	}
+	epic_issue := localID(t, second, models.RedmineEntityIssue, 102)
+	if issue, err := models.GetIssueByID(db, epic_issue); err != nil {
+		t.Fatal(err)
+	} else if issue.TrackerID != epic.ID {
+		t.Errorf("ticket nuevo con tracker %d, se esperaba %d", issue.TrackerID, epic.ID)
+	}
+
+	// Tercera pasada: el ticket importado se ha borrado localmente y se vuelve a crear con sus notas
+	if err := models.DeleteIssue(db, issue_id); err != nil {
+		t.Fatal(err)
+	}
+	third := NewImporter(db, NewClient(server.URL, "secret"))
+	if err := third.Run(); err != nil {
+		t.Fatal(err)
+	}
+	if third.Created[models.RedmineEntityIssue] != 1 || third.Created[models.RedmineEntityJournal] != 2 {
+		t.Errorf("tercera pasada: creados = %v", third.Created)
+	}
+	recreated := localID(t, third, models.RedmineEntityIssue, 100)
+	if recreated == issue_id {
+		t.Fatalf("el ticket 100 sigue apuntando al ticket borrado %d", issue_id)
+	}
+	comments, err = models.GetCommentsByIssueID(db, recreated)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(comments) != 2 {
+		t.Errorf("el ticket recreado tiene %d comentarios, se esperaban 2", len(comments))
+	}
+}
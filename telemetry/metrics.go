@@ -0,0 +1,105 @@
+package telemetry
+
+import (
+	"database/sql"
+	"errors"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+// namespace es el prefijo de las métricas propias
+const namespace = "redmineish"
+
+var (
+	// HTTPRequests cuenta las peticiones por método, ruta de gin y estado
+	HTTPRequests = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "http_requests_total",
+		Help:      "HTTP requests by method, route and status.",
+	}, []string{"method", "route", "status"})
+
+	// HTTPDuration es la latencia de las peticiones por método y ruta de gin
+	HTTPDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "http_request_duration_seconds",
+		Help:      "HTTP request latency by method and route.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"method", "route"})
+
+	// HTTPInFlight son las peticiones en curso
+	HTTPInFlight = promauto.NewGauge(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "http_requests_in_flight",
+		Help:      "HTTP requests being served.",
+	})
+
+	// OAuthProfileDuration es la latencia de las llamadas al perfil OAuth por resultado (ok,
+	// unauthorized o error)
+	OAuthProfileDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "oauth_profile_duration_seconds",
+		Help:      "Latency of the OAuth profile calls by result.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"result"})
+
+	// DBQueryDuration es la latencia de las consultas SQL por operación (query o exec)
+	DBQueryDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "db_query_duration_seconds",
+		Help:      "SQL statement latency by operation.",
+		Buckets:   []float64{.001, .0025, .005, .01, .025, .05, .1, .25, .5, 1, 2.5},
+	}, []string{"operation"})
+
+	// DBPoolsOpened cuenta los pools abiertos con database.InitDB, uno por petición
+	DBPoolsOpened = promauto.NewCounter(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "db_pools_opened_total",
+		Help:      "Database pools opened.",
+	})
+)
+
+// ObserveSince registra en un histograma el tiempo transcurrido desde start
+func ObserveSince(observer prometheus.Observer, start time.Time) {
+	observer.Observe(time.Since(start).Seconds())
+}
+
+// RegisterDBPoolStats publica las conexiones de los pools abiertos que devuelve stats
+func RegisterDBPoolStats(stats func() sql.DBStats) {
+	gauges := []struct {
+		name, help string
+		value      func(sql.DBStats) int
+	}{
+		{"db_open_connections", "Open database connections, in use and idle.", func(s sql.DBStats) int { return s.OpenConnections }},
+		{"db_in_use_connections", "Database connections in use.", func(s sql.DBStats) int { return s.InUse }},
+		{"db_idle_connections", "Idle database connections.", func(s sql.DBStats) int { return s.Idle }},
+	}
+	for _, gauge := range gauges {
+		value := gauge.value
+		promauto.NewGaugeFunc(prometheus.GaugeOpts{
+			Namespace: namespace,
+			Name:      gauge.name,
+			Help:      gauge.help,
+		}, func() float64 { return float64(value(stats())) })
+	}
+}
+
+// ServeMetrics sirve /metrics en addr en segundo plano y devuelve el servidor para poder pararlo
+// con Shutdown
+func ServeMetrics(addr string) *http.Server {
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", promhttp.Handler())
+
+	server := &http.Server{Addr: addr, Handler: mux, ReadHeaderTimeout: 10 * time.Second}
+	go func() {
+		slog.Info("metrics server listening", "addr", addr)
+		if err := server.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			slog.Error("metrics server stopped", "error", err)
+		}
+	}()
+	return server
+}
@@ -0,0 +1,56 @@
+package telemetry
+
+import (
+	"context"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// serviceName es el nombre del servicio en las trazas si no se indica OTEL_SERVICE_NAME
+const serviceName = "go-redmine-ish"
+
+// Tracer devuelve el tracer de la aplicación. Sin SetupTracing los spans no se exportan
+func Tracer() trace.Tracer {
+	return otel.Tracer(serviceName)
+}
+
+// SetupTracing exporta las trazas por OTLP/HTTP al colector de endpoint. El exportador lee las
+// variables estándar (OTEL_EXPORTER_OTLP_ENDPOINT, OTEL_EXPORTER_OTLP_HEADERS...) y el recurso
+// OTEL_SERVICE_NAME y OTEL_RESOURCE_ATTRIBUTES. Devuelve la función que vacía los spans pendientes
+// al terminar; con endpoint vacío no hace nada
+func SetupTracing(ctx context.Context, endpoint string) (func(context.Context) error, error) {
+	// Se propaga el contexto de traza W3C aunque no se exporte, para no romper las trazas de otros
+	otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(propagation.TraceContext{}, propagation.Baggage{}))
+	if endpoint == "" {
+		return func(context.Context) error { return nil }, nil
+	}
+
+	exporter, err := otlptracehttp.New(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	// Las opciones posteriores tienen prioridad: el nombre por defecto cede ante OTEL_SERVICE_NAME
+	res, err := resource.New(ctx,
+		resource.WithAttributes(attribute.String("service.name", serviceName)),
+		resource.WithTelemetrySDK(),
+		resource.WithHost(),
+		resource.WithFromEnv(),
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	provider := sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exporter),
+		sdktrace.WithResource(res),
+	)
+	otel.SetTracerProvider(provider)
+	return provider.Shutdown, nil
+}